@@ -0,0 +1,378 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+// Package cli implements the cryptotax command line.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+	"cryptotax/report"
+	"cryptotax/server"
+)
+
+// reportFlag collects repeated -report values.
+type reportFlag []report.Spec
+
+func (r *reportFlag) String() string {
+	parts := []string{}
+	for _, spec := range *r {
+		if spec.Path != "" {
+			parts = append(parts, spec.Format+"="+spec.Path)
+		} else {
+			parts = append(parts, spec.Format)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r *reportFlag) Set(v string) error {
+	format, path, _ := strings.Cut(v, "=")
+	format = strings.ToLower(strings.TrimSpace(format))
+	if _, ok := report.Writers[format]; !ok {
+		return fmt.Errorf("unknown report format %q", format)
+	}
+	*r = append(*r, report.Spec{Format: format, Path: strings.TrimSpace(path)})
+	return nil
+}
+
+// command is a cryptotax subcommand. run gets the arguments after the command name.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string)
+}
+
+// commands lists the subcommands in the order they are shown in the usage text.
+var commands = []command{
+	{"report", "compute gains and income and print the summary and reports (default)", runReport},
+	{"holdings", "print the remaining inventory per wallet and commodity", runHoldings},
+	{"compare-methods", "compute the yearly gains under each cost basis method side by side", runCompareMethods},
+	{"simulate", "show the lots a sale would consume and its gain, without recording it", runSimulate},
+	{"diff", "compare two -output json results and list the yearly figures that changed", runDiff},
+	{"import", "parse and normalize transaction files and list the result", runImport},
+	{"review", "interactively review how each row was classified, re-classify rows and re-run", runReview},
+	{"verify", "process transactions and list data problems; exit status 1 if any are found", runVerify},
+	{"validate", "dry run: show what the inputs contain and what would happen, without a tax report", runValidate},
+	{"prices", "look up the price of assets on a date from -pricefile or -priceapi", runPrices},
+	{"serve", "run an HTTP API to upload files, calculate and fetch reports", runServe},
+}
+
+// Main dispatches to the subcommand named by the first argument. Without a known command name
+// the arguments are handled by report, so "cryptotax [flags] files..." keeps working.
+func Main() {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "-help", "--help":
+			usage()
+			return
+		}
+		for _, c := range commands {
+			if args[0] == c.name {
+				c.run(args[1:])
+				closeOutput()
+				return
+			}
+		}
+	}
+	runReport(args)
+	closeOutput()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags] file1.csv|dir|glob [...]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun %s <command> -h for the flags of a command.\n", os.Args[0])
+}
+
+// newFlagSet returns the flag set of a subcommand with a usage line listing its arguments.
+func newFlagSet(name, arguments string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s [flags] %s\n", os.Args[0], name, arguments)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// runReport computes gains and income and prints the summary and the requested reports.
+func runReport(args []string) {
+	var o options
+	fs := newFlagSet("report", "file1.csv|dir|glob [...]")
+	year := fs.Int("year", 0, "tax year to report (e.g. 2023). 0 = all years")
+	o.addFilterFlags(fs)
+	o.addPriceFlags(fs)
+	o.addOutputFlags(fs)
+	o.addEngineFlags(fs)
+	holdings := fs.Bool("holdings", false, "also print remaining inventory per wallet and commodity as of Dec 31 of -year (end of data when -year is 0)")
+	unrealized := fs.Bool("unrealized", false, "also print unrealized gain/loss of the holdings at Dec 31 prices of -year (today's prices when -year is 0); needs -pricefile or -priceapi")
+	detail := fs.String("detail", "", "write every consumed lot (per-disposal detail) to this CSV file")
+	period := fs.String("period", "yearly", "summary period: yearly, semiannual, quarterly or monthly")
+	output := fs.String("output", "text", "summary output format: text or json")
+	interval := fs.String("portfolio-interval", "day", "interval of the portfolio report: day or week")
+	var reports reportFlag
+	fs.Var(&reports, "report", "additional report FORMAT[=PATH] (repeatable; stdout if no path). Formats: anlage-so, e1kv, 2086, cgt-schedule, schedule-3, ppdg-3r, box3, wertschriften, modelo-721, audit, derivatives, disposals, donations, fees, harvest, holdings, import-issues, lots, mining, network-fees, reconciliation, tds, transactions, portfolio, unrealized, html, pdf, xlsx, beancount, ledger")
+	fs.Parse(args)
+	o.setup()
+	switch *period {
+	case "yearly", "semiannual", "quarterly", "monthly":
+	default:
+		log.Fatalf("unknown -period %q (expected yearly, semiannual, quarterly or monthly)", *period)
+	}
+	if *output != "text" && *output != "json" {
+		log.Fatalf("unknown -output %q (expected text or json)", *output)
+	}
+	if *interval != "day" && *interval != "week" {
+		log.Fatalf("unknown -portfolio-interval %q (expected day or week)", *interval)
+	}
+	for _, r := range reports {
+		if r.Format == "portfolio" {
+			o.series = *interval
+		}
+	}
+	files := inputFiles(fs.Args())
+	if len(files) == 0 && o.loadState == "" && o.opening == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	o.openPrices()
+	all, err := o.loadTransactions(files)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Verbose listing: show transactions that match the command-line wallet and commodity filters
+	if o.verbose {
+		fmt.Println("Transactions matching filters:")
+		printTransactions(all)
+	}
+	state, err := o.process(all)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if o.miningExpenses != "" {
+		reports = append(reports, report.Spec{Format: "mining"})
+	}
+	if r, ok := engine.Jurisdictions[o.jurisdiction]; ok && r.Report() != "" {
+		reports = append(reports, report.Spec{Format: r.Report()})
+	}
+
+	// print results
+	if *output == "json" {
+		if err := report.WriteJSON(os.Stdout, state, *year); err != nil {
+			log.Fatalf("error writing json: %v", err)
+		}
+	} else if o.from != "" || o.to != "" || o.asOf != "" {
+		to := o.to
+		if o.asOf != "" {
+			to = o.asOf
+		}
+		report.PrintRangeSummary(state, o.from, to, o.color)
+	} else if *period != "yearly" {
+		report.PrintPeriodSummary(state, *year, *period, o.color)
+	} else {
+		report.PrintSummary(state, *year, o.defaultWallets, o.commodityFilter, o.color)
+	}
+	if *output != "json" && len(state.Derivatives) > 0 {
+		report.WriteDerivatives(os.Stdout, state, *year)
+	}
+	if *output != "json" {
+		report.WriteDonations(os.Stdout, state, *year)
+		report.WriteNetworkFees(os.Stdout, state, *year)
+		report.WriteWithholding(os.Stdout, state, *year)
+		report.WriteReconciliation(os.Stdout, state, *year)
+		report.WriteImportIssues(os.Stdout, state, *year)
+	}
+	if *holdings {
+		reports = append(reports, report.Spec{Format: "holdings"})
+	}
+	if *unrealized {
+		reports = append(reports, report.Spec{Format: "unrealized"})
+	}
+	if *detail != "" {
+		reports = append(reports, report.Spec{Format: "disposals", Path: *detail})
+	}
+	if err := report.WriteAll(reports, state, *year); err != nil {
+		log.Fatalf("report error: %v", err)
+	}
+}
+
+// runHoldings prints the inventory left at the end of -year (end of data when 0).
+func runHoldings(args []string) {
+	var o options
+	fs := newFlagSet("holdings", "file1.csv|dir|glob [...]")
+	year := fs.Int("year", 0, "print holdings as of Dec 31 of this year. 0 = end of data")
+	o.addFilterFlags(fs)
+	o.addPriceFlags(fs)
+	o.addOutputFlags(fs)
+	o.addEngineFlags(fs)
+	unrealized := fs.Bool("unrealized", false, "also print unrealized gain/loss at Dec 31 prices of -year (today's prices when -year is 0); needs -pricefile or -priceapi")
+	fs.Parse(args)
+	o.setup()
+	files := inputFiles(fs.Args())
+	if len(files) == 0 && o.loadState == "" && o.opening == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	o.openPrices()
+	_, state := o.run(files)
+	reports := []report.Spec{{Format: "holdings"}}
+	if *unrealized {
+		reports = append(reports, report.Spec{Format: "unrealized"})
+	}
+	if err := report.WriteAll(reports, state, *year); err != nil {
+		log.Fatalf("report error: %v", err)
+	}
+}
+
+// runImport parses the files and lists the normalized transactions without processing them.
+func runImport(args []string) {
+	var o options
+	fs := newFlagSet("import", "file1.csv|dir|glob [...]")
+	o.addFilterFlags(fs)
+	o.addPriceFlags(fs)
+	o.addOutputFlags(fs)
+	fs.Parse(args)
+	o.setup()
+	files := inputFiles(fs.Args())
+	if len(files) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	o.openPrices()
+	all, err := o.loadTransactions(files)
+	o.saveCache()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Transactions (%d):\n", len(all))
+	printTransactions(all)
+	report.WriteImportIssues(os.Stdout, &engine.State{ImportIssues: o.issues}, 0)
+}
+
+// runVerify processes the files and lists the warnings collected on the way.
+func runVerify(args []string) {
+	var o options
+	fs := newFlagSet("verify", "file1.csv|dir|glob [...]")
+	o.addFilterFlags(fs)
+	o.addPriceFlags(fs)
+	o.addOutputFlags(fs)
+	o.addEngineFlags(fs)
+	fs.Parse(args)
+	o.setup()
+	files := inputFiles(fs.Args())
+	if len(files) == 0 && o.loadState == "" && o.opening == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	o.openPrices()
+	all, state := o.run(files)
+	if len(state.Warnings) == 0 && len(state.ImportIssues) == 0 {
+		fmt.Printf("OK: %d transactions processed without problems\n", len(all))
+		return
+	}
+	report.WriteImportIssues(os.Stdout, state, 0)
+	if len(state.Warnings) > 0 {
+		fmt.Printf("%d problems found in %d transactions:\n", len(state.Warnings), len(all))
+		for _, w := range state.Warnings {
+			fmt.Println("  " + w)
+		}
+	}
+	exit(1)
+}
+
+// runPrices prints the price of each asset on a date, e.g. "prices -priceapi coingecko BTC,ETH 2024-12-31".
+func runPrices(args []string) {
+	var o options
+	fs := newFlagSet("prices", "ASSET[,ASSET...] [YYYY-MM-DD]")
+	o.addPriceFlags(fs)
+	o.addOutputFlags(fs)
+	fs.Parse(args)
+	o.setup()
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	at := time.Now().UTC()
+	if fs.NArg() == 2 {
+		d, err := time.Parse("2006-01-02", fs.Arg(1))
+		if err != nil {
+			log.Fatalf("invalid date %q (expected YYYY-MM-DD)", fs.Arg(1))
+		}
+		at = d
+	}
+	o.openPrices()
+	if o.prices == nil {
+		log.Fatalf("no price source: use -pricefile and/or -priceapi")
+	}
+	defer o.saveCache()
+	currency := o.priceCurrency()
+	failed := false
+	for _, asset := range splitList(fs.Arg(0)) {
+		asset = strings.ToUpper(asset)
+		p, err := o.prices.Price(asset, currency, at)
+		if err != nil {
+			log.Printf("%s: %v", asset, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s %s %s %s\n", asset, at.Format("2006-01-02"), p.String(), currency)
+	}
+	if failed {
+		o.saveCache()
+		exit(1)
+	}
+}
+
+// runServe serves the HTTP API (see server.Server.Handler) until interrupted. The tax treatment
+// and price flags apply to every calculation.
+func runServe(args []string) {
+	var o options
+	fs := newFlagSet("serve", "")
+	addr := fs.String("addr", "127.0.0.1:8080", "address to listen on")
+	dir := fs.String("dir", "", "directory for uploaded files (default: a new temporary directory)")
+	ui := fs.Bool("ui", false, "also serve the web dashboard at / (summaries, holdings, income and a searchable transaction table)")
+	o.addFilterFlags(fs)
+	o.addPriceFlags(fs)
+	o.addEngineFlags(fs)
+	fs.Parse(args)
+	o.setup()
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *dir == "" {
+		d, err := os.MkdirTemp("", "cryptotax-uploads-")
+		if err != nil {
+			log.Fatalf("error creating upload directory: %v", err)
+		}
+		*dir = d
+	}
+	o.series = "day" // for the portfolio report
+	o.openPrices()
+	srv, err := server.New(*dir, func(files []string) ([]engine.Tx, *engine.State, error) {
+		all, err := o.loadTransactions(files)
+		if err != nil {
+			return nil, nil, err
+		}
+		state, err := o.process(all)
+		return all, state, err
+	})
+	if err != nil {
+		log.Fatalf("error creating upload directory: %v", err)
+	}
+	srv.UI = *ui
+	log.Printf("serving on http://%s (uploads in %s)", *addr, *dir)
+	log.Fatal(http.ListenAndServe(*addr, srv.Handler()))
+}
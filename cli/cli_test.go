@@ -0,0 +1,41 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package cli
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestReportGolden runs the report command on the sample with -holdings. The yearly figures and
+// the holdings are those the command line printed before the packages were split.
+func TestReportGolden(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "report.txt")
+	args, stdout := os.Args, os.Stdout
+	defer func() { os.Args, os.Stdout = args, stdout }()
+	os.Args = []string{"cryptotax", "report", "-o", out, "-holdings", "../testdata/sample.csv"}
+	Main()
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.WriteFile("testdata/report.golden", got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile("testdata/report.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("report output differs:\n--- got\n%s--- want\n%s", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package cli
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"cryptotax/engine"
+	"cryptotax/report"
+)
+
+// runCompareMethods processes the same transactions once per cost basis method and prints the
+// yearly gains of each side by side.
+func runCompareMethods(args []string) {
+	var o options
+	fs := newFlagSet("compare-methods", "file1.csv|dir|glob [...]")
+	year := fs.Int("year", 0, "compare only this year. 0 = all years")
+	methods := fs.String("methods", strings.Join(engine.Methods, ","), "comma-separated cost basis methods to compare")
+	o.addFilterFlags(fs)
+	o.addPriceFlags(fs)
+	o.addOutputFlags(fs)
+	o.addEngineFlags(fs)
+	fs.Parse(args)
+	o.setup()
+	files := inputFiles(fs.Args())
+	if len(files) == 0 && o.loadState == "" && o.opening == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	list := splitList(*methods)
+	for _, m := range list {
+		if !engine.IsMethod(m) {
+			log.Fatalf("unknown method %q in -methods (expected %s)", m, strings.Join(engine.Methods, ", "))
+		}
+	}
+	if len(list) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	o.saveState = ""
+	o.openPrices()
+	all, err := o.loadTransactions(files)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var results []report.MethodResult
+	prescribed := false
+	for _, m := range list {
+		o.method = m
+		state, err := o.process(all)
+		if err != nil {
+			log.Fatalf("%s: %v", m, err)
+		}
+		prescribed = prescribed || state.Method != m || state.GlobalFIFO
+		results = append(results, report.MethodResult{Method: m, State: state})
+	}
+	if prescribed {
+		log.Printf("-jurisdiction %s prescribes the cost basis method; it is used in every column", o.jurisdiction)
+	}
+	report.PrintMethodComparison(os.Stdout, results, *year, o.color)
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package cli
+
+import (
+	"log"
+	"os"
+
+	"cryptotax/report"
+)
+
+// runDiff compares two results written with -output json, e.g. before and after adding a missing
+// export or under two cost basis methods, and lists the yearly figures per wallet and asset that
+// changed. The exit status is 1 when they differ, like diff(1).
+func runDiff(args []string) {
+	fs := newFlagSet("diff", "before.json after.json")
+	noColor := fs.Bool("no-color", false, "do not color the changes")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	a, err := report.ReadResult(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := report.ReadResult(fs.Arg(1))
+	if err != nil {
+		log.Fatal(err)
+	}
+	color := !*noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	if report.PrintResultDiff(os.Stdout, a, b, fs.Arg(0), fs.Arg(1), color) > 0 {
+		os.Exit(1)
+	}
+}
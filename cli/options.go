@@ -0,0 +1,675 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+	"cryptotax/importer"
+	"cryptotax/pricing"
+	"github.com/shopspring/decimal"
+)
+
+// options holds the flags shared by the subcommands. Each command registers the groups it needs
+// on its own flag set; the others keep their defaults.
+type options struct {
+	// input and filters (addFilterFlags)
+	wallets     string
+	commodities string
+	verbose     bool
+	overrides   string
+	classify    string
+	strict      bool
+	sourceTZ    string
+	taxTZ       string
+	aliases     string
+	numbers     string
+	walletMap   string
+	sheet       string
+	formats     string
+
+	// output (addOutputFlags)
+	outFile string
+	quiet   bool
+	noColor bool
+	color   bool // color gains and losses in the summary, set by redirectOutput
+
+	// prices and currency (addPriceFlags)
+	priceFile     string
+	priceAPI      string
+	priceCache    string
+	priceCacheTTL time.Duration
+	offline       bool
+	stablecoins   string
+	base          string
+	audit         bool
+
+	// tax treatment and state (addEngineFlags)
+	airdrop        string
+	fork           string
+	wrapPairs      string
+	rebase         string
+	cryptoFees     bool
+	fxGains        bool
+	gift           string
+	migrations     string
+	forks          string
+	noBuiltins     bool
+	assetIDs       string
+	miningExpenses string
+	saveState      string
+	loadState      string
+	opening        string
+	balances       string
+	lots           string
+	balanceTol     string
+	matchTransfers time.Duration
+	transferMaxFee float64
+	transferFee    string
+	missingBasis   string
+	missingDate    string
+	holdingRule    string
+	jurisdiction   string
+	personalUse    string
+	method         string
+	netting        string
+	ordinaryLimit  string
+	valuationDates string
+	from           string
+	to             string
+	asOf           string
+
+	defaultWallets  []string
+	commodityFilter []string
+	assets          *engine.Assets  // -asset-aliases and -stablecoins-as-fiat
+	input           importer.Config // how the input files are read
+	taxLocation     *time.Location
+	missingBasisAt  time.Time
+	personalLimit   decimal.Decimal
+	ordinaryLimitAt decimal.Decimal
+	valuationAt     []time.Time
+	fromTime        time.Time // start of -from
+	toTime          time.Time // end of -to or -as-of (exclusive)
+	asOfTime        time.Time // last second of -as-of
+	series          string    // State.SeriesInterval: "day" or "week" for the portfolio report
+	issues          []engine.ImportIssue
+	prices          engine.PriceSource
+	cache           *pricing.CachedSource
+}
+
+func (o *options) addFilterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.wallets, "wallet", "", "comma-separated wallet(s) to include (default: all). If not specified each file name becomes a wallet")
+	fs.StringVar(&o.walletMap, "wallet-map", "", "CSV (match,pattern,wallet) naming wallets by file name or account column, with glob or /regexp/ patterns")
+	fs.StringVar(&o.commodities, "commodity", "", "comma-separated commodity symbols to include (default: all). Example: BTC,ETH")
+	fs.BoolVar(&o.verbose, "v", false, "verbose logging")
+	fs.StringVar(&o.sourceTZ, "source-timezone", "", "zone of timestamps without an offset: ZONE for all files, or comma-separated SOURCE=ZONE where SOURCE is a file or format name (default UTC)")
+	fs.StringVar(&o.formats, "format", "", "comma-separated FILE=FORMAT forcing the importer of input files (file name or pattern, e.g. kraken-*.csv=kraken, other.csv=generic) instead of detecting it from the header")
+	fs.StringVar(&o.sheet, "sheet", "", "sheet of .xlsx inputs to read: SHEET for all workbooks, or comma-separated FILE=SHEET (default the first sheet)")
+	fs.StringVar(&o.taxTZ, "tax-timezone", "UTC", "time zone of the tax year: a disposal at 23:30 Dec 31 UTC falls into the next year in Europe/Berlin")
+	fs.StringVar(&o.numbers, "number-format", "en", "how numbers in generic CSV files are written: en (1,234.56) or eu (1.234,56) for all of them, or comma-separated SOURCE=FORMAT where SOURCE is a file name or generic; exchange exports keep their own format")
+	fs.BoolVar(&o.strict, "strict", false, "fail on negative balances, rows without a timestamp, malformed numbers and unknown transaction types instead of warning or guessing")
+	fs.StringVar(&o.aliases, "asset-aliases", "", "extra comma-separated asset aliases ALIAS=ASSET read as the same asset, e.g. XBT=BTC (Kraken's X/Z prefixes and ETH2.S are built in)")
+	fs.StringVar(&o.overrides, "overrides", "", "CSV (file,line,type) re-classifying single input rows, e.g. written by the review command")
+	fs.StringVar(&o.classify, "classify", "", "CSV of classification rules (type,description,asset,amount,source,wallet,operation) mapping matching rows to buy, sell, income:staking, transfer, ignore, ...; the first matching rule wins")
+}
+
+func (o *options) addOutputFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.outFile, "o", "", "write the output (summary and reports without a path) to this file instead of stdout")
+	fs.BoolVar(&o.quiet, "quiet", false, "print nothing but errors; the output still goes to -o when set")
+	fs.BoolVar(&o.noColor, "no-color", false, "do not color gains and losses in the summary (off anyway when the output is not a terminal or NO_COLOR is set)")
+}
+
+func (o *options) addPriceFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.priceFile, "pricefile", "", "CSV with historical prices (asset,timestamp,price,currency) used to value income without fiat cost")
+	fs.StringVar(&o.priceAPI, "priceapi", "", "external price source for lookups not covered by -pricefile: coingecko (default: none)")
+	fs.StringVar(&o.priceCache, "price-cache", pricing.DefaultCachePath(), "on-disk cache for external price/FX lookups")
+	fs.DurationVar(&o.priceCacheTTL, "price-cache-ttl", 30*24*time.Hour, "refetch cached prices older than this (0 = never expire)")
+	fs.BoolVar(&o.offline, "offline", false, "never call external price APIs; only -pricefile and cached prices are used and a missing price is an error")
+	fs.StringVar(&o.stablecoins, "stablecoins-as-fiat", "", "comma-separated stablecoins treated as fiat at their peg instead of tracked commodities (USDT,USDC,DAI,... or all; COIN=USD for others)")
+	fs.StringVar(&o.base, "base", "", "report all gains, income and basis in this fiat currency (e.g. EUR, USD); costs in other currencies are converted at the daily rate")
+	fs.BoolVar(&o.audit, "audit", false, "print every currency conversion applied for -base with the FX rate used")
+}
+
+func (o *options) addEngineFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.from, "from", "", "report gains and income from this date (YYYY-MM-DD) on; earlier transactions only build the inventory")
+	fs.StringVar(&o.to, "to", "", "ignore transactions after this date (YYYY-MM-DD), e.g. the end of a fiscal year")
+	fs.StringVar(&o.asOf, "as-of", "", "take holdings and cumulative gains at the end of this date (YYYY-MM-DD): later transactions are left out and holdings are valued at that day's prices")
+	fs.StringVar(&o.airdrop, "airdrop", "income", "tax treatment of airdrops: income (fair market value on receipt) or zero-cost (acquisition with zero basis)")
+	fs.StringVar(&o.fork, "fork", "zero", "tax treatment of chain-split coins (type fork): zero (zero basis), income (fair market value on receipt) or split (share of the parent asset's basis by market value)")
+	fs.StringVar(&o.wrapPairs, "wrap-pairs", "", "extra comma-separated asset pairs A=B whose swaps (wrapping, bridging) keep basis and holding period, e.g. STETH=ETH")
+	fs.StringVar(&o.rebase, "rebase", "income", "balance increases of rebasing/reward-bearing tokens (rebase and balance snapshot rows): income (market value on the day) or adjust (spread over existing lots keeping basis)")
+	fs.BoolVar(&o.cryptoFees, "crypto-fees", false, "treat fees charged in a crypto asset (fee_asset column, fee rows) as disposals of that asset at market value")
+	fs.BoolVar(&o.fxGains, "fx-gains", false, "hold fiat currencies other than -base received from sales as inventory and realize exchange gains when they are spent on purchases")
+	fs.StringVar(&o.gift, "gift", "nontaxable", "tax treatment of gifts sent: nontaxable (lots leave at basis) or taxable (disposal at fair market value)")
+	fs.StringVar(&o.assetIDs, "asset-ids", "", "CSV (chain,contract,symbol) naming on-chain tokens by contract address; tokens sharing a ticker are otherwise kept apart as TICKER.0xabcd")
+	fs.StringVar(&o.migrations, "migrations", "", "CSV of token migrations/renames (from,to[,date[,ratio]]) carrying basis and acquisition dates to the new ticker; rows replace the built-in ones of the same asset")
+	fs.StringVar(&o.forks, "forks", "", "CSV of chain splits (asset,parent[,date]) naming the parent of fork rows; airdrops of the forked coins are forks. Rows replace the built-in ones of the same asset")
+	fs.BoolVar(&o.noBuiltins, "no-builtin-events", false, "do not apply the built-in chain splits (BCH, BTG, BSV, ETC, ETHW) and ticker changes (LUNA/LUNC, UST/USTC, MATIC/POL)")
+	fs.StringVar(&o.miningExpenses, "mining-expenses", "", "CSV of mining expenses (date,amount,category,description[,currency]) netted against mining income in a mining report")
+	fs.StringVar(&o.saveState, "save-state", "", "write the remaining inventory (lots with dates and costs) to this JSON file as the opening state of a later run")
+	fs.DurationVar(&o.matchTransfers, "match-transfers", 0, "pair a withdrawal with a deposit of the same asset into another wallet up to this far apart (e.g. 24h) as a transfer keeping basis (0 = off)")
+	fs.Float64Var(&o.transferMaxFee, "transfer-max-fee", 0.05, "largest network fee of a matched transfer, as a fraction of the amount sent")
+	fs.StringVar(&o.missingBasis, "missing-basis", "warn", "coins sold beyond the inventory and buys without a cost: warn (not taxed, warning), zero (zero basis), fmv (market value on -missing-basis-date) or abort")
+	fs.StringVar(&o.missingDate, "missing-basis-date", "", "assumed acquisition date (YYYY-MM-DD) of coins without a known basis (default: the disposal date)")
+	fs.StringVar(&o.jurisdiction, "jurisdiction", "", "apply a country's rules and add its tax return report: at (Austria, E 1kv), au (Australia, CGT schedule), ca (Canada, Schedule 3), ch (Switzerland, Wertschriftenverzeichnis), de (Germany, Anlage SO), es (Spain, Modelo 721), fr (France, 2086), nl (Netherlands, Box 3), rs (Serbia, PPDG-3R), us (United States, netting by term)")
+	fs.StringVar(&o.valuationDates, "valuation-date", "", "comma-separated dates (YYYY-MM-DD) to value the holdings on in the box3 report instead of January 1")
+	fs.StringVar(&o.personalUse, "personal-use-limit", "0", "coins spent on goods or services with a basis below this amount are exempt personal use assets in the cgt-schedule report (Australia: 10000; 0 = off)")
+	fs.StringVar(&o.method, "method", "fifo", "cost basis method: fifo (first in, first out per wallet), lifo (last in, first out), hifo (highest cost first) or acb (average cost of all coins of an asset)")
+	fs.StringVar(&o.netting, "netting", "", "how the summary nets the losses of a year: pooled (all gains against all losses) or us (short and long term separately, then against each other, with up to -ordinary-loss-limit of a net loss offsetting ordinary income) (default: the -jurisdiction's, else none)")
+	fs.StringVar(&o.ordinaryLimit, "ordinary-loss-limit", "3000", "net capital loss deductible from ordinary income per year under -netting us (1500 when married filing separately)")
+	fs.StringVar(&o.holdingRule, "holding-rule", "more-than", "long-term holding period on calendar dates: more-than (sold after the anniversary of the purchase; US, Germany) or at-least (the anniversary counts)")
+	fs.StringVar(&o.transferFee, "transfer-fee", "deductible", "network fee of transfers between own wallets: deductible (leaves at basis, listed as a cost), disposal (sold at market value) or basis (added to the basis of the coins moved)")
+	fs.StringVar(&o.balances, "balances", "", "CSV of declared balances (wallet,asset,date,balance[,tolerance]) to reconcile the computed inventory against")
+	fs.StringVar(&o.lots, "lots", "", "CSV of disposal instructions (lot_id,amount,reference_id or date[,wallet]) naming the lots a sale takes first instead of the oldest; -report lots lists the lot IDs")
+	fs.StringVar(&o.balanceTol, "balance-tolerance", "0.00000001", "largest difference between a declared and computed balance that still matches")
+	fs.StringVar(&o.opening, "opening", "", "CSV of coins bought before the earliest input (wallet,asset,amount,date,unit_cost) added to the opening inventory")
+	fs.StringVar(&o.loadState, "load-state", "", "start from the inventory saved with -save-state; transactions up to the time it was saved are skipped")
+}
+
+// setup validates the parsed flags and prepares the filter lists.
+func (o *options) setup() {
+	o.redirectOutput()
+	o.base = strings.ToUpper(strings.TrimSpace(o.base))
+	o.assets = &engine.Assets{}
+	if err := o.assets.SetFiatEquivalents(o.stablecoins); err != nil {
+		log.Fatalf("invalid -stablecoins-as-fiat: %v", err)
+	}
+	if err := o.assets.AddAliases(o.aliases); err != nil {
+		log.Fatalf("invalid -asset-aliases: %v", err)
+	}
+	o.input = importer.Config{Assets: o.assets, Strict: o.strict}
+	if err := o.input.LoadWalletMap(o.walletMap); err != nil {
+		log.Fatalf("invalid -wallet-map: %v", err)
+	}
+	if err := o.input.SetSourceTimezones(o.sourceTZ); err != nil {
+		log.Fatalf("invalid -source-timezone: %v", err)
+	}
+	if err := o.input.SetNumberFormats(o.numbers); err != nil {
+		log.Fatalf("invalid -number-format: %v", err)
+	}
+	o.input.SetSheets(o.sheet)
+	if err := o.input.SetFormats(o.formats); err != nil {
+		log.Fatalf("invalid -format: %v", err)
+	}
+	o.taxLocation = time.UTC
+	if o.taxTZ != "" {
+		loc, err := time.LoadLocation(o.taxTZ)
+		if err != nil {
+			log.Fatalf("invalid -tax-timezone: %v", err)
+		}
+		o.taxLocation = loc
+	}
+	if o.fxGains && o.base == "" {
+		log.Fatalf("-fx-gains needs -base: exchange gains are measured in the base currency")
+	}
+	if o.airdrop != "" && o.airdrop != "income" && o.airdrop != "zero-cost" {
+		log.Fatalf("unknown -airdrop %q (expected income or zero-cost)", o.airdrop)
+	}
+	if o.rebase != "" && o.rebase != "income" && o.rebase != "adjust" {
+		log.Fatalf("unknown -rebase %q (expected income or adjust)", o.rebase)
+	}
+	if o.gift != "" && o.gift != "nontaxable" && o.gift != "taxable" {
+		log.Fatalf("unknown -gift %q (expected nontaxable or taxable)", o.gift)
+	}
+	if o.transferFee != "" && o.transferFee != "deductible" && o.transferFee != "disposal" && o.transferFee != "basis" {
+		log.Fatalf("unknown -transfer-fee %q (expected deductible, disposal or basis)", o.transferFee)
+	}
+	if o.fork != "" && o.fork != "zero" && o.fork != "income" && o.fork != "split" {
+		log.Fatalf("unknown -fork %q (expected zero, income or split)", o.fork)
+	}
+	if _, ok := engine.Jurisdictions[o.jurisdiction]; o.jurisdiction != "" && !ok {
+		log.Fatalf("unknown -jurisdiction %q (expected %s)", o.jurisdiction, engine.JurisdictionCodes())
+	}
+	if o.method != "" && !engine.IsMethod(o.method) {
+		log.Fatalf("unknown -method %q (expected %s)", o.method, strings.Join(engine.Methods, ", "))
+	}
+	if o.personalUse != "" {
+		l, err := decimal.NewFromString(o.personalUse)
+		if err != nil || l.IsNegative() {
+			log.Fatalf("invalid -personal-use-limit %q (expected an amount)", o.personalUse)
+		}
+		o.personalLimit = l
+	}
+	if o.netting != "" && o.netting != "pooled" && o.netting != "us" {
+		log.Fatalf("unknown -netting %q (expected pooled or us)", o.netting)
+	}
+	if o.ordinaryLimit != "" {
+		l, err := decimal.NewFromString(o.ordinaryLimit)
+		if err != nil || l.IsNegative() {
+			log.Fatalf("invalid -ordinary-loss-limit %q (expected an amount)", o.ordinaryLimit)
+		}
+		o.ordinaryLimitAt = l
+	}
+	if o.holdingRule != "" && o.holdingRule != "more-than" && o.holdingRule != "at-least" {
+		log.Fatalf("unknown -holding-rule %q (expected more-than or at-least)", o.holdingRule)
+	}
+	switch o.missingBasis {
+	case "", "warn", "zero", "fmv", "abort":
+	default:
+		log.Fatalf("unknown -missing-basis %q (expected warn, zero, fmv or abort)", o.missingBasis)
+	}
+	if o.missingDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", o.missingDate, o.taxLocation)
+		if err != nil {
+			log.Fatalf("invalid -missing-basis-date: %v", err)
+		}
+		o.missingBasisAt = t.UTC()
+	}
+	for _, d := range splitList(o.valuationDates) {
+		t, err := time.ParseInLocation("2006-01-02", d, o.taxLocation)
+		if err != nil {
+			log.Fatalf("invalid -valuation-date: %v", err)
+		}
+		o.valuationAt = append(o.valuationAt, t)
+	}
+	if o.from != "" {
+		t, err := time.ParseInLocation("2006-01-02", o.from, o.taxLocation)
+		if err != nil {
+			log.Fatalf("invalid -from: %v", err)
+		}
+		o.fromTime = t
+	}
+	if o.to != "" {
+		t, err := time.ParseInLocation("2006-01-02", o.to, o.taxLocation)
+		if err != nil {
+			log.Fatalf("invalid -to: %v", err)
+		}
+		o.toTime = t.AddDate(0, 0, 1)
+		if !o.fromTime.IsZero() && !o.toTime.After(o.fromTime) {
+			log.Fatalf("-to %s is before -from %s", o.to, o.from)
+		}
+	}
+	if o.asOf != "" {
+		if o.to != "" {
+			log.Fatalf("use either -to or -as-of")
+		}
+		t, err := time.ParseInLocation("2006-01-02", o.asOf, o.taxLocation)
+		if err != nil {
+			log.Fatalf("invalid -as-of: %v", err)
+		}
+		o.toTime = t.AddDate(0, 0, 1)
+		o.asOfTime = o.toTime.Add(-time.Second)
+		if !o.fromTime.IsZero() && !o.toTime.After(o.fromTime) {
+			log.Fatalf("-as-of %s is before -from %s", o.asOf, o.from)
+		}
+	}
+	o.defaultWallets = splitList(o.wallets)
+	o.commodityFilter = splitList(o.commodities)
+}
+
+// output is the -o file os.Stdout points at, closed by closeOutput.
+var output *os.File
+
+// redirectOutput points os.Stdout, where the summaries and the reports without a path are written,
+// at the -o file, or discards it with -quiet. Errors still go to stderr. The summary is colored
+// only when it goes to a terminal.
+func (o *options) redirectOutput() {
+	if o.quiet {
+		o.verbose = false
+	}
+	switch {
+	case o.outFile != "":
+		f, err := os.Create(o.outFile)
+		if err != nil {
+			log.Fatalf("invalid -o: %v", err)
+		}
+		output, os.Stdout = f, f
+	case o.quiet:
+		f, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			log.Fatalf("-quiet: %v", err)
+		}
+		os.Stdout = f
+	}
+	o.color = !o.noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+}
+
+// closeOutput flushes and closes the -o file, failing when what was written to it could not be
+// saved.
+func closeOutput() {
+	if output == nil {
+		return
+	}
+	f := output
+	output = nil
+	err := f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Fatalf("-o: %v", err)
+	}
+}
+
+// exit closes the -o file and exits with code.
+func exit(code int) {
+	closeOutput()
+	os.Exit(code)
+}
+
+// isTerminal reports whether f is a terminal rather than a file or a pipe.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(v string) []string {
+	out := []string{}
+	for _, s := range strings.Split(v, ",") {
+		s = strings.TrimSpace(s)
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// openPrices sets up the price sources: the price file always wins, external lookups go through
+// the on-disk cache.
+func (o *options) openPrices() {
+	var sources pricing.ChainSource
+	if o.priceFile != "" {
+		pf, err := pricing.LoadFile(o.priceFile)
+		if err != nil {
+			log.Fatalf("error loading price file %s: %v", o.priceFile, err)
+		}
+		sources = append(sources, pf)
+	}
+	if o.priceAPI != "" || o.offline {
+		var api engine.PriceSource
+		switch strings.ToLower(o.priceAPI) {
+		case "":
+		case "coingecko":
+			api = &pricing.CoinGecko{Client: &http.Client{Timeout: 30 * time.Second}}
+		default:
+			log.Fatalf("unknown -priceapi %q", o.priceAPI)
+		}
+		var err error
+		o.cache, err = pricing.OpenCache(o.priceCache, api, o.priceCacheTTL, o.offline)
+		if err != nil {
+			log.Fatalf("error opening price cache: %v", err)
+		}
+		sources = append(sources, o.cache)
+	}
+	if len(sources) > 0 {
+		o.prices = sources
+	}
+}
+
+// priceCurrency is the currency income and holdings are valued in.
+func (o *options) priceCurrency() string {
+	if o.base != "" {
+		return o.base
+	}
+	return engine.DefaultCurrency
+}
+
+// saveCache keeps whatever was fetched, so a re-run doesn't fetch it again.
+func (o *options) saveCache() {
+	if o.cache == nil {
+		return
+	}
+	if err := o.cache.Save(); err != nil {
+		log.Printf("warning: could not save price cache: %v", err)
+	}
+}
+
+// inputFiles expands the directories and glob patterns among the file arguments of a command.
+func inputFiles(args []string) []string {
+	files, err := importer.ExpandInputs(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return files
+}
+
+// loadTransactions parses the files, applies the wallet and commodity filters and converts fiat
+// costs to -base. Rows the importers skipped are kept in o.issues. openPrices must have been called.
+func (o *options) loadTransactions(files []string) ([]engine.Tx, error) {
+	allParsed := [][]engine.Tx{}
+	o.issues = nil
+	for _, f := range files {
+		txs, issues, err := o.input.ParseFile(f, o.defaultWallets, o.verbose)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", f, err)
+		}
+		if o.strict && len(issues) > 0 {
+			return nil, fmt.Errorf("error parsing %s: line %d: %s", f, issues[0].Line, issues[0].Reason)
+		}
+		allParsed = append(allParsed, txs)
+		o.issues = append(o.issues, issues...)
+	}
+	all := importer.MergeAndSort(allParsed)
+	if o.overrides != "" {
+		ov, err := importer.LoadOverrides(o.overrides)
+		if err != nil {
+			return nil, fmt.Errorf("error loading overrides: %w", err)
+		}
+		ov.Apply(all)
+	}
+	// Years, periods and dates are taken from the tax time zone from here on
+	for i := range all {
+		all[i].Time = all[i].Time.In(o.taxLocation)
+	}
+
+	if !o.toTime.IsZero() {
+		n := sort.Search(len(all), func(i int) bool { return !all[i].Time.Before(o.toTime) })
+		all = all[:n]
+	}
+
+	// If commodity filter provided, filter transactions before processing to avoid tracking unwanted commodities
+	if len(o.commodityFilter) > 0 {
+		cset := map[string]bool{}
+		for _, c := range o.commodityFilter {
+			cset[strings.ToLower(c)] = true
+		}
+		filtered := []engine.Tx{}
+		for _, tx := range all {
+			if tx.Commodity == "" {
+				continue
+			}
+			if cset[strings.ToLower(tx.Commodity)] {
+				filtered = append(filtered, tx)
+			}
+		}
+		all = filtered
+	}
+
+	// If wallet filter provided, filter transactions before processing to avoid tracking unwanted wallets
+	if len(o.defaultWallets) > 0 {
+		wset := map[string]bool{}
+		for _, w := range o.defaultWallets {
+			wset[w] = true
+		}
+		filtered := []engine.Tx{}
+		for _, tx := range all {
+			if wset[tx.Wallet] {
+				filtered = append(filtered, tx)
+			}
+		}
+		all = filtered
+	}
+
+	// Convert fiat costs and fees to the base currency before anything is listed or processed
+	if o.base != "" {
+		if err := engine.ConvertToBase(all, o.base, o.prices, o.assets); err != nil {
+			o.saveCache()
+			return nil, fmt.Errorf("currency conversion error: %w", err)
+		}
+	}
+	if o.audit {
+		fmt.Printf("Currency conversions (base %s):\n", o.base)
+		for _, tx := range all {
+			if tx.FXRate.IsZero() {
+				continue
+			}
+			fmt.Printf("  %s  wallet=%s  type=%s  amt=%s %s  cost=%s fee=%s %s  rate=%s  src=%s ref=%s\n",
+				tx.Time.Format(time.RFC3339), tx.Wallet, tx.Type, tx.Amount.String(), tx.Commodity, tx.Cost.String(), tx.Fee.String(), tx.Currency, tx.OrigCurrency+"/"+tx.Currency+"="+tx.FXRate.String(), tx.SourceFile, tx.ReferenceID)
+		}
+	}
+	return all, nil
+}
+
+// run loads and processes the files, exiting on the first error.
+func (o *options) run(files []string) ([]engine.Tx, *engine.State) {
+	all, err := o.loadTransactions(files)
+	if err != nil {
+		log.Fatal(err)
+	}
+	state, err := o.process(all)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return all, state
+}
+
+// printTransactions lists the normalized transactions, one per line.
+func printTransactions(all []engine.Tx) {
+	for _, tx := range all {
+		fx := ""
+		if !tx.FXRate.IsZero() {
+			fx = "  fx=" + tx.OrigCurrency + "/" + tx.Currency + "=" + tx.FXRate.String()
+		}
+		fmt.Printf("  %s  wallet=%s  type=%s  amt=%s %s  cost=%s fee=%s%s src=%s ref=%s\n",
+			tx.Time.Format(time.RFC3339), tx.Wallet, tx.Type, tx.Amount.String(), tx.Commodity, tx.Cost.String(), tx.Fee.String(), fx, tx.SourceFile, tx.ReferenceID)
+	}
+}
+
+// process runs the engine over the transactions with the configured tax treatments, loading and
+// saving the opening state when asked to.
+func (o *options) process(all []engine.Tx) (*engine.State, error) {
+	// Create state with filters so verbose logging can respect them
+	state := engine.NewState(o.verbose, o.defaultWallets, o.commodityFilter)
+	state.Assets = o.assets
+	state.Prices = o.prices
+	state.PriceCurrency = o.priceCurrency()
+	state.BaseCurrency = o.base
+	state.AirdropTreatment = o.airdrop
+	state.GiftTreatment = o.gift
+	state.CryptoFees = o.cryptoFees
+	state.FXGains = o.fxGains
+	state.RebaseTreatment = o.rebase
+	state.ForkTreatment = o.fork
+	state.Strict = o.strict
+	state.ImportIssues = o.issues
+	state.TransferWindow = o.matchTransfers
+	state.TransferMaxFee = decimal.NewFromFloat(o.transferMaxFee)
+	state.TransferFees = o.transferFee
+	state.MissingBasis = o.missingBasis
+	state.HoldingRule = o.holdingRule
+	state.ReportFrom = o.fromTime
+	state.MissingBasisDate = o.missingBasisAt
+	state.PersonalUseLimit = o.personalLimit
+	state.ValuationDates = o.valuationAt
+	state.AsOf = o.asOfTime
+	state.SeriesInterval = o.series
+	if o.method != "" {
+		state.Method = o.method
+	}
+	if r, ok := engine.Jurisdictions[o.jurisdiction]; ok {
+		state.Rules = r
+		r.Configure(state)
+	}
+	if o.netting != "" {
+		state.LossNetting = o.netting
+	}
+	if o.ordinaryLimit != "" {
+		state.OrdinaryLossLimit = o.ordinaryLimitAt
+	}
+	if err := engine.AddWrapPairs(state.WrapPairs, o.wrapPairs); err != nil {
+		return nil, fmt.Errorf("invalid -wrap-pairs: %w", err)
+	}
+	if o.loadState != "" {
+		if err := engine.LoadState(o.loadState, state); err != nil {
+			return nil, fmt.Errorf("error loading state: %w", err)
+		}
+	}
+	if o.opening != "" {
+		lots, err := importer.LoadOpening(o.opening, o.taxLocation, o.assets)
+		if err != nil {
+			return nil, fmt.Errorf("error loading opening balances: %w", err)
+		}
+		state.AddLots(lots)
+	}
+	if o.assetIDs != "" {
+		ids, err := importer.LoadAssetIDs(o.assetIDs)
+		if err != nil {
+			return nil, fmt.Errorf("error loading asset ids: %w", err)
+		}
+		state.AssetIDs = ids
+	}
+	if o.noBuiltins {
+		state.Migrations = nil
+		state.Forks = nil
+	}
+	if o.migrations != "" {
+		ms, err := importer.LoadMigrations(o.migrations)
+		if err != nil {
+			return nil, fmt.Errorf("error loading migrations: %w", err)
+		}
+		state.Migrations = engine.MergeMigrations(state.Migrations, ms)
+	}
+	if o.classify != "" {
+		rules, err := importer.LoadClassRules(o.classify)
+		if err != nil {
+			return nil, fmt.Errorf("error loading classification rules: %w", err)
+		}
+		state.ClassRules = rules
+	}
+	if o.forks != "" {
+		fks, err := importer.LoadForks(o.forks)
+		if err != nil {
+			return nil, fmt.Errorf("error loading forks: %w", err)
+		}
+		state.Forks = engine.MergeForks(state.Forks, fks)
+	}
+	if o.balances != "" {
+		tol, err := decimal.NewFromString(o.balanceTol)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -balance-tolerance %q", o.balanceTol)
+		}
+		checks, err := importer.LoadBalances(o.balances, o.taxLocation, tol.Abs())
+		if err != nil {
+			return nil, fmt.Errorf("error loading balances: %w", err)
+		}
+		state.BalanceChecks = checks
+	}
+	if o.lots != "" {
+		picks, err := importer.LoadLotPicks(o.lots, o.taxLocation)
+		if err != nil {
+			return nil, fmt.Errorf("error loading lot instructions: %w", err)
+		}
+		state.LotPicks = picks
+	}
+	if o.miningExpenses != "" {
+		exp, err := importer.LoadExpenses(o.miningExpenses, o.base, o.prices)
+		if err != nil {
+			o.saveCache()
+			return nil, fmt.Errorf("error loading mining expenses: %w", err)
+		}
+		state.MiningExpenses = exp
+	}
+	err := engine.ProcessTransactions(state, all)
+	// keep whatever was fetched even if processing failed, so a re-run doesn't fetch it again
+	o.saveCache()
+	if err != nil {
+		return nil, fmt.Errorf("processing error: %w", err)
+	}
+	if o.saveState != "" {
+		if err := engine.SaveState(o.saveState, state); err != nil {
+			return nil, fmt.Errorf("error saving state: %w", err)
+		}
+	}
+	return state, nil
+}
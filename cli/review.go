@@ -0,0 +1,348 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+	"cryptotax/importer"
+	"github.com/shopspring/decimal"
+)
+
+const reviewPageSize = 20
+
+const reviewHelp = `Commands:
+  l [N]        list rows (from row N)       n / p      next / previous page
+  / [TEXT]     show only rows containing TEXT (no TEXT: all rows)
+  s N          show row N: raw columns, lots added and consumed, disposals
+  t N TYPE     re-classify row N as TYPE    u N        undo the re-classification of row N
+  r            re-run the calculation with the re-classifications and show the totals
+  types        list the types with a dedicated handler
+  w            write the re-classifications to -overrides
+  q            quit                         h          this help
+`
+
+// review is the state of an interactive review session. rows are the parsed transactions with
+// their original types; overrides are applied to a copy on every run.
+type review struct {
+	o         *options
+	rows      []engine.Tx
+	overrides importer.Overrides
+	dirty     bool
+
+	state   *engine.State
+	journal map[importer.OverrideKey]*engine.JournalEntry
+	filter  string
+	visible []int
+	pos     int
+	out     io.Writer
+}
+
+// runReview lists the parsed transactions with the handler that consumed each and lets the user
+// re-classify rows and re-run the calculation. Re-classifications are kept in the -overrides file.
+func runReview(args []string) {
+	var o options
+	fs := newFlagSet("review", "file1.csv|dir|glob [...]")
+	o.addFilterFlags(fs)
+	o.addPriceFlags(fs)
+	o.addEngineFlags(fs)
+	fs.Parse(args)
+	o.setup()
+	files := inputFiles(fs.Args())
+	if len(files) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	rv := &review{o: &o, overrides: importer.Overrides{}, out: os.Stdout}
+	if o.overrides != "" {
+		ov, err := importer.LoadOverrides(o.overrides)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Fatalf("error loading overrides: %v", err)
+		}
+		if ov != nil {
+			rv.overrides = ov
+		}
+	}
+	// load with the original types; the overrides are applied per run
+	path := o.overrides
+	o.overrides = ""
+	o.openPrices()
+	rows, err := o.loadTransactions(files)
+	if err != nil {
+		log.Fatal(err)
+	}
+	o.overrides = path
+	rv.rows = rows
+	rv.setFilter("")
+	rv.recalculate()
+	fmt.Fprint(rv.out, reviewHelp)
+	rv.list()
+	rv.loop(os.Stdin)
+}
+
+func (rv *review) loop(in io.Reader) {
+	sc := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(rv.out, "> ")
+		if !sc.Scan() {
+			fmt.Fprintln(rv.out)
+			return
+		}
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		arg := strings.TrimSpace(strings.TrimPrefix(sc.Text(), fields[0]))
+		switch fields[0] {
+		case "l":
+			if n, ok := rv.rowArg(fields, false); ok {
+				rv.seek(n)
+			}
+			rv.list()
+		case "n":
+			if rv.pos+reviewPageSize < len(rv.visible) {
+				rv.pos += reviewPageSize
+			}
+			rv.list()
+		case "p":
+			rv.pos = max(rv.pos-reviewPageSize, 0)
+			rv.list()
+		case "/":
+			rv.setFilter(arg)
+			rv.list()
+		case "s":
+			if n, ok := rv.rowArg(fields, true); ok {
+				rv.show(n)
+			}
+		case "t":
+			n, ok := rv.rowArg(fields, true)
+			if !ok {
+				continue
+			}
+			if len(fields) < 3 {
+				fmt.Fprintln(rv.out, "usage: t N TYPE")
+				continue
+			}
+			typ := engine.NormalizeType(strings.Join(fields[2:], " "))
+			if !isHandledType(typ) {
+				fmt.Fprintf(rv.out, "note: %q has no dedicated handler and is classified by heuristics (see types)\n", typ)
+			}
+			rv.overrides[importer.KeyOf(rv.rows[n])] = typ
+			rv.dirty = true
+			fmt.Fprintf(rv.out, "row %d: %s -> %s (r to re-run)\n", n, rv.rows[n].Type, typ)
+		case "u":
+			if n, ok := rv.rowArg(fields, true); ok {
+				delete(rv.overrides, importer.KeyOf(rv.rows[n]))
+				rv.dirty = true
+				fmt.Fprintf(rv.out, "row %d: back to %s (r to re-run)\n", n, rv.rows[n].Type)
+			}
+		case "r":
+			rv.recalculate()
+			rv.list()
+		case "types":
+			fmt.Fprintln(rv.out, strings.Join(engine.Types(), ", "))
+		case "w":
+			rv.write()
+		case "q", "quit", "exit":
+			if rv.dirty && rv.o.overrides != "" {
+				fmt.Fprintln(rv.out, "unsaved re-classifications: w to write them, q! to quit anyway")
+				continue
+			}
+			return
+		case "q!":
+			return
+		case "h", "?", "help":
+			fmt.Fprint(rv.out, reviewHelp)
+		default:
+			fmt.Fprintf(rv.out, "unknown command %q (h for help)\n", fields[0])
+		}
+	}
+}
+
+// rowArg parses the row number in fields[1]; required reports a missing number as an error.
+func (rv *review) rowArg(fields []string, required bool) (int, bool) {
+	if len(fields) < 2 {
+		if required {
+			fmt.Fprintf(rv.out, "usage: %s N\n", fields[0])
+		}
+		return 0, false
+	}
+	n, err := strconv.Atoi(fields[1])
+	if err != nil || n < 0 || n >= len(rv.rows) {
+		fmt.Fprintf(rv.out, "no row %q (0-%d)\n", fields[1], len(rv.rows)-1)
+		return 0, false
+	}
+	return n, true
+}
+
+func isHandledType(t string) bool {
+	for _, h := range engine.Types() {
+		if h == t {
+			return true
+		}
+	}
+	return false
+}
+
+// recalculate processes a copy of the rows with the overrides applied.
+func (rv *review) recalculate() {
+	txs := append([]engine.Tx{}, rv.rows...)
+	rv.overrides.Apply(txs)
+	state, err := rv.o.process(txs)
+	if err != nil {
+		fmt.Fprintf(rv.out, "calculation failed: %v\n", err)
+		return
+	}
+	rv.state = state
+	rv.journal = map[importer.OverrideKey]*engine.JournalEntry{}
+	for i := range state.Journal {
+		je := &state.Journal[i]
+		if je.Tx.SourceLine != 0 {
+			rv.journal[importer.KeyOf(je.Tx)] = je
+		}
+	}
+	rv.totals()
+}
+
+// totals prints short, long and income per year and the number of warnings.
+func (rv *review) totals() {
+	type total struct{ short, long, income decimal.Decimal }
+	years := map[int]*total{}
+	for y, wallets := range rv.state.TaxYears {
+		t := &total{}
+		for _, commods := range wallets {
+			for _, g := range commods {
+				t.short = t.short.Add(g.Short)
+				t.long = t.long.Add(g.Long)
+				t.income = t.income.Add(g.Income)
+			}
+		}
+		years[y] = t
+	}
+	keys := []int{}
+	for y := range years {
+		keys = append(keys, y)
+	}
+	sort.Ints(keys)
+	fmt.Fprintf(rv.out, "Totals (%d re-classified rows):\n", len(rv.overrides))
+	for _, y := range keys {
+		t := years[y]
+		fmt.Fprintf(rv.out, "  %d: short=%s long=%s income=%s\n", y, t.short.StringFixed(2), t.long.StringFixed(2), t.income.StringFixed(2))
+	}
+	if n := len(rv.state.Warnings); n > 0 {
+		fmt.Fprintf(rv.out, "  %d warnings (s N on a row shows its lots; verify lists them all)\n", n)
+	}
+}
+
+func (rv *review) setFilter(text string) {
+	rv.filter = strings.ToLower(text)
+	rv.visible = rv.visible[:0]
+	for i := range rv.rows {
+		if rv.filter == "" || strings.Contains(strings.ToLower(rv.line(i)), rv.filter) {
+			rv.visible = append(rv.visible, i)
+		}
+	}
+	rv.pos = 0
+}
+
+// seek moves the page to the first visible row at or after row n.
+func (rv *review) seek(n int) {
+	rv.pos = sort.SearchInts(rv.visible, n)
+	if rv.pos >= len(rv.visible) {
+		rv.pos = max(len(rv.visible)-reviewPageSize, 0)
+	}
+}
+
+// handler returns how the last run treated row i ("-" when it was not processed on its own,
+// e.g. merged into a pair or before a loaded state).
+func (rv *review) handler(i int) string {
+	if je := rv.journal[importer.KeyOf(rv.rows[i])]; je != nil {
+		return je.Handler
+	}
+	return "-"
+}
+
+func (rv *review) line(i int) string {
+	tx := rv.rows[i]
+	typ := tx.Type
+	if t, ok := rv.overrides[importer.KeyOf(tx)]; ok {
+		typ = tx.Type + "=>" + t
+	}
+	return fmt.Sprintf("%4d  %s  %-16s %-18s %-10s %s %s  cost=%s  %s:%d",
+		i, tx.Time.Format("2006-01-02 15:04"), tx.Wallet, typ, rv.handler(i), tx.Amount.String(), tx.Commodity, tx.Cost.String(), tx.SourceFile, tx.SourceLine)
+}
+
+func (rv *review) list() {
+	if len(rv.visible) == 0 {
+		fmt.Fprintln(rv.out, "no rows")
+		return
+	}
+	end := min(rv.pos+reviewPageSize, len(rv.visible))
+	fmt.Fprintf(rv.out, "   #  time              wallet           type               handled as\n")
+	for _, i := range rv.visible[rv.pos:end] {
+		fmt.Fprintln(rv.out, rv.line(i))
+	}
+	filter := ""
+	if rv.filter != "" {
+		filter = fmt.Sprintf(" matching %q", rv.filter)
+	}
+	fmt.Fprintf(rv.out, "rows %d-%d of %d%s\n", rv.pos+1, end, len(rv.visible), filter)
+}
+
+func (rv *review) show(i int) {
+	tx := rv.rows[i]
+	fmt.Fprintln(rv.out, rv.line(i))
+	fmt.Fprintf(rv.out, "  time=%s ref=%s fee=%s currency=%s\n", tx.Time.Format(time.RFC3339), tx.ReferenceID, tx.Fee.String(), tx.Currency)
+	keys := []string{}
+	for k := range tx.Raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if tx.Raw[k] != "" {
+			fmt.Fprintf(rv.out, "  %s: %s\n", k, tx.Raw[k])
+		}
+	}
+	je := rv.journal[importer.KeyOf(tx)]
+	if je == nil {
+		return
+	}
+	for _, l := range je.Added {
+		fmt.Fprintf(rv.out, "  added     %s %s to %s, cost %s\n", l.Lot.Amount.String(), l.Commodity, l.Wallet, l.Lot.TotalCost.StringFixed(2))
+	}
+	for _, l := range je.Consumed {
+		fmt.Fprintf(rv.out, "  consumed  %s %s from %s acquired %s, basis %s\n", l.Lot.Amount.String(), l.Commodity, l.Wallet, l.Lot.Time.Format("2006-01-02"), l.Lot.TotalCost.StringFixed(2))
+	}
+	for _, d := range je.Disposals {
+		term := "short"
+		if d.Long {
+			term = "long"
+		}
+		fmt.Fprintf(rv.out, "  disposal  %s %s proceeds %s basis %s gain %s (%s)\n", d.Amount.String(), d.Commodity, d.Proceeds.StringFixed(2), d.CostBasis.StringFixed(2), d.Gain.StringFixed(2), term)
+	}
+}
+
+func (rv *review) write() {
+	if rv.o.overrides == "" {
+		fmt.Fprintln(rv.out, "no -overrides file given")
+		return
+	}
+	if err := rv.overrides.Save(rv.o.overrides); err != nil {
+		fmt.Fprintf(rv.out, "error writing %s: %v\n", rv.o.overrides, err)
+		return
+	}
+	rv.dirty = false
+	fmt.Fprintf(rv.out, "wrote %d re-classifications to %s\n", len(rv.overrides), rv.o.overrides)
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// runSimulate shows what selling coins would realize: the lots a sale of -amount -asset at -price
+// on -date would consume and the short and long gain, computed on a copy of the inventory built
+// from the files. Nothing is saved (-save-state is ignored).
+func runSimulate(args []string) {
+	var o options
+	fs := newFlagSet("simulate", "-asset ASSET -amount N file1.csv|dir|glob [...]")
+	asset := fs.String("asset", "", "asset to sell, e.g. BTC")
+	amountFlag := fs.String("amount", "", "number of coins to sell")
+	priceFlag := fs.String("price", "", "sale price per coin in -base (default: the price from -pricefile or -priceapi on -date)")
+	date := fs.String("date", "", "day of the sale (YYYY-MM-DD in -tax-timezone); transactions after that day are left out (default: now)")
+	saleWallet := fs.String("sale-wallet", "", "wallet the coins are sold from (default: the only wallet holding the asset)")
+	o.addFilterFlags(fs)
+	o.addPriceFlags(fs)
+	o.addOutputFlags(fs)
+	o.addEngineFlags(fs)
+	fs.Parse(args)
+	o.setup()
+	files := inputFiles(fs.Args())
+	if *asset == "" || *amountFlag == "" || (len(files) == 0 && o.loadState == "" && o.opening == "") {
+		fs.Usage()
+		os.Exit(2)
+	}
+	commodity := strings.ToUpper(strings.TrimSpace(*asset))
+	amount, err := decimal.NewFromString(*amountFlag)
+	if err != nil || !amount.IsPositive() {
+		log.Fatalf("invalid -amount %q (expected a positive number)", *amountFlag)
+	}
+	at := time.Now().UTC()
+	if *date != "" {
+		d, err := time.ParseInLocation("2006-01-02", *date, o.taxLocation)
+		if err != nil {
+			log.Fatalf("invalid -date: %v", err)
+		}
+		// the sale is the last event of the day
+		o.toTime = d.AddDate(0, 0, 1)
+		at = o.toTime.Add(-time.Second)
+	}
+	o.saveState = ""
+	o.openPrices()
+	_, state := o.run(files)
+
+	var price decimal.Decimal
+	if *priceFlag != "" {
+		if price, err = decimal.NewFromString(*priceFlag); err != nil || price.IsNegative() {
+			log.Fatalf("invalid -price %q (expected a number)", *priceFlag)
+		}
+	} else {
+		if o.prices == nil {
+			log.Fatalf("no sale price: use -price, or -pricefile and/or -priceapi")
+		}
+		if price, err = o.prices.Price(commodity, state.PriceCurrency, at); err != nil {
+			o.saveCache()
+			log.Fatalf("no price for %s/%s on %s: %v (use -price)", commodity, state.PriceCurrency, at.Format("2006-01-02"), err)
+		}
+		o.saveCache()
+	}
+
+	wallet := *saleWallet
+	if wallet == "" {
+		var holding []string
+		for w, commods := range state.Inventories {
+			for _, lot := range commods[commodity] {
+				if lot.Amount.IsPositive() {
+					holding = append(holding, w)
+					break
+				}
+			}
+		}
+		sort.Strings(holding)
+		switch len(holding) {
+		case 0:
+			log.Fatalf("no %s held on %s", commodity, at.In(o.taxLocation).Format("2006-01-02"))
+		case 1:
+			wallet = holding[0]
+		default:
+			log.Fatalf("%s is held in several wallets (%s): choose one with -sale-wallet", commodity, strings.Join(holding, ", "))
+		}
+	}
+
+	sim, err := engine.SimulateSale(state, wallet, commodity, amount, price, at)
+	if err != nil {
+		log.Fatalf("simulation error: %v", err)
+	}
+	fmt.Printf("Simulated sale of %s %s from %s at %s %s on %s (proceeds %s):\n",
+		amount.String(), commodity, wallet, price.StringFixed(2), state.PriceCurrency, at.In(o.taxLocation).Format("2006-01-02"), price.Mul(amount).StringFixed(2))
+	for _, d := range sim.Disposals {
+		term := "short"
+		if d.Long {
+			term = "long"
+		}
+		if d.Exempt {
+			term += " (exempt)"
+		}
+		fmt.Printf("  lot=%s acquired=%s days=%d term=%s amount=%s basis=%s proceeds=%s gain=%s\n",
+			d.LotID, d.Acquired.Format("2006-01-02"), int(d.HoldingDays), term, d.Amount.String(),
+			d.CostBasis.StringFixed(2), d.Proceeds.StringFixed(2), d.Gain.StringFixed(2))
+	}
+	g := sim.Gains
+	fmt.Printf("  Total: short=%s long=%s gain=%s\n", g.Short.StringFixed(2), g.Long.StringFixed(2), g.Short.Add(g.Long).StringFixed(2))
+	for _, w := range sim.Warnings {
+		fmt.Println("  " + w)
+	}
+}
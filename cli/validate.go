@@ -0,0 +1,146 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+	"cryptotax/report"
+)
+
+// maxListed is the number of possible duplicates validate lists before summarizing the rest.
+const maxListed = 10
+
+// runValidate is a dry run for sanity-checking new data: it parses and processes the files like
+// report, then shows the detected formats, the transactions per type, wallet and handler, matched
+// transfers, possible duplicate rows, balance checks and warnings instead of gains.
+func runValidate(args []string) {
+	var o options
+	fs := newFlagSet("validate", "file1.csv|dir|glob [...]")
+	o.addFilterFlags(fs)
+	o.addPriceFlags(fs)
+	o.addOutputFlags(fs)
+	o.addEngineFlags(fs)
+	fs.Parse(args)
+	o.setup()
+	files := inputFiles(fs.Args())
+	if len(files) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	o.openPrices()
+	all, state := o.run(files)
+	o.saveCache()
+
+	fmt.Println("Files:")
+	perFile := map[string]int{}
+	for _, tx := range all {
+		perFile[tx.SourceFile]++
+	}
+	issues := map[string]int{}
+	for _, is := range state.ImportIssues {
+		issues[is.File]++
+	}
+	for _, f := range files {
+		format, err := o.input.DetectFile(f)
+		if err != nil {
+			log.Fatalf("error reading %s: %v", f, err)
+		}
+		name := filepath.Base(f)
+		fmt.Printf("  %s: format=%s transactions=%d skipped=%d\n", f, format, perFile[name], issues[name])
+	}
+	if len(all) > 0 {
+		fmt.Printf("Transactions: %d from %s to %s\n", len(all), all[0].Time.Format(time.DateOnly), all[len(all)-1].Time.Format(time.DateOnly))
+	} else {
+		fmt.Println("Transactions: 0")
+	}
+
+	byType, byWallet, byHandler := map[string]int{}, map[string]int{}, map[string]int{}
+	for _, tx := range all {
+		byType[engine.NormalizeType(tx.Type)]++
+		byWallet[tx.Wallet]++
+	}
+	matched := 0
+	for _, je := range state.Journal {
+		byHandler[je.Handler]++
+		if _, ok := je.Tx.Raw["deposit_ref"]; ok {
+			matched++
+		}
+	}
+	printCounts("By type", byType)
+	printCounts("By wallet", byWallet)
+	printCounts("Handled as", byHandler)
+	if matched > 0 {
+		fmt.Printf("Transfers matched across wallets: %d\n", matched)
+	}
+	printDuplicates(all)
+	report.WriteReconciliation(os.Stdout, state, 0)
+	report.WriteImportIssues(os.Stdout, state, 0)
+	if len(state.Warnings) > 0 {
+		fmt.Printf("Warnings (%d):\n", len(state.Warnings))
+		for _, w := range state.Warnings {
+			fmt.Println("  " + w)
+		}
+	}
+}
+
+// printCounts prints counts by name, most frequent first.
+func printCounts(title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	names := make([]string, 0, len(counts))
+	for n := range counts {
+		names = append(names, n)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	fmt.Printf("%s:\n", title)
+	for _, n := range names {
+		if n == "" {
+			fmt.Printf("  %-24s %d\n", "(none)", counts[n])
+			continue
+		}
+		fmt.Printf("  %-24s %d\n", n, counts[n])
+	}
+}
+
+// printDuplicates lists transactions that look like the same event imported twice (same time,
+// wallet, type, asset, amount and reference id), typically overlapping exports.
+func printDuplicates(all []engine.Tx) {
+	seen := map[string]engine.Tx{}
+	var dups [][2]engine.Tx
+	for _, tx := range all {
+		key := strings.Join([]string{tx.Time.UTC().Format(time.RFC3339Nano), tx.Wallet, engine.NormalizeType(tx.Type), strings.ToUpper(tx.Commodity), tx.Amount.String(), tx.ReferenceID}, "|")
+		if first, ok := seen[key]; ok {
+			dups = append(dups, [2]engine.Tx{first, tx})
+			continue
+		}
+		seen[key] = tx
+	}
+	if len(dups) == 0 {
+		return
+	}
+	fmt.Printf("Possible duplicates (%d):\n", len(dups))
+	for i, d := range dups {
+		if i == maxListed {
+			fmt.Printf("  ... and %d more\n", len(dups)-maxListed)
+			break
+		}
+		fmt.Printf("  %s %s %s %s %s: %s:%d and %s:%d\n", d[1].Time.Format(time.RFC3339), d[1].Wallet, d[1].Type, d[1].Amount.String(), d[1].Commodity,
+			d[0].SourceFile, d[0].SourceLine, d[1].SourceFile, d[1].SourceLine)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// addPooled adds entry, already in the journal, under the average cost method (State.Method "acb"): each wallet holds one
+// lot per asset, and all lots of the asset, in every wallet, carry the average cost of the pool
+// (the adjusted cost base of identical properties). A purchase therefore changes the unit cost
+// of the coins held elsewhere too; a transfer, which moves coins at that cost, does not.
+func addPooled(s *State, wallet, commodity string, entry InventoryEntry) {
+	inv := s.Inventories[wallet][commodity]
+	if len(inv) == 0 {
+		s.Inventories[wallet][commodity] = []InventoryEntry{entry}
+	} else {
+		lot := &inv[0]
+		if entry.Time.Before(lot.Time) {
+			lot.Time = entry.Time
+		}
+		lot.Amount = lot.Amount.Add(entry.Amount)
+		lot.TotalCost = lot.TotalCost.Add(entry.TotalCost)
+		s.Inventories[wallet][commodity] = inv[:1]
+	}
+	averageCost(s, commodity)
+}
+
+// averageCost sets the unit cost of every lot of commodity to the average of the pool.
+func averageCost(s *State, commodity string) {
+	amount, cost := decimal.Zero, decimal.Zero
+	for _, commods := range s.Inventories {
+		for _, lot := range commods[commodity] {
+			amount = amount.Add(lot.Amount)
+			cost = cost.Add(lot.TotalCost)
+		}
+	}
+	if !amount.IsPositive() {
+		return
+	}
+	avg := cost.Div(amount)
+	for _, commods := range s.Inventories {
+		lots := commods[commodity]
+		for i := range lots {
+			lots[i].UnitCost = avg
+			lots[i].TotalCost = avg.Mul(lots[i].Amount)
+		}
+	}
+}
+
+// superficialWindow is how many days before or after a disposal at a loss buying the same asset
+// makes the loss superficial (Canada, ITA s. 54).
+const superficialWindow = 30
+
+// flow is a change of the amount held of an asset: acquisitions are positive, disposals negative.
+type flow struct {
+	at     time.Time
+	amount decimal.Decimal
+}
+
+// buildFlows collects the acquisitions and disposals of every asset in time order for the
+// superficial loss rule, which looks 30 days ahead of each disposal. Moves between own wallets
+// and assets (transfers, wraps, loan collateral) are not acquisitions.
+func buildFlows(s *State, txs []Tx) map[string][]flow {
+	flows := map[string][]flow{}
+	for _, tx := range txs {
+		t := NormalizeType(tx.Type)
+		if tx.Amount.IsZero() || s.Assets.IsFiat(tx.Commodity) || t == "transfer" || isWrapType(t) || isWithdrawalType(t) {
+			continue
+		}
+		switch t {
+		case "borrow", "loan", "loan withdrawal", "repay", "repayment":
+			continue
+		}
+		if isCollateralType(t) {
+			continue
+		}
+		flows[tx.Commodity] = append(flows[tx.Commodity], flow{tx.Time, tx.Amount})
+	}
+	for c := range flows {
+		f := flows[c]
+		sort.SliceStable(f, func(i, j int) bool { return f[i].at.Before(f[j].at) })
+	}
+	return flows
+}
+
+// isCollateralType reports whether typ locks or unlocks loan collateral, which stays owned.
+func isCollateralType(t string) bool {
+	switch t {
+	case "collateral", "collateral lock", "collateral_lock", "collateral unlock", "collateral_unlock",
+		"locking term deposit", "unlocking term deposit", "transfer in (collateral)", "transfer out (collateral)":
+		return true
+	}
+	return false
+}
+
+// denySuperficialLoss applies the superficial loss rule to the disposals of one sale (the
+// Disposals from index first on): when the asset is bought within 30 days before or after a sale
+// at a loss and still held 30 days after it, the loss is denied in proportion
+// min(sold, bought in the window, held at the end) / sold and added to the cost of the coins
+// held, or of the next coins bought when none are left.
+func denySuperficialLoss(s *State, tx Tx, amount decimal.Decimal, first int) {
+	loss := decimal.Zero
+	for _, d := range s.Disposals[first:] {
+		if d.Gain.IsNegative() {
+			loss = loss.Sub(d.Gain)
+		}
+	}
+	if !loss.IsPositive() || !amount.IsPositive() {
+		return
+	}
+	from, to := tx.Time.AddDate(0, 0, -superficialWindow), tx.Time.AddDate(0, 0, superficialWindow)
+	bought, held := decimal.Zero, decimal.Zero
+	for _, commods := range s.Inventories {
+		for _, lot := range commods[tx.Commodity] {
+			held = held.Add(lot.Amount)
+		}
+	}
+	for _, f := range s.flows[tx.Commodity] {
+		if f.at.After(to) {
+			break
+		}
+		if f.amount.IsPositive() && !f.at.Before(from) {
+			bought = bought.Add(f.amount)
+		}
+		if f.at.After(tx.Time) {
+			held = held.Add(f.amount)
+		}
+	}
+	share := decimal.Min(amount, bought, decimal.Max(held, decimal.Zero)).Div(amount)
+	if !share.IsPositive() {
+		return
+	}
+	je := s.currentJournal()
+	firstJournal := 0
+	if je != nil {
+		firstJournal = len(je.Disposals) - (len(s.Disposals) - first)
+	}
+	denied := decimal.Zero
+	for i := first; i < len(s.Disposals); i++ {
+		d := &s.Disposals[i]
+		if !d.Gain.IsNegative() {
+			continue
+		}
+		part := d.Gain.Neg().Mul(share)
+		d.Gain = d.Gain.Add(part)
+		d.DeniedLoss = part
+		denied = denied.Add(part)
+		slot := getGainsSlot(s, d.Disposed.Year(), d.Wallet, d.Commodity)
+		if d.Long {
+			slot.Long = slot.Long.Add(part)
+		} else {
+			slot.Short = slot.Short.Add(part)
+		}
+		if j := firstJournal + i - first; je != nil && j >= 0 && j < len(je.Disposals) {
+			je.Disposals[j] = *d
+		}
+	}
+	if s.Verbose {
+		log.Printf("SUPERFICIAL LOSS: %s of the loss on %s %s denied, added to the cost of the %s held or bought back ref=%s", denied.String(), amount.String(), tx.Commodity, tx.Commodity, tx.ReferenceID)
+	}
+	addBasis(s, tx.Commodity, denied)
+}
+
+// addBasis spreads cost over the lots of commodity held in all wallets by amount, or keeps it for
+// the next acquisition when nothing is held.
+func addBasis(s *State, commodity string, cost decimal.Decimal) {
+	held := decimal.Zero
+	for _, commods := range s.Inventories {
+		for _, lot := range commods[commodity] {
+			held = held.Add(lot.Amount)
+		}
+	}
+	if !held.IsPositive() {
+		if s.deniedLosses == nil {
+			s.deniedLosses = map[string]decimal.Decimal{}
+		}
+		s.deniedLosses[commodity] = s.deniedLosses[commodity].Add(cost)
+		return
+	}
+	for _, commods := range s.Inventories {
+		lots := commods[commodity]
+		for i := range lots {
+			lots[i].TotalCost = lots[i].TotalCost.Add(cost.Mul(lots[i].Amount).Div(held))
+			if lots[i].Amount.IsPositive() {
+				lots[i].UnitCost = lots[i].TotalCost.Div(lots[i].Amount)
+			}
+		}
+	}
+}
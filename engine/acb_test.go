@@ -0,0 +1,176 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// acbTx is a buy (positive amount) or sell (negative amount) of BTC on day of 2024 for cost EUR.
+func acbTx(day int, wallet, amount, cost string) Tx {
+	typ := "buy"
+	if decimal.RequireFromString(amount).IsNegative() {
+		typ = "sell"
+	}
+	return Tx{
+		Time:      time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).AddDate(0, 0, day),
+		Type:      typ,
+		Wallet:    wallet,
+		Commodity: "BTC",
+		Amount:    decimal.RequireFromString(amount),
+		Cost:      decimal.RequireFromString(cost),
+		Currency:  "EUR",
+	}
+}
+
+// heldCost returns the cost of the BTC held in each wallet.
+func heldCost(s *State) map[string]string {
+	res := map[string]string{}
+	for w, commods := range s.Inventories {
+		total := decimal.Zero
+		for _, lot := range commods["BTC"] {
+			total = total.Add(lot.TotalCost)
+		}
+		if len(commods["BTC"]) > 0 {
+			res[w] = total.String()
+		}
+	}
+	return res
+}
+
+func TestAverageCostPool(t *testing.T) {
+	s := NewState(false, nil, nil)
+	s.Method = "acb"
+	txs := []Tx{
+		acbTx(0, "a", "1", "100"),
+		acbTx(10, "b", "1", "300"),
+		acbTx(20, "a", "-1", "250"),
+	}
+	if err := ProcessTransactions(s, txs); err != nil {
+		t.Fatal(err)
+	}
+	// the coins of both wallets cost 200 each on average
+	if len(s.Disposals) != 1 || s.Disposals[0].CostBasis.String() != "200" || s.Disposals[0].Gain.String() != "50" {
+		t.Fatalf("got %+v, want a disposal at basis 200 with gain 50", s.Disposals)
+	}
+	if got := heldCost(s); len(got) != 1 || got["b"] != "200" {
+		t.Errorf("got held cost %v, want b: 200", got)
+	}
+}
+
+func TestSuperficialLoss(t *testing.T) {
+	tests := []struct {
+		name   string
+		txs    []Tx
+		gain   string // of the sale at a loss
+		denied string
+		held   map[string]string // cost of the coins held per wallet at the end
+	}{
+		{
+			name:   "repurchase within 30 days after",
+			txs:    []Tx{acbTx(0, "a", "1", "1000"), acbTx(40, "a", "-1", "600"), acbTx(50, "a", "1", "500")},
+			gain:   "0",
+			denied: "400",
+			held:   map[string]string{"a": "900"},
+		},
+		{
+			name:   "repurchase on the 30th day",
+			txs:    []Tx{acbTx(0, "a", "1", "1000"), acbTx(40, "a", "-1", "600"), acbTx(70, "a", "1", "500")},
+			gain:   "0",
+			denied: "400",
+			held:   map[string]string{"a": "900"},
+		},
+		{
+			name:   "repurchase after the window",
+			txs:    []Tx{acbTx(0, "a", "1", "1000"), acbTx(40, "a", "-1", "600"), acbTx(71, "a", "1", "500")},
+			gain:   "-400",
+			denied: "0",
+			held:   map[string]string{"a": "500"},
+		},
+		{
+			// bought within 30 days before the sale and still held: the loss on the average cost
+			// (700) goes to the coin left
+			name:   "purchase within 30 days before",
+			txs:    []Tx{acbTx(0, "a", "1", "1000"), acbTx(40, "a", "1", "400"), acbTx(50, "a", "-1", "500")},
+			gain:   "0",
+			denied: "200",
+			held:   map[string]string{"a": "900"},
+		},
+		{
+			// a quarter of the coins sold are bought back: a quarter of the loss is denied
+			name:   "partial repurchase",
+			txs:    []Tx{acbTx(0, "a", "2", "2000"), acbTx(40, "a", "-2", "1200"), acbTx(45, "a", "0.5", "300")},
+			gain:   "-600",
+			denied: "200",
+			held:   map[string]string{"a": "500"},
+		},
+		{
+			// bought back but sold again before the window ends: only what is still held counts
+			name:   "repurchase not held at the end of the window",
+			txs:    []Tx{acbTx(0, "a", "2", "2000"), acbTx(40, "a", "-2", "1200"), acbTx(45, "a", "1", "600"), acbTx(60, "a", "-0.5", "400")},
+			gain:   "-600",
+			denied: "200",
+			held:   map[string]string{"a": "400"},
+		},
+		{
+			name:   "repurchase in another wallet",
+			txs:    []Tx{acbTx(0, "a", "1", "1000"), acbTx(40, "a", "-1", "600"), acbTx(45, "b", "1", "500")},
+			gain:   "0",
+			denied: "400",
+			held:   map[string]string{"b": "900"},
+		},
+		{
+			name:   "sale at a gain",
+			txs:    []Tx{acbTx(0, "a", "1", "500"), acbTx(40, "a", "-1", "600"), acbTx(45, "a", "1", "600")},
+			gain:   "100",
+			denied: "0",
+			held:   map[string]string{"a": "600"},
+		},
+	}
+	for _, tt := range tests {
+		s := NewState(false, nil, nil)
+		s.Method = "acb"
+		s.SuperficialLoss = true
+		if err := ProcessTransactions(s, tt.txs); err != nil {
+			t.Fatal(err)
+		}
+		if len(s.Disposals) == 0 {
+			t.Errorf("%s: no disposals", tt.name)
+			continue
+		}
+		d := s.Disposals[0]
+		if d.Gain.String() != tt.gain || d.DeniedLoss.String() != tt.denied {
+			t.Errorf("%s: got gain %s denied %s, want %s and %s", tt.name, d.Gain.String(), d.DeniedLoss.String(), tt.gain, tt.denied)
+		}
+		got := heldCost(s)
+		for w, cost := range tt.held {
+			if got[w] != cost {
+				t.Errorf("%s: got held cost %v, want %v", tt.name, got, tt.held)
+				break
+			}
+		}
+		if len(got) != len(tt.held) {
+			t.Errorf("%s: got held cost %v, want %v", tt.name, got, tt.held)
+		}
+		// the denied loss moves out of the yearly totals
+		if g := s.TaxYears[2024]["a"]["BTC"]; g == nil || !g.Short.Equal(sumGains(s)) {
+			t.Errorf("%s: yearly short gains do not match the disposals", tt.name)
+		}
+	}
+}
+
+// sumGains sums the gains of the disposals of BTC in wallet a.
+func sumGains(s *State) decimal.Decimal {
+	sum := decimal.Zero
+	for _, d := range s.Disposals {
+		if d.Wallet == "a" && d.Commodity == "BTC" {
+			sum = sum.Add(d.Gain)
+		}
+	}
+	return sum
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// builtinAssetAliases maps exchange-specific symbols to the common ticker, so the same asset
+// shares one inventory whichever export it came from. Kraken prefixes its older assets with X
+// (crypto) or Z (fiat) in ledger exports and lists staked ETH as ETH2/ETH2.S; its other staking
+// variants are mapped by stakedParent.
+var builtinAssetAliases = map[string]string{
+	"XBT":    "BTC",
+	"XXBT":   "BTC",
+	"XETH":   "ETH",
+	"ETH2":   "ETH",
+	"ETH2.S": "ETH",
+	"XXDG":   "DOGE",
+	"XDG":    "DOGE",
+	"XLTC":   "LTC",
+	"XXRP":   "XRP",
+	"XXLM":   "XLM",
+	"XXMR":   "XMR",
+	"XZEC":   "ZEC",
+	"XETC":   "ETC",
+	"XREP":   "REP",
+	"XMLN":   "MLN",
+	"ZEUR":   "EUR",
+	"ZUSD":   "USD",
+	"ZGBP":   "GBP",
+	"ZCAD":   "CAD",
+	"ZJPY":   "JPY",
+	"ZAUD":   "AUD",
+	"ZCHF":   "CHF",
+}
+
+// Assets is the symbol table of a calculation: the aliases added to the built-in ones
+// (-asset-aliases) and the stablecoins treated as fiat (-stablecoins-as-fiat). A nil *Assets has
+// the built-in aliases only.
+type Assets struct {
+	aliases map[string]string // alias -> asset, looked up before builtinAssetAliases
+	// stablecoins treated as fiat mapped to their peg: they are never tracked as commodities and
+	// count as the peg currency when priced
+	fiatEquivalents map[string]string
+}
+
+// AddAliases adds aliases given as "ALIAS=ASSET[,...]" (e.g. "WXT=WIRTUAL") to the table.
+func (a *Assets) AddAliases(spec string) error {
+	for _, p := range strings.Split(spec, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		alias, asset, ok := strings.Cut(p, "=")
+		alias = strings.ToUpper(strings.TrimSpace(alias))
+		asset = strings.ToUpper(strings.TrimSpace(asset))
+		if !ok || alias == "" || asset == "" {
+			return fmt.Errorf("invalid asset alias %q (expected ALIAS=ASSET)", p)
+		}
+		if a.aliases == nil {
+			a.aliases = map[string]string{}
+		}
+		a.aliases[alias] = asset
+	}
+	return nil
+}
+
+// alias returns the asset symbol stands for, if it is an alias.
+func (a *Assets) alias(symbol string) (string, bool) {
+	if a != nil {
+		if asset, ok := a.aliases[symbol]; ok {
+			return asset, true
+		}
+	}
+	asset, ok := builtinAssetAliases[symbol]
+	return asset, ok
+}
+
+// Normalize returns the common ticker of an exchange symbol, or the symbol (trimmed) when it has
+// no alias. Kraken staking variants (DOT.S, ATOM21.S, XBT.M) are the asset they stand for.
+func (a *Assets) Normalize(symbol string) string {
+	s := strings.TrimSpace(symbol)
+	if asset, ok := a.alias(strings.ToUpper(s)); ok {
+		return asset
+	}
+	if p := stakedParent(s); p != "" {
+		if asset, ok := a.alias(p); ok {
+			return asset
+		}
+		return p
+	}
+	return s
+}
+
+// NormalizeAsset returns the common ticker of symbol by the built-in aliases, see Assets.Normalize.
+func NormalizeAsset(symbol string) string {
+	return (*Assets)(nil).Normalize(symbol)
+}
+
+// stakedSuffixes are the suffixes Kraken gives an asset held in staking or earn: .S staked, .B
+// bonding (or unbonding), .M opt-in rewards, .P parachain staking, .F flexible earn and .HOLD fiat
+// on hold.
+var stakedSuffixes = []string{".S", ".B", ".M", ".P", ".F", ".HOLD"}
+
+// stakedParent returns the symbol (upper case) of the asset a Kraken staking variant stands for,
+// or "" when symbol is not one. Bonded staking names carry the bonding period in days (ATOM21.S,
+// KSM07.S) as two digits, which are dropped; a stem of fewer than three letters keeps them (C98.S).
+func stakedParent(symbol string) string {
+	s := strings.ToUpper(strings.TrimSpace(symbol))
+	for _, suffix := range stakedSuffixes {
+		stem, ok := strings.CutSuffix(s, suffix)
+		if !ok || stem == "" {
+			continue
+		}
+		if suffix == ".S" {
+			letters := strings.TrimRight(stem, "0123456789")
+			if len(stem)-len(letters) == 2 && len(letters) >= 3 && strings.Trim(letters, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == "" {
+				stem = letters
+			}
+		}
+		return stem
+	}
+	return ""
+}
+
+// IsStakedAsset reports whether symbol is a Kraken staking variant of another asset (DOT.S,
+// ETH2.S, ETH2), i.e. coins moved into staking rather than a different asset.
+func IsStakedAsset(symbol string) bool {
+	s := strings.ToUpper(strings.TrimSpace(symbol))
+	return s == "ETH2" || s == "ETH2.S" || stakedParent(s) != ""
+}
+
+// AssetKey is the normalized on-chain identity of a token: "chain:contract" in lower case, or
+// just the contract when the chain is unknown.
+func AssetKey(chain, contract string) string {
+	chain = strings.ToLower(strings.TrimSpace(chain))
+	contract = strings.ToLower(strings.TrimSpace(contract))
+	if contract == "" {
+		return ""
+	}
+	if chain == "" {
+		return contract
+	}
+	return chain + ":" + contract
+}
+
+// AssetID returns the AssetKey of tx's asset from a contract address or asset id column (on-chain
+// and wallet exports), or "" when the row has none.
+func AssetID(tx Tx) string {
+	return AssetKey(FirstNonEmpty(tx.Raw, "chain", "network", "blockchain"),
+		FirstNonEmpty(tx.Raw, "contract_address", "token_address", "token_contract", "asset_id"))
+}
+
+// disambiguateAssets keys commodities by their AssetID, so different tokens sharing a ticker (two
+// MIMs on different chains) never share one inventory. Ids listed in s.AssetIDs take the configured
+// symbol; other ids keep their ticker unless another id (or rows without one) use it too, in which
+// case the ticker is suffixed with the start of the contract (MIM.0x99d8).
+func disambiguateAssets(s *State, txs []Tx) []Tx {
+	ids := map[string]map[string]bool{} // ticker -> ids seen ("" = rows without an id)
+	for _, tx := range txs {
+		id := AssetID(tx)
+		if _, ok := s.AssetIDs[id]; ok && id != "" {
+			continue
+		}
+		c := strings.ToUpper(strings.TrimSpace(tx.Commodity))
+		if ids[c] == nil {
+			ids[c] = map[string]bool{}
+		}
+		ids[c][id] = true
+	}
+	res := make([]Tx, len(txs))
+	warned := map[string]bool{}
+	for i, tx := range txs {
+		id := AssetID(tx)
+		if id != "" {
+			if sym, ok := s.AssetIDs[id]; ok {
+				tx.Commodity = sym
+			} else if c := strings.ToUpper(strings.TrimSpace(tx.Commodity)); len(ids[c]) > 1 {
+				contract := id[strings.LastIndex(id, ":")+1:]
+				if len(contract) > 6 {
+					contract = contract[:6]
+				}
+				tx.Commodity = c + "." + contract
+				if !warned[id] {
+					warned[id] = true
+					s.Warnf("ASSET ID: %s (%s) is kept apart as %s because other tokens use the same ticker; name it with -asset-ids", c, id, tx.Commodity)
+				}
+			}
+		}
+		res[i] = tx
+	}
+	return res
+}
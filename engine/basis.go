@@ -0,0 +1,68 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// handlePurchase handles buy rows. A purchase without a cost has an unknown basis, which is
+// resolved as configured by State.MissingBasis.
+func handlePurchase(s *State, tx Tx) error {
+	if tx.Cost.IsZero() && !tx.Amount.IsZero() {
+		switch s.MissingBasis {
+		case "abort":
+			return fmt.Errorf("buy of %s %s in %s without a cost ref=%s", tx.Amount.Abs().String(), tx.Commodity, tx.Wallet, tx.ReferenceID)
+		case "fmv":
+			v, err := valueIn(s, tx.Commodity, tx.Amount.Abs(), tx.Time)
+			if err != nil {
+				if errors.Is(err, ErrOffline) {
+					return fmt.Errorf("valuing buy of %s %s ref=%s: %w", tx.Amount.Abs().String(), tx.Commodity, tx.ReferenceID, err)
+				}
+				s.Warnf("MISSING BASIS: buy of %s %s in %s without a cost and no price on %s ref=%s: %v; using zero basis", tx.Amount.Abs().String(), tx.Commodity, tx.Wallet, tx.Time.Format("2006-01-02"), tx.ReferenceID, err)
+				break
+			}
+			s.Warnf("MISSING BASIS: buy of %s %s in %s without a cost valued at %s (market value on %s) ref=%s", tx.Amount.Abs().String(), tx.Commodity, tx.Wallet, v.StringFixed(2), tx.Time.Format("2006-01-02"), tx.ReferenceID)
+			tx.Cost = v
+		}
+	}
+	return handleBuy(s, tx)
+}
+
+// missingBasisLot returns the lot assumed for amount sold beyond the inventory by tx, or nil when
+// the shortfall is only reported (State.MissingBasis "warn"). The lot is acquired on
+// State.MissingBasisDate (the disposal date when unset) at zero cost ("zero") or at the market
+// value on that date ("fmv"); "abort" stops processing.
+func missingBasisLot(s *State, tx Tx, amount decimal.Decimal) (*InventoryEntry, error) {
+	acquired := s.MissingBasisDate
+	if acquired.IsZero() || acquired.After(tx.Time) {
+		acquired = tx.Time
+	}
+	lot := &InventoryEntry{Time: acquired, Amount: amount, ReferenceID: tx.ReferenceID}
+	switch s.MissingBasis {
+	case "abort":
+		return nil, fmt.Errorf("selling %s %s more than held in %s ref=%s", amount.String(), tx.Commodity, tx.Wallet, tx.ReferenceID)
+	case "zero":
+		s.Warnf("MISSING BASIS: sold %s %s more than held in %s ref=%s; assuming zero basis acquired %s", amount.String(), tx.Commodity, tx.Wallet, tx.ReferenceID, acquired.Format("2006-01-02"))
+	case "fmv":
+		v, err := valueIn(s, tx.Commodity, amount, acquired)
+		if err != nil {
+			if errors.Is(err, ErrOffline) {
+				return nil, fmt.Errorf("valuing missing basis of %s %s ref=%s: %w", amount.String(), tx.Commodity, tx.ReferenceID, err)
+			}
+			s.Warnf("MISSING BASIS: sold %s %s more than held in %s ref=%s; no price on %s (%v), assuming zero basis", amount.String(), tx.Commodity, tx.Wallet, tx.ReferenceID, acquired.Format("2006-01-02"), err)
+			break
+		}
+		lot.TotalCost = v
+		lot.UnitCost = v.Div(amount)
+		s.Warnf("MISSING BASIS: sold %s %s more than held in %s ref=%s; assuming acquired %s at market value %s", amount.String(), tx.Commodity, tx.Wallet, tx.ReferenceID, acquired.Format("2006-01-02"), v.StringFixed(2))
+	default:
+		return nil, nil
+	}
+	return lot, nil
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ClassRule classifies the transactions it matches as Operation. Every pattern that is set must
+// match (nil matches anything) and the amount must satisfy AmountOp, see Matches.
+type ClassRule struct {
+	Type        *regexp.Regexp
+	Description *regexp.Regexp // the description, notes or memo column of the row
+	Asset       *regexp.Regexp
+	Source      *regexp.Regexp // source file name without its directory
+	Wallet      *regexp.Regexp
+	AmountOp    string // "<", "<=", ">", ">=", "=" or "" (any amount)
+	Amount      decimal.Decimal
+	// the type the tx is processed as (buy, sell, trade, transfer, withdrawal, staking, ... or any
+	// other type with a handler), "ignore" to drop it, or "income:KIND" for income booked under
+	// the type KIND (plain income when KIND has no handler of its own)
+	Operation string
+}
+
+// IsOperation reports whether op can be the Operation of a ClassRule.
+func IsOperation(op string) bool {
+	op = NormalizeType(op)
+	if op == "ignore" || strings.HasPrefix(op, "income:") || isWithdrawalType(op) || isDepositType(op) {
+		return true
+	}
+	_, ok := getHandlers()[op]
+	return ok
+}
+
+// Matches reports whether tx matches all the conditions of r.
+func (r ClassRule) Matches(tx Tx) bool {
+	match := func(re *regexp.Regexp, s string) bool {
+		return re == nil || re.MatchString(s)
+	}
+	if !match(r.Type, NormalizeType(tx.Type)) || !match(r.Asset, tx.Commodity) || !match(r.Wallet, tx.Wallet) ||
+		!match(r.Source, filepath.Base(tx.SourceFile)) || !match(r.Description, txDescription(tx)) {
+		return false
+	}
+	c := tx.Amount.Cmp(r.Amount)
+	switch r.AmountOp {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "=":
+		return c == 0
+	}
+	return true
+}
+
+// txDescription returns the free-text column of tx's row, if it has one.
+func txDescription(tx Tx) string {
+	return FirstNonEmpty(tx.Raw, "description", "notes", "note", "memo", "comment", "label", "remark", "details")
+}
+
+// matchRule returns the first of rules that matches tx.
+func matchRule(rules []ClassRule, tx Tx) (ClassRule, bool) {
+	for _, r := range rules {
+		if r.Matches(tx) {
+			return r, true
+		}
+	}
+	return ClassRule{}, false
+}
+
+// fallbackRules classify the types without a handler by keywords in the type and the sign of the
+// amount; the last one matches everything.
+var fallbackRules = []ClassRule{
+	{Type: regexp.MustCompile(`sell`), Operation: "sell"},
+	{AmountOp: "<", Operation: "sell"},
+	{Type: regexp.MustCompile(`buy`), Operation: "buy"},
+	{AmountOp: ">", Operation: "buy"},
+	{Type: regexp.MustCompile(`reward|staking|deposit|income`), Operation: "income"},
+	{Type: regexp.MustCompile(`convert|trade`), Operation: "convert"},
+	{Type: regexp.MustCompile(`transfer`), Operation: "transfer"},
+	{Operation: "sell"},
+}
+
+// classifyTransactions applies State.ClassRules before anything else looks at the types: the
+// first rule matching a tx gives it the rule's operation as its type, and txs matched by an
+// "ignore" rule are dropped.
+func classifyTransactions(s *State, txs []Tx) []Tx {
+	if len(s.ClassRules) == 0 {
+		return txs
+	}
+	handlers := getHandlers()
+	out := txs[:0:0]
+	for _, tx := range txs {
+		r, ok := matchRule(s.ClassRules, tx)
+		if !ok {
+			out = append(out, tx)
+			continue
+		}
+		op := strings.ToLower(strings.TrimSpace(r.Operation))
+		if op == "ignore" {
+			if s.Verbose {
+				log.Printf("CLASSIFY: ignoring %s %s %s %s ref=%s", tx.Wallet, tx.Type, tx.Amount.String(), tx.Commodity, tx.ReferenceID)
+			}
+			continue
+		}
+		if kind, ok := strings.CutPrefix(op, "income:"); ok {
+			op = "income"
+			if handlers[kind] != nil {
+				op = kind
+			}
+		}
+		if s.Verbose {
+			log.Printf("CLASSIFY: %s %s %s %s ref=%s as %s", tx.Wallet, tx.Type, tx.Amount.String(), tx.Commodity, tx.ReferenceID, op)
+		}
+		tx.Type = op
+		out = append(out, tx)
+	}
+	return out
+}
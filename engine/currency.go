@@ -0,0 +1,166 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// stablecoinPegs maps well-known stablecoins to the fiat currency they track.
+var stablecoinPegs = map[string]string{
+	"USDT":  "USD",
+	"USDC":  "USD",
+	"DAI":   "USD",
+	"BUSD":  "USD",
+	"TUSD":  "USD",
+	"USDP":  "USD",
+	"PYUSD": "USD",
+	"FDUSD": "USD",
+	"EURC":  "EUR",
+	"EURT":  "EUR",
+}
+
+// SetFiatEquivalents sets the stablecoins treated as fiat from "USDT,USDC" or "XUSD=USD" entries,
+// replacing those set before; "all" selects every known stablecoin.
+func (a *Assets) SetFiatEquivalents(spec string) error {
+	fiatEquivalents := map[string]string{}
+	for _, p := range strings.Split(spec, ",") {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if p == "ALL" {
+			for c, peg := range stablecoinPegs {
+				fiatEquivalents[c] = peg
+			}
+			continue
+		}
+		coin, peg, ok := strings.Cut(p, "=")
+		if !ok {
+			if peg, ok = stablecoinPegs[coin]; !ok {
+				return fmt.Errorf("unknown stablecoin %s (use %s=USD to name its peg)", coin, coin)
+			}
+		}
+		if !IsFiat(peg) {
+			return fmt.Errorf("peg of %s must be a fiat currency, got %s", coin, peg)
+		}
+		fiatEquivalents[coin] = peg
+	}
+	a.fiatEquivalents = fiatEquivalents
+	return nil
+}
+
+// fiatOf returns the fiat currency an amount in asset counts as: the peg of a fiat-equivalent
+// stablecoin, otherwise asset itself (upper-cased).
+func (a *Assets) fiatOf(asset string) string {
+	s := strings.ToUpper(strings.TrimSpace(asset))
+	if a != nil {
+		if peg, ok := a.fiatEquivalents[s]; ok {
+			return peg
+		}
+	}
+	return s
+}
+
+// IsFiat reports whether asset is a fiat currency or a stablecoin treated as one.
+func (a *Assets) IsFiat(asset string) bool {
+	s := strings.ToLower(a.Normalize(asset))
+	if s == "" {
+		return false
+	}
+	if a != nil {
+		if _, ok := a.fiatEquivalents[strings.ToUpper(s)]; ok {
+			return true
+		}
+	}
+	return IsFiat(s)
+}
+
+// IsFiat reports whether asset is a fiat currency (no stablecoin is, see Assets.IsFiat).
+func IsFiat(asset string) bool {
+	a := strings.ToLower(NormalizeAsset(asset))
+	if a == "" {
+		return false
+	}
+	switch a {
+	case "eur", "usd", "gbp", "chf", "cad", "aud", "jpy", "krw", "zar", "myr", "idr", "ngn", "inr", "mxn", "brl":
+		return true
+	}
+	return false
+}
+
+// Price lookups
+// A PriceSource returns the price of one unit of asset expressed in currency at the given time.
+// Daily granularity is enough for tax valuation, so sources and the cache key prices by UTC day.
+type PriceSource interface {
+	Price(asset, currency string, at time.Time) (decimal.Decimal, error)
+}
+
+var (
+	ErrPriceNotFound = errors.New("price not found")
+	ErrOffline       = errors.New("price not cached and running offline")
+)
+
+// ConvertToBase converts the fiat cost and fee of every transaction priced in a currency other
+// than base, recording the applied rate on the transaction so it can be audited. Stablecoins
+// count as fiat when assets says so.
+func ConvertToBase(txs []Tx, base string, prices PriceSource, assets *Assets) error {
+	for i := range txs {
+		tx := &txs[i]
+		cur := strings.TrimSpace(tx.Currency)
+		if !assets.IsFiat(cur) || strings.EqualFold(cur, base) {
+			continue
+		}
+		if strings.EqualFold(assets.fiatOf(cur), base) {
+			// stablecoin pegged to base counts 1:1
+			tx.Currency = base
+			continue
+		}
+		if prices == nil {
+			return fmt.Errorf("tx ref=%s is priced in %s: -pricefile or -priceapi is needed to convert to %s", tx.ReferenceID, cur, base)
+		}
+		rate, err := prices.Price(assets.fiatOf(cur), base, tx.Time)
+		if err != nil {
+			return fmt.Errorf("converting %s to %s for tx ref=%s on %s: %w", cur, base, tx.ReferenceID, tx.Time.Format("2006-01-02"), err)
+		}
+		tx.OrigCurrency = cur
+		tx.FXRate = rate
+		tx.Cost = tx.Cost.Mul(rate)
+		tx.Fee = tx.Fee.Mul(rate)
+		tx.PricePerUnit = tx.PricePerUnit.Mul(rate)
+		tx.Currency = base
+	}
+	return nil
+}
+
+// valueIn converts amount of asset to the currency gains are reported in: fiat amounts are taken
+// as-is (or converted when -base is set), crypto amounts are valued with the price source.
+func valueIn(s *State, asset string, amount decimal.Decimal, at time.Time) (decimal.Decimal, error) {
+	if amount.IsZero() {
+		return decimal.Zero, nil
+	}
+	target := s.BaseCurrency
+	if s.Assets.IsFiat(asset) {
+		asset = s.Assets.fiatOf(asset)
+		if target == "" || strings.EqualFold(asset, target) {
+			return amount, nil
+		}
+	} else if target == "" {
+		target = s.PriceCurrency
+	}
+	if s.Prices == nil {
+		return decimal.Zero, fmt.Errorf("%w: no price source to value %s in %s", ErrPriceNotFound, asset, target)
+	}
+	p, err := s.Prices.Price(asset, target, at)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return amount.Mul(p), nil
+}
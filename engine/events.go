@@ -0,0 +1,103 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Fork is a chain split: holders of Parent were credited Asset on Date (zero = unknown).
+type Fork struct {
+	Asset  string
+	Parent string
+	Date   time.Time
+}
+
+func utcDay(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// builtinForks are well-known chain splits. They name the parent of fork rows that do not name
+// one and turn airdrops of the forked coins into forks, see forkOf.
+var builtinForks = []Fork{
+	{Asset: "ETC", Parent: "ETH", Date: utcDay(2016, time.July, 20)},
+	{Asset: "BCH", Parent: "BTC", Date: utcDay(2017, time.August, 1)},
+	{Asset: "BTG", Parent: "BTC", Date: utcDay(2017, time.October, 24)},
+	{Asset: "BSV", Parent: "BCH", Date: utcDay(2018, time.November, 15)},
+	{Asset: "ETHW", Parent: "ETH", Date: utcDay(2022, time.September, 15)},
+}
+
+// builtinMigrations are well-known ticker changes: after the Terra collapse the original chain
+// went on as Terra Classic (LUNA became LUNC, UST became USTC) and a new LUNA was launched, and
+// Polygon upgraded MATIC to POL one for one. They only apply to transactions that use the new
+// ticker (Migration.Builtin), so exports that still list the old one are left alone.
+var builtinMigrations = []Migration{
+	{From: "LUNA", To: "LUNC", Date: utcDay(2022, time.May, 28), Ratio: decimal.NewFromInt(1), Builtin: true},
+	{From: "UST", To: "USTC", Date: utcDay(2022, time.May, 28), Ratio: decimal.NewFromInt(1), Builtin: true},
+	{From: "MATIC", To: "POL", Date: utcDay(2024, time.September, 4), Ratio: decimal.NewFromInt(1), Builtin: true},
+}
+
+func defaultForks() []Fork {
+	return append([]Fork{}, builtinForks...)
+}
+
+func defaultMigrations() []Migration {
+	return append([]Migration{}, builtinMigrations...)
+}
+
+// MergeForks returns forks with each fork of the same asset replaced by the one in override.
+func MergeForks(forks, override []Fork) []Fork {
+	replaced := map[string]bool{}
+	for _, f := range override {
+		replaced[strings.ToUpper(f.Asset)] = true
+	}
+	var res []Fork
+	for _, f := range forks {
+		if !replaced[strings.ToUpper(f.Asset)] {
+			res = append(res, f)
+		}
+	}
+	return append(res, override...)
+}
+
+// MergeMigrations returns ms with each migration of the same asset replaced by the ones in
+// override.
+func MergeMigrations(ms, override []Migration) []Migration {
+	replaced := map[string]bool{}
+	for _, m := range override {
+		replaced[strings.ToUpper(m.From)] = true
+	}
+	var res []Migration
+	for _, m := range ms {
+		if !replaced[strings.ToUpper(m.From)] {
+			res = append(res, m)
+		}
+	}
+	return append(res, override...)
+}
+
+// forkOf returns the known fork that created asset.
+func (s *State) forkOf(asset string) (Fork, bool) {
+	asset = strings.TrimSpace(asset)
+	for _, f := range s.Forks {
+		if strings.EqualFold(f.Asset, asset) {
+			return f, true
+		}
+	}
+	return Fork{}, false
+}
+
+// forkAirdrop reports whether tx is an airdrop of coins of a known fork within a year of the split,
+// i.e. the exchange or wallet crediting the holders of the parent asset.
+func forkAirdrop(s *State, tx Tx) bool {
+	f, ok := s.forkOf(tx.Commodity)
+	if !ok || !tx.Amount.IsPositive() {
+		return false
+	}
+	return f.Date.IsZero() || (!tx.Time.Before(f.Date) && tx.Time.Before(f.Date.AddDate(1, 0, 0)))
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Fee kinds of FeePaid.Kind.
+const (
+	FeeTrading = "trading" // fees of buys, sells and trades and fee-only exchange rows
+	FeeNetwork = "network" // gas and the network fees of transfers
+	FeeMargin  = "margin"  // margin rollover and interest and futures fees
+)
+
+// FeePaid is a fee as charged, for the fees report. The fee itself is booked by the handler of its
+// transaction (folded into basis or proceeds, disposed of, or deducted); this only lists it.
+type FeePaid struct {
+	Time        time.Time       `json:"time"`
+	Wallet      string          `json:"wallet"`
+	Kind        string          `json:"kind"`
+	Commodity   string          `json:"commodity"` // asset the fee was charged in
+	Amount      decimal.Decimal `json:"amount"`    // in Commodity
+	Value       decimal.Decimal `json:"value"`     // in the report currency, zero when it could not be valued
+	SourceFile  string          `json:"source_file"`
+	ReferenceID string          `json:"reference_id"`
+}
+
+// recordTxFees lists the fee of tx handled by handler key: the fee column of trades, or the
+// amount of fee-only rows. Margin, derivatives and transfer fees are listed by their handlers.
+func recordTxFees(s *State, key string, tx Tx) error {
+	switch key {
+	case "fee", "transaction fee", "trading fee":
+		return recordFee(s, FeeTrading, tx, tx.Commodity, tx.Amount, tx.Cost)
+	case "network fee", "gas":
+		return recordFee(s, FeeNetwork, tx, tx.Commodity, tx.Amount, tx.Cost)
+	case "transfer", "rollover", "margin_fee", "margin fee", "margin interest",
+		"margin", "margin_pnl", "margin pnl", "realized pnl", "futures_pnl", "funding", "futures_fee":
+		return nil
+	}
+	asset := feeAsset(s, tx)
+	if asset == "" {
+		asset = tx.Currency
+	}
+	return recordFee(s, FeeTrading, tx, asset, tx.Fee, decimal.Zero)
+}
+
+// recordFee adds amount of asset paid as a fee of kind to State.Fees. A zero value is looked up
+// (fiat as-is, crypto with the price source); a fee that cannot be valued is listed with value
+// zero.
+func recordFee(s *State, kind string, tx Tx, asset string, amount, value decimal.Decimal) error {
+	amount = amount.Abs()
+	if amount.IsZero() {
+		return nil
+	}
+	value = value.Abs()
+	if value.IsZero() {
+		if asset == "" {
+			// no currency given: the fee is in the report currency like the cost
+			value = amount
+		} else {
+			v, err := valueIn(s, asset, amount, tx.Time)
+			if errors.Is(err, ErrOffline) {
+				return err
+			}
+			value = v
+		}
+	}
+	s.Fees = append(s.Fees, FeePaid{
+		Time:        tx.Time,
+		Wallet:      tx.Wallet,
+		Kind:        kind,
+		Commodity:   asset,
+		Amount:      amount,
+		Value:       value,
+		SourceFile:  tx.SourceFile,
+		ReferenceID: tx.ReferenceID,
+	})
+	return nil
+}
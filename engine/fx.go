@@ -0,0 +1,83 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// trackForeignFiat books the fiat side of a buy or sell priced in a currency other than the base
+// currency (State.FXGains). A sale adds the currency received to the inventory of the wallet at
+// its base value on the day; a purchase disposes of the currency paid at its base value on the
+// day, realizing the exchange gain or loss since it was received (type "fx"). Currency paid
+// beyond the amount held, e.g. deposited from a bank account that is not imported, is taken at the
+// day's rate without a gain.
+func trackForeignFiat(s *State, handler string, tx Tx) error {
+	if tx.OrigCurrency == "" || !tx.FXRate.IsPositive() {
+		return nil
+	}
+	currency := s.Assets.fiatOf(tx.OrigCurrency)
+	if strings.EqualFold(currency, s.BaseCurrency) {
+		return nil
+	}
+	switch handler {
+	case "sell":
+		value := tx.Cost.Sub(tx.Fee)
+		if !value.IsPositive() {
+			return nil
+		}
+		addInventory(s, tx.Wallet, currency, InventoryEntry{
+			Time:        tx.Time,
+			Amount:      value.Div(tx.FXRate),
+			UnitCost:    tx.FXRate,
+			TotalCost:   value,
+			SourceFiles: []string{tx.SourceFile},
+			SourceLine:  tx.SourceLine,
+			ReferenceID: tx.ReferenceID,
+		})
+	case "buy":
+		if !tx.Cost.IsPositive() {
+			return nil
+		}
+		amount := tx.Cost.Div(tx.FXRate)
+		held := decimal.Zero
+		for w, commods := range s.Inventories {
+			if w != tx.Wallet && !s.GlobalFIFO {
+				continue
+			}
+			for _, lot := range commods[currency] {
+				held = held.Add(lot.Amount)
+			}
+		}
+		use := minDecimal(amount, held)
+		if use.IsPositive() {
+			fx := tx
+			fx.Type = "fx"
+			fx.Commodity = currency
+			fx.Amount = use.Neg()
+			fx.Cost = tx.Cost.Mul(use).Div(amount)
+			fx.Fee = decimal.Zero
+			fx.PricePerUnit = tx.FXRate
+			fx.Currency = s.BaseCurrency
+			if err := handleSell(s, fx); err != nil {
+				return err
+			}
+		}
+		if use.LessThan(amount) {
+			key := tx.Wallet + "|" + currency
+			if s.fxUncovered == nil {
+				s.fxUncovered = map[string]bool{}
+			}
+			if !s.fxUncovered[key] {
+				s.fxUncovered[key] = true
+				s.Warnf("FX: %s %s paid in wallet %s beyond the %s held (deposited from outside the imported data?); no exchange gain computed for it ref=%s (further ones in this wallet not listed)",
+					amount.Sub(use).StringFixed(2), currency, tx.Wallet, currency, tx.ReferenceID)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// takeOldestLots prepares wallet for consuming amount of commodity under State.GlobalFIFO: the
+// oldest units held in any wallet are swapped into the head of wallet's lots, and the other
+// wallets get as many of wallet's later units in return, so every wallet keeps its balance.
+func takeOldestLots(s *State, wallet, commodity string, amount decimal.Decimal) {
+	held := decimal.Zero
+	for _, lot := range s.Inventories[wallet][commodity] {
+		held = held.Add(lot.Amount)
+	}
+	amount = minDecimal(amount, held)
+	wallets := []string{}
+	for w, commods := range s.Inventories {
+		if len(commods[commodity]) > 0 {
+			wallets = append(wallets, w)
+		}
+	}
+	sort.Strings(wallets)
+	type ref struct {
+		wallet string
+		lot    InventoryEntry
+	}
+	refs := []ref{}
+	for _, w := range wallets {
+		for _, lot := range s.Inventories[w][commodity] {
+			refs = append(refs, ref{w, lot})
+		}
+	}
+	sort.SliceStable(refs, func(i, j int) bool { return refs[i].lot.Time.Before(refs[j].lot.Time) })
+	// units of each wallet among the oldest amount units
+	taken := map[string]decimal.Decimal{}
+	borrowed := decimal.Zero
+	for _, r := range refs {
+		if !amount.IsPositive() {
+			break
+		}
+		use := minDecimal(r.lot.Amount, amount)
+		amount = amount.Sub(use)
+		taken[r.wallet] = taken[r.wallet].Add(use)
+		if r.wallet != wallet {
+			borrowed = borrowed.Add(use)
+		}
+	}
+	if !borrowed.IsPositive() {
+		return
+	}
+	head, rest := splitLots(s.Inventories[wallet][commodity], taken[wallet])
+	give, keep := splitLots(rest, borrowed)
+	for _, w := range wallets {
+		if w == wallet || !taken[w].IsPositive() {
+			continue
+		}
+		oldest, later := splitLots(s.Inventories[w][commodity], taken[w])
+		head = append(head, oldest...)
+		var back []InventoryEntry
+		back, give = splitLots(give, taken[w])
+		s.Inventories[w][commodity] = sortLots(append(back, later...))
+	}
+	s.Inventories[wallet][commodity] = append(sortLots(head), keep...)
+}
+
+// splitLots splits lots (oldest first) into the first amount units and the rest.
+func splitLots(lots []InventoryEntry, amount decimal.Decimal) (head, tail []InventoryEntry) {
+	for i, lot := range lots {
+		if !amount.IsPositive() {
+			return head, append(tail, lots[i:]...)
+		}
+		if lot.Amount.LessThanOrEqual(amount) {
+			head = append(head, lot)
+			amount = amount.Sub(lot.Amount)
+			continue
+		}
+		first, second := lot, lot
+		first.Amount = amount
+		first.TotalCost = lot.UnitCost.Mul(amount)
+		second.Amount = lot.Amount.Sub(amount)
+		second.TotalCost = lot.TotalCost.Sub(first.TotalCost)
+		head = append(head, first)
+		tail = append(tail, second)
+		amount = decimal.Zero
+	}
+	return head, tail
+}
+
+// sortLots orders lots by acquisition time, keeping the order of lots acquired at the same time.
+func sortLots(lots []InventoryEntry) []InventoryEntry {
+	sort.SliceStable(lots, func(i, j int) bool { return lots[i].Time.Before(lots[j].Time) })
+	return lots
+}
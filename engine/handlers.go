@@ -0,0 +1,828 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func handleBuy(s *State, tx Tx) error {
+	if tx.Amount.Cmp(decimal.Zero) <= 0 {
+		// treat as buy of positive amount; if negative probably recorded as sell elsewhere
+	}
+	wallet := tx.Wallet
+	commodity := tx.Commodity
+	amount := tx.Amount.Abs()
+	unitCost := decimal.Zero
+	if !amount.IsZero() {
+		unitCost = tx.Cost.Div(amount)
+	}
+	entry := InventoryEntry{
+		Time:        tx.Time,
+		Amount:      amount,
+		UnitCost:    unitCost,
+		TotalCost:   unitCost.Mul(amount),
+		SourceFiles: []string{tx.SourceFile},
+		SourceLine:  tx.SourceLine,
+		ReferenceID: tx.ReferenceID,
+	}
+	if s.Verbose {
+		log.Printf("BUY: wallet=%s commodity=%s amt=%s unitCost=%s total=%s", wallet, commodity, amount.String(), unitCost.String(), entry.TotalCost.String())
+	}
+	addInventory(s, wallet, commodity, entry)
+	return nil
+}
+
+func handleIncome(s *State, tx Tx) error {
+	// Rewards/stakes: add to inventory and mark income (taxable in year)
+	wallet := tx.Wallet
+	commodity := tx.Commodity
+	amount := tx.Amount
+	if amount.IsZero() {
+		return nil
+	}
+	amountAbs := amount.Abs()
+	// Use provided cost if available; otherwise zero
+	unitCost := decimal.Zero
+	totalCost := decimal.Zero
+	if !tx.Cost.IsZero() {
+		totalCost = tx.Cost
+		if !amountAbs.IsZero() {
+			unitCost = totalCost.Div(amountAbs)
+		}
+	} else if s.Prices != nil {
+		p, err := s.Prices.Price(commodity, s.PriceCurrency, tx.Time)
+		switch {
+		case err == nil:
+			unitCost = p
+			totalCost = p.Mul(amountAbs)
+		case errors.Is(err, ErrOffline):
+			return fmt.Errorf("valuing income %s %s ref=%s: %w", amountAbs.String(), commodity, tx.ReferenceID, err)
+		default:
+			s.Warnf("INCOME: no price for %s/%s on %s (ref=%s): %v", commodity, s.PriceCurrency, tx.Time.Format("2006-01-02"), tx.ReferenceID, err)
+		}
+	}
+	// Add to inventory
+	entry := InventoryEntry{
+		Time:        tx.Time,
+		Amount:      amountAbs,
+		UnitCost:    unitCost,
+		TotalCost:   totalCost,
+		SourceFiles: []string{tx.SourceFile},
+		SourceLine:  tx.SourceLine,
+		ReferenceID: tx.ReferenceID,
+	}
+	addInventory(s, wallet, commodity, entry)
+	year := tx.Time.Year()
+	slot := getGainsSlot(s, year, wallet, commodity)
+	// Income should be recorded as the fair value at receipt; we approximate with tx.Cost if present else zero
+	slot.Income = slot.Income.Add(totalCost)
+	s.Incomes = append(s.Incomes, IncomeEvent{
+		Time:        tx.Time,
+		Wallet:      wallet,
+		Commodity:   commodity,
+		Type:        tx.Type,
+		Amount:      amountAbs,
+		Value:       totalCost,
+		SourceFile:  tx.SourceFile,
+		ReferenceID: tx.ReferenceID,
+	})
+	if s.Verbose {
+		log.Printf("INCOME: wallet=%s commodity=%s amt=%s value=%s year=%d", wallet, commodity, amountAbs.String(), totalCost.String(), year)
+	}
+	return nil
+}
+
+func handleSell(s *State, tx Tx) error {
+	wallet := tx.Wallet
+	commodity := tx.Commodity
+	amount := tx.Amount.Abs() // amount sold
+	if amount.IsZero() {
+		// no-op
+		return nil
+	}
+	grossProceeds := tx.Cost
+	// If cost field was not provided, attempt to compute proceeds from price*amount
+	if grossProceeds.IsZero() {
+		if !tx.PricePerUnit.IsZero() {
+			grossProceeds = tx.PricePerUnit.Mul(amount)
+		}
+	}
+	// Fees reduce proceeds for sells
+	proceedsTotal := grossProceeds.Sub(tx.Fee)
+	if s.Verbose {
+		log.Printf("SELL: wallet=%s commodity=%s amt=%s proceeds=%s fee=%s", wallet, commodity, amount.String(), proceedsTotal.String(), tx.Fee.String())
+	}
+	var cession *Cession
+	if s.PortfolioCost && !s.Assets.IsFiat(commodity) {
+		var err error
+		if cession, err = portfolioCession(s, tx, amount, grossProceeds); err != nil {
+			return err
+		}
+	}
+	dispose := func(entry InventoryEntry, use decimal.Decimal) {
+		portionCostBasis := entry.UnitCost.Mul(use)
+		if cession != nil {
+			portionCostBasis = cession.CostShare.Mul(use).Div(amount)
+		}
+		// allocate matching portion of proceeds and fees proportionally
+		portionProceeds := proceedsTotal.Mul(use).Div(amount)
+		portionGross := grossProceeds.Mul(use).Div(amount)
+		portionFee := tx.Fee.Mul(use).Div(amount)
+		// determine holding period
+		holdingDays := tx.Time.Sub(entry.Time).Hours() / 24.0
+		year := tx.Time.Year()
+		gainsSlot := getGainsSlot(s, year, wallet, commodity)
+		gain := portionProceeds.Sub(portionCostBasis)
+		long := s.IsLongTerm(entry.Time, tx.Time)
+		if long {
+			gainsSlot.Long = gainsSlot.Long.Add(gain)
+		} else {
+			gainsSlot.Short = gainsSlot.Short.Add(gain)
+		}
+		disposal := Disposal{
+			Wallet:       wallet,
+			Commodity:    commodity,
+			Acquired:     entry.Time,
+			Disposed:     tx.Time,
+			Amount:       use,
+			UnitCost:     entry.UnitCost,
+			CostBasis:    portionCostBasis,
+			Proceeds:     portionGross,
+			Fee:          portionFee,
+			Gain:         gain,
+			HoldingDays:  holdingDays,
+			Long:         long,
+			SourceFiles:  append(append([]string{}, entry.SourceFiles...), tx.SourceFile),
+			ReferenceID:  tx.ReferenceID,
+			Type:         NormalizeType(tx.Type),
+			AcquiredLine: entry.SourceLine,
+			AcquiredRef:  entry.ReferenceID,
+			DisposedFile: tx.SourceFile,
+			DisposedLine: tx.SourceLine,
+			LotID:        entry.ID,
+		}
+		if len(entry.SourceFiles) > 0 {
+			disposal.AcquiredFile = entry.SourceFiles[0]
+		}
+		disposal.Exempt = s.Rules != nil && s.Rules.Exempt(s, disposal)
+		s.Disposals = append(s.Disposals, disposal)
+		if je := s.currentJournal(); je != nil {
+			je.Consumed = append(je.Consumed, JournalLot{Wallet: wallet, Commodity: commodity, Lot: InventoryEntry{
+				Time: entry.Time, Amount: use, UnitCost: entry.UnitCost, TotalCost: portionCostBasis,
+				SourceFiles: entry.SourceFiles, SourceLine: entry.SourceLine, ReferenceID: entry.ReferenceID, ID: entry.ID,
+			}})
+			je.Disposals = append(je.Disposals, disposal)
+		}
+		if s.Verbose {
+			holdingStr := "SHORT"
+			if long {
+				holdingStr = "LONG"
+			}
+			log.Printf("  Consumed FIFO entry: time=%s use=%s unitCost=%s cost=%s proceeds=%s gain=%s holdingDays=%.1f -> %s",
+				entry.Time.Format("2006-01-02"), use.String(), entry.UnitCost.String(), portionCostBasis.String(), portionProceeds.String(), gain.String(), holdingDays, holdingStr)
+		}
+	}
+	first := len(s.Disposals)
+	if s.SuperficialLoss {
+		defer func() { denySuperficialLoss(s, tx, amount, first) }()
+	}
+	lotBasis := decimal.Zero
+	remaining := consumeLots(s, wallet, commodity, amount, func(entry InventoryEntry, use decimal.Decimal) {
+		lotBasis = lotBasis.Add(entry.UnitCost.Mul(use))
+		dispose(entry, use)
+	})
+	if cession != nil {
+		allocatePortfolioCost(s, cession, lotBasis)
+	}
+	eps := decimal.NewFromFloat(1e-9)
+	if remaining.Cmp(eps) > 0 {
+		// sold more than held: the rest is realized against an assumed lot, see -missing-basis
+		lot, err := missingBasisLot(s, tx, remaining)
+		if err != nil {
+			return err
+		}
+		if lot != nil {
+			dispose(*lot, remaining)
+			return nil
+		}
+		// sold more than inventory: treat as negative inventory (short) or ignore with warning
+		if err := s.shortf("WARNING: selling more (%s) than available in inventory for %s/%s; remaining=%s ref=%s", amount.String(), wallet, commodity, remaining.String(), tx.ReferenceID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func handleAirdrop(s *State, tx Tx) error {
+	// Airdrops are either income at fair market value on receipt (US-style) or an acquisition
+	// at zero cost that is only taxed on disposal (several EU regimes), see -airdrop. Coins of a
+	// known chain split credited as an airdrop are a fork, see -fork.
+	if forkAirdrop(s, tx) {
+		return handleFork(s, tx)
+	}
+	if s.AirdropTreatment == "zero-cost" {
+		tx.Cost = decimal.Zero
+		tx.PricePerUnit = decimal.Zero
+		return handleBuy(s, tx)
+	}
+	return handleIncome(s, tx)
+}
+
+func handleGift(s *State, tx Tx) error {
+	// Gifts received are acquisitions at the donor's declared basis and acquisition date (columns
+	// basis/cost and acquired), so later sales compute the right gain and holding period. Gifts
+	// sent either leave inventory at basis or are disposals at fair market value, see -gift.
+	t := NormalizeType(tx.Type)
+	received := strings.Contains(t, "received") || (t == "gift" && tx.Amount.IsPositive())
+	if received {
+		// the donor's basis; the importers fill Cost from the value column, which is only the
+		// fallback
+		if b := FirstNonEmpty(tx.Raw, "basis", "cost_basis"); b != "" {
+			tx.Cost = ParseDecimal(b)
+			if !tx.FXRate.IsZero() {
+				tx.Cost = tx.Cost.Mul(tx.FXRate)
+			}
+		}
+		amount := tx.Amount.Abs()
+		if amount.IsZero() {
+			return nil
+		}
+		acquired := tx.Time
+		if d := FirstNonEmpty(tx.Raw, "acquired", "acquisition_date", "basis_date"); d != "" {
+			loc := tx.Location
+			if loc == nil {
+				loc = time.UTC
+			}
+			at, err := ParseTimeIn(d, loc)
+			if err != nil {
+				s.Warnf("GIFT: cannot parse acquisition date %q ref=%s: %v; using the receipt date", d, tx.ReferenceID, err)
+			} else {
+				acquired = at
+			}
+		}
+		entry := InventoryEntry{
+			Time:        acquired,
+			Amount:      amount,
+			UnitCost:    tx.Cost.Div(amount),
+			TotalCost:   tx.Cost,
+			SourceFiles: []string{tx.SourceFile},
+			SourceLine:  tx.SourceLine,
+			ReferenceID: tx.ReferenceID,
+		}
+		if s.Verbose {
+			log.Printf("GIFT RECEIVED: wallet=%s commodity=%s amt=%s basis=%s acquired=%s", tx.Wallet, tx.Commodity, amount.String(), tx.Cost.String(), acquired.Format("2006-01-02"))
+		}
+		addInventory(s, tx.Wallet, tx.Commodity, entry)
+		return nil
+	}
+	if s.GiftTreatment == "taxable" {
+		if tx.Cost.IsZero() && tx.PricePerUnit.IsZero() {
+			v, err := valueIn(s, tx.Commodity, tx.Amount.Abs(), tx.Time)
+			if err != nil {
+				if errors.Is(err, ErrOffline) {
+					return err
+				}
+				s.Warnf("GIFT: cannot value %s %s sent ref=%s: %v", tx.Amount.Abs().String(), tx.Commodity, tx.ReferenceID, err)
+			}
+			tx.Cost = v
+		}
+		return handleSell(s, tx)
+	}
+	return removeAtBasis(s, tx, true)
+}
+
+// handleDonation gives coins to charity: the lots leave inventory without a realized gain and the
+// fair market value donated is recorded for the deduction (see report.WriteDonations).
+func handleDonation(s *State, tx Tx) error {
+	tx.Type = "donation"
+	return removeAtBasis(s, tx, true)
+}
+
+// handleLost writes off lost or stolen coins: the lots leave inventory and their basis is booked
+// as a casualty loss, a category separate from short/long gains.
+func handleLost(s *State, tx Tx) error {
+	tx.Type = "lost"
+	n := len(s.Removals)
+	if err := removeAtBasis(s, tx, false); err != nil {
+		return err
+	}
+	if len(s.Removals) == n {
+		return nil
+	}
+	r := s.Removals[n]
+	slot := getGainsSlot(s, tx.Time.Year(), tx.Wallet, tx.Commodity)
+	slot.Casualty = slot.Casualty.Add(r.CostBasis)
+	return nil
+}
+
+// handleLoan keeps collateralized loans out of the gains engine: collateral moves are no-ops (the
+// coins are still owned), borrowed coins are acquired at fair market value without income and
+// repaid coins leave inventory at basis. Liquidations of collateral are disposals at fair market
+// value (or the cost column).
+func handleLoan(s *State, tx Tx) error {
+	t := NormalizeType(tx.Type)
+	switch {
+	case t == "liquidation":
+		if tx.Cost.IsZero() && tx.PricePerUnit.IsZero() {
+			v, err := valueIn(s, tx.Commodity, tx.Amount.Abs(), tx.Time)
+			if err != nil {
+				if errors.Is(err, ErrOffline) {
+					return err
+				}
+				s.Warnf("LIQUIDATION: cannot value %s %s ref=%s: %v", tx.Amount.Abs().String(), tx.Commodity, tx.ReferenceID, err)
+			}
+			tx.Cost = v
+		}
+		return handleSell(s, tx)
+	case t == "borrow" || t == "loan" || t == "loan withdrawal":
+		if tx.Amount.Sign() < 0 {
+			return nil
+		}
+		if tx.Cost.IsZero() {
+			v, err := valueIn(s, tx.Commodity, tx.Amount, tx.Time)
+			if err != nil {
+				if errors.Is(err, ErrOffline) {
+					return err
+				}
+				s.Warnf("LOAN: cannot value borrowed %s %s ref=%s: %v", tx.Amount.String(), tx.Commodity, tx.ReferenceID, err)
+			}
+			tx.Cost = v
+		}
+		return handleBuy(s, tx)
+	case t == "repay" || t == "repayment":
+		tx.Type = "repay"
+		return removeAtBasis(s, tx, false)
+	}
+	if s.Verbose {
+		log.Printf("COLLATERAL: %s %s %s in %s (no tax event)", tx.Type, tx.Amount.String(), tx.Commodity, tx.Wallet)
+	}
+	return nil
+}
+
+// IsSpendType reports whether typ pays for goods or services with crypto, see handleSpend.
+func IsSpendType(typ string) bool {
+	switch NormalizeType(typ) {
+	case "spend", "payment", "card spend", "card payment", "purchase":
+		return true
+	}
+	return false
+}
+
+// handleSpend disposes of crypto spent on goods or services. Proceeds are the fiat amount charged
+// (cost column, or a native amount/fiat amount column as in card exports), otherwise the market
+// value of the coins.
+func handleSpend(s *State, tx Tx) error {
+	if tx.Cost.IsZero() && tx.PricePerUnit.IsZero() {
+		if v := FirstNonEmpty(tx.Raw, "native amount", "native_amount", "fiat amount", "fiat_amount"); v != "" {
+			// in the row's currency; ConvertToBase only converted the cost columns
+			tx.Cost = ParseDecimal(v).Abs()
+			if !tx.FXRate.IsZero() {
+				tx.Cost = tx.Cost.Mul(tx.FXRate)
+			}
+		} else {
+			v, err := valueIn(s, tx.Commodity, tx.Amount.Abs(), tx.Time)
+			if err != nil {
+				if errors.Is(err, ErrOffline) {
+					return err
+				}
+				s.Warnf("SPEND: cannot value %s %s ref=%s: %v", tx.Amount.Abs().String(), tx.Commodity, tx.ReferenceID, err)
+			}
+			tx.Cost = v
+		}
+	}
+	return handleSell(s, tx)
+}
+
+// feeAsset returns the asset a tx's fee was charged in when the row names one.
+func feeAsset(s *State, tx Tx) string {
+	return strings.ToUpper(s.Assets.Normalize(FirstNonEmpty(tx.Raw, "fee_asset", "fee asset", "fee_currency", "fee currency", "feecurrency", "fee coin", "fee_coin")))
+}
+
+// disposeCryptoFee handles a fee charged in a crypto asset (-crypto-fees): the fee coins are a
+// micro-disposal at market value, and tx continues with the fee as that fiat value.
+func disposeCryptoFee(s *State, tx Tx) (Tx, error) {
+	asset := feeAsset(s, tx)
+	if asset == "" || s.Assets.IsFiat(asset) || tx.Fee.IsZero() {
+		return tx, nil
+	}
+	amount := tx.Fee.Abs()
+	value, err := valueIn(s, asset, amount, tx.Time)
+	if err != nil {
+		if errors.Is(err, ErrOffline) {
+			return tx, err
+		}
+		s.Warnf("FEE: cannot value %s %s fee ref=%s: %v", amount.String(), asset, tx.ReferenceID, err)
+	}
+	fee := tx
+	fee.Type = "fee"
+	fee.Commodity = asset
+	fee.Amount = amount.Neg()
+	fee.Cost = value
+	fee.PricePerUnit = decimal.Zero
+	fee.Fee = decimal.Zero
+	if err := handleSell(s, fee); err != nil {
+		return tx, err
+	}
+	if s.Verbose {
+		log.Printf("FEE DISPOSAL: wallet=%s %s %s valued %s ref=%s", tx.Wallet, amount.String(), asset, value.String(), tx.ReferenceID)
+	}
+	if strings.Contains(NormalizeType(tx.Type), "buy") {
+		// the parsers fold the fee into the cost of buys; swap the coin amount for its value
+		tx.Cost = tx.Cost.Sub(amount).Add(value)
+	}
+	tx.Fee = value
+	return tx, nil
+}
+
+// handleCryptoFee handles a row that only charges a fee. With -crypto-fees a fee in crypto is a
+// disposal at market value; otherwise it is consumed like a sell without proceeds.
+func handleCryptoFee(s *State, tx Tx) error {
+	if s.CryptoFees && !s.Assets.IsFiat(tx.Commodity) && tx.Cost.IsZero() {
+		v, err := valueIn(s, tx.Commodity, tx.Amount.Abs(), tx.Time)
+		if err != nil {
+			if errors.Is(err, ErrOffline) {
+				return err
+			}
+			s.Warnf("FEE: cannot value %s %s fee ref=%s: %v", tx.Amount.Abs().String(), tx.Commodity, tx.ReferenceID, err)
+		}
+		tx.Cost = v
+	}
+	return handleSell(s, tx)
+}
+
+// handleRebase reconciles tokens whose balance changes without transactions. Snapshot rows
+// (balance, balance snapshot, snapshot) carry the balance held, rebase/balance adjustment rows the
+// change. An increase is income at market value or, with -rebase adjust, spread over the existing
+// lots keeping their basis; a decrease always shrinks the lots keeping their basis.
+func handleRebase(s *State, tx Tx) error {
+	held := decimal.Zero
+	for _, lot := range s.Inventories[tx.Wallet][tx.Commodity] {
+		held = held.Add(lot.Amount)
+	}
+	delta := tx.Amount
+	switch NormalizeType(tx.Type) {
+	case "balance", "balance snapshot", "snapshot":
+		delta = tx.Amount.Sub(held)
+	}
+	if delta.IsZero() {
+		return nil
+	}
+	if s.Verbose {
+		log.Printf("REBASE: wallet=%s commodity=%s held=%s delta=%s", tx.Wallet, tx.Commodity, held.String(), delta.String())
+	}
+	if (delta.IsPositive() && s.RebaseTreatment == "income") || !held.IsPositive() {
+		if delta.IsNegative() {
+			return s.shortf("REBASE: %s %s balance drops by %s with nothing held ref=%s", tx.Wallet, tx.Commodity, delta.Neg().String(), tx.ReferenceID)
+		}
+		income := tx
+		income.Type = "rebase"
+		income.Amount = delta
+		income.Cost = decimal.Zero
+		income.PricePerUnit = decimal.Zero
+		return handleIncome(s, income)
+	}
+	newHeld := held.Add(delta)
+	lots := s.Inventories[tx.Wallet][tx.Commodity]
+	for i := range lots {
+		lots[i].Amount = lots[i].Amount.Mul(newHeld).Div(held)
+		if lots[i].Amount.IsPositive() {
+			lots[i].UnitCost = lots[i].TotalCost.Div(lots[i].Amount)
+		}
+	}
+	return nil
+}
+
+// removeAtBasis takes tx.Amount of tx.Commodity out of inventory FIFO without realizing a gain
+// and records it in State.Removals, valued at fair market value when valued is set.
+func removeAtBasis(s *State, tx Tx, valued bool) error {
+	amount := tx.Amount.Abs()
+	if amount.IsZero() {
+		return nil
+	}
+	value := tx.Cost
+	if value.IsZero() && valued {
+		v, err := valueIn(s, tx.Commodity, amount, tx.Time)
+		if err != nil {
+			if errors.Is(err, ErrOffline) {
+				return err
+			}
+			s.Warnf("%s: cannot value %s %s ref=%s: %v", strings.ToUpper(tx.Type), amount.String(), tx.Commodity, tx.ReferenceID, err)
+		}
+		value = v
+	}
+	basis, remaining := removeLots(s, tx.Wallet, tx.Commodity, amount)
+	if remaining.Cmp(decimal.NewFromFloat(1e-9)) > 0 {
+		if err := s.shortf("WARNING: removing more (%s) than available in inventory for %s/%s; remaining=%s ref=%s", amount.String(), tx.Wallet, tx.Commodity, remaining.String(), tx.ReferenceID); err != nil {
+			return err
+		}
+	}
+	s.Removals = append(s.Removals, Removal{
+		Time:        tx.Time,
+		Wallet:      tx.Wallet,
+		Commodity:   tx.Commodity,
+		Type:        NormalizeType(tx.Type),
+		Amount:      amount,
+		CostBasis:   basis,
+		Value:       value,
+		SourceFile:  tx.SourceFile,
+		ReferenceID: tx.ReferenceID,
+	})
+	if s.Verbose {
+		log.Printf("%s: wallet=%s commodity=%s amt=%s basis=%s value=%s", strings.ToUpper(tx.Type), tx.Wallet, tx.Commodity, amount.String(), basis.String(), value.String())
+	}
+	return nil
+}
+
+// handleMargin books the realized PnL of a closed margin position (tx.Amount in tx.Commodity,
+// signed) as a short-term gain or loss; the fee, if any, is a deductible margin cost.
+func handleMargin(s *State, tx Tx) error {
+	pnl, err := valueIn(s, tx.Commodity, tx.Amount, tx.Time)
+	if err != nil {
+		if errors.Is(err, ErrOffline) {
+			return err
+		}
+		s.Warnf("MARGIN: cannot value PnL %s %s ref=%s: %v", tx.Amount.String(), tx.Commodity, tx.ReferenceID, err)
+	}
+	slot := getGainsSlot(s, tx.Time.Year(), tx.Wallet, tx.Commodity)
+	slot.Short = slot.Short.Add(pnl)
+	if s.Verbose {
+		log.Printf("MARGIN: wallet=%s asset=%s pnl=%s", tx.Wallet, tx.Commodity, pnl.String())
+	}
+	return handleMarginFee(s, tx)
+}
+
+// handleDerivative books a futures PnL, funding payment or fee into State.Derivatives.
+func handleDerivative(s *State, tx Tx) error {
+	v, err := valueIn(s, tx.Commodity, tx.Amount, tx.Time)
+	if err != nil {
+		if errors.Is(err, ErrOffline) {
+			return err
+		}
+		s.Warnf("DERIVATIVES: cannot value %s %s %s ref=%s: %v", tx.Type, tx.Amount.String(), tx.Commodity, tx.ReferenceID, err)
+	}
+	contract := tx.Raw["contract"]
+	if contract == "" {
+		contract = tx.Commodity
+	}
+	y := tx.Time.Year()
+	if _, ok := s.Derivatives[y]; !ok {
+		s.Derivatives[y] = map[string]map[string]*DerivativesResult{}
+	}
+	if _, ok := s.Derivatives[y][tx.Wallet]; !ok {
+		s.Derivatives[y][tx.Wallet] = map[string]*DerivativesResult{}
+	}
+	d, ok := s.Derivatives[y][tx.Wallet][contract]
+	if !ok {
+		d = &DerivativesResult{Asset: tx.Commodity}
+		s.Derivatives[y][tx.Wallet][contract] = d
+	}
+	switch NormalizeType(tx.Type) {
+	case "futures_pnl":
+		d.PnL = d.PnL.Add(v)
+	case "funding":
+		d.Funding = d.Funding.Add(v)
+	case "futures_fee":
+		d.Fees = d.Fees.Add(v)
+		if err := recordFee(s, FeeMargin, tx, tx.Commodity, tx.Amount, v); err != nil {
+			return err
+		}
+	}
+	if s.Verbose {
+		log.Printf("DERIVATIVES: wallet=%s contract=%s %s=%s", tx.Wallet, contract, tx.Type, v.String())
+	}
+	return nil
+}
+
+// handleMarginFee books rollover/interest/trading fees of margin positions as deductible costs:
+// they reduce short-term gains and are tracked separately as MarginFees.
+func handleMarginFee(s *State, tx Tx) error {
+	feeAmount := tx.Fee.Abs()
+	if feeAmount.IsZero() && NormalizeType(tx.Type) != "margin" {
+		// fee-only rows may carry the fee in the amount column
+		feeAmount = tx.Amount.Abs()
+	}
+	if feeAmount.IsZero() {
+		return nil
+	}
+	fee, err := valueIn(s, tx.Commodity, feeAmount, tx.Time)
+	if err != nil {
+		if errors.Is(err, ErrOffline) {
+			return err
+		}
+		s.Warnf("MARGIN: cannot value fee %s %s ref=%s: %v", feeAmount.String(), tx.Commodity, tx.ReferenceID, err)
+	}
+	slot := getGainsSlot(s, tx.Time.Year(), tx.Wallet, tx.Commodity)
+	slot.Short = slot.Short.Sub(fee)
+	slot.MarginFees = slot.MarginFees.Add(fee)
+	if err := recordFee(s, FeeMargin, tx, tx.Commodity, feeAmount, fee); err != nil {
+		return err
+	}
+	if s.Verbose {
+		log.Printf("MARGIN FEE: wallet=%s asset=%s fee=%s", tx.Wallet, tx.Commodity, fee.String())
+	}
+	return nil
+}
+
+// forkParent returns the asset tx's forked coins split from: the parent column, else the parent of
+// the known fork (State.Forks).
+func forkParent(s *State, tx Tx) string {
+	if p := FirstNonEmpty(tx.Raw, "parent", "fork_of", "original_asset", "from_asset"); p != "" {
+		return strings.TrimSpace(p)
+	}
+	f, _ := s.forkOf(tx.Commodity)
+	return f.Parent
+}
+
+func handleFork(s *State, tx Tx) error {
+	// Coins received from a chain split: zero basis, income at fair market value, or a share of
+	// the parent asset's basis proportional to market value (see -fork).
+	switch s.ForkTreatment {
+	case "income":
+		return handleIncome(s, tx)
+	case "split":
+		return splitForkBasis(s, tx)
+	}
+	tx.Cost = decimal.Zero
+	tx.PricePerUnit = decimal.Zero
+	return handleBuy(s, tx)
+}
+
+// splitForkBasis moves part of the parent asset's basis to the forked coins. Each parent lot in
+// the wallet yields a forked lot with the same acquisition time, so the holding period carries over.
+// The share moved is value(fork) / (value(fork) + value(parent)) at the fork date.
+func splitForkBasis(s *State, tx Tx) error {
+	wallet := tx.Wallet
+	commodity := tx.Commodity
+	amount := tx.Amount.Abs()
+	if amount.IsZero() {
+		return nil
+	}
+	parent := forkParent(s, tx)
+	if parent == "" {
+		s.Warnf("FORK: unknown parent asset for %s ref=%s; booking with zero basis", commodity, tx.ReferenceID)
+		tx.Cost = decimal.Zero
+		return handleBuy(s, tx)
+	}
+	ensureInventoryBucket(s, wallet, parent)
+	lots := s.Inventories[wallet][parent]
+	parentAmount := decimal.Zero
+	for _, lot := range lots {
+		parentAmount = parentAmount.Add(lot.Amount)
+	}
+	if parentAmount.IsZero() {
+		s.Warnf("FORK: no %s held in %s for fork %s ref=%s; booking with zero basis", parent, wallet, commodity, tx.ReferenceID)
+		tx.Cost = decimal.Zero
+		return handleBuy(s, tx)
+	}
+	share := decimal.Zero
+	if s.Prices != nil {
+		pf, errF := s.Prices.Price(commodity, s.PriceCurrency, tx.Time)
+		pp, errP := s.Prices.Price(parent, s.PriceCurrency, tx.Time)
+		if errors.Is(errF, ErrOffline) || errors.Is(errP, ErrOffline) {
+			return fmt.Errorf("valuing fork %s/%s ref=%s: %w", commodity, parent, tx.ReferenceID, ErrOffline)
+		}
+		if errF == nil && errP == nil {
+			forkValue := pf.Mul(amount)
+			total := forkValue.Add(pp.Mul(parentAmount))
+			if !total.IsZero() {
+				share = forkValue.Div(total)
+			}
+		}
+	}
+	if share.IsZero() {
+		s.Warnf("FORK: no prices for %s and %s on %s ref=%s; forked coins get zero basis", commodity, parent, tx.Time.Format("2006-01-02"), tx.ReferenceID)
+	}
+	keep := decimal.NewFromInt(1).Sub(share)
+	for i := range lots {
+		lot := &lots[i]
+		moved := lot.TotalCost.Mul(share)
+		forkAmount := amount.Mul(lot.Amount).Div(parentAmount)
+		lot.TotalCost = lot.TotalCost.Mul(keep)
+		if !lot.Amount.IsZero() {
+			lot.UnitCost = lot.TotalCost.Div(lot.Amount)
+		}
+		unit := decimal.Zero
+		if !forkAmount.IsZero() {
+			unit = moved.Div(forkAmount)
+		}
+		addInventory(s, wallet, commodity, InventoryEntry{
+			Time:        lot.Time,
+			Amount:      forkAmount,
+			UnitCost:    unit,
+			TotalCost:   moved,
+			SourceFiles: append(append([]string{}, lot.SourceFiles...), tx.SourceFile),
+			SourceLine:  tx.SourceLine,
+			ReferenceID: tx.ReferenceID,
+		})
+	}
+	if s.Verbose {
+		log.Printf("FORK: wallet=%s %s from %s amt=%s basis share=%s", wallet, commodity, parent, amount.String(), share.String())
+	}
+	return nil
+}
+
+// handleConvert books a trade between crypto assets: both legs at once when pairTrades merged
+// them (see handleSwap), else the one leg as a sale (amount < 0) or purchase (amount > 0) at the
+// value the export gives, or the market value of its asset when it gives none.
+func handleConvert(s *State, tx Tx) error {
+	if tx.Raw["to_commodity"] != "" && tx.Amount.IsNegative() {
+		return handleSwap(s, tx)
+	}
+	if tx.Cost.IsZero() && tx.PricePerUnit.IsZero() && !tx.Amount.IsZero() && !s.Assets.IsFiat(tx.Commodity) {
+		v, err := valueIn(s, tx.Commodity, tx.Amount.Abs(), tx.Time)
+		if err != nil {
+			if errors.Is(err, ErrOffline) {
+				return err
+			}
+			s.Warnf("TRADE: cannot value %s %s of an unpaired trade leg ref=%s: %v", tx.Amount.Abs().String(), tx.Commodity, tx.ReferenceID, err)
+		}
+		tx.Cost = v
+	}
+	if tx.Amount.Cmp(decimal.Zero) < 0 {
+		// treat as sell
+		return handleSell(s, tx)
+	} else if tx.Amount.Cmp(decimal.Zero) > 0 {
+		// treat as buy
+		return handleBuy(s, tx)
+	}
+	return nil
+}
+
+func handleTransfer(s *State, tx Tx) error {
+	// Move FIFO inventory from source wallet (PairedComment) to destination wallet (tx.Wallet) preserving original unit costs and timestamps.
+	srcWallet := strings.TrimSpace(tx.PairedComment)
+	destWallet := tx.Wallet
+	commodity := tx.Commodity
+	amountToMove := tx.Amount.Abs()
+	if amountToMove.IsZero() {
+		return nil
+	}
+	if srcWallet == "" {
+		s.Warnf("TRANSFER: missing source wallet in PairedComment for tx ref=%s", tx.ReferenceID)
+		return nil
+	}
+	extraCost, err := transferFee(s, tx, srcWallet)
+	if err != nil {
+		return err
+	}
+	remaining := moveLots(s, srcWallet, commodity, destWallet, commodity, amountToMove, decimal.NewFromInt(1), extraCost)
+	if remaining.Cmp(decimal.NewFromFloat(1e-9)) > 0 {
+		return s.shortf("TRANSFER WARNING: moved less (%s) than requested (%s) for %s from %s to %s ref=%s", amountToMove.Sub(remaining).String(), amountToMove.String(), commodity, srcWallet, destWallet, tx.ReferenceID)
+	}
+	return nil
+}
+
+// transferFee takes the network fee of a transfer (Raw["network_fee"], in coins of the asset
+// moved) out of the source wallet before the rest is moved, as set by TransferFees:
+// "deductible" removes it at basis and lists it under Removals, "disposal" sells it at market
+// value, and "basis" returns its basis to be added to the moved lots.
+func transferFee(s *State, tx Tx, srcWallet string) (decimal.Decimal, error) {
+	fee := ParseDecimal(tx.Raw["network_fee"]).Abs()
+	if fee.IsZero() {
+		return decimal.Zero, nil
+	}
+	feeTx := tx
+	feeTx.Wallet = srcWallet
+	feeTx.Type = "network fee"
+	feeTx.Amount = fee.Neg()
+	feeTx.Cost = decimal.Zero
+	feeTx.PricePerUnit = decimal.Zero
+	feeTx.Fee = decimal.Zero
+	if err := recordFee(s, FeeNetwork, feeTx, tx.Commodity, fee, decimal.Zero); err != nil {
+		return decimal.Zero, err
+	}
+	switch s.TransferFees {
+	case "disposal":
+		v, err := valueIn(s, tx.Commodity, fee, tx.Time)
+		if err != nil {
+			if errors.Is(err, ErrOffline) {
+				return decimal.Zero, err
+			}
+			s.Warnf("NETWORK FEE: cannot value %s %s ref=%s: %v", fee.String(), tx.Commodity, tx.ReferenceID, err)
+		}
+		feeTx.Cost = v
+		return decimal.Zero, handleSell(s, feeTx)
+	case "basis":
+		basis, remaining := removeLots(s, srcWallet, tx.Commodity, fee)
+		if remaining.Cmp(decimal.NewFromFloat(1e-9)) > 0 {
+			if err := s.shortf("WARNING: network fee (%s) exceeds inventory for %s/%s; remaining=%s ref=%s", fee.String(), srcWallet, tx.Commodity, remaining.String(), tx.ReferenceID); err != nil {
+				return decimal.Zero, err
+			}
+		}
+		if s.Verbose {
+			log.Printf("NETWORK FEE: %s %s basis %s added to the lots moved to %s", fee.String(), tx.Commodity, basis.String(), tx.Wallet)
+		}
+		return basis, nil
+	default:
+		return decimal.Zero, removeAtBasis(s, feeTx, true)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// fixedRates is a PriceSource with one rate per asset.
+type fixedRates map[string]decimal.Decimal
+
+func (r fixedRates) Price(asset, currency string, at time.Time) (decimal.Decimal, error) {
+	if p, ok := r[asset]; ok {
+		return p, nil
+	}
+	return decimal.Zero, ErrPriceNotFound
+}
+
+func TestSpendNativeAmountInBase(t *testing.T) {
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	txs := []Tx{
+		{Time: at, Type: "buy", Wallet: "card", Commodity: "BTC", Amount: decimal.RequireFromString("0.01"), Cost: decimal.RequireFromString("500"), Currency: "EUR"},
+		{Time: at.Add(time.Hour), Type: "card spend", Wallet: "card", Commodity: "BTC", Amount: decimal.RequireFromString("-0.01"), Currency: "USD",
+			Raw: map[string]string{"native amount": "-600"}},
+	}
+	prices := fixedRates{"USD": decimal.RequireFromString("0.9")}
+	if err := ConvertToBase(txs, "EUR", prices, nil); err != nil {
+		t.Fatal(err)
+	}
+	s := NewState(false, nil, nil)
+	s.BaseCurrency = "EUR"
+	s.Prices = prices
+	if err := ProcessTransactions(s, txs); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Disposals) != 1 {
+		t.Fatalf("got %d disposals, want 1", len(s.Disposals))
+	}
+	if d := s.Disposals[0]; d.Proceeds.String() != "540" || d.Gain.String() != "40" {
+		t.Errorf("got proceeds %s gain %s, want 540 and 40 (600 USD at 0.9)", d.Proceeds.String(), d.Gain.String())
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import "time"
+
+// IsLongTerm reports whether coins acquired at acquired and disposed of at disposed get the
+// long-term treatment: State.Rules decide when set, otherwise they must have been held for one
+// year, see heldOneYear.
+func (s *State) IsLongTerm(acquired, disposed time.Time) bool {
+	if s.Rules != nil {
+		return s.Rules.LongTerm(s, acquired, disposed)
+	}
+	return heldOneYear(s.HoldingRule, acquired, disposed)
+}
+
+// heldOneYear reports whether coins acquired at acquired and disposed of at disposed were held
+// for one year. The year is counted on calendar dates in the time zone of disposed (the tax time
+// zone), so leap years and DST changes do not shift it: with rule (State.HoldingRule) "more-than"
+// (default; US, Germany) the disposal must be after the anniversary of the acquisition date,
+// with "at-least" the anniversary itself is long term. The anniversary of Feb 29 is Feb 28.
+func heldOneYear(rule string, acquired, disposed time.Time) bool {
+	loc := disposed.Location()
+	ay, am, ad := acquired.In(loc).Date()
+	anniversary := time.Date(ay+1, am, ad, 0, 0, 0, 0, time.UTC)
+	if anniversary.Month() != am {
+		// Feb 29 -> Feb 28
+		anniversary = time.Date(ay+1, am+1, 0, 0, 0, 0, 0, time.UTC)
+	}
+	dy, dm, dd := disposed.Date()
+	day := time.Date(dy, dm, dd, 0, 0, 0, 0, time.UTC)
+	if rule == "at-least" {
+		return !day.Before(anniversary)
+	}
+	return day.After(anniversary)
+}
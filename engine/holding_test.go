@@ -0,0 +1,48 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeldOneYear(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(s string, loc *time.Location) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	tests := []struct {
+		name               string
+		acquired, disposed time.Time
+		moreThan, atLeast  bool
+	}{
+		{"day before the anniversary", at("2023-03-01 10:00", time.UTC), at("2024-02-29 23:59", time.UTC), false, false},
+		{"anniversary, later in the day", at("2023-03-01 10:00", time.UTC), at("2024-03-01 12:00", time.UTC), false, true},
+		{"anniversary, earlier in the day", at("2023-03-01 10:00", time.UTC), at("2024-03-01 08:00", time.UTC), false, true},
+		{"day after the anniversary", at("2023-03-01 10:00", time.UTC), at("2024-03-02 00:00", time.UTC), true, true},
+		{"Feb 29, on Feb 28", at("2024-02-29 10:00", time.UTC), at("2025-02-28 12:00", time.UTC), false, true},
+		{"Feb 29, on Mar 1", at("2024-02-29 10:00", time.UTC), at("2025-03-01 12:00", time.UTC), true, true},
+		{"Feb 28 before a leap day, on Feb 29", at("2023-02-28 10:00", time.UTC), at("2024-02-29 12:00", time.UTC), true, true},
+		// 2023-03-01 23:30 UTC is already March 2 in Berlin, the tax time zone
+		{"acquired late in UTC, counted in the tax time zone", at("2023-03-01 23:30", time.UTC), at("2024-03-02 12:00", berlin), false, true},
+		{"across a DST change", at("2023-10-29 01:30", berlin), at("2024-10-30 00:30", berlin), true, true},
+	}
+	for _, tt := range tests {
+		if got := heldOneYear("more-than", tt.acquired, tt.disposed); got != tt.moreThan {
+			t.Errorf("%s: more-than got %v, want %v", tt.name, got, tt.moreThan)
+		}
+		if got := heldOneYear("at-least", tt.acquired, tt.disposed); got != tt.atLeast {
+			t.Errorf("%s: at-least got %v, want %v", tt.name, got, tt.atLeast)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Methods are the cost basis methods State.Method can be set to.
+var Methods = []string{"fifo", "lifo", "hifo", "acb"}
+
+// IsMethod reports whether m is one of Methods.
+func IsMethod(m string) bool {
+	for _, x := range Methods {
+		if x == m {
+			return true
+		}
+	}
+	return false
+}
+
+// lotID names a lot after its acquisition: the asset, the day and a hash of the acquiring row, so
+// the lot gets the same ID in every run over the same inputs ("BTC-20230110-9f86d081"). Parts of a
+// lot moved to another wallet keep its ID.
+func lotID(commodity string, e InventoryEntry) string {
+	file := ""
+	if len(e.SourceFiles) > 0 {
+		file = e.SourceFiles[0]
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s|%s|%d|%s|%s", commodity, file, e.SourceLine, e.ReferenceID, e.Time.UTC().Format(time.RFC3339Nano))
+	return fmt.Sprintf("%s-%s-%08x", commodity, e.Time.UTC().Format("20060102"), h.Sum32())
+}
+
+// LotPick is a disposal instruction (-lots): the lot to take first when a disposal matching the
+// instruction consumes coins of the lot's asset, instead of the oldest lots.
+type LotPick struct {
+	LotID       string
+	Amount      decimal.Decimal // most coins to take from the lot; zero = as many as the disposal needs
+	ReferenceID string          // disposals with this reference id, or
+	Date        time.Time       // disposals on this day (start of the day)
+	Wallet      string          // only disposals from this wallet ("" = any)
+	Line        int             // line of the instruction file, for warnings
+	used        decimal.Decimal
+}
+
+// matches reports whether the instruction applies to tx.
+func (p *LotPick) matches(tx Tx) bool {
+	if p.Wallet != "" && p.Wallet != tx.Wallet {
+		return false
+	}
+	if p.ReferenceID != "" {
+		return p.ReferenceID == tx.ReferenceID
+	}
+	day := tx.Time.In(p.Date.Location())
+	return day.Year() == p.Date.Year() && day.YearDay() == p.Date.YearDay()
+}
+
+// picksFor returns the instructions that apply to tx and still have coins to take.
+func picksFor(s *State, tx Tx) []*LotPick {
+	var picks []*LotPick
+	for i := range s.LotPicks {
+		p := &s.LotPicks[i]
+		if p.Amount.IsPositive() && !p.used.LessThan(p.Amount) {
+			continue
+		}
+		if p.matches(tx) {
+			picks = append(picks, p)
+		}
+	}
+	return picks
+}
+
+// pickLots moves the lots named by the instructions of the tx being processed to the head of the
+// inventory of wallet/commodity, so that consumeLots takes them first. A lot needed only in part
+// is split and only that part moved, so no picked coins are left out of acquisition order after
+// amount has been consumed. It returns the number of lots moved to the head and the amount they
+// hold.
+func pickLots(s *State, wallet, commodity string, amount decimal.Decimal) (int, decimal.Decimal) {
+	inv := s.Inventories[wallet][commodity]
+	var head []InventoryEntry
+	left := amount
+	for _, p := range s.activePicks {
+		if !left.IsPositive() {
+			break
+		}
+		for i := range inv {
+			lot := &inv[i]
+			if lot.ID != p.LotID || !lot.Amount.IsPositive() {
+				continue
+			}
+			take := minDecimal(lot.Amount, left)
+			if p.Amount.IsPositive() {
+				take = minDecimal(take, p.Amount.Sub(p.used))
+			}
+			if !take.IsPositive() {
+				break
+			}
+			part := *lot
+			part.Amount = take
+			part.TotalCost = part.UnitCost.Mul(take)
+			head = append(head, part)
+			lot.Amount = lot.Amount.Sub(take)
+			lot.TotalCost = lot.UnitCost.Mul(lot.Amount)
+			p.used = p.used.Add(take)
+			left = left.Sub(take)
+			if s.Verbose {
+				log.Printf("  LOTS: taking %s %s of lot %s first (instruction line %d)", take.String(), commodity, lot.ID, p.Line)
+			}
+		}
+	}
+	if len(head) == 0 {
+		return 0, decimal.Zero
+	}
+	rest := inv[:0:0]
+	for _, lot := range inv {
+		if lot.Amount.IsPositive() {
+			rest = append(rest, lot)
+		}
+	}
+	s.Inventories[wallet][commodity] = append(head, rest...)
+	return len(head), amount.Sub(left)
+}
+
+// orderLots moves the lots a disposal of amount takes under State.Method "lifo" (newest first) or
+// "hifo" (highest unit cost first, the oldest of equal cost first) to the head of the inventory of
+// wallet/commodity, behind the first skip lots (those picked by -lots instructions). As in
+// pickLots a lot needed only in part is split, so the lots left keep acquisition order.
+func orderLots(s *State, wallet, commodity string, skip int, amount decimal.Decimal) {
+	inv := s.Inventories[wallet][commodity]
+	rest := inv[skip:]
+	order := make([]int, len(rest))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		x, y := rest[order[a]], rest[order[b]]
+		if s.Method == "hifo" {
+			if !x.UnitCost.Equal(y.UnitCost) {
+				return x.UnitCost.GreaterThan(y.UnitCost)
+			}
+			return order[a] < order[b]
+		}
+		if !x.Time.Equal(y.Time) {
+			return x.Time.After(y.Time)
+		}
+		return order[a] > order[b]
+	})
+	var head []InventoryEntry
+	left := amount
+	for _, i := range order {
+		if !left.IsPositive() {
+			break
+		}
+		lot := &rest[i]
+		if !lot.Amount.IsPositive() {
+			continue
+		}
+		take := minDecimal(lot.Amount, left)
+		part := *lot
+		part.Amount = take
+		part.TotalCost = part.UnitCost.Mul(take)
+		head = append(head, part)
+		lot.Amount = lot.Amount.Sub(take)
+		lot.TotalCost = lot.UnitCost.Mul(lot.Amount)
+		left = left.Sub(take)
+	}
+	if len(head) == 0 {
+		return
+	}
+	ordered := append(append([]InventoryEntry{}, inv[:skip]...), head...)
+	for _, lot := range rest {
+		if lot.Amount.IsPositive() {
+			ordered = append(ordered, lot)
+		}
+	}
+	s.Inventories[wallet][commodity] = ordered
+}
+
+// checkLotPicks warns about instructions that no disposal used.
+func checkLotPicks(s *State) {
+	for _, p := range s.LotPicks {
+		if !p.used.IsPositive() {
+			s.Warnf("LOTS: instruction on line %d (lot %s) was not applied: no matching disposal consumed that lot", p.Line, p.LotID)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Utilities
+func parseFloat(s string) float64 {
+	s = trimNumber(s)
+	if s == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		// try strip any non-digit characters
+		f, _ = strconv.ParseFloat(cleanNumber(s), 64)
+	}
+	return f
+}
+
+// trimNumber trims s and drops thousands separators, without copying in the common case of a
+// number without them.
+func trimNumber(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.IndexByte(s, ',') >= 0 {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	return s
+}
+
+// cleanNumber keeps only the digits, dots and minus signs of s.
+func cleanNumber(s string) string {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c >= '0' && c <= '9') || c == '.' || c == '-' {
+			b = append(b, c)
+		}
+	}
+	return string(b)
+}
+
+// parsePlainDecimal parses [+-]digits[.digits] with at most 18 digits (so the value fits an
+// int64) without rewriting the string first, which covers nearly every amount in an export; ok is
+// false for anything else (exponents, longer numbers, stray characters), which then takes the
+// general path.
+func parsePlainDecimal(s string) (d decimal.Decimal, ok bool) {
+	i := 0
+	neg := false
+	if i < len(s) && (s[i] == '-' || s[i] == '+') {
+		neg = s[i] == '-'
+		i++
+	}
+	var v int64
+	digits, scale := 0, int32(0)
+	dot := false
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			if digits == 18 {
+				return decimal.Decimal{}, false
+			}
+			v = v*10 + int64(c-'0')
+			digits++
+			if dot {
+				scale++
+			}
+		case c == '.' && !dot:
+			dot = true
+		default:
+			return decimal.Decimal{}, false
+		}
+	}
+	if digits == 0 {
+		return decimal.Decimal{}, false
+	}
+	if neg {
+		v = -v
+	}
+	return decimal.New(v, -scale), true
+}
+
+// NormalizeNumber rewrites a number as written in an export into the form decimal.NewFromString
+// reads: thousands separators are dropped and, with decimalComma (1.234,56 rather than 1,234.56),
+// the decimal comma becomes a dot. Spaces and apostrophes group thousands in both (1 234,56,
+// 1'234.56). It fails on anything that is not clearly a number in that format, such as 1,23 with
+// a decimal point (a decimal comma or a misplaced separator?) or a currency sign.
+func NormalizeNumber(s string, decimalComma bool) (string, error) {
+	s = strings.TrimSpace(s)
+	group, point := ",", "."
+	if decimalComma {
+		group, point = ".", ","
+	}
+	// thousands separators used in every locale
+	s = strings.NewReplacer(" ", group, "\u00a0", group, "\u202f", group, "'", group, "\u2019", group).Replace(s)
+	exp := ""
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		// 1.5e-8
+		if _, err := strconv.Atoi(s[i+1:]); err != nil {
+			return "", fmt.Errorf("malformed exponent")
+		}
+		s, exp = s[:i], s[i:]
+	}
+	if strings.Count(s, point) > 1 {
+		return "", fmt.Errorf("more than one decimal separator %q", point)
+	}
+	intPart, frac, hasPoint := strings.Cut(s, point)
+	sign := ""
+	if strings.HasPrefix(intPart, "-") || strings.HasPrefix(intPart, "+") {
+		sign, intPart = intPart[:1], intPart[1:]
+	}
+	groups := strings.Split(intPart, group)
+	for i, g := range groups {
+		if !isDigits(g) || (len(groups) > 1 && (len(g) > 3 || (i > 0 && len(g) != 3) || g == "")) {
+			return "", fmt.Errorf("ambiguous or malformed number")
+		}
+	}
+	if hasPoint {
+		if !isDigits(frac) || frac == "" && intPart == "" {
+			return "", fmt.Errorf("malformed decimals")
+		}
+		return sign + strings.Join(groups, "") + "." + frac + exp, nil
+	}
+	if intPart == "" {
+		return "", fmt.Errorf("no digits")
+	}
+	return sign + strings.Join(groups, "") + exp, nil
+}
+
+// isDigits reports whether s is empty or all ASCII digits.
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"1/2/2006 15:04",
+	"1/2/2006 15:04:05",
+	"1/2/2006 3:04PM",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05Z07", // Shakepay
+}
+
+func ParseTimeGuess(s string) (time.Time, error) {
+	return ParseTimeIn(s, time.UTC)
+}
+
+// ParseTimeIn parses a timestamp in one of the known layouts. A timestamp with an offset keeps
+// it; one without is taken as wall clock time in loc. The result is in UTC.
+func ParseTimeIn(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, l := range timeLayouts {
+		if t, err := time.ParseInLocation(l, s, loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse time: %q", s)
+}
+
+// IsMarginType reports whether a row type is a margin PnL or margin fee entry.
+func IsMarginType(typ string) bool {
+	switch NormalizeType(typ) {
+	case "margin", "margin_pnl", "margin pnl", "realized pnl", "rollover", "margin_fee", "margin fee", "margin interest":
+		return true
+	}
+	return false
+}
+
+// CheckDecimal returns an error unless s is empty or a plain number (comma thousands separators
+// allowed), i.e. one ParseDecimal reads without dropping characters.
+func CheckDecimal(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if _, ok := parsePlainDecimal(s); ok {
+		return nil
+	}
+	n, err := NormalizeNumber(s, false)
+	if err != nil {
+		return fmt.Errorf("not a number: %q (%v)", s, err)
+	}
+	if _, err := decimal.NewFromString(n); err != nil {
+		return fmt.Errorf("not a number: %q", s)
+	}
+	return nil
+}
+
+func ParseDecimal(s string) decimal.Decimal {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return decimal.Zero
+	}
+	// plain numbers (the hot path of large imports)
+	if d, ok := parsePlainDecimal(s); ok {
+		return d
+	}
+	if n, err := NormalizeNumber(s, false); err == nil {
+		if d, err := decimal.NewFromString(n); err == nil {
+			return d
+		}
+	}
+	s = trimNumber(s)
+	// try direct parse
+	if d, err := decimal.NewFromString(s); err == nil {
+		return d
+	}
+	// strip non-numeric (fallback)
+	d, _ := decimal.NewFromString(cleanNumber(s))
+	return d
+}
+
+func minDecimal(a, b decimal.Decimal) decimal.Decimal {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return b
+}
+
+func FirstNonEmpty(m map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v, ok := m[strings.ToLower(k)]; ok {
+			if strings.TrimSpace(v) != "" {
+				return v
+			}
+		}
+		// also try raw key as-is
+		if v, ok := m[k]; ok {
+			if strings.TrimSpace(v) != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  string
+		plain bool // read by parsePlainDecimal
+	}{
+		{"", "0", false},
+		{".", "0", false},
+		{"-", "0", false},
+		{"+", "0", false},
+		{"0", "0", true},
+		{"1.", "1", true},
+		{".5", "0.5", true},
+		{"-.5", "-0.5", true},
+		{"+1.25", "1.25", true},
+		{"-1.25", "-1.25", true},
+		{" 42 ", "42", true},
+		{"123456789012345678", "123456789012345678", true},
+		{"-123456789012345678", "-123456789012345678", true},
+		{"0.00000000000000001", "0.00000000000000001", true},
+		{"1234567890123456789", "1234567890123456789", false},
+		{"-1234567890123456789", "-1234567890123456789", false},
+		{"0.000000000000000001", "0.000000000000000001", false},
+		{"999999999999999999.5", "999999999999999999.5", false},
+		{"1,234.5", "1234.5", false},
+		{"1.5e-8", "0.000000015", false},
+	}
+	for _, tt := range tests {
+		got := ParseDecimal(tt.in)
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("ParseDecimal(%q) = %s, want %s", tt.in, got.String(), tt.want)
+		}
+		d, ok := parsePlainDecimal(tt.in)
+		if tt.in == " 42 " {
+			// the callers trim first
+			d, ok = parsePlainDecimal("42")
+		}
+		if ok != tt.plain {
+			t.Errorf("parsePlainDecimal(%q) ok = %v, want %v", tt.in, ok, tt.plain)
+		}
+		if ok && !d.Equal(got) {
+			t.Errorf("parsePlainDecimal(%q) = %s, want %s", tt.in, d.String(), got.String())
+		}
+	}
+}
+
+func TestCheckDecimal(t *testing.T) {
+	tests := []struct {
+		in string
+		ok bool
+	}{
+		{"", true},
+		{".", false},
+		{"-", false},
+		{"+1.5", true},
+		{"-1.5", true},
+		{"123456789012345678", true},
+		{"1234567890123456789", true},
+		{"1,234.5", true},
+		{"1,23", false},
+		{"1.2.3", false},
+		{"$5", false},
+	}
+	for _, tt := range tests {
+		if err := CheckDecimal(tt.in); (err == nil) != tt.ok {
+			t.Errorf("CheckDecimal(%q) = %v, want ok %v", tt.in, err, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeNumber(t *testing.T) {
+	tests := []struct {
+		in    string
+		comma bool
+		want  string // "" for an error
+	}{
+		{"1,234.56", false, "1234.56"},
+		{"1.234,56", true, "1234.56"},
+		{"1 234,56", true, "1234.56"},
+		{"1'234.56", false, "1234.56"},
+		{"-0,5", true, "-0.5"},
+		{"1,5e-8", true, "1.5e-8"},
+		{"1,23", false, ""},
+		{"1.5", true, ""},
+		{"1,2,3", true, ""},
+		{",", true, ""},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeNumber(tt.in, tt.comma)
+		if tt.want == "" && err == nil {
+			t.Errorf("NormalizeNumber(%q, %v) = %q, want an error", tt.in, tt.comma, got)
+		} else if tt.want != "" && got != tt.want {
+			t.Errorf("NormalizeNumber(%q, %v) = %q, %v, want %q", tt.in, tt.comma, got, err, tt.want)
+		}
+	}
+}
+
+var benchAmounts = []string{"0.00012345", "-1500.5", "42", "123456.78901234", "-0.5"}
+
+// generalDecimal is the path every amount took before parsePlainDecimal.
+func generalDecimal(s string) (decimal.Decimal, error) {
+	n, err := NormalizeNumber(s, false)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return decimal.NewFromString(n)
+}
+
+func BenchmarkParseDecimal(b *testing.B) {
+	b.Run("plain", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ParseDecimal(benchAmounts[i%len(benchAmounts)])
+		}
+	})
+	b.Run("general", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			generalDecimal(benchAmounts[i%len(benchAmounts)])
+		}
+	})
+}
+
+func BenchmarkCheckDecimal(b *testing.B) {
+	b.Run("plain", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			CheckDecimal(benchAmounts[i%len(benchAmounts)])
+		}
+	})
+	b.Run("general", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			generalDecimal(benchAmounts[i%len(benchAmounts)])
+		}
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Cession is a sale of crypto for fiat, goods or services under the global portfolio method
+// (State.PortfolioCost, France): the gain is the price received less the share of the total
+// acquisition price of the portfolio that the price represents of the portfolio's value.
+type Cession struct {
+	Time            time.Time       `json:"time"`
+	Wallet          string          `json:"wallet"`
+	Commodity       string          `json:"commodity"`
+	Amount          decimal.Decimal `json:"amount"`
+	Proceeds        decimal.Decimal `json:"proceeds"`         // price received, before fees
+	Fee             decimal.Decimal `json:"fee"`              // fees of the sale
+	PortfolioValue  decimal.Decimal `json:"portfolio_value"`  // value of all crypto held just before the sale
+	AcquisitionCost decimal.Decimal `json:"acquisition_cost"` // total acquisition price net of the shares of earlier cessions
+	CostShare       decimal.Decimal `json:"cost_share"`       // AcquisitionCost * Proceeds / PortfolioValue
+	Gain            decimal.Decimal `json:"gain"`             // Proceeds - Fee - CostShare
+	SourceFile      string          `json:"source_file"`
+	ReferenceID     string          `json:"reference_id"`
+}
+
+// portfolioCession values everything held just before tx sells amount of its commodity for gross
+// and returns the cession with the share of the total acquisition price the sale realizes. The
+// total acquisition price is the basis of all lots held: purchases and income add to it, trades
+// between crypto assets carry it over (TaxRules.SwapsTaxable) and each cession takes its share off,
+// see allocatePortfolioCost. The asset sold is valued at the sale price, the others with the price
+// source (at basis, with a warning, when no price is found).
+func portfolioCession(s *State, tx Tx, amount, gross decimal.Decimal) (*Cession, error) {
+	held := map[string]decimal.Decimal{}
+	basis := map[string]decimal.Decimal{}
+	for _, commods := range s.Inventories {
+		for c, lots := range commods {
+			if s.Assets.IsFiat(c) {
+				continue
+			}
+			for _, lot := range lots {
+				held[c] = held[c].Add(lot.Amount)
+				basis[c] = basis[c].Add(lot.TotalCost)
+			}
+		}
+	}
+	commods := []string{}
+	for c := range held {
+		commods = append(commods, c)
+	}
+	sort.Strings(commods)
+	total, value := decimal.Zero, decimal.Zero
+	for _, c := range commods {
+		total = total.Add(basis[c])
+		if !held[c].IsPositive() {
+			continue
+		}
+		if c == tx.Commodity && gross.IsPositive() {
+			value = value.Add(gross.Mul(held[c]).Div(amount))
+			continue
+		}
+		v, err := valueIn(s, c, held[c], tx.Time)
+		if err != nil {
+			if errors.Is(err, ErrOffline) {
+				return nil, err
+			}
+			s.Warnf("PORTFOLIO: cannot value %s %s held on %s for the sale ref=%s: %v; counted at basis", held[c].String(), c, tx.Time.Format("2006-01-02"), tx.ReferenceID, err)
+			v = basis[c]
+		}
+		value = value.Add(v)
+	}
+	share := decimal.Zero
+	if value.IsPositive() {
+		share = decimal.Min(total.Mul(gross).Div(value), total)
+	}
+	return &Cession{
+		Time:            tx.Time,
+		Wallet:          tx.Wallet,
+		Commodity:       tx.Commodity,
+		Amount:          amount,
+		Proceeds:        gross,
+		Fee:             tx.Fee,
+		PortfolioValue:  value,
+		AcquisitionCost: total,
+		CostShare:       share,
+		Gain:            gross.Sub(tx.Fee).Sub(share),
+		SourceFile:      tx.SourceFile,
+		ReferenceID:     tx.ReferenceID,
+	}, nil
+}
+
+// allocatePortfolioCost books c once its lots are consumed: lotBasis is the basis the consumed
+// lots carried, while the cession realized c.CostShare. The lots left are scaled so their basis
+// adds up to the acquisition price net of c.CostShare, keeping the total acquisition price of the
+// portfolio in the lots (and in -save-state).
+func allocatePortfolioCost(s *State, c *Cession, lotBasis decimal.Decimal) {
+	left := c.AcquisitionCost.Sub(lotBasis)
+	if left.IsPositive() {
+		factor := c.AcquisitionCost.Sub(c.CostShare).Div(left)
+		for _, commods := range s.Inventories {
+			for commodity, lots := range commods {
+				if s.Assets.IsFiat(commodity) {
+					continue
+				}
+				for i := range lots {
+					lots[i].TotalCost = lots[i].TotalCost.Mul(factor)
+					if lots[i].Amount.IsPositive() {
+						lots[i].UnitCost = lots[i].TotalCost.Div(lots[i].Amount)
+					}
+				}
+			}
+		}
+	}
+	s.Cessions = append(s.Cessions, *c)
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TestPortfolioCession works through the global portfolio method of form 2086: the gain of a
+// cession is the price received less fees less the total acquisition price times the price over
+// the value of the whole portfolio, and each cession takes its share off the acquisition price.
+func TestPortfolioCession(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).AddDate(0, 0, n) }
+	d := decimal.RequireFromString
+	txs := []Tx{
+		{Time: day(0), Type: "buy", Wallet: "w", Commodity: "BTC", Amount: d("1"), Cost: d("10000"), Currency: "EUR"},
+		// BTC worth 30000 (6000 for 0.2): 10000 * 6000 / 30000 = 2000 of the acquisition price
+		// is realized, leaving 8000
+		{Time: day(10), Type: "sell", Wallet: "w", Commodity: "BTC", Amount: d("-0.2"), Cost: d("6000"), Currency: "EUR", ReferenceID: "c1"},
+		// the acquisition price grows to 10000
+		{Time: day(20), Type: "buy", Wallet: "w", Commodity: "ETH", Amount: d("1"), Cost: d("2000"), Currency: "EUR"},
+		// a trade between crypto assets is not a cession and keeps the acquisition price
+		{Time: day(30), Type: "trade", Wallet: "w", Commodity: "BTC", Amount: d("-0.2"), ReferenceID: "t1"},
+		{Time: day(30), Type: "trade", Wallet: "w", Commodity: "ETH", Amount: d("2"), ReferenceID: "t1"},
+		// 0.6 BTC worth 24000 (4000 for 0.1) and 3 ETH at 3000: a portfolio of 33000, of which
+		// 4000 realizes 10000 * 4000 / 33000 = 1212.12 of the acquisition price
+		{Time: day(40), Type: "sell", Wallet: "w", Commodity: "BTC", Amount: d("-0.1"), Cost: d("4000"), Fee: d("50"), Currency: "EUR", ReferenceID: "c2"},
+	}
+	s := NewState(false, nil, nil)
+	s.Rules = Jurisdictions["fr"]
+	s.Rules.Configure(s)
+	s.Prices = fixedRates{"ETH": d("3000")}
+	if err := ProcessTransactions(s, txs); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ ref, value, cost, share, gain string }{
+		{"c1", "30000.00", "10000.00", "2000.00", "4000.00"},
+		{"c2", "33000.00", "10000.00", "1212.12", "2737.88"},
+	}
+	if len(s.Cessions) != len(want) {
+		t.Fatalf("got %d cessions, want %d (trades are not cessions)", len(s.Cessions), len(want))
+	}
+	for i, w := range want {
+		c := s.Cessions[i]
+		got := []string{c.ReferenceID, c.PortfolioValue.StringFixed(2), c.AcquisitionCost.StringFixed(2), c.CostShare.StringFixed(2), c.Gain.StringFixed(2)}
+		if got[0] != w.ref || got[1] != w.value || got[2] != w.cost || got[3] != w.share || got[4] != w.gain {
+			t.Errorf("cession %d: got %v, want %v", i+1, got, w)
+		}
+	}
+	// the lots left carry the acquisition price net of both shares
+	left := decimal.Zero
+	for _, lots := range s.Inventories["w"] {
+		for _, lot := range lots {
+			left = left.Add(lot.TotalCost)
+		}
+	}
+	if left.StringFixed(2) != "8787.88" {
+		t.Errorf("got acquisition price left %s, want 8787.88", left.StringFixed(2))
+	}
+	gains := decimal.Zero
+	for _, d := range s.Disposals {
+		gains = gains.Add(d.Gain)
+	}
+	if gains.StringFixed(2) != "6737.88" {
+		t.Errorf("got gains %s, want 6737.88 (4000 + 2737.88)", gains.StringFixed(2))
+	}
+}
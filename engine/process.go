@@ -0,0 +1,456 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Processing pass
+type txHandlerFunc func(s *State, tx Tx) error
+
+func ProcessTransactions(state *State, txs []Tx) error {
+	handlers := getHandlers()
+	txs = disambiguateAssets(state, txs)
+	txs = renameAssets(state, txs)
+	txs = classifyTransactions(state, txs)
+	txs = pairWraps(state, txs)
+	txs = pairDust(state, txs)
+	txs = pairTrades(state, txs)
+	txs = matchTransfers(state, txs)
+	if state.SuperficialLoss {
+		state.flows = buildFlows(state, txs)
+	}
+	pending := datedMigrations(state, txs)
+	checks := pendingChecks(state)
+	valuations := append([]time.Time{}, state.ValuationDates...)
+	sort.Slice(valuations, func(i, j int) bool { return valuations[i].Before(valuations[j]) })
+	lastYear := 0
+	skipped := 0
+	reporting := state.ReportFrom.IsZero()
+	picking := len(state.LotPicks) > 0
+	if picking && (state.Method == "acb" || state.GlobalFIFO) {
+		state.Warnf("LOTS: disposal instructions ignored: lots cannot be picked under the average cost method or FIFO across wallets")
+		picking = false
+	}
+	for _, tx := range txs {
+		if !state.OpeningAsOf.IsZero() && !tx.Time.After(state.OpeningAsOf) {
+			// already part of the loaded opening state
+			skipped++
+			continue
+		}
+		for len(pending) > 0 && !pending[0].Date.After(tx.Time) {
+			migrateHoldings(state, pending[0])
+			pending = pending[1:]
+		}
+		if !reporting && !tx.Time.Before(state.ReportFrom) {
+			startReporting(state)
+			reporting = true
+		}
+		if state.SeriesInterval != "" {
+			recordSeries(state, tx.Time)
+		}
+		for len(valuations) > 0 && !tx.Time.Before(valuations[0]) {
+			state.Valuations = append(state.Valuations, Valuation{At: valuations[0], Holdings: snapshotHoldings(state)})
+			valuations = valuations[1:]
+		}
+		for len(checks) > 0 && tx.Time.After(checks[0].At) {
+			reconcile(state, checks[0])
+			checks = checks[1:]
+		}
+		// snapshot holdings for every year that ended before this tx (including years without activity)
+		if lastYear != 0 {
+			for y := lastYear; y < tx.Time.Year(); y++ {
+				snapshotYearEnd(state, y)
+			}
+		}
+		lastYear = tx.Time.Year()
+		if state.Verbose {
+			// Only show verbose logs for transactions that match wallet and commodity filters (if filters provided)
+			show := true
+			if len(state.WalletFilter) > 0 {
+				if !state.WalletFilter[tx.Wallet] {
+					show = false
+				}
+			}
+			if len(state.CommodityFilter) > 0 {
+				if !state.CommodityFilter[strings.ToLower(strings.TrimSpace(tx.Commodity))] {
+					show = false
+				}
+			}
+			if show {
+				log.Printf("processing tx: %s %s %s %s cost=%s fee=%s src=%s ref=%s",
+					tx.Time.Format(time.RFC3339), tx.Type, tx.Amount.String(), tx.Commodity, tx.Cost.String(), tx.Fee.String(), tx.SourceFile, tx.ReferenceID)
+			}
+		}
+		key := NormalizeType(tx.Type)
+		h := handlers[key]
+		if h == nil && state.Strict {
+			return fmt.Errorf("unknown transaction type %q (%s:%d ref=%s)", tx.Type, tx.SourceFile, tx.SourceLine, tx.ReferenceID)
+		}
+		if h == nil {
+			r, _ := matchRule(fallbackRules, tx)
+			key = r.Operation
+			h = handlers[key]
+		}
+		state.Journal = append(state.Journal, JournalEntry{Tx: tx, Handler: key})
+		if err := recordWithholding(state, tx); err != nil {
+			return err
+		}
+		if err := recordTxFees(state, key, tx); err != nil {
+			return err
+		}
+		if picking {
+			state.activePicks = picksFor(state, tx)
+		}
+		if state.CryptoFees {
+			var err error
+			if tx, err = disposeCryptoFee(state, tx); err != nil {
+				return err
+			}
+		}
+		if err := h(state, tx); err != nil {
+			return err
+		}
+		state.activePicks = nil
+		if state.FXGains {
+			if err := trackForeignFiat(state, key, tx); err != nil {
+				return err
+			}
+		}
+	}
+	if !reporting {
+		startReporting(state)
+	}
+	if !state.seriesNext.IsZero() {
+		state.Series = append(state.Series, seriesPoint(state, state.seriesNext))
+	}
+	if skipped > 0 {
+		state.Warnf("LOAD STATE: skipped %d transactions dated on or before the loaded state (%s)", skipped, state.OpeningAsOf.Format(time.RFC3339))
+	}
+	for _, at := range valuations {
+		for len(pending) > 0 && !pending[0].Date.After(at) {
+			migrateHoldings(state, pending[0])
+			pending = pending[1:]
+		}
+		state.Valuations = append(state.Valuations, Valuation{At: at, Holdings: snapshotHoldings(state)})
+	}
+	// migrations after the last tx still apply to the final holdings
+	for len(pending) > 0 && pending[0].Date.Year() <= lastYear {
+		migrateHoldings(state, pending[0])
+		pending = pending[1:]
+	}
+	if lastYear != 0 {
+		snapshotYearEnd(state, lastYear)
+	}
+	for _, m := range pending {
+		migrateHoldings(state, m)
+	}
+	for _, c := range checks {
+		reconcile(state, c)
+	}
+	if picking {
+		checkLotPicks(state)
+	}
+	return nil
+}
+
+// startReporting drops the results of the transactions before State.ReportFrom: they were only
+// processed to build the inventory (basis and acquisition dates) the reporting window starts with.
+func startReporting(state *State) {
+	state.TaxYears = make(map[int]map[string]map[string]*Gains)
+	state.Derivatives = make(map[int]map[string]map[string]*DerivativesResult)
+	state.Disposals = nil
+	state.Journal = nil
+	state.Removals = nil
+	state.Cessions = nil
+	state.Incomes = nil
+	state.Withheld = nil
+	state.Fees = nil
+}
+
+// snapshotYearEnd copies the current inventories as the holdings at the end of year.
+func snapshotYearEnd(state *State, year int) {
+	state.YearEnd[year] = snapshotHoldings(state)
+}
+
+// snapshotHoldings returns a copy of the non-empty lots of the current inventories.
+func snapshotHoldings(state *State) map[string]map[string][]InventoryEntry {
+	snap := map[string]map[string][]InventoryEntry{}
+	for w, commods := range state.Inventories {
+		for c, lots := range commods {
+			if len(lots) == 0 {
+				continue
+			}
+			if _, ok := snap[w]; !ok {
+				snap[w] = map[string][]InventoryEntry{}
+			}
+			snap[w][c] = append([]InventoryEntry{}, lots...)
+		}
+	}
+	return snap
+}
+
+func NormalizeType(t string) string {
+	return strings.ToLower(strings.TrimSpace(t))
+}
+
+// Types returns the transaction types with a dedicated handler, sorted.
+func Types() []string {
+	types := []string{}
+	for t := range getHandlers() {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func getHandlers() map[string]txHandlerFunc {
+	return map[string]txHandlerFunc{
+		"buy":      handlePurchase,
+		"sell":     handleSell,
+		"income":   handleIncome,
+		"reward":   handleIncome,
+		"staking":  handleIncome,
+		"deposit":  handleIncome,
+		"convert":  handleConvert,
+		"trade":    handleConvert,
+		"transfer": handleTransfer,
+		"wrap":     handleWrap,
+		"unwrap":   handleWrap,
+		"bridge":   handleWrap,
+		// token migrations/rebrands swap the old ticker for the new one at basis
+		"migration":     handleWrap,
+		"migrate":       handleWrap,
+		"airdrop":       handleAirdrop,
+		"gift":          handleGift,
+		"gift_sent":     handleGift,
+		"gift sent":     handleGift,
+		"gift_received": handleGift,
+		"gift received": handleGift,
+		"donation":      handleDonation,
+		"donate":        handleDonation,
+		"charity":       handleDonation,
+		"lost":          handleLost,
+		"stolen":        handleLost,
+		"theft":         handleLost,
+		// collateralized loans (Nexo, Aave): locking collateral and loan proceeds are not disposals
+		"borrow":                    handleLoan,
+		"loan":                      handleLoan,
+		"loan withdrawal":           handleLoan,
+		"repay":                     handleLoan,
+		"repayment":                 handleLoan,
+		"collateral":                handleLoan,
+		"collateral lock":           handleLoan,
+		"collateral_lock":           handleLoan,
+		"collateral unlock":         handleLoan,
+		"collateral_unlock":         handleLoan,
+		"locking term deposit":      handleLoan,
+		"unlocking term deposit":    handleLoan,
+		"transfer in (collateral)":  handleLoan,
+		"transfer out (collateral)": handleLoan,
+		"liquidation":               handleLoan,
+		// Binance dust conversion: many small balances swapped for BNB at once
+		"small assets exchange bnb": handleDust,
+		"dust":                      handleDust,
+		// paying with crypto (card spend, BitPay) disposes of the coins at the fiat amount charged
+		"spend":        handleSpend,
+		"payment":      handleSpend,
+		"card spend":   handleSpend,
+		"card payment": handleSpend,
+		"purchase":     handleSpend,
+		// rebasing/reward-bearing tokens (stETH, AMPL): balance snapshots or deltas
+		"balance":            handleRebase,
+		"balance snapshot":   handleRebase,
+		"snapshot":           handleRebase,
+		"rebase":             handleRebase,
+		"balance adjustment": handleRebase,
+		// fee-only rows (e.g. Binance "Transaction Fee" in BNB, gas paid from a self-custody wallet)
+		"fee":             handleCryptoFee,
+		"transaction fee": handleCryptoFee,
+		"trading fee":     handleCryptoFee,
+		"network fee":     handleCryptoFee,
+		"gas":             handleCryptoFee,
+		"fork":            handleFork,
+		"mining":          handleIncome,
+		// margin trading: realized PnL and fees are booked directly, spot inventory is untouched
+		"margin":          handleMargin,
+		"margin_pnl":      handleMargin,
+		"margin pnl":      handleMargin,
+		"realized pnl":    handleMargin,
+		"rollover":        handleMarginFee,
+		"margin_fee":      handleMarginFee,
+		"margin fee":      handleMarginFee,
+		"margin interest": handleMarginFee,
+		// futures/perpetuals: settled outside FIFO inventory, reported in the derivatives section
+		"futures_pnl": handleDerivative,
+		"funding":     handleDerivative,
+		"futures_fee": handleDerivative,
+	}
+}
+
+// Inventory helpers
+func ensureInventoryBucket(state *State, wallet, commodity string) {
+	if _, ok := state.Inventories[wallet]; !ok {
+		state.Inventories[wallet] = make(map[string][]InventoryEntry)
+	}
+	if _, ok := state.Inventories[wallet][commodity]; !ok {
+		state.Inventories[wallet][commodity] = []InventoryEntry{}
+	}
+}
+
+func addInventory(state *State, wallet, commodity string, entry InventoryEntry) {
+	ensureInventoryBucket(state, wallet, commodity)
+	if entry.ID == "" {
+		entry.ID = lotID(commodity, entry)
+	}
+	if pending, ok := state.deniedLosses[commodity]; ok && entry.Amount.IsPositive() {
+		// a superficial loss denied while nothing was held goes to the coins bought back
+		entry.TotalCost = entry.TotalCost.Add(pending)
+		entry.UnitCost = entry.TotalCost.Div(entry.Amount)
+		delete(state.deniedLosses, commodity)
+	}
+	if je := state.currentJournal(); je != nil {
+		je.Added = append(je.Added, JournalLot{Wallet: wallet, Commodity: commodity, Lot: entry})
+	}
+	if state.Method == "acb" {
+		addPooled(state, wallet, commodity, entry)
+		return
+	}
+	inv := append(state.Inventories[wallet][commodity], entry)
+	// keep oldest first: lots almost always arrive in time order, so only an older lot is moved
+	// into place (after the lots with the same time)
+	if i := sort.Search(len(inv)-1, func(i int) bool { return inv[i].Time.After(entry.Time) }); i < len(inv)-1 {
+		copy(inv[i+1:], inv[i:len(inv)-1])
+		inv[i] = entry
+	}
+	state.Inventories[wallet][commodity] = inv
+}
+
+// Get or create gains entry for year/wallet/commodity
+func getGainsSlot(state *State, year int, wallet, commodity string) *Gains {
+	if _, ok := state.TaxYears[year]; !ok {
+		state.TaxYears[year] = make(map[string]map[string]*Gains)
+	}
+	if _, ok := state.TaxYears[year][wallet]; !ok {
+		state.TaxYears[year][wallet] = make(map[string]*Gains)
+	}
+	if _, ok := state.TaxYears[year][wallet][commodity]; !ok {
+		state.TaxYears[year][wallet][commodity] = &Gains{
+			Short:  decimal.Zero,
+			Long:   decimal.Zero,
+			Income: decimal.Zero,
+		}
+	}
+	return state.TaxYears[year][wallet][commodity]
+}
+
+// removeLots consumes amount of commodity FIFO from wallet, recording the consumed lots in the
+// journal. It returns the removed basis and the amount not covered by inventory.
+func removeLots(s *State, wallet, commodity string, amount decimal.Decimal) (decimal.Decimal, decimal.Decimal) {
+	basis := decimal.Zero
+	remaining := consumeLots(s, wallet, commodity, amount, func(entry InventoryEntry, use decimal.Decimal) {
+		cost := entry.UnitCost.Mul(use)
+		basis = basis.Add(cost)
+		if je := s.currentJournal(); je != nil {
+			je.Consumed = append(je.Consumed, JournalLot{Wallet: wallet, Commodity: commodity, Lot: InventoryEntry{
+				Time: entry.Time, Amount: use, UnitCost: entry.UnitCost, TotalCost: cost,
+				SourceFiles: entry.SourceFiles, SourceLine: entry.SourceLine, ReferenceID: entry.ReferenceID, ID: entry.ID,
+			}})
+		}
+	})
+	return basis, remaining
+}
+
+// consumeLots takes amount FIFO from the inventory of wallet/commodity and calls take for each lot
+// used, with the lot before the reduction and the amount used. The inventory is in acquisition
+// order, so used-up lots are dropped by moving the head of the slice and a partly used lot is
+// reduced in place: a sale costs the lots it touches, not the whole inventory. Under GlobalFIFO the
+// oldest lots of all wallets are swapped in first; lots named by -lots instructions and, under
+// the lifo and hifo methods, the lots the method takes are moved to the head first. It returns
+// the amount not covered by the inventory.
+func consumeLots(s *State, wallet, commodity string, amount decimal.Decimal, take func(entry InventoryEntry, use decimal.Decimal)) decimal.Decimal {
+	ensureInventoryBucket(s, wallet, commodity)
+	if s.GlobalFIFO {
+		takeOldestLots(s, wallet, commodity, amount)
+	} else if s.Method != "acb" {
+		picked, pickedAmount := 0, decimal.Zero
+		if len(s.activePicks) > 0 {
+			picked, pickedAmount = pickLots(s, wallet, commodity, amount)
+		}
+		if (s.Method == "lifo" || s.Method == "hifo") && pickedAmount.LessThan(amount) {
+			orderLots(s, wallet, commodity, picked, amount.Sub(pickedAmount))
+		}
+	}
+	inv := s.Inventories[wallet][commodity]
+	remaining := amount
+	dust := decimal.NewFromFloat(1e-12)
+	head := 0
+	for head < len(inv) && remaining.IsPositive() {
+		entry := &inv[head]
+		if entry.Amount.IsPositive() {
+			use := minDecimal(entry.Amount, remaining)
+			take(*entry, use)
+			remaining = remaining.Sub(use)
+			entry.Amount = entry.Amount.Sub(use)
+			entry.TotalCost = entry.UnitCost.Mul(entry.Amount)
+			if entry.Amount.Cmp(dust) > 0 {
+				break
+			}
+		}
+		head++
+	}
+	s.Inventories[wallet][commodity] = inv[head:]
+	return remaining
+}
+
+// moveLots moves amount of srcCommodity FIFO from srcWallet to destWallet as destCommodity,
+// preserving each lot's acquisition time and total basis. ratio is the number of destination
+// units per source unit (1 for plain transfers); extraCost (e.g. a fee) is added to the moved
+// basis pro rata. It returns the amount not covered by inventory.
+func moveLots(s *State, srcWallet, srcCommodity, destWallet, destCommodity string, amount, ratio, extraCost decimal.Decimal) decimal.Decimal {
+	ensureInventoryBucket(s, destWallet, destCommodity)
+	var moved []InventoryEntry
+	remaining := consumeLots(s, srcWallet, srcCommodity, amount, func(entry InventoryEntry, use decimal.Decimal) {
+		// create a moved entry for dest preserving time and basis
+		basis := entry.UnitCost.Mul(use)
+		unitCost := entry.UnitCost
+		if !extraCost.IsZero() {
+			basis = basis.Add(extraCost.Mul(use).Div(amount))
+			unitCost = basis.Div(use.Mul(ratio))
+		} else if !ratio.Equal(decimal.NewFromInt(1)) {
+			unitCost = entry.UnitCost.Div(ratio)
+		}
+		lot := InventoryEntry{
+			Time:        entry.Time,
+			Amount:      use.Mul(ratio),
+			UnitCost:    unitCost,
+			TotalCost:   basis,
+			SourceFiles: append([]string{}, entry.SourceFiles...),
+			SourceLine:  entry.SourceLine,
+			ReferenceID: entry.ReferenceID,
+			ID:          entry.ID,
+		}
+		if je := s.currentJournal(); je != nil {
+			consumed := lot
+			consumed.Amount = use
+			consumed.UnitCost = entry.UnitCost
+			consumed.TotalCost = entry.UnitCost.Mul(use)
+			je.Consumed = append(je.Consumed, JournalLot{Wallet: srcWallet, Commodity: srcCommodity, Lot: consumed})
+		}
+		moved = append(moved, lot)
+	})
+	// added after consuming, the source and destination inventory can be the same
+	for _, lot := range moved {
+		addInventory(s, destWallet, destCommodity, lot)
+	}
+	return remaining
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine_test
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"cryptotax/engine"
+	"cryptotax/importer"
+	"github.com/shopspring/decimal"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got with testdata/name, or rewrites the file with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := "testdata/" + name
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs:\n--- got\n%s--- want\n%s", name, got, want)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TestProcessGolden runs the sample through FIFO and prints the gains, income and remaining lots
+// the way the command line printed them before the packages were split: testdata/sample.golden is
+// its summary followed by its -report holdings.
+func TestProcessGolden(t *testing.T) {
+	var cfg importer.Config
+	txs, _, err := cfg.ParseFile("../testdata/sample.csv", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := engine.NewState(false, nil, nil)
+	if err := engine.ProcessTransactions(state, importer.MergeAndSort([][]engine.Tx{txs})); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	var years []int
+	for y := range state.TaxYears {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	for _, y := range years {
+		fmt.Fprintf(&b, "Year %d:\n", y)
+		for _, w := range sortedKeys(state.TaxYears[y]) {
+			fmt.Fprintf(&b, "  Wallet: %s\n", w)
+			for _, c := range sortedKeys(state.TaxYears[y][w]) {
+				g := state.TaxYears[y][w][c]
+				fmt.Fprintf(&b, "    %s: short=%s long=%s income=%s\n", c, g.Short.StringFixed(2), g.Long.StringFixed(2), g.Income.StringFixed(2))
+			}
+		}
+	}
+	fmt.Fprintln(&b, "Holdings at end of data:")
+	for _, w := range sortedKeys(state.Inventories) {
+		fmt.Fprintf(&b, "  Wallet: %s\n", w)
+		for _, c := range sortedKeys(state.Inventories[w]) {
+			lots := state.Inventories[w][c]
+			amount, basis := decimal.Zero, decimal.Zero
+			for _, lot := range lots {
+				amount = amount.Add(lot.Amount)
+				basis = basis.Add(lot.TotalCost)
+			}
+			fmt.Fprintf(&b, "    %s: amount=%s avg_cost=%s basis=%s oldest=%s lots=%d\n", c, amount.StringFixed(2),
+				basis.Div(amount).StringFixed(2), basis.StringFixed(2), lots[0].Time.Format("2006-01-02"), len(lots))
+		}
+	}
+	checkGolden(t, "sample.golden", b.String())
+}
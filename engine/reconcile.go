@@ -0,0 +1,60 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// BalanceCheck is a balance declared by an exchange or wallet (statement, export, explorer) that
+// the computed inventory must match. An empty Wallet means the total over all wallets.
+type BalanceCheck struct {
+	Wallet    string          `json:"wallet,omitempty"`
+	Commodity string          `json:"commodity"`
+	At        time.Time       `json:"at"` // balance after every tx up to and including this time
+	Balance   decimal.Decimal `json:"balance"`
+	Tolerance decimal.Decimal `json:"tolerance"`
+}
+
+// BalanceResult is a BalanceCheck with the amount the engine held at that time.
+type BalanceResult struct {
+	BalanceCheck
+	Computed   decimal.Decimal `json:"computed"`
+	Difference decimal.Decimal `json:"difference"` // Computed - Balance
+	OK         bool            `json:"ok"`
+}
+
+// pendingChecks returns the balance checks in time order.
+func pendingChecks(state *State) []BalanceCheck {
+	checks := append([]BalanceCheck{}, state.BalanceChecks...)
+	sort.SliceStable(checks, func(i, j int) bool { return checks[i].At.Before(checks[j].At) })
+	return checks
+}
+
+// reconcile compares a declared balance with the current inventory and warns on a mismatch.
+func reconcile(s *State, c BalanceCheck) {
+	held := decimal.Zero
+	for w, commods := range s.Inventories {
+		if c.Wallet != "" && w != c.Wallet {
+			continue
+		}
+		for _, lot := range commods[c.Commodity] {
+			held = held.Add(lot.Amount)
+		}
+	}
+	r := BalanceResult{BalanceCheck: c, Computed: held, Difference: held.Sub(c.Balance)}
+	r.OK = r.Difference.Abs().LessThanOrEqual(c.Tolerance)
+	s.Reconciliation = append(s.Reconciliation, r)
+	if !r.OK {
+		wallet := c.Wallet
+		if wallet == "" {
+			wallet = "all wallets"
+		}
+		s.Warnf("BALANCE: %s %s on %s: computed %s, declared %s (difference %s)", wallet, c.Commodity, c.At.Format("2006-01-02 15:04"), held.String(), c.Balance.String(), r.Difference.String())
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TaxRules are the rules of a country the engine consults while processing (State.Rules). The
+// reports laid out for a tax return apply the rates and allowances of the country themselves.
+type TaxRules interface {
+	// Name is the country, Report the report laid out for its tax return.
+	Name() string
+	Report() string
+	// Configure sets the processing options the country needs: cost basis method, lot matching
+	// across wallets, the portfolio method and the superficial loss rule.
+	Configure(s *State)
+	// LongTerm reports whether coins acquired at acquired and disposed of at disposed get the
+	// long-term treatment (Disposal.Long and the Long gains).
+	LongTerm(s *State, acquired, disposed time.Time) bool
+	// SwapsTaxable reports whether trading one crypto asset for another disposes of the coins
+	// given; when it does not, the coins acquired take over their basis and acquisition dates.
+	SwapsTaxable() bool
+	// Exempt reports whether the gain or loss of d is tax-free (Disposal.Exempt).
+	Exempt(s *State, d Disposal) bool
+	// LossCarryYears is how many years after the year it arose a net capital loss can be offset
+	// against gains (0 = not at all, -1 = without limit).
+	LossCarryYears() int
+}
+
+// countryRules implements TaxRules from a few settings, enough for the countries supported.
+type countryRules struct {
+	name      string
+	report    string
+	longTerm  bool // gains on coins held for more than a year are long term, see State.IsLongTerm
+	swaps     bool // crypto-to-crypto trades are disposals
+	carry     int  // LossCarryYears
+	exempt    func(s *State, d Disposal) bool
+	configure func(s *State)
+}
+
+func (r countryRules) Name() string   { return r.name }
+func (r countryRules) Report() string { return r.report }
+
+func (r countryRules) Configure(s *State) {
+	if r.configure != nil {
+		r.configure(s)
+	}
+}
+
+func (r countryRules) LongTerm(s *State, acquired, disposed time.Time) bool {
+	return r.longTerm && heldOneYear(s.HoldingRule, acquired, disposed)
+}
+
+func (r countryRules) SwapsTaxable() bool  { return r.swaps }
+func (r countryRules) LossCarryYears() int { return r.carry }
+
+func (r countryRules) Exempt(s *State, d Disposal) bool {
+	return r.exempt != nil && r.exempt(s, d)
+}
+
+// exemptAll is the Exempt rule of countries that do not tax private capital gains at all.
+func exemptAll(*State, Disposal) bool { return true }
+
+// exemptLongTerm exempts coins held for more than a year (Germany, § 23 EStG).
+func exemptLongTerm(s *State, d Disposal) bool { return d.Long }
+
+// exemptAltvermoegen exempts coins acquired before the Austrian special rate took effect on March
+// 1, 2021 (Altvermögen) and held for more than a year.
+func exemptAltvermoegen(s *State, d Disposal) bool {
+	return d.Long && d.Acquired.Before(time.Date(2021, time.March, 1, 0, 0, 0, 0, d.Acquired.Location()))
+}
+
+// Jurisdictions are the TaxRules selectable with -jurisdiction, by country code.
+var Jurisdictions = map[string]TaxRules{
+	// Germany: private sales are tax-free after one year (§ 23 EStG); losses are carried forward
+	// without limit
+	"de": countryRules{name: "Germany", report: "anlage-so", longTerm: true, swaps: true, carry: -1, exempt: exemptLongTerm},
+	// Serbia: 15% on transfers of digital assets for money, goods or services; trading one digital
+	// asset for another is not a transfer
+	"rs": countryRules{name: "Serbia", report: "ppdg-3r", carry: 5},
+	// Austria: 27.5% on coins acquired from March 2021, older coins tax-free after a year; trades
+	// of one crypto asset for another are not taxable since the 2022 reform
+	"at": countryRules{name: "Austria", report: "e1kv", longTerm: true, exempt: exemptAltvermoegen},
+	// France: only sales for fiat, goods or services are taxable, with the gain computed on the
+	// whole portfolio (art. 150 VH bis CGI) and taxed at the 30% flat rate
+	"fr": countryRules{name: "France", report: "2086", configure: func(s *State) { s.PortfolioCost = true }},
+	// Australia: crypto-to-crypto trades are CGT events; the 50% discount and the July-June income
+	// year are applied by the report
+	"au": countryRules{name: "Australia", report: "cgt-schedule", longTerm: true, swaps: true, carry: -1},
+	// Canada: adjusted cost base averaged over all coins of an asset, superficial losses denied,
+	// half of the net gain taxable
+	"ca": countryRules{name: "Canada", report: "schedule-3", swaps: true, carry: -1, configure: func(s *State) {
+		s.Method = "acb"
+		s.SuperficialLoss = true
+	}},
+	// Netherlands: holdings are taxed as wealth in Box 3 on their value on January 1; disposals are
+	// not taxable events
+	"nl": countryRules{name: "Netherlands", report: "box3", swaps: true, exempt: exemptAll},
+	// Switzerland: capital gains on private wealth are tax-free; holdings are subject to the
+	// wealth tax at their year-end value and staking or mining rewards are income
+	"ch": countryRules{name: "Switzerland", report: "wertschriften", swaps: true, exempt: exemptAll},
+	// United States: gains on coins held for more than a year are long term; short and long term
+	// are netted separately, up to $3,000 of a net loss offsets ordinary income and the rest is
+	// carried forward without limit keeping its term
+	"us": countryRules{name: "United States", longTerm: true, swaps: true, carry: -1, configure: func(s *State) { s.LossNetting = "us" }},
+	// Spain: FIFO over the coins of an asset in all wallets; crypto held at foreign custodians is
+	// declared in Modelo 721; losses are carried forward four years
+	"es": countryRules{name: "Spain", report: "modelo-721", swaps: true, carry: 4, configure: func(s *State) { s.GlobalFIFO = true }},
+}
+
+// Netting is the net capital gain of a year under State.Rules.
+type Netting struct {
+	Gains    decimal.Decimal // taxable gains of the year
+	Losses   decimal.Decimal // taxable losses of the year, positive
+	Carried  decimal.Decimal // losses of earlier years offset against the net gain
+	Taxable  decimal.Decimal // Gains - Losses - Carried, zero when the year has a net loss
+	CarryOut decimal.Decimal // losses that can still be offset in the following year
+	// the short and long term results of the year; netted by term (State.LossNetting "us") they
+	// are the results after the carried losses and netting against each other, with the net loss
+	// deducted from ordinary income and the parts of CarryOut that stay short and long term
+	Short, Long                 decimal.Decimal
+	Ordinary                    decimal.Decimal
+	CarryOutShort, CarryOutLong decimal.Decimal
+}
+
+// NetGains nets the disposals of each year under s.Rules: exempt disposals are left out, the
+// losses of a year are offset against its gains, and a net loss against the gains of the next
+// LossCarryYears years, oldest loss first. With State.LossNetting "us" the terms are netted
+// separately, see netByTerm.
+func NetGains(s *State) map[int]*Netting {
+	limit := 0
+	if s.Rules != nil {
+		limit = s.Rules.LossCarryYears()
+	}
+	out := map[int]*Netting{}
+	for _, d := range s.Disposals {
+		if d.Exempt {
+			continue
+		}
+		y := d.Disposed.Year()
+		if out[y] == nil {
+			out[y] = &Netting{}
+		}
+		if d.Gain.IsNegative() {
+			out[y].Losses = out[y].Losses.Sub(d.Gain)
+		} else {
+			out[y].Gains = out[y].Gains.Add(d.Gain)
+		}
+		if d.Long {
+			out[y].Long = out[y].Long.Add(d.Gain)
+		} else {
+			out[y].Short = out[y].Short.Add(d.Gain)
+		}
+	}
+	years := []int{}
+	for y := range out {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	if s.LossNetting == "us" {
+		netByTerm(s, out, years)
+		return out
+	}
+	type carry struct {
+		year   int
+		amount decimal.Decimal
+	}
+	var carries []carry
+	usable := func(c carry, year int) bool {
+		return c.amount.IsPositive() && (limit < 0 || c.year >= year-limit)
+	}
+	for _, y := range years {
+		n := out[y]
+		net := n.Gains.Sub(n.Losses)
+		for i := range carries {
+			if !net.IsPositive() {
+				break
+			}
+			if !usable(carries[i], y) {
+				continue
+			}
+			use := decimal.Min(carries[i].amount, net)
+			carries[i].amount = carries[i].amount.Sub(use)
+			n.Carried = n.Carried.Add(use)
+			net = net.Sub(use)
+		}
+		if net.IsNegative() {
+			carries = append(carries, carry{y, net.Neg()})
+			net = decimal.Zero
+		}
+		n.Taxable = net
+		for _, c := range carries {
+			if usable(c, y+1) {
+				n.CarryOut = n.CarryOut.Add(c.amount)
+			}
+		}
+	}
+	return out
+}
+
+// netByTerm nets the years the US way (Schedule D): short-term losses are offset against
+// short-term gains and long-term losses against long-term gains, then a net loss of one term
+// against a net gain of the other. Up to State.OrdinaryLossLimit of a net loss is deducted from
+// ordinary income, taken from the short-term loss first, and the rest is carried forward
+// without limit keeping its term. A year without sales between years (the ones of years) still
+// deducts a carried loss from ordinary income.
+func netByTerm(s *State, out map[int]*Netting, years []int) {
+	if len(years) == 0 {
+		return
+	}
+	carryShort, carryLong := decimal.Zero, decimal.Zero
+	for y := years[0]; y <= years[len(years)-1]; y++ {
+		n := out[y]
+		if n == nil {
+			if carryShort.Add(carryLong).IsZero() {
+				continue
+			}
+			n = &Netting{}
+			out[y] = n
+		}
+		before := n.Short.Add(n.Long)
+		short := n.Short.Sub(carryShort)
+		long := n.Long.Sub(carryLong)
+		carriedIn := carryShort.Add(carryLong)
+		switch {
+		case short.IsNegative() && long.IsPositive():
+			use := decimal.Min(short.Neg(), long)
+			short, long = short.Add(use), long.Sub(use)
+		case long.IsNegative() && short.IsPositive():
+			use := decimal.Min(long.Neg(), short)
+			short, long = short.Sub(use), long.Add(use)
+		}
+		n.Carried = decimal.Min(carriedIn, decimal.Max(before, decimal.Zero))
+		n.Short, n.Long = short, long
+		n.Taxable = decimal.Max(short, decimal.Zero).Add(decimal.Max(long, decimal.Zero))
+		// what is left negative is a loss of that term
+		lossShort := decimal.Max(short.Neg(), decimal.Zero)
+		lossLong := decimal.Max(long.Neg(), decimal.Zero)
+		n.Ordinary = decimal.Min(lossShort.Add(lossLong), decimal.Max(s.OrdinaryLossLimit, decimal.Zero))
+		fromShort := decimal.Min(n.Ordinary, lossShort)
+		carryShort = lossShort.Sub(fromShort)
+		carryLong = lossLong.Sub(n.Ordinary.Sub(fromShort))
+		n.CarryOutShort, n.CarryOutLong = carryShort, carryLong
+		n.CarryOut = carryShort.Add(carryLong)
+	}
+}
+
+// JurisdictionCodes lists the Jurisdictions codes for messages.
+func JurisdictionCodes() string {
+	codes := []string{}
+	for c := range Jurisdictions {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+	return strings.Join(codes, ", ")
+}
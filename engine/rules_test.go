@@ -0,0 +1,107 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestNetByTerm(t *testing.T) {
+	// sale is a disposal in year with gain, long term when long
+	type sale struct {
+		year int
+		gain string
+		long bool
+	}
+	// netted are the Netting figures of a year: short, long, taxable, ordinary, carried in,
+	// carried out short and long term
+	type netted struct {
+		year                                                   int
+		short, long, taxable, ordinary, carried, cShort, cLong string
+	}
+	tests := []struct {
+		name  string
+		sales []sale
+		want  []netted
+	}{
+		{"gains of both terms", []sale{{2023, "5000", false}, {2023, "-1000", false}, {2023, "2000", true}},
+			[]netted{{2023, "4000", "2000", "6000", "0", "0", "0", "0"}}},
+		{"long-term loss against a short-term gain", []sale{{2023, "5000", false}, {2023, "-2000", true}},
+			[]netted{{2023, "3000", "0", "3000", "0", "0", "0", "0"}}},
+		{"short-term loss against a long-term gain", []sale{{2023, "-4000", false}, {2023, "1000", true}},
+			[]netted{{2023, "-3000", "0", "0", "3000", "0", "0", "0"}}},
+		{"losses of both terms, ordinary offset from short term first", []sale{{2023, "-2000", false}, {2023, "-5000", true}},
+			[]netted{{2023, "-2000", "-5000", "0", "3000", "0", "0", "4000"}}},
+		{"loss under the limit", []sale{{2023, "-1200", true}},
+			[]netted{{2023, "0", "-1200", "0", "1200", "0", "0", "0"}}},
+		{"carryforward over several years", []sale{{2021, "-10000", false}, {2022, "2000", true}, {2023, "500", false}},
+			[]netted{
+				{2021, "-10000", "0", "0", "3000", "0", "7000", "0"},
+				// the short-term carryforward absorbs the long-term gain, 3000 of the rest is ordinary
+				{2022, "-5000", "0", "0", "3000", "2000", "2000", "0"},
+				{2023, "-1500", "0", "0", "1500", "500", "0", "0"},
+			}},
+		{"carryforward keeps its term", []sale{{2022, "-8000", true}, {2023, "1000", false}, {2023, "6000", true}},
+			[]netted{
+				{2022, "0", "-8000", "0", "3000", "0", "0", "5000"},
+				{2023, "1000", "1000", "2000", "0", "5000", "0", "0"},
+			}},
+		{"year without sales", []sale{{2021, "-10000", false}, {2023, "1000", false}},
+			[]netted{
+				{2021, "-10000", "0", "0", "3000", "0", "7000", "0"},
+				{2022, "-7000", "0", "0", "3000", "0", "4000", "0"},
+				{2023, "-3000", "0", "0", "3000", "1000", "0", "0"},
+			}},
+	}
+	for _, tt := range tests {
+		s := NewState(false, nil, nil)
+		s.LossNetting = "us"
+		for _, sl := range tt.sales {
+			s.Disposals = append(s.Disposals, Disposal{
+				Disposed: time.Date(sl.year, 6, 1, 0, 0, 0, 0, time.UTC),
+				Gain:     decimal.RequireFromString(sl.gain),
+				Long:     sl.long,
+			})
+		}
+		out := NetGains(s)
+		if len(out) != len(tt.want) {
+			t.Errorf("%s: got %d years, want %d", tt.name, len(out), len(tt.want))
+		}
+		for _, w := range tt.want {
+			n := out[w.year]
+			if n == nil {
+				t.Errorf("%s: no netting for %d", tt.name, w.year)
+				continue
+			}
+			got := netted{w.year, n.Short.String(), n.Long.String(), n.Taxable.String(), n.Ordinary.String(), n.Carried.String(),
+				n.CarryOutShort.String(), n.CarryOutLong.String()}
+			if got != w {
+				t.Errorf("%s: got %+v, want %+v", tt.name, got, w)
+			}
+		}
+	}
+}
+
+func TestNetGainsCarryYears(t *testing.T) {
+	s := NewState(false, nil, nil)
+	s.Rules = Jurisdictions["es"] // losses carried forward four years
+	for _, sl := range []struct {
+		year int
+		gain string
+	}{{2018, "-1000"}, {2019, "-500"}, {2023, "800"}, {2024, "900"}} {
+		s.Disposals = append(s.Disposals, Disposal{Disposed: time.Date(sl.year, 6, 1, 0, 0, 0, 0, time.UTC), Gain: decimal.RequireFromString(sl.gain)})
+	}
+	out := NetGains(s)
+	// the 2018 loss has expired by 2023; the 2019 loss is used up in 2023, its last year
+	if n := out[2023]; n.Carried.String() != "500" || n.Taxable.String() != "300" || n.CarryOut.String() != "0" {
+		t.Errorf("2023: got %+v", *n)
+	}
+	if n := out[2024]; n.Carried.String() != "0" || n.Taxable.String() != "900" {
+		t.Errorf("2024: got %+v", *n)
+	}
+}
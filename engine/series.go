@@ -0,0 +1,72 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// SeriesPoint is what was held of each asset, over all wallets, at the end of a day or week of
+// State.SeriesInterval.
+type SeriesPoint struct {
+	Date     time.Time                // last day of the period
+	Holdings map[string]SeriesHolding // commodity -> holding
+}
+
+// SeriesHolding is the amount held of an asset and its basis.
+type SeriesHolding struct {
+	Amount decimal.Decimal
+	Basis  decimal.Decimal
+}
+
+// periodStart returns the first day of the day or week (starting on Monday) containing t, in the
+// location of t.
+func periodStart(interval string, t time.Time) time.Time {
+	d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if interval == "week" {
+		d = d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+	}
+	return d
+}
+
+func nextPeriod(interval string, start time.Time) time.Time {
+	if interval == "week" {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// recordSeries adds a point for every period that ended before t, starting with the period of the
+// first transaction. Periods without transactions repeat the holdings of the one before.
+func recordSeries(s *State, t time.Time) {
+	start := periodStart(s.SeriesInterval, t)
+	if s.seriesNext.IsZero() {
+		s.seriesNext = start
+	}
+	for s.seriesNext.Before(start) {
+		s.Series = append(s.Series, seriesPoint(s, s.seriesNext))
+		s.seriesNext = nextPeriod(s.SeriesInterval, s.seriesNext)
+	}
+}
+
+// seriesPoint sums the current inventory of all wallets as the point of the period starting at start.
+func seriesPoint(s *State, start time.Time) SeriesPoint {
+	p := SeriesPoint{Date: nextPeriod(s.SeriesInterval, start).AddDate(0, 0, -1), Holdings: map[string]SeriesHolding{}}
+	for _, commods := range s.Inventories {
+		for c, lots := range commods {
+			h := p.Holdings[c]
+			for _, lot := range lots {
+				h.Amount = h.Amount.Add(lot.Amount)
+				h.Basis = h.Basis.Add(lot.TotalCost)
+			}
+			if h.Amount.IsPositive() {
+				p.Holdings[c] = h
+			}
+		}
+	}
+	return p
+}
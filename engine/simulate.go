@@ -0,0 +1,71 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Simulation is the outcome of a sale that was not made, see SimulateSale.
+type Simulation struct {
+	Disposals []Disposal // one per lot the sale would consume, in consumption order
+	Gains     Gains      // short and long gain of the sale
+	Warnings  []string   // problems the sale would raise, e.g. selling more than held
+}
+
+// SimulateSale books a sale of amount coins of commodity from wallet at price per unit at time at
+// on a copy of the inventory of s, with the cost basis method and tax rules of s, and returns the
+// lots it would consume and the resulting gains. s itself is left unchanged.
+func SimulateSale(s *State, wallet, commodity string, amount, price decimal.Decimal, at time.Time) (Simulation, error) {
+	if !amount.IsPositive() {
+		return Simulation{}, fmt.Errorf("amount to sell must be positive")
+	}
+	sim := *s
+	sim.Inventories = map[string]map[string][]InventoryEntry{}
+	for w, commods := range s.Inventories {
+		sim.Inventories[w] = map[string][]InventoryEntry{}
+		for c, lots := range commods {
+			sim.Inventories[w][c] = append([]InventoryEntry{}, lots...)
+		}
+	}
+	sim.deniedLosses = map[string]decimal.Decimal{}
+	for c, d := range s.deniedLosses {
+		sim.deniedLosses[c] = d
+	}
+	sim.TaxYears = make(map[int]map[string]map[string]*Gains)
+	sim.Cessions = append([]Cession{}, s.Cessions...)
+	sim.Disposals = nil
+	sim.Journal = nil
+	sim.Warnings = nil
+	sim.activePicks = nil
+	tx := Tx{
+		Wallet:       wallet,
+		Time:         at,
+		Type:         "sell",
+		Commodity:    commodity,
+		Currency:     s.PriceCurrency,
+		Amount:       amount.Neg(),
+		Cost:         price.Mul(amount),
+		PricePerUnit: price,
+		SourceFile:   "simulation",
+		ReferenceID:  "simulation",
+	}
+	sim.Journal = append(sim.Journal, JournalEntry{Tx: tx, Handler: "sell"})
+	if err := handleSell(&sim, tx); err != nil {
+		return Simulation{}, err
+	}
+	res := Simulation{Disposals: sim.Disposals, Warnings: sim.Warnings}
+	for _, d := range sim.Disposals {
+		if d.Long {
+			res.Gains.Long = res.Gains.Long.Add(d.Gain)
+		} else {
+			res.Gains.Short = res.Gains.Short.Add(d.Gain)
+		}
+	}
+	return res, nil
+}
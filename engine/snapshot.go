@@ -0,0 +1,97 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Snapshot is the inventory left at the end of a run, saved with -save-state and loaded with
+// -load-state as the opening state of the next run.
+type Snapshot struct {
+	AsOf         time.Time                              `json:"as_of"` // time of the last processed tx
+	BaseCurrency string                                 `json:"base_currency,omitempty"`
+	Inventories  map[string]map[string][]InventoryEntry `json:"inventories"` // wallet -> commodity -> lots
+}
+
+// Snapshot returns the current inventory (empty lots omitted) as of the last processed tx.
+func (s *State) Snapshot() Snapshot {
+	snap := Snapshot{
+		AsOf:         s.OpeningAsOf,
+		BaseCurrency: s.BaseCurrency,
+		Inventories:  map[string]map[string][]InventoryEntry{},
+	}
+	if n := len(s.Journal); n > 0 {
+		snap.AsOf = s.Journal[n-1].Tx.Time
+	}
+	for w, commods := range s.Inventories {
+		for c, lots := range commods {
+			if len(lots) == 0 {
+				continue
+			}
+			if _, ok := snap.Inventories[w]; !ok {
+				snap.Inventories[w] = map[string][]InventoryEntry{}
+			}
+			snap.Inventories[w][c] = append([]InventoryEntry{}, lots...)
+		}
+	}
+	return snap
+}
+
+// Restore makes snap the opening inventory of s. Transactions up to snap.AsOf are then skipped by
+// ProcessTransactions, since the snapshot already includes them.
+func (s *State) Restore(snap Snapshot) error {
+	if snap.BaseCurrency != s.BaseCurrency {
+		return fmt.Errorf("state was saved with base currency %q, this run uses %q", snap.BaseCurrency, s.BaseCurrency)
+	}
+	s.AddLots(snap.Inventories)
+	s.OpeningAsOf = snap.AsOf
+	return nil
+}
+
+// AddLots adds lots (wallet -> commodity -> lots) to the inventory of s, keeping each inventory in
+// acquisition order. Used for the opening state and opening balances; lots saved without an ID
+// get one.
+func (s *State) AddLots(inventories map[string]map[string][]InventoryEntry) {
+	for w, commods := range inventories {
+		for c, lots := range commods {
+			ensureInventoryBucket(s, w, c)
+			inv := append(s.Inventories[w][c], lots...)
+			for i := range inv {
+				if inv[i].ID == "" {
+					inv[i].ID = lotID(c, inv[i])
+				}
+			}
+			sort.SliceStable(inv, func(i, j int) bool { return inv[i].Time.Before(inv[j].Time) })
+			s.Inventories[w][c] = inv
+		}
+	}
+}
+
+// SaveState writes the snapshot of s to path as JSON.
+func SaveState(path string, s *State) error {
+	data, err := json.MarshalIndent(s.Snapshot(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// LoadState reads a snapshot written by SaveState into s.
+func LoadState(path string, s *State) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return s.Restore(snap)
+}
@@ -0,0 +1,350 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+// Package engine holds the transaction model and the FIFO processing engine: every imported
+// Tx is dispatched to a handler that updates inventory lots, realized gains, income and the
+// journal kept in a State.
+package engine
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultCurrency is the fiat currency income is valued in when a price lookup is needed.
+const DefaultCurrency = "EUR"
+
+// Data models
+type Tx struct {
+	Wallet        string
+	Time          time.Time
+	Type          string
+	Commodity     string
+	Currency      string // price currency if present
+	Amount        decimal.Decimal
+	Cost          decimal.Decimal // total cost/consideration (including fees when appropriate)
+	PricePerUnit  decimal.Decimal // cost per unit (Cost / AmountAbs) when applicable
+	Fee           decimal.Decimal
+	Raw           map[string]string
+	SourceFile    string
+	SourceLine    int // line of the row in SourceFile (header is line 1)
+	ReferenceID   string
+	PairedComment string
+	OrigCurrency  string          // currency Cost/Fee were given in before conversion to the base currency
+	FXRate        decimal.Decimal // rate applied to convert Cost/Fee from OrigCurrency (zero = not converted)
+	Location      *time.Location  // zone of the source's timestamps without an offset (nil = UTC)
+}
+
+type InventoryEntry struct {
+	Time        time.Time       `json:"time"`
+	Amount      decimal.Decimal `json:"amount"`     // positive amount
+	UnitCost    decimal.Decimal `json:"unit_cost"`  // cost per unit
+	TotalCost   decimal.Decimal `json:"total_cost"` // Amount * UnitCost (keeps rounding)
+	SourceFiles []string        `json:"source_files"`
+	SourceLine  int             `json:"source_line,omitempty"`  // line of the acquiring row in SourceFiles[0]
+	ReferenceID string          `json:"reference_id,omitempty"` // reference id of the acquiring tx
+	ID          string          `json:"id,omitempty"`           // lot ID, see lotID
+}
+
+type Gains struct {
+	Short  decimal.Decimal `json:"short"`
+	Long   decimal.Decimal `json:"long"`
+	Income decimal.Decimal `json:"income"`
+	// margin fees already deducted from Short, kept for reporting
+	MarginFees decimal.Decimal `json:"margin_fees"`
+	// basis forfeited by lost or stolen coins, not part of Short/Long
+	Casualty decimal.Decimal `json:"casualty_loss"`
+}
+
+// Disposal records one FIFO lot (or part of it) consumed by a sell.
+type Disposal struct {
+	Wallet      string          `json:"wallet"`
+	Commodity   string          `json:"commodity"`
+	Acquired    time.Time       `json:"acquired"`
+	Disposed    time.Time       `json:"disposed"`
+	Amount      decimal.Decimal `json:"amount"`
+	UnitCost    decimal.Decimal `json:"unit_cost"`
+	CostBasis   decimal.Decimal `json:"cost_basis"` // UnitCost * Amount
+	Proceeds    decimal.Decimal `json:"proceeds"`   // gross proceeds allocated to this lot, before fees
+	Fee         decimal.Decimal `json:"fee"`        // share of the sell fee allocated to this lot
+	Gain        decimal.Decimal `json:"gain"`       // Proceeds - Fee - CostBasis
+	HoldingDays float64         `json:"holding_days"`
+	Long        bool            `json:"long"`
+	SourceFiles []string        `json:"source_files"` // acquisition source files followed by the disposal source file
+	ReferenceID string          `json:"reference_id"` // reference id of the disposing tx
+	Type        string          `json:"type"`         // normalized type of the disposing tx (sell, spend, ...)
+	// audit trail: where the consumed lot and the disposal came from
+	AcquiredFile string `json:"acquired_file"`
+	AcquiredLine int    `json:"acquired_line,omitempty"`
+	AcquiredRef  string `json:"acquired_ref"`
+	DisposedFile string `json:"disposed_file"`
+	DisposedLine int    `json:"disposed_line,omitempty"`
+	// part of the loss denied as superficial and added to the cost of the coins held instead
+	DeniedLoss decimal.Decimal `json:"denied_loss"`
+	// the gain or loss is tax-free under State.Rules
+	Exempt bool `json:"exempt,omitempty"`
+	// ID of the lot consumed, see lotID
+	LotID string `json:"lot_id,omitempty"`
+}
+
+// JournalEntry is one processed tx with the handler that booked it and the inventory lots it
+// added or consumed, so exports can reproduce the lot-level bookings.
+type JournalEntry struct {
+	Tx        Tx
+	Handler   string
+	Added     []JournalLot
+	Consumed  []JournalLot
+	Disposals []Disposal // for sells, one per consumed lot in the same order
+}
+
+type JournalLot struct {
+	Wallet    string
+	Commodity string
+	Lot       InventoryEntry // Amount/TotalCost are the part added or consumed
+}
+
+// IncomeEvent records one income receipt valued at receipt time.
+type IncomeEvent struct {
+	Time        time.Time       `json:"time"`
+	Wallet      string          `json:"wallet"`
+	Commodity   string          `json:"commodity"`
+	Type        string          `json:"type"`
+	Amount      decimal.Decimal `json:"amount"`
+	Value       decimal.Decimal `json:"value"` // zero when no fiat cost or price was available
+	SourceFile  string          `json:"source_file"`
+	ReferenceID string          `json:"reference_id"`
+}
+
+// Removal records lots that left inventory without a sale (gift sent, donation), with the
+// fair market value at removal time.
+type Removal struct {
+	Time        time.Time       `json:"time"`
+	Wallet      string          `json:"wallet"`
+	Commodity   string          `json:"commodity"`
+	Type        string          `json:"type"`
+	Amount      decimal.Decimal `json:"amount"`
+	CostBasis   decimal.Decimal `json:"cost_basis"`
+	Value       decimal.Decimal `json:"value"` // zero when no fiat cost or price was available
+	SourceFile  string          `json:"source_file"`
+	ReferenceID string          `json:"reference_id"`
+}
+
+// ImportIssue is an input row an importer skipped because it could not be read.
+type ImportIssue struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Format string `json:"format"`
+	Reason string `json:"reason"`
+}
+
+// Expense is a deductible cost (e.g. mining hardware or electricity) loaded from -mining-expenses.
+type Expense struct {
+	Time        time.Time       `json:"time"`
+	Category    string          `json:"category"`
+	Description string          `json:"description"`
+	Amount      decimal.Decimal `json:"amount"`
+}
+
+// DerivativesResult sums the settled results of futures/perpetual positions on one contract,
+// valued in the report currency. Futures never hold inventory, so they are reported apart from
+// spot gains.
+type DerivativesResult struct {
+	Asset   string          `json:"asset"` // settlement asset
+	PnL     decimal.Decimal `json:"pnl"`
+	Funding decimal.Decimal `json:"funding"` // positive = received
+	Fees    decimal.Decimal `json:"fees"`
+}
+
+// Net is the taxable result: PnL plus funding less fees.
+func (d *DerivativesResult) Net() decimal.Decimal {
+	return d.PnL.Add(d.Funding).Sub(d.Fees)
+}
+
+type State struct {
+	Inventories      map[string]map[string][]InventoryEntry           // wallet -> commodity -> FIFO sorted by Time (oldest first)
+	TaxYears         map[int]map[string]map[string]*Gains             // year -> wallet -> commodity -> Gains
+	Disposals        []Disposal                                       // every consumed lot in processing order
+	Journal          []JournalEntry                                   // every processed tx in order
+	Removals         []Removal                                        // gifts sent and other non-sale removals
+	Cessions         []Cession                                        // sales under PortfolioCost in processing order
+	Incomes          []IncomeEvent                                    // every income receipt in processing order
+	Warnings         []string                                         // data problems found while processing
+	MiningExpenses   []Expense                                        // expenses netted against mining income
+	YearEnd          map[int]map[string]map[string][]InventoryEntry   // year -> wallet -> commodity -> lots held on Dec 31
+	Derivatives      map[int]map[string]map[string]*DerivativesResult // year -> wallet -> contract -> settled futures results
+	Reconciliation   []BalanceResult                                  // BalanceChecks compared with the inventory, in time order
+	ImportIssues     []ImportIssue                                    // rows skipped while importing, set by the caller
+	Verbose          bool
+	WalletFilter     map[string]bool
+	CommodityFilter  map[string]bool
+	Prices           PriceSource     // optional; used to value income rows without fiat cost
+	PriceCurrency    string          // currency requested from Prices
+	BaseCurrency     string          // set when -base converted all amounts to one currency
+	Assets           *Assets         // asset aliases and stablecoins treated as fiat; nil for the built-in aliases
+	AirdropTreatment string          // "income" (FMV on receipt) or "zero-cost"
+	ForkTreatment    string          // "zero", "income" or "split"
+	WrapPairs        map[string]bool // "A|B" keys (both orders) of assets whose swaps keep basis, see wrapKey
+	Migrations       []Migration     // ticker renames and token migrations applied while processing
+	GiftTreatment    string          // gifts sent: "nontaxable" (removed at basis) or "taxable" (disposal at FMV)
+	CryptoFees       bool            // fees charged in a crypto asset are disposals of that asset
+	RebaseTreatment  string          // balance increases of rebasing tokens: "income" or "adjust"
+	OpeningAsOf      time.Time       // set by Restore: txs up to this time are already in the opening inventory
+	BalanceChecks    []BalanceCheck  // declared balances to reconcile while processing
+	Strict           bool            // negative balances and unknown types stop processing instead of warning
+	TransferWindow   time.Duration   // withdrawals and deposits this close in time can be one transfer (0 = no matching)
+	TransferMaxFee   decimal.Decimal // largest network fee of a matched transfer, as a fraction of the amount sent
+	TransferFees     string          // network fees of transfers: "deductible", "disposal" or "basis"
+	// AssetKey (chain:contract) -> ticker of on-chain tokens, see disambiguateAssets
+	AssetIDs map[string]string
+	// coins sold beyond the inventory and buys without a cost: "warn", "zero", "fmv" or "abort",
+	// see missingBasisLot; assumed lots are acquired on MissingBasisDate (zero = disposal date)
+	MissingBasis     string
+	MissingBasisDate time.Time
+	HoldingRule      string // long term after one year: "more-than" or "at-least", see IsLongTerm
+	// gains, income and the journal cover only transactions from this time on; earlier ones
+	// only build the inventory (zero = all), see startReporting
+	ReportFrom time.Time
+	// the rules of the country selected with -jurisdiction (nil = generic rules), see TaxRules
+	Rules TaxRules
+	// the basis of a sale is its share of the total acquisition price of all crypto held
+	// (France, art. 150 VH bis CGI) instead of the cost of the lots sold, see portfolioCession
+	PortfolioCost bool
+	// coins spent on goods or services whose basis is below this amount are personal use assets
+	// whose gains and losses are disregarded (Australia; zero = off)
+	PersonalUseLimit decimal.Decimal
+	// cost basis method: "fifo" (default), "lifo" (newest lots first), "hifo" (highest cost lots
+	// first, see orderLots) or "acb", the average cost of all coins of an asset in all wallets
+	// (Canada), see addPooled
+	Method string
+	// losses on sales followed or preceded by a purchase of the same asset within 30 days are
+	// denied and added to the cost of the coins held (Canada), see denySuperficialLoss
+	SuperficialLoss bool
+	flows           map[string][]flow          // acquisitions and disposals per asset, for SuperficialLoss
+	deniedLosses    map[string]decimal.Decimal // denied losses waiting for the next acquisition of the asset
+	// the holdings just before each of these times are kept in Valuations, for wealth taxes
+	// assessed on a reference date (the Dutch Box 3 on January 1), see snapshotHoldings
+	ValuationDates []time.Time
+	Valuations     []Valuation
+	// lots are consumed first in, first out across all wallets instead of per wallet (Spain), see
+	// takeOldestLots
+	GlobalFIFO bool
+	// tax withheld at source by exchanges, in processing order, see recordWithholding
+	Withheld []Withholding
+	// disposal instructions naming the lots a disposal takes first (-lots), see pickLots; those
+	// of the tx being processed are in activePicks
+	LotPicks    []LotPick
+	activePicks []*LotPick
+	// fiat currencies other than BaseCurrency received from sales are held as inventory and
+	// spending them on purchases realizes exchange gains, see trackForeignFiat; fxUncovered marks
+	// the wallet|currency pairs already warned about spending more than held
+	FXGains     bool
+	fxUncovered map[string]bool
+	// known chain splits: the parent of fork rows that name none, and airdrops of forked coins
+	// are forks, see forkOf
+	Forks []Fork
+	// how NetGains offsets the losses of a year: "pooled" (all gains against all losses; the
+	// default) or "us" (short and long term netted separately, then against each other, with up
+	// to OrdinaryLossLimit of a net loss deducted from ordinary income), see netByTerm
+	LossNetting       string
+	OrdinaryLossLimit decimal.Decimal
+	// the time the results are taken at (-as-of; zero = end of data): later transactions are left
+	// out by the caller and the holdings reports value the inventory at this time, see
+	// report.valuationTime
+	AsOf time.Time
+	// holdings over time: with SeriesInterval "day" or "week" a point is kept for every period from
+	// the first transaction to the last, see recordSeries
+	SeriesInterval string
+	Series         []SeriesPoint
+	seriesNext     time.Time // first day of the earliest period without a point
+	// fees as charged (trading, network and margin), in processing order, see recordTxFees
+	Fees []FeePaid
+	// user classification rules (-classify), applied in order before processing: the first rule
+	// matching a tx sets its type or drops it, see classifyTransactions
+	ClassRules []ClassRule
+}
+
+// Valuation is the inventory held at a time of State.ValuationDates.
+type Valuation struct {
+	At       time.Time
+	Holdings map[string]map[string][]InventoryEntry // wallet -> commodity -> lots
+}
+
+// Migration replaces asset From by To: without a date it is a rename applied to every tx, with a
+// date all holdings of From are converted at Ratio (To units per From unit) on that day, keeping
+// basis and acquisition dates.
+type Migration struct {
+	From    string
+	To      string
+	Date    time.Time // zero = rename
+	Ratio   decimal.Decimal
+	Builtin bool // applied only when the transactions use To, see builtinMigrations
+}
+
+func NewState(verbose bool, walletFilters []string, commodityFilters []string) *State {
+	wf := map[string]bool{}
+	for _, w := range walletFilters {
+		w = strings.TrimSpace(w)
+		if w != "" {
+			wf[w] = true
+		}
+	}
+	cf := map[string]bool{}
+	for _, c := range commodityFilters {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c != "" {
+			cf[c] = true
+		}
+	}
+	return &State{
+		Inventories:       make(map[string]map[string][]InventoryEntry),
+		TaxYears:          make(map[int]map[string]map[string]*Gains),
+		YearEnd:           make(map[int]map[string]map[string][]InventoryEntry),
+		Derivatives:       make(map[int]map[string]map[string]*DerivativesResult),
+		Verbose:           verbose,
+		WalletFilter:      wf,
+		CommodityFilter:   cf,
+		AirdropTreatment:  "income",
+		ForkTreatment:     "zero",
+		WrapPairs:         defaultWrapPairs(),
+		Migrations:        defaultMigrations(),
+		GiftTreatment:     "nontaxable",
+		RebaseTreatment:   "income",
+		TransferFees:      "deductible",
+		MissingBasis:      "warn",
+		HoldingRule:       "more-than",
+		Method:            "fifo",
+		Forks:             defaultForks(),
+		OrdinaryLossLimit: decimal.NewFromInt(3000),
+	}
+}
+
+// Warnf records a processing warning (reported at the end) and logs it immediately in verbose mode.
+func (s *State) Warnf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	s.Warnings = append(s.Warnings, msg)
+	if s.Verbose {
+		log.Print(msg)
+	}
+}
+
+// shortf reports removing more than is held: an error in strict mode, a warning otherwise.
+func (s *State) shortf(format string, args ...interface{}) error {
+	if s.Strict {
+		return fmt.Errorf(format, args...)
+	}
+	s.Warnf(format, args...)
+	return nil
+}
+
+// currentJournal returns the journal entry of the tx being processed, if any.
+func (s *State) currentJournal() *JournalEntry {
+	if len(s.Journal) == 0 {
+		return nil
+	}
+	return &s.Journal[len(s.Journal)-1]
+}
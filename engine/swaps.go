@@ -0,0 +1,527 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// builtinWrapPairs are wrapped tokens and their underlying asset; swapping one for the other
+// (wrapping, unwrapping or bridging) keeps basis and holding period.
+var builtinWrapPairs = [][2]string{
+	{"ETH", "WETH"},
+	{"BTC", "WBTC"},
+	{"BTC", "BTCB"},
+	{"BNB", "WBNB"},
+	{"MATIC", "WMATIC"},
+	{"POL", "WPOL"},
+	{"AVAX", "WAVAX"},
+	{"SOL", "WSOL"},
+	{"FTM", "WFTM"},
+	{"USDC", "USDC.E"},
+}
+
+func wrapKey(a, b string) string {
+	return strings.ToUpper(strings.TrimSpace(a)) + "|" + strings.ToUpper(strings.TrimSpace(b))
+}
+
+func defaultWrapPairs() map[string]bool {
+	m := map[string]bool{}
+	for _, p := range builtinWrapPairs {
+		m[wrapKey(p[0], p[1])] = true
+		m[wrapKey(p[1], p[0])] = true
+	}
+	return m
+}
+
+// AddWrapPairs adds user pairs given as "A=B[,C=D...]".
+func AddWrapPairs(m map[string]bool, spec string) error {
+	for _, p := range strings.Split(spec, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		a, b, ok := strings.Cut(p, "=")
+		if !ok || strings.TrimSpace(a) == "" || strings.TrimSpace(b) == "" {
+			return fmt.Errorf("invalid wrap pair %q (expected A=B)", p)
+		}
+		m[wrapKey(a, b)] = true
+		m[wrapKey(b, a)] = true
+	}
+	return nil
+}
+
+func isWrapType(typ string) bool {
+	switch NormalizeType(typ) {
+	case "wrap", "unwrap", "bridge", "migration", "migrate":
+		return true
+	}
+	return false
+}
+
+// pairWraps merges the two legs of a wrap/unwrap/bridge into one tx handled by handleWrap: legs
+// are convert/trade (or explicit wrap/unwrap/bridge) rows sharing a reference id, one outgoing
+// and one incoming, whose assets are a wrap pair. Explicitly typed legs are merged for any
+// assets, and when State.Rules make swaps not taxable so is any trade of one crypto asset for another. The merged
+// tx is the outgoing leg with the incoming side in Raw (to_commodity, to_amount, to_wallet).
+func pairWraps(s *State, txs []Tx) []Tx {
+	legs := map[string][]int{}
+	for i, tx := range txs {
+		t := NormalizeType(tx.Type)
+		ref := legRef(tx)
+		if ref == "" || !(t == "convert" || t == "trade" || isWrapType(t)) {
+			continue
+		}
+		legs[ref] = append(legs[ref], i)
+	}
+	merged := map[int]Tx{}
+	drop := map[int]bool{}
+	for _, idx := range legs {
+		if len(idx) != 2 {
+			continue
+		}
+		out, in := txs[idx[0]], txs[idx[1]]
+		outI, inI := idx[0], idx[1]
+		if out.Amount.Sign() > 0 {
+			out, in = in, out
+			outI, inI = inI, outI
+		}
+		if out.Amount.Sign() >= 0 || in.Amount.Sign() <= 0 {
+			continue
+		}
+		explicit := isWrapType(out.Type) || isWrapType(in.Type)
+		swap := s.Rules != nil && !s.Rules.SwapsTaxable() && !s.Assets.IsFiat(out.Commodity) && !s.Assets.IsFiat(in.Commodity)
+		if !explicit && !swap && !s.WrapPairs[wrapKey(out.Commodity, in.Commodity)] {
+			continue
+		}
+		m := out
+		m.Raw = map[string]string{}
+		for k, v := range out.Raw {
+			m.Raw[k] = v
+		}
+		if !isWrapType(m.Type) {
+			m.Type = "wrap"
+		}
+		m.Raw["to_commodity"] = in.Commodity
+		m.Raw["to_amount"] = in.Amount.String()
+		m.Raw["to_wallet"] = in.Wallet
+		m.Fee = out.Fee.Add(in.Fee)
+		merged[outI] = m
+		drop[inI] = true
+	}
+	if len(merged) == 0 {
+		return txs
+	}
+	res := make([]Tx, 0, len(txs)-len(drop))
+	for i, tx := range txs {
+		if drop[i] {
+			continue
+		}
+		if m, ok := merged[i]; ok {
+			tx = m
+		}
+		res = append(res, tx)
+	}
+	return res
+}
+
+// pairTrades merges the two legs of a trade of one crypto asset for another (convert/trade rows
+// sharing a reference id, one outgoing and one incoming, left over by pairWraps) into one tx
+// handled by handleSwap, so the disposal is valued at what was received. The merged tx is the
+// outgoing leg with the incoming side in Raw (to_commodity, to_amount, to_wallet, to_cost) and the
+// fiat fees of both legs.
+func pairTrades(s *State, txs []Tx) []Tx {
+	legs := map[string][]int{}
+	for i, tx := range txs {
+		t := NormalizeType(tx.Type)
+		ref := legRef(tx)
+		if ref == "" || !(t == "convert" || t == "trade") || s.Assets.IsFiat(tx.Commodity) {
+			continue
+		}
+		legs[ref] = append(legs[ref], i)
+	}
+	merged := map[int]Tx{}
+	drop := map[int]bool{}
+	for _, idx := range legs {
+		if len(idx) != 2 {
+			continue
+		}
+		out, in := txs[idx[0]], txs[idx[1]]
+		outI, inI := idx[0], idx[1]
+		if out.Amount.Sign() > 0 {
+			out, in = in, out
+			outI, inI = inI, outI
+		}
+		if out.Amount.Sign() >= 0 || in.Amount.Sign() <= 0 || out.Commodity == in.Commodity {
+			continue
+		}
+		m := out
+		m.Raw = map[string]string{}
+		for k, v := range out.Raw {
+			m.Raw[k] = v
+		}
+		m.Raw["to_commodity"] = in.Commodity
+		m.Raw["to_amount"] = in.Amount.String()
+		m.Raw["to_wallet"] = in.Wallet
+		if !in.Cost.IsZero() {
+			m.Raw["to_cost"] = in.Cost.String()
+		}
+		m.Fee = out.Fee.Add(in.Fee)
+		merged[outI] = m
+		drop[inI] = true
+	}
+	if len(merged) == 0 {
+		return txs
+	}
+	res := make([]Tx, 0, len(txs)-len(drop))
+	for i, tx := range txs {
+		if drop[i] {
+			continue
+		}
+		if m, ok := merged[i]; ok {
+			tx = m
+		}
+		res = append(res, tx)
+	}
+	return res
+}
+
+// handleSwap books a trade of one crypto asset for another merged by pairTrades: the asset given
+// up is sold for the value of the asset received, which becomes its basis. The value is the one
+// the export gives for the incoming leg, else for the outgoing leg, else the market value of the
+// asset received (of the asset given up when that has no price).
+func handleSwap(s *State, tx Tx) error {
+	toCommodity := tx.Raw["to_commodity"]
+	toAmount := ParseDecimal(tx.Raw["to_amount"])
+	toWallet := tx.Raw["to_wallet"]
+	if toWallet == "" {
+		toWallet = tx.Wallet
+	}
+	value := ParseDecimal(tx.Raw["to_cost"])
+	if value.IsZero() {
+		value = tx.Cost
+	}
+	if value.IsZero() && !tx.PricePerUnit.IsZero() {
+		value = tx.PricePerUnit.Mul(tx.Amount.Abs())
+	}
+	if value.IsZero() {
+		v, err := valueIn(s, toCommodity, toAmount, tx.Time)
+		if err != nil && !errors.Is(err, ErrOffline) {
+			v, err = valueIn(s, tx.Commodity, tx.Amount.Abs(), tx.Time)
+		}
+		if err != nil {
+			if errors.Is(err, ErrOffline) {
+				return err
+			}
+			s.Warnf("TRADE: cannot value %s %s received for %s %s ref=%s: %v", toAmount.String(), toCommodity, tx.Amount.Abs().String(), tx.Commodity, tx.ReferenceID, err)
+		}
+		value = v
+	}
+	if s.Verbose {
+		log.Printf("TRADE: wallet=%s %s %s -> %s %s value=%s", tx.Wallet, tx.Amount.Abs().String(), tx.Commodity, toAmount.String(), toCommodity, value.String())
+	}
+	sell := tx
+	sell.Cost = value
+	sell.PricePerUnit = decimal.Zero
+	if err := handleSell(s, sell); err != nil {
+		return err
+	}
+	buy := tx
+	buy.Wallet = toWallet
+	buy.Commodity = toCommodity
+	buy.Amount = toAmount
+	buy.Cost = value
+	buy.Fee = decimal.Zero
+	return handleBuy(s, buy)
+}
+
+// legRef returns the reference shared by the legs of one trade: the refid of ledger exports
+// (Kraken), whose txid is unique per row, otherwise the reference id.
+func legRef(tx Tx) string {
+	if ref := strings.TrimSpace(tx.Raw["refid"]); ref != "" {
+		return ref
+	}
+	return tx.ReferenceID
+}
+
+// renameAssets applies undated migrations (ticker renames) to every tx.
+func renameAssets(s *State, txs []Tx) []Tx {
+	renames := map[string]string{}
+	for _, m := range s.Migrations {
+		if m.Date.IsZero() {
+			renames[strings.ToUpper(m.From)] = m.To
+		}
+	}
+	if len(renames) == 0 {
+		return txs
+	}
+	res := make([]Tx, len(txs))
+	for i, tx := range txs {
+		if to, ok := renames[strings.ToUpper(strings.TrimSpace(tx.Commodity))]; ok {
+			tx.Commodity = to
+		}
+		res[i] = tx
+	}
+	return res
+}
+
+// datedMigrations returns the migrations with a date, oldest first, leaving out built-in ones
+// whose new ticker none of txs uses.
+func datedMigrations(s *State, txs []Tx) []Migration {
+	used := map[string]bool{}
+	for _, tx := range txs {
+		used[strings.ToUpper(strings.TrimSpace(tx.Commodity))] = true
+	}
+	var res []Migration
+	for _, m := range s.Migrations {
+		if !m.Date.IsZero() && (!m.Builtin || used[strings.ToUpper(m.To)]) {
+			res = append(res, m)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Date.Before(res[j].Date) })
+	return res
+}
+
+// migrateHoldings converts every wallet's holdings of m.From to m.To at basis. Each wallet gets
+// a synthetic journal entry so ledger exports show the swap.
+func migrateHoldings(s *State, m Migration) {
+	wallets := []string{}
+	for w, commods := range s.Inventories {
+		if len(commods[m.From]) > 0 {
+			wallets = append(wallets, w)
+		}
+	}
+	sort.Strings(wallets)
+	for _, w := range wallets {
+		total := decimal.Zero
+		for _, lot := range s.Inventories[w][m.From] {
+			total = total.Add(lot.Amount)
+		}
+		if !total.IsPositive() {
+			continue
+		}
+		s.Journal = append(s.Journal, JournalEntry{
+			Tx: Tx{
+				Wallet:      w,
+				Time:        m.Date,
+				Type:        "migration",
+				Commodity:   m.From,
+				Amount:      total.Neg(),
+				Raw:         map[string]string{"to_commodity": m.To, "to_amount": total.Mul(m.Ratio).String()},
+				ReferenceID: "migration:" + m.From + "-" + m.To,
+			},
+			Handler: "migration",
+		})
+		moveLots(s, w, m.From, w, m.To, total, m.Ratio, decimal.Zero)
+		if s.Verbose {
+			log.Printf("MIGRATION: wallet=%s %s %s -> %s %s", w, total.String(), m.From, total.Mul(m.Ratio).String(), m.To)
+		}
+	}
+}
+
+func IsDustType(typ string) bool {
+	t := NormalizeType(typ)
+	return t == "small assets exchange bnb" || t == "dust"
+}
+
+// pairDust merges a dust conversion group (dust rows sharing wallet and reference id, or wallet
+// and time when there is no reference id) into one tx per dust asset carrying its share of the
+// BNB received in Raw (to_commodity, to_amount). When the export has one BNB row per dust asset
+// each is paired with the dust row next to it by source line; otherwise the BNB is split by the market value of each dust asset
+// (evenly when it cannot be valued).
+func pairDust(s *State, txs []Tx) []Tx {
+	groups := map[string][]int{}
+	order := []string{}
+	for i, tx := range txs {
+		if !IsDustType(tx.Type) {
+			continue
+		}
+		key := tx.Wallet + "|" + tx.ReferenceID
+		if tx.ReferenceID == "" {
+			key = tx.Wallet + "|" + tx.Time.Format(time.RFC3339)
+		}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+	if len(groups) == 0 {
+		return txs
+	}
+	merged := map[int]Tx{}
+	drop := map[int]bool{}
+	for _, key := range order {
+		var outs, ins []int
+		for _, i := range groups[key] {
+			if txs[i].Amount.Sign() < 0 {
+				outs = append(outs, i)
+			} else if txs[i].Amount.Sign() > 0 {
+				ins = append(ins, i)
+			}
+		}
+		if len(outs) == 0 || len(ins) == 0 {
+			s.Warnf("DUST: conversion group %s has %d dust and %d received rows; left unpaired", key, len(outs), len(ins))
+			continue
+		}
+		// the rows of a group tie on time and reference id; the source lines give the export order
+		byLine := func(idx []int) {
+			sort.SliceStable(idx, func(a, b int) bool {
+				ta, tb := txs[idx[a]], txs[idx[b]]
+				if ta.SourceFile != tb.SourceFile {
+					return ta.SourceFile < tb.SourceFile
+				}
+				return ta.SourceLine < tb.SourceLine
+			})
+		}
+		byLine(outs)
+		byLine(ins)
+		toCommodity := txs[ins[0]].Commodity
+		received := decimal.Zero
+		for _, i := range ins {
+			received = received.Add(txs[i].Amount)
+			drop[i] = true
+		}
+		shares := make([]decimal.Decimal, len(outs))
+		if len(ins) == len(outs) {
+			for k, i := range ins {
+				shares[k] = txs[i].Amount
+			}
+		} else {
+			values := make([]decimal.Decimal, len(outs))
+			total := decimal.Zero
+			for k, i := range outs {
+				v, err := valueIn(s, txs[i].Commodity, txs[i].Amount.Abs(), txs[i].Time)
+				if err != nil {
+					total = decimal.Zero
+					break
+				}
+				values[k] = v
+				total = total.Add(v)
+			}
+			for k := range outs {
+				if total.IsPositive() {
+					shares[k] = received.Mul(values[k]).Div(total)
+				} else {
+					shares[k] = received.Div(decimal.NewFromInt(int64(len(outs))))
+				}
+			}
+			if !total.IsPositive() {
+				s.Warnf("DUST: cannot value all dust assets of group %s; %s %s split evenly", key, received.String(), toCommodity)
+			}
+		}
+		for k, i := range outs {
+			m := txs[i]
+			m.Raw = map[string]string{}
+			for rk, rv := range txs[i].Raw {
+				m.Raw[rk] = rv
+			}
+			m.Raw["to_commodity"] = toCommodity
+			m.Raw["to_amount"] = shares[k].String()
+			merged[i] = m
+		}
+	}
+	res := make([]Tx, 0, len(txs)-len(drop))
+	for i, tx := range txs {
+		if drop[i] {
+			continue
+		}
+		if m, ok := merged[i]; ok {
+			tx = m
+		}
+		res = append(res, tx)
+	}
+	return res
+}
+
+// handleDust disposes of one dust asset at the market value of its BNB share and acquires that
+// BNB with the same value as basis.
+func handleDust(s *State, tx Tx) error {
+	toCommodity := tx.Raw["to_commodity"]
+	if toCommodity == "" {
+		// unpaired leg (warned in pairDust)
+		return handleConvert(s, tx)
+	}
+	toAmount := ParseDecimal(tx.Raw["to_amount"])
+	value := tx.Cost
+	if value.IsZero() {
+		v, err := valueIn(s, toCommodity, toAmount, tx.Time)
+		if err != nil {
+			if errors.Is(err, ErrOffline) {
+				return err
+			}
+			s.Warnf("DUST: cannot value %s %s received for %s ref=%s: %v", toAmount.String(), toCommodity, tx.Commodity, tx.ReferenceID, err)
+		}
+		value = v
+	}
+	sell := tx
+	sell.Cost = value
+	sell.PricePerUnit = decimal.Zero
+	if err := handleSell(s, sell); err != nil {
+		return err
+	}
+	buy := tx
+	buy.Commodity = toCommodity
+	buy.Amount = toAmount
+	buy.Cost = value
+	return handleBuy(s, buy)
+}
+
+// handleWrap swaps an asset for its wrapped/bridged counterpart without realizing a gain: lots
+// move to the new asset with their basis and acquisition dates. A fiat fee is added to the basis,
+// and so is a fee in coins once -crypto-fees has disposed of them at their value; without it a fee
+// in coins is not added (it would be taken as that many units of fiat).
+func handleWrap(s *State, tx Tx) error {
+	toCommodity := tx.Raw["to_commodity"]
+	toAmountText := tx.Raw["to_amount"]
+	if toCommodity == "" {
+		// single-row migration: the new asset (and optionally its amount) are columns of the row;
+		// Raw is shared with the journal and the source row, so it is left as is
+		if to := strings.ToUpper(s.Assets.Normalize(FirstNonEmpty(tx.Raw, "to_asset", "new_asset", "to"))); to != "" {
+			toCommodity = to
+			if toAmountText == "" {
+				toAmount := tx.Amount.Abs()
+				if v := FirstNonEmpty(tx.Raw, "new_amount"); v != "" {
+					toAmount = ParseDecimal(v)
+				}
+				toAmountText = toAmount.String()
+			}
+		}
+	}
+	if toCommodity == "" {
+		s.Warnf("WRAP: %s of %s has no matching incoming leg (same reference id) ref=%s; treated as a trade", tx.Type, tx.Commodity, tx.ReferenceID)
+		return handleConvert(s, tx)
+	}
+	outAmount := tx.Amount.Abs()
+	toAmount, err := decimal.NewFromString(toAmountText)
+	if err != nil || outAmount.IsZero() || !toAmount.IsPositive() {
+		return fmt.Errorf("wrap ref=%s: invalid amounts %s -> %s", tx.ReferenceID, outAmount.String(), toAmountText)
+	}
+	toWallet := tx.Raw["to_wallet"]
+	if toWallet == "" {
+		toWallet = tx.Wallet
+	}
+	fee := tx.Fee.Abs()
+	if asset := feeAsset(s, tx); asset != "" && !s.Assets.IsFiat(asset) && !s.CryptoFees && !fee.IsZero() {
+		s.Warnf("WRAP: fee of %s %s not added to the basis of %s (see -crypto-fees) ref=%s", fee.String(), asset, toCommodity, tx.ReferenceID)
+		fee = decimal.Zero
+	}
+	remaining := moveLots(s, tx.Wallet, tx.Commodity, toWallet, toCommodity, outAmount, toAmount.Div(outAmount), fee)
+	if remaining.Cmp(decimal.NewFromFloat(1e-9)) > 0 {
+		if err := s.shortf("WRAP WARNING: moved less (%s) than requested (%s) for %s -> %s in %s ref=%s", outAmount.Sub(remaining).String(), outAmount.String(), tx.Commodity, toCommodity, tx.Wallet, tx.ReferenceID); err != nil {
+			return err
+		}
+	}
+	if s.Verbose {
+		log.Printf("WRAP: %s %s %s -> %s %s (%s -> %s)", tx.Type, outAmount.String(), tx.Commodity, toAmount.String(), toCommodity, tx.Wallet, toWallet)
+	}
+	return nil
+}
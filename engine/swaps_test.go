@@ -0,0 +1,88 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPairDustBySourceLine(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	row := func(line int, asset, amount string) Tx {
+		return Tx{
+			Time:        at,
+			Type:        "small assets exchange bnb",
+			Wallet:      "binance",
+			Commodity:   asset,
+			Amount:      decimal.RequireFromString(amount),
+			SourceFile:  "binance.csv",
+			SourceLine:  line,
+			ReferenceID: "dust-20240301T120000",
+		}
+	}
+	// each dust asset is followed by its BNB row, but the rows tie on everything else and can
+	// come in any order
+	txs := []Tx{
+		row(5, "BNB", "0.003"),
+		row(2, "DOT", "-0.5"),
+		row(7, "BNB", "0.002"),
+		row(3, "BNB", "0.001"),
+		row(6, "XRP", "-4"),
+		row(4, "ADA", "-2"),
+	}
+	res := pairDust(NewState(false, nil, nil), txs)
+	want := map[string]string{"DOT": "0.001", "ADA": "0.003", "XRP": "0.002"}
+	if len(res) != len(want) {
+		t.Fatalf("got %d txs, want %d", len(res), len(want))
+	}
+	for _, tx := range res {
+		if tx.Raw["to_commodity"] != "BNB" || tx.Raw["to_amount"] != want[tx.Commodity] {
+			t.Errorf("%s: got %s %s, want %s BNB", tx.Commodity, tx.Raw["to_amount"], tx.Raw["to_commodity"], want[tx.Commodity])
+		}
+	}
+}
+
+func TestWrapFee(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		feeAsset   string
+		cryptoFees bool
+		basis      string
+		warned     bool
+	}{
+		{"fiat fee", "EUR", false, "1005", false},
+		{"fee without an asset", "", false, "1005", false},
+		{"coin fee", "ETH", false, "1000", true},
+		{"coin fee with -crypto-fees", "ETH", true, "1001.5", false},
+	}
+	for _, tt := range tests {
+		fee := "5"
+		if tt.feeAsset == "ETH" {
+			fee = "0.001"
+		}
+		txs := []Tx{
+			{Time: at, Type: "buy", Wallet: "w", Commodity: "ETH", Amount: decimal.RequireFromString("2"), Cost: decimal.RequireFromString("2000"), Currency: "EUR"},
+			{Time: at.Add(time.Hour), Type: "wrap", Wallet: "w", Commodity: "ETH", Amount: decimal.RequireFromString("-1"), Fee: decimal.RequireFromString(fee), Currency: "EUR",
+				Raw: map[string]string{"to_commodity": "WETH", "to_amount": "1", "fee_asset": tt.feeAsset}},
+		}
+		s := NewState(false, nil, nil)
+		s.CryptoFees = tt.cryptoFees
+		s.Prices = fixedRates{"ETH": decimal.RequireFromString("1500")}
+		if err := ProcessTransactions(s, txs); err != nil {
+			t.Fatal(err)
+		}
+		lots := s.Inventories["w"]["WETH"]
+		if len(lots) != 1 || lots[0].TotalCost.String() != tt.basis {
+			t.Errorf("%s: got WETH lots %v, want one with basis %s", tt.name, lots, tt.basis)
+		}
+		if warned := len(s.Warnings) > 0; warned != tt.warned {
+			t.Errorf("%s: got warnings %v", tt.name, s.Warnings)
+		}
+	}
+}
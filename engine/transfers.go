@@ -0,0 +1,119 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+func isWithdrawalType(typ string) bool {
+	switch NormalizeType(typ) {
+	case "withdrawal", "withdraw", "send", "sent":
+		return true
+	}
+	return false
+}
+
+func isDepositType(typ string) bool {
+	switch NormalizeType(typ) {
+	case "deposit", "receive", "received":
+		return true
+	}
+	return false
+}
+
+// matchTransfers pairs withdrawals with deposits of the same asset into another wallet, usually
+// from different files (an exchange export and a wallet export), and merges each pair into one
+// transfer handled by handleTransfer. A deposit matches when it arrives within TransferWindow of
+// the withdrawal and is at most TransferMaxFee (a fraction) smaller; the difference is the network
+// fee, kept in Raw["network_fee"]. Among several candidates the closest amount, then the closest
+// time, wins. The transfer takes the withdrawal's place so the lots leave when the coins did.
+func matchTransfers(s *State, txs []Tx) []Tx {
+	if s.TransferWindow <= 0 {
+		return txs
+	}
+	var withdrawals, deposits []int
+	for i, tx := range txs {
+		switch {
+		case isWithdrawalType(tx.Type) && tx.Amount.Sign() < 0:
+			withdrawals = append(withdrawals, i)
+		case isDepositType(tx.Type) && tx.Amount.Sign() > 0:
+			deposits = append(deposits, i)
+		}
+	}
+	if len(withdrawals) == 0 || len(deposits) == 0 {
+		return txs
+	}
+	sort.SliceStable(withdrawals, func(a, b int) bool { return txs[withdrawals[a]].Time.Before(txs[withdrawals[b]].Time) })
+	merged := map[int]Tx{}
+	drop := map[int]bool{}
+	for _, wi := range withdrawals {
+		out := txs[wi]
+		sent := out.Amount.Abs()
+		minReceived := sent.Sub(sent.Mul(s.TransferMaxFee))
+		best := -1
+		var bestFee decimal.Decimal
+		for _, di := range deposits {
+			in := txs[di]
+			if drop[di] || in.Wallet == out.Wallet || !strings.EqualFold(in.Commodity, out.Commodity) {
+				continue
+			}
+			dt := in.Time.Sub(out.Time)
+			if dt < -s.TransferWindow || dt > s.TransferWindow {
+				continue
+			}
+			if in.Amount.GreaterThan(sent) || in.Amount.LessThan(minReceived) {
+				continue
+			}
+			fee := sent.Sub(in.Amount)
+			if best >= 0 {
+				c := fee.Cmp(bestFee)
+				if c > 0 || (c == 0 && dt.Abs() >= txs[best].Time.Sub(out.Time).Abs()) {
+					continue
+				}
+			}
+			best, bestFee = di, fee
+		}
+		if best < 0 {
+			continue
+		}
+		in := txs[best]
+		m := out
+		m.Type = "transfer"
+		m.Wallet = in.Wallet
+		m.Commodity = in.Commodity
+		m.Amount = in.Amount
+		m.PairedComment = out.Wallet
+		m.Raw = map[string]string{}
+		for k, v := range out.Raw {
+			m.Raw[k] = v
+		}
+		m.Raw["network_fee"] = bestFee.String()
+		m.Raw["deposit_ref"] = in.ReferenceID
+		merged[wi] = m
+		drop[best] = true
+		if s.Verbose {
+			log.Printf("TRANSFER MATCH: %s %s %s (%s) -> %s %s (%s) fee=%s", out.Wallet, sent.String(), out.Commodity, out.SourceFile, in.Wallet, in.Amount.String(), in.SourceFile, bestFee.String())
+		}
+	}
+	if len(merged) == 0 {
+		return txs
+	}
+	res := make([]Tx, 0, len(txs)-len(drop))
+	for i, tx := range txs {
+		if drop[i] {
+			continue
+		}
+		if m, ok := merged[i]; ok {
+			tx = m
+		}
+		res = append(res, tx)
+	}
+	return res
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMatchTransfers(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	withdrawal := func(wallet, amount string) Tx {
+		return Tx{Time: at, Type: "withdrawal", Wallet: wallet, Commodity: "BTC", Amount: decimal.RequireFromString("-" + amount), ReferenceID: "w"}
+	}
+	deposit := func(ref, wallet, amount string, after time.Duration) Tx {
+		return Tx{Time: at.Add(after), Type: "deposit", Wallet: wallet, Commodity: "BTC", Amount: decimal.RequireFromString(amount), ReferenceID: ref}
+	}
+	tests := []struct {
+		name    string
+		txs     []Tx
+		deposit string // ReferenceID of the deposit paired with the withdrawal, "" for none
+		fee     string
+	}{
+		{"same amount", []Tx{withdrawal("exchange", "1"), deposit("d", "ledger", "1", time.Hour)}, "d", "0"},
+		{"network fee remainder", []Tx{withdrawal("exchange", "1"), deposit("d", "ledger", "0.9995", time.Hour)}, "d", "0.0005"},
+		{"fee at the limit", []Tx{withdrawal("exchange", "1"), deposit("d", "ledger", "0.95", time.Hour)}, "d", "0.05"},
+		{"fee over the limit", []Tx{withdrawal("exchange", "1"), deposit("d", "ledger", "0.9499", time.Hour)}, "", ""},
+		{"more received than sent", []Tx{withdrawal("exchange", "1"), deposit("d", "ledger", "1.0001", time.Hour)}, "", ""},
+		{"at the end of the window", []Tx{withdrawal("exchange", "1"), deposit("d", "ledger", "1", 24*time.Hour)}, "d", "0"},
+		{"after the window", []Tx{withdrawal("exchange", "1"), deposit("d", "ledger", "1", 24*time.Hour+time.Second)}, "", ""},
+		{"deposit time before the withdrawal", []Tx{deposit("d", "ledger", "1", -24*time.Hour), withdrawal("exchange", "1")}, "d", "0"},
+		{"same wallet", []Tx{withdrawal("exchange", "1"), deposit("d", "exchange", "1", time.Hour)}, "", ""},
+		{"closest amount wins", []Tx{
+			withdrawal("exchange", "1"),
+			deposit("far", "ledger", "0.98", time.Minute),
+			deposit("near", "trezor", "0.999", 12*time.Hour),
+		}, "near", "0.001"},
+		{"same amount, closest time wins", []Tx{
+			withdrawal("exchange", "1"),
+			deposit("late", "ledger", "0.999", 12*time.Hour),
+			deposit("early", "trezor", "0.999", time.Hour),
+		}, "early", "0.001"},
+	}
+	for _, tt := range tests {
+		s := NewState(false, nil, nil)
+		s.TransferWindow = 24 * time.Hour
+		s.TransferMaxFee = decimal.RequireFromString("0.05")
+		res := matchTransfers(s, tt.txs)
+		var transfer *Tx
+		for i := range res {
+			if res[i].Type == "transfer" {
+				transfer = &res[i]
+			}
+		}
+		if tt.deposit == "" {
+			if transfer != nil || len(res) != len(tt.txs) {
+				t.Errorf("%s: matched %v", tt.name, res)
+			}
+			continue
+		}
+		if transfer == nil || len(res) != len(tt.txs)-1 {
+			t.Errorf("%s: got %v, want a transfer to deposit %s", tt.name, res, tt.deposit)
+			continue
+		}
+		if transfer.Raw["deposit_ref"] != tt.deposit || transfer.Raw["network_fee"] != tt.fee || transfer.PairedComment != "exchange" {
+			t.Errorf("%s: got deposit %s fee %s from %s, want deposit %s fee %s from exchange", tt.name,
+				transfer.Raw["deposit_ref"], transfer.Raw["network_fee"], transfer.PairedComment, tt.deposit, tt.fee)
+		}
+	}
+}
+
+func TestMatchTransfersUsesEachDepositOnce(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	txs := []Tx{
+		{Time: at, Type: "withdrawal", Wallet: "exchange", Commodity: "ETH", Amount: decimal.RequireFromString("-2")},
+		{Time: at.Add(time.Minute), Type: "withdrawal", Wallet: "exchange", Commodity: "ETH", Amount: decimal.RequireFromString("-2")},
+		{Time: at.Add(time.Hour), Type: "deposit", Wallet: "ledger", Commodity: "ETH", Amount: decimal.RequireFromString("1.999"), ReferenceID: "d1"},
+	}
+	s := NewState(false, nil, nil)
+	s.TransferWindow = 24 * time.Hour
+	s.TransferMaxFee = decimal.RequireFromString("0.05")
+	res := matchTransfers(s, txs)
+	if len(res) != 2 || res[0].Type != "transfer" || res[0].Raw["deposit_ref"] != "d1" || res[1].Type != "withdrawal" {
+		t.Fatalf("got %v, want the first withdrawal paired with d1 and the second left alone", res)
+	}
+}
+
+func TestMatchTransfersOff(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	txs := []Tx{
+		{Time: at, Type: "withdrawal", Wallet: "exchange", Commodity: "BTC", Amount: decimal.RequireFromString("-1")},
+		{Time: at.Add(time.Hour), Type: "deposit", Wallet: "ledger", Commodity: "BTC", Amount: decimal.RequireFromString("1")},
+	}
+	if res := matchTransfers(NewState(false, nil, nil), txs); len(res) != 2 || res[0].Type != "withdrawal" {
+		t.Fatalf("matched without a window: %v", res)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package engine
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Withholding is tax an exchange withheld at source from a trade (India's 1% TDS), credited
+// against the tax due: it does not change the gain of the trade.
+type Withholding struct {
+	Time        time.Time       `json:"time"`
+	Wallet      string          `json:"wallet"`
+	Commodity   string          `json:"commodity"` // asset traded
+	Amount      decimal.Decimal `json:"amount"`    // in Currency
+	Currency    string          `json:"currency"`
+	Value       decimal.Decimal `json:"value"` // Amount in the report currency, zero when it could not be valued
+	SourceFile  string          `json:"source_file"`
+	ReferenceID string          `json:"reference_id"`
+}
+
+// recordWithholding adds the tax withheld from tx, as set by the importer in
+// Raw["tax_withheld"] and Raw["tax_withheld_currency"] (default: the tx currency), to
+// State.Withheld.
+func recordWithholding(s *State, tx Tx) error {
+	amount := ParseDecimal(tx.Raw["tax_withheld"]).Abs()
+	if amount.IsZero() {
+		return nil
+	}
+	currency := strings.ToUpper(strings.TrimSpace(tx.Raw["tax_withheld_currency"]))
+	if currency == "" {
+		currency = tx.Currency
+	}
+	v, err := valueIn(s, currency, amount, tx.Time)
+	if err != nil {
+		if errors.Is(err, ErrOffline) {
+			return err
+		}
+		s.Warnf("WITHHOLDING: cannot value %s %s ref=%s: %v", amount.String(), currency, tx.ReferenceID, err)
+	}
+	s.Withheld = append(s.Withheld, Withholding{
+		Time:        tx.Time,
+		Wallet:      tx.Wallet,
+		Commodity:   tx.Commodity,
+		Amount:      amount,
+		Currency:    currency,
+		Value:       v,
+		SourceFile:  tx.SourceFile,
+		ReferenceID: tx.ReferenceID,
+	})
+	return nil
+}
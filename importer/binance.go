@@ -0,0 +1,68 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"cryptotax/engine"
+)
+
+func init() {
+	Register("binance", binanceImporter{})
+}
+
+// binanceImporter reads the Binance transaction history.
+type binanceImporter struct{}
+
+func (binanceImporter) Detect(header map[string]int) bool {
+	return hasColumns(header, "utc_time", "operation")
+}
+
+func (binanceImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseBinanceRows(in), nil
+}
+
+// parseBinanceRows maps the Binance transaction history (UTC_Time, Account, Operation, Coin,
+// Change, Remark) to one tx per crypto row typed by the lowercased operation. Dust conversions
+// ("Small Assets Exchange BNB") share a reference id per timestamp so pairDust can group them.
+func parseBinanceRows(in *Input) []engine.Tx {
+	rows, path, defaultWallets := in.Rows, in.Path, in.DefaultWallets
+	wallet := "binance"
+	if len(defaultWallets) > 0 && defaultWallets[0] != "" {
+		wallet = defaultWallets[0]
+	}
+	var txs []engine.Tx
+	for _, rr := range rows {
+		asset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "coin")))
+		if asset == "" || in.Assets.IsFiat(asset) {
+			continue
+		}
+		t, err := engine.ParseTimeGuess(engine.FirstNonEmpty(rr.Rec, "utc_time"))
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		typ := engine.NormalizeType(engine.FirstNonEmpty(rr.Rec, "operation"))
+		ref := fmt.Sprintf("%s-%d", filepath.Base(path), rr.Index)
+		if engine.IsDustType(typ) {
+			ref = "dust-" + t.Format("20060102T150405")
+		}
+		txs = append(txs, engine.Tx{
+			Wallet:      wallet,
+			Time:        t,
+			Type:        typ,
+			Commodity:   asset,
+			Amount:      engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "change")),
+			Raw:         rr.Rec,
+			SourceFile:  filepath.Base(path),
+			SourceLine:  rr.Line,
+			ReferenceID: ref,
+		})
+	}
+	return txs
+}
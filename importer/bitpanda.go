@@ -0,0 +1,127 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	Register("bitpanda", bitpandaImporter{})
+}
+
+// bitpandaImporter reads the Bitpanda transaction history export.
+type bitpandaImporter struct{}
+
+func (bitpandaImporter) Detect(header map[string]int) bool {
+	return hasColumns(header, "transaction type", "in/out", "amount asset", "asset class")
+}
+
+func (bitpandaImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseBitpandaRows(in), nil
+}
+
+// parseBitpandaRows maps Bitpanda rows (Transaction ID, Timestamp, Transaction Type, In/Out,
+// Amount Fiat, Fiat, Amount Asset, Asset, Asset market price, Asset market price currency, Asset
+// class, Fee, Fee asset). Only the Cryptocurrency asset class is read: fiat, metals, stocks and
+// ETFs are not crypto assets. Buys and sells cost the fiat amount paid or received (including
+// Bitpanda's spread), a fee in crypto (BEST) is a separate fee row. Incoming transfers are BEST
+// rewards, bonuses and airdrops: income at the asset market price. Staking moves are internal.
+func parseBitpandaRows(in *Input) []engine.Tx {
+	wallet := "bitpanda"
+	if len(in.DefaultWallets) > 0 && in.DefaultWallets[0] != "" {
+		wallet = in.DefaultWallets[0]
+	}
+	var txs []engine.Tx
+	for _, rr := range in.Rows {
+		class := strings.ToLower(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "asset class")))
+		if !strings.Contains(class, "crypto") {
+			if in.Verbose && class != "fiat" {
+				log.Printf("skipping bitpanda row %d: asset class %q", rr.Line, engine.FirstNonEmpty(rr.Rec, "asset class"))
+			}
+			continue
+		}
+		t, err := engine.ParseTimeIn(engine.FirstNonEmpty(rr.Rec, "timestamp"), in.Location)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		asset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "asset")))
+		amount := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "amount asset")).Abs()
+		if asset == "" || amount.IsZero() {
+			continue
+		}
+		tx := engine.Tx{
+			Wallet:      wallet,
+			Time:        t,
+			Commodity:   asset,
+			Currency:    strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "fiat"))),
+			Amount:      amount,
+			Cost:        engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "amount fiat")).Abs(),
+			Raw:         rr.Rec,
+			SourceFile:  filepath.Base(in.Path),
+			SourceLine:  rr.Line,
+			ReferenceID: engine.FirstNonEmpty(rr.Rec, "transaction id"),
+		}
+		if tx.ReferenceID == "" {
+			tx.ReferenceID = fmt.Sprintf("%s-%d", filepath.Base(in.Path), rr.Index)
+		}
+		outgoing := strings.EqualFold(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "in/out")), "outgoing")
+		typ := engine.NormalizeType(engine.FirstNonEmpty(rr.Rec, "transaction type"))
+		switch {
+		case typ == "buy":
+			tx.Type = "buy"
+		case typ == "sell":
+			tx.Type = "sell"
+			tx.Amount = amount.Neg()
+		case strings.Contains(typ, "stake"):
+			// moving coins in or out of staking keeps them in the account
+			continue
+		case typ == "reward" || (typ == "transfer" && !outgoing):
+			tx.Type = "income"
+			tx.Cost = engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "asset market price")).Mul(amount)
+			tx.Currency = strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "asset market price currency")))
+		case typ == "deposit":
+			tx.Type = "deposit"
+			tx.Cost = engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "asset market price")).Mul(amount)
+			tx.Currency = strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "asset market price currency")))
+		case typ == "withdrawal" || typ == "transfer":
+			tx.Type = "withdrawal"
+			tx.Amount = amount.Neg()
+			tx.Cost = decimal.Zero
+		default:
+			in.Skip(rr.Line, "unsupported Bitpanda transaction type %q", engine.FirstNonEmpty(rr.Rec, "transaction type"))
+			continue
+		}
+		if !tx.Cost.IsZero() {
+			tx.PricePerUnit = tx.Cost.Div(amount)
+		}
+		txs = append(txs, tx)
+
+		fee := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "fee")).Abs()
+		feeAsset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "fee asset")))
+		if fee.IsZero() || feeAsset == "" || in.Assets.IsFiat(feeAsset) {
+			continue
+		}
+		txs = append(txs, engine.Tx{
+			Wallet:      wallet,
+			Time:        t,
+			Type:        "fee",
+			Commodity:   feeAsset,
+			Amount:      fee.Neg(),
+			Raw:         rr.Rec,
+			SourceFile:  tx.SourceFile,
+			SourceLine:  rr.Line,
+			ReferenceID: tx.ReferenceID + "-fee",
+		})
+	}
+	return txs
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"cryptotax/engine"
+)
+
+func init() {
+	Register("bitso", bitsoImporter{})
+}
+
+// bitsoImporter reads the Bitso trade history export.
+type bitsoImporter struct{}
+
+func (bitsoImporter) Detect(header map[string]int) bool {
+	return hasColumns(header, "book", "major", "minor")
+}
+
+func (bitsoImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseBitsoRows(in), nil
+}
+
+// parseBitsoRows maps Bitso trades (book, side, major, minor, price, fees_amount, fees_currency,
+// created_at, tid, oid): major is the change of the book's first currency and minor of the second
+// (btc_mxn: BTC for MXN), both signed. Bitso charges the fee in the currency received: the fee of
+// a buy is a fee row in the coin bought, the fee of a sale is deducted from its proceeds. Books
+// quoted in a coin (eth_btc, btc_usdt) are a pair of trade legs.
+func parseBitsoRows(in *Input) []engine.Tx {
+	wallet := "bitso"
+	if len(in.DefaultWallets) > 0 && in.DefaultWallets[0] != "" {
+		wallet = in.DefaultWallets[0]
+	}
+	var txs []engine.Tx
+	for _, rr := range in.Rows {
+		t, err := engine.ParseTimeIn(engine.FirstNonEmpty(rr.Rec, "created_at", "date", "datetime"), in.Location)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		asset, quote, ok := strings.Cut(strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "book"))), "_")
+		if !ok {
+			in.Skip(rr.Line, "unknown Bitso book %q", engine.FirstNonEmpty(rr.Rec, "book"))
+			continue
+		}
+		major := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "major"))
+		minor := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "minor"))
+		if major.IsZero() {
+			continue
+		}
+		fee := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "fees_amount", "fee")).Abs()
+		feeAsset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "fees_currency", "fee_currency")))
+		side := engine.NormalizeType(engine.FirstNonEmpty(rr.Rec, "side", "type"))
+		buying := side == "buy" || (side == "" && major.IsPositive())
+		if feeAsset == "" {
+			feeAsset = quote
+			if buying {
+				feeAsset = asset
+			}
+		}
+		base := engine.Tx{
+			Wallet:      wallet,
+			Time:        t,
+			Raw:         rr.Rec,
+			SourceFile:  filepath.Base(in.Path),
+			SourceLine:  rr.Line,
+			ReferenceID: engine.FirstNonEmpty(rr.Rec, "tid", "oid"),
+		}
+		if base.ReferenceID == "" {
+			base.ReferenceID = fmt.Sprintf("%s-%d", filepath.Base(in.Path), rr.Index)
+		}
+		amount, total := major.Abs(), minor.Abs()
+		if feeAsset == quote {
+			if buying {
+				total = total.Add(fee)
+			} else {
+				total = total.Sub(fee)
+			}
+		}
+		if in.Assets.IsFiat(quote) {
+			tx := base
+			tx.Type = "buy"
+			tx.Commodity, tx.Currency = asset, quote
+			tx.Amount, tx.Cost = amount, total
+			if !buying {
+				tx.Type = "sell"
+				tx.Amount = amount.Neg()
+			}
+			tx.PricePerUnit = total.Div(amount)
+			txs = append(txs, tx)
+		} else {
+			out, got := base, base
+			out.Type, got.Type = "trade", "trade"
+			if buying {
+				out.Commodity, out.Amount = quote, total.Neg()
+				got.Commodity, got.Amount = asset, amount
+			} else {
+				out.Commodity, out.Amount = asset, amount.Neg()
+				got.Commodity, got.Amount = quote, total
+			}
+			txs = append(txs, out, got)
+		}
+		if fee.IsPositive() && feeAsset != quote && !in.Assets.IsFiat(feeAsset) {
+			f := base
+			f.Type = "fee"
+			f.Commodity, f.Amount = feeAsset, fee.Neg()
+			f.ReferenceID += "-fee"
+			txs = append(txs, f)
+		}
+	}
+	return txs
+}
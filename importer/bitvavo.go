@@ -0,0 +1,110 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"cryptotax/engine"
+)
+
+func init() {
+	Register("bitvavo", bitvavoImporter{})
+}
+
+// bitvavoImporter reads the Bitvavo transaction export.
+type bitvavoImporter struct{}
+
+func (bitvavoImporter) Detect(header map[string]int) bool {
+	_, price := header["price (eur)"]
+	_, quote := header["quote price"]
+	return hasColumns(header, "time", "type", "currency", "amount") && (price || quote)
+}
+
+func (bitvavoImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseBitvavoRows(in), nil
+}
+
+// parseBitvavoRows maps Bitvavo rows (Time, Type, Currency, Amount, Price (EUR), EUR received /
+// paid, Fee currency, Fee amount, Status; newer exports split Date and Time and name the price
+// and total Quote Price and Received / Paid Amount). Buys and sells cost the EUR paid or
+// received (price x amount when missing), staking and other rewards are income, crypto deposits
+// and withdrawals are left to transfer matching. EUR rows and rows not completed are skipped.
+func parseBitvavoRows(in *Input) []engine.Tx {
+	wallet := "bitvavo"
+	if len(in.DefaultWallets) > 0 && in.DefaultWallets[0] != "" {
+		wallet = in.DefaultWallets[0]
+	}
+	var txs []engine.Tx
+	for _, rr := range in.Rows {
+		asset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "currency")))
+		if asset == "" || in.Assets.IsFiat(asset) {
+			continue
+		}
+		if status := strings.ToLower(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "status"))); status != "" && status != "completed" {
+			continue
+		}
+		ts := engine.FirstNonEmpty(rr.Rec, "time")
+		if d := engine.FirstNonEmpty(rr.Rec, "date"); d != "" {
+			ts = d + " " + ts
+		}
+		t, err := engine.ParseTimeIn(strings.TrimSpace(ts), in.Location)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		amount := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "amount")).Abs()
+		total := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "eur received / paid", "received / paid amount")).Abs()
+		if total.IsZero() {
+			total = engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "price (eur)", "quote price")).Mul(amount)
+		}
+		tx := engine.Tx{
+			Wallet:      wallet,
+			Time:        t,
+			Commodity:   asset,
+			Currency:    "EUR",
+			Amount:      amount,
+			Fee:         engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "fee amount")).Abs(),
+			Raw:         rr.Rec,
+			SourceFile:  filepath.Base(in.Path),
+			SourceLine:  rr.Line,
+			ReferenceID: engine.FirstNonEmpty(rr.Rec, "transaction id", "txid"),
+		}
+		if tx.ReferenceID == "" {
+			tx.ReferenceID = fmt.Sprintf("%s-%d", filepath.Base(in.Path), rr.Index)
+		}
+		typ := engine.NormalizeType(engine.FirstNonEmpty(rr.Rec, "type"))
+		switch {
+		case typ == "buy":
+			tx.Type = "buy"
+			tx.Cost = total
+			if in.Assets.IsFiat(engine.FirstNonEmpty(rr.Rec, "fee currency")) {
+				tx.Cost = tx.Cost.Add(tx.Fee)
+			}
+		case typ == "sell":
+			tx.Type = "sell"
+			tx.Amount = amount.Neg()
+			tx.Cost = total
+		case strings.Contains(typ, "staking") || typ == "rebate" || typ == "affiliate" || typ == "reward":
+			tx.Type = "staking"
+			tx.Cost = total
+		case typ == "deposit":
+			tx.Type = "deposit"
+		case typ == "withdrawal":
+			tx.Type = "withdrawal"
+			tx.Amount = amount.Neg()
+		default:
+			in.Skip(rr.Line, "unsupported Bitvavo type %q", engine.FirstNonEmpty(rr.Rec, "type"))
+			continue
+		}
+		if !tx.Amount.IsZero() && !tx.Cost.IsZero() {
+			tx.PricePerUnit = tx.Cost.Div(amount)
+		}
+		txs = append(txs, tx)
+	}
+	return txs
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"cryptotax/engine"
+)
+
+func init() {
+	Register("coinjar", coinjarImporter{})
+}
+
+// coinjarImporter reads the CoinJar transaction history export.
+type coinjarImporter struct{}
+
+func (coinjarImporter) Detect(header map[string]int) bool {
+	return hasColumns(header, "type", "amount", "currency", "counter amount", "counter currency")
+}
+
+func (coinjarImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseCoinjarRows(in), nil
+}
+
+// parseCoinjarRows maps CoinJar rows (Date, Type, Amount, Currency, Counter Amount, Counter
+// Currency, Fee, Fee Currency, Reference). A trade is one row bundling both sides: the coins
+// bought or sold in Amount/Currency and what was paid or received in the counter columns. Buys
+// cost the counter amount (plus a fee in the same currency), sales are the counter amount less
+// the fee; when neither side is fiat the row is a pair of trade legs. Received and sent coins are
+// deposits and withdrawals, rewards and cashback are income.
+func parseCoinjarRows(in *Input) []engine.Tx {
+	wallet := "coinjar"
+	if len(in.DefaultWallets) > 0 && in.DefaultWallets[0] != "" {
+		wallet = in.DefaultWallets[0]
+	}
+	var txs []engine.Tx
+	for _, rr := range in.Rows {
+		t, err := engine.ParseTimeIn(engine.FirstNonEmpty(rr.Rec, "date", "timestamp"), in.Location)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		asset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "currency")))
+		amount := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "amount"))
+		counter := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "counter currency")))
+		counterAmount := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "counter amount")).Abs()
+		fee := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "fee")).Abs()
+		feeAsset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "fee currency")))
+		if asset == "" || amount.IsZero() {
+			continue
+		}
+		base := engine.Tx{
+			Wallet:      wallet,
+			Time:        t,
+			Raw:         rr.Rec,
+			SourceFile:  filepath.Base(in.Path),
+			SourceLine:  rr.Line,
+			ReferenceID: engine.FirstNonEmpty(rr.Rec, "reference", "id"),
+		}
+		if base.ReferenceID == "" {
+			base.ReferenceID = fmt.Sprintf("%s-%d", filepath.Base(in.Path), rr.Index)
+		}
+		typ := engine.NormalizeType(engine.FirstNonEmpty(rr.Rec, "type"))
+		selling := strings.Contains(typ, "sell") || (amount.IsNegative() && !strings.Contains(typ, "buy"))
+		amount = amount.Abs()
+		switch {
+		case strings.Contains(typ, "buy") || strings.Contains(typ, "sell") || strings.Contains(typ, "trade") || strings.Contains(typ, "swap"):
+			if counter == "" || counterAmount.IsZero() {
+				in.Skip(rr.Line, "CoinJar trade without counter amount")
+				continue
+			}
+			if in.Assets.IsFiat(asset) {
+				// the row is written from the fiat side: swap so that Currency is the coin
+				asset, counter = counter, asset
+				amount, counterAmount = counterAmount, amount
+				selling = !selling
+			}
+			if !in.Assets.IsFiat(counter) {
+				out, got := base, base
+				out.Type, got.Type = "trade", "trade"
+				if selling {
+					out.Commodity, out.Amount = asset, amount.Neg()
+					got.Commodity, got.Amount = counter, counterAmount
+				} else {
+					out.Commodity, out.Amount = counter, counterAmount.Neg()
+					got.Commodity, got.Amount = asset, amount
+				}
+				txs = append(txs, out, got)
+				break
+			}
+			tx := base
+			tx.Commodity, tx.Currency = asset, counter
+			tx.Amount, tx.Cost = amount, counterAmount
+			tx.Type = "buy"
+			if selling {
+				tx.Type = "sell"
+				tx.Amount = amount.Neg()
+				if feeAsset == counter {
+					tx.Fee = fee
+				}
+			} else if feeAsset == counter {
+				tx.Cost = tx.Cost.Add(fee)
+			}
+			tx.PricePerUnit = tx.Cost.Div(amount)
+			txs = append(txs, tx)
+			if feeAsset == counter {
+				continue
+			}
+		case in.Assets.IsFiat(asset):
+			// fiat deposits and withdrawals
+			continue
+		case strings.Contains(typ, "reward") || strings.Contains(typ, "cashback") || strings.Contains(typ, "referral") || strings.Contains(typ, "interest"):
+			tx := base
+			tx.Type = "income"
+			tx.Commodity, tx.Amount = asset, amount
+			if in.Assets.IsFiat(counter) && counterAmount.IsPositive() {
+				tx.Currency, tx.Cost = counter, counterAmount
+				tx.PricePerUnit = counterAmount.Div(amount)
+			}
+			txs = append(txs, tx)
+		case strings.Contains(typ, "send") || strings.Contains(typ, "withdraw") || selling:
+			tx := base
+			tx.Type = "withdrawal"
+			tx.Commodity, tx.Amount = asset, amount.Neg()
+			txs = append(txs, tx)
+		default:
+			tx := base
+			tx.Type = "deposit"
+			tx.Commodity, tx.Amount = asset, amount
+			txs = append(txs, tx)
+		}
+		if fee.IsPositive() && feeAsset != "" && !in.Assets.IsFiat(feeAsset) {
+			f := base
+			f.Type = "fee"
+			f.Commodity, f.Amount = feeAsset, fee.Neg()
+			f.ReferenceID += "-fee"
+			txs = append(txs, f)
+		}
+	}
+	return txs
+}
@@ -0,0 +1,498 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// LoadMigrations reads a migrations CSV: from,to[,date[,ratio]]. Rows without a date rename the
+// asset everywhere; rows with a date convert holdings on that day (ratio defaults to 1).
+func LoadMigrations(path string) ([]engine.Migration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	headerRow, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	headerIdx := map[string]int{}
+	for i, h := range headerRow {
+		headerIdx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	var out []engine.Migration
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]string)
+		for k, i := range headerIdx {
+			if i < len(row) {
+				record[k] = row[i]
+			}
+		}
+		line, _ := r.FieldPos(0)
+		m := engine.Migration{
+			From:  strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(record, "from", "old", "asset"))),
+			To:    strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(record, "to", "new", "new_asset"))),
+			Ratio: decimal.NewFromInt(1),
+		}
+		if m.From == "" || m.To == "" {
+			return nil, fmt.Errorf("%s:%d: from and to are required", path, line)
+		}
+		if d := engine.FirstNonEmpty(record, "date", "time"); d != "" {
+			t, err := engine.ParseTimeGuess(d)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+			}
+			m.Date = t
+		}
+		if v := engine.FirstNonEmpty(record, "ratio"); v != "" {
+			m.Ratio = engine.ParseDecimal(v)
+			if !m.Ratio.IsPositive() {
+				return nil, fmt.Errorf("%s:%d: ratio must be positive", path, line)
+			}
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// LoadForks reads chain splits: asset,parent[,date]. Holders of parent were credited asset on
+// date; without a date airdrops of asset are forks whenever they arrive.
+func LoadForks(path string) ([]engine.Fork, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	headerRow, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	headerIdx := map[string]int{}
+	for i, h := range headerRow {
+		headerIdx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	var out []engine.Fork
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]string)
+		for k, i := range headerIdx {
+			if i < len(row) {
+				record[k] = row[i]
+			}
+		}
+		line, _ := r.FieldPos(0)
+		fk := engine.Fork{
+			Asset:  strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(record, "asset", "fork", "commodity"))),
+			Parent: strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(record, "parent", "fork_of", "from"))),
+		}
+		if fk.Asset == "" || fk.Parent == "" {
+			return nil, fmt.Errorf("%s:%d: asset and parent are required", path, line)
+		}
+		if d := engine.FirstNonEmpty(record, "date", "time"); d != "" {
+			t, err := engine.ParseTimeGuess(d)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+			}
+			fk.Date = t
+		}
+		out = append(out, fk)
+	}
+	return out, nil
+}
+
+// LoadClassRules reads classification rules: type,description,asset,amount,source,wallet,operation.
+// The text columns are regular expressions matched case-insensitively (empty = any), amount is a
+// condition such as <0, >=100 or =0 (empty = any) and operation is what a matching row is
+// processed as, see engine.ClassRule. The first matching rule wins.
+func LoadClassRules(path string) ([]engine.ClassRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	headerRow, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	headerIdx := map[string]int{}
+	for i, h := range headerRow {
+		headerIdx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	var out []engine.ClassRule
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]string)
+		for k, i := range headerIdx {
+			if i < len(row) {
+				record[k] = row[i]
+			}
+		}
+		line, _ := r.FieldPos(0)
+		rule := engine.ClassRule{Operation: engine.NormalizeType(engine.FirstNonEmpty(record, "operation", "as", "class"))}
+		if !engine.IsOperation(rule.Operation) {
+			return nil, fmt.Errorf("%s:%d: unknown operation %q (expected a transaction type, income:KIND or ignore)", path, line, rule.Operation)
+		}
+		for _, p := range []struct {
+			re   **regexp.Regexp
+			cols []string
+		}{
+			{&rule.Type, []string{"type"}},
+			{&rule.Description, []string{"description", "notes", "memo"}},
+			{&rule.Asset, []string{"asset", "commodity"}},
+			{&rule.Source, []string{"source", "file", "source_file"}},
+			{&rule.Wallet, []string{"wallet"}},
+		} {
+			pattern := strings.TrimSpace(engine.FirstNonEmpty(record, p.cols...))
+			if pattern == "" {
+				continue
+			}
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %s: %v", path, line, p.cols[0], err)
+			}
+			*p.re = re
+		}
+		if cond := strings.ReplaceAll(engine.FirstNonEmpty(record, "amount"), " ", ""); cond != "" {
+			op := ""
+			for _, o := range []string{"<=", ">=", "<", ">", "="} {
+				if strings.HasPrefix(cond, o) {
+					op = o
+					break
+				}
+			}
+			v, err := decimal.NewFromString(cond[len(op):])
+			if op == "" {
+				op = "="
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: amount %q: expected a condition such as <0, >=100 or =0", path, line, cond)
+			}
+			rule.AmountOp, rule.Amount = op, v
+		}
+		out = append(out, rule)
+	}
+	return out, nil
+}
+
+// LoadAssetIDs reads the tickers of on-chain tokens: chain,contract,symbol (or asset_id,symbol).
+// The result maps engine.AssetKey to the ticker.
+func LoadAssetIDs(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	headerRow, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	headerIdx := map[string]int{}
+	for i, h := range headerRow {
+		headerIdx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	out := map[string]string{}
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]string)
+		for k, i := range headerIdx {
+			if i < len(row) {
+				record[k] = row[i]
+			}
+		}
+		line, _ := r.FieldPos(0)
+		key := engine.AssetKey(engine.FirstNonEmpty(record, "chain", "network", "blockchain"),
+			engine.FirstNonEmpty(record, "contract", "contract_address", "token_address", "asset_id"))
+		symbol := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(record, "symbol", "asset", "ticker")))
+		if key == "" || symbol == "" {
+			return nil, fmt.Errorf("%s:%d: contract and symbol are required", path, line)
+		}
+		out[key] = symbol
+	}
+	return out, nil
+}
+
+// LoadExpenses reads a CSV with columns date,amount[,category][,description][,currency]. Amounts in a
+// fiat currency other than base are converted with prices when base is set.
+func LoadExpenses(path, base string, prices engine.PriceSource) ([]engine.Expense, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	headerRow, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	headerIdx := map[string]int{}
+	for i, h := range headerRow {
+		headerIdx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	var out []engine.Expense
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]string)
+		for k, i := range headerIdx {
+			if i < len(row) {
+				record[k] = row[i]
+			}
+		}
+		line, _ := r.FieldPos(0)
+		t, err := engine.ParseTimeGuess(engine.FirstNonEmpty(record, "date", "time", "timestamp"))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		amount := engine.ParseDecimal(engine.FirstNonEmpty(record, "amount", "cost", "value")).Abs()
+		if cur := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(record, "currency"))); base != "" && cur != "" && cur != base {
+			if prices == nil {
+				return nil, fmt.Errorf("%s:%d: expense in %s needs -pricefile or -priceapi to convert to %s", path, line, cur, base)
+			}
+			rate, err := prices.Price(cur, base, t)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: converting %s to %s: %w", path, line, cur, base, err)
+			}
+			amount = amount.Mul(rate)
+		}
+		category := strings.TrimSpace(engine.FirstNonEmpty(record, "category", "type"))
+		if category == "" {
+			category = "other"
+		}
+		out = append(out, engine.Expense{Time: t, Category: category, Description: engine.FirstNonEmpty(record, "description", "note", "memo"), Amount: amount})
+	}
+	return out, nil
+}
+
+// LoadOpening reads opening balances, coins bought before the earliest imported transaction:
+// wallet,asset,amount,date,unit_cost (or cost for the total). Dates without a time or offset are in loc,
+// assets are named by the aliases of assets. The result maps wallet -> asset -> lots, see
+// engine.State.AddLots.
+func LoadOpening(path string, loc *time.Location, assets *engine.Assets) (map[string]map[string][]engine.InventoryEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	headerRow, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	headerIdx := map[string]int{}
+	for i, h := range headerRow {
+		headerIdx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	out := map[string]map[string][]engine.InventoryEntry{}
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]string)
+		for k, i := range headerIdx {
+			if i < len(row) {
+				record[k] = row[i]
+			}
+		}
+		line, _ := r.FieldPos(0)
+		wallet := strings.TrimSpace(engine.FirstNonEmpty(record, "wallet", "account"))
+		asset := strings.ToUpper(assets.Normalize(engine.FirstNonEmpty(record, "asset", "commodity", "coin", "symbol")))
+		amount := engine.ParseDecimal(engine.FirstNonEmpty(record, "amount", "balance", "quantity"))
+		d := strings.TrimSpace(engine.FirstNonEmpty(record, "date", "acquired", "time", "timestamp"))
+		if wallet == "" || asset == "" || d == "" || !amount.IsPositive() {
+			return nil, fmt.Errorf("%s:%d: wallet, asset, a positive amount and date are required", path, line)
+		}
+		t, err := engine.ParseTimeIn(d, loc)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		lot := engine.InventoryEntry{Time: t, Amount: amount, SourceFiles: []string{filepath.Base(path)}, SourceLine: line}
+		if v := engine.FirstNonEmpty(record, "unit_cost", "price"); v != "" {
+			lot.UnitCost = engine.ParseDecimal(v)
+			lot.TotalCost = lot.UnitCost.Mul(amount)
+		} else if v := engine.FirstNonEmpty(record, "cost", "total_cost", "basis"); v != "" {
+			lot.TotalCost = engine.ParseDecimal(v)
+			lot.UnitCost = lot.TotalCost.Div(amount)
+		} else {
+			return nil, fmt.Errorf("%s:%d: unit_cost or cost is required", path, line)
+		}
+		if out[wallet] == nil {
+			out[wallet] = map[string][]engine.InventoryEntry{}
+		}
+		out[wallet][asset] = append(out[wallet][asset], lot)
+	}
+	return out, nil
+}
+
+// LoadBalances reads declared balances: wallet,asset,date,balance[,tolerance]. A date without a
+// time is the end of that day in loc; an empty wallet is the total over all wallets. Rows without
+// a tolerance use tolerance.
+func LoadBalances(path string, loc *time.Location, tolerance decimal.Decimal) ([]engine.BalanceCheck, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	headerRow, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	headerIdx := map[string]int{}
+	for i, h := range headerRow {
+		headerIdx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	var out []engine.BalanceCheck
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]string)
+		for k, i := range headerIdx {
+			if i < len(row) {
+				record[k] = row[i]
+			}
+		}
+		line, _ := r.FieldPos(0)
+		c := engine.BalanceCheck{
+			Wallet:    strings.TrimSpace(engine.FirstNonEmpty(record, "wallet", "account")),
+			Commodity: strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(record, "asset", "commodity", "coin", "symbol"))),
+			Tolerance: tolerance,
+		}
+		balance := strings.TrimSpace(engine.FirstNonEmpty(record, "balance", "amount"))
+		d := strings.TrimSpace(engine.FirstNonEmpty(record, "date", "time", "timestamp"))
+		if c.Commodity == "" || balance == "" || d == "" {
+			return nil, fmt.Errorf("%s:%d: asset, date and balance are required", path, line)
+		}
+		if day, err := time.ParseInLocation("2006-01-02", d, loc); err == nil {
+			c.At = day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		} else if c.At, err = engine.ParseTimeIn(d, loc); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		c.Balance = engine.ParseDecimal(balance)
+		if v := engine.FirstNonEmpty(record, "tolerance"); v != "" {
+			c.Tolerance = engine.ParseDecimal(v).Abs()
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// LoadLotPicks reads disposal instructions: lot_id,amount,reference_id or date[,wallet]. Each row
+// names a lot (as listed by the lots report) to take first when the disposal with reference_id,
+// or any disposal on date (a day in loc), consumes coins of its asset; without an amount the lot
+// is used as far as the disposal needs.
+func LoadLotPicks(path string, loc *time.Location) ([]engine.LotPick, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	headerRow, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	headerIdx := map[string]int{}
+	for i, h := range headerRow {
+		headerIdx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	var out []engine.LotPick
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]string)
+		for k, i := range headerIdx {
+			if i < len(row) {
+				record[k] = row[i]
+			}
+		}
+		line, _ := r.FieldPos(0)
+		p := engine.LotPick{
+			LotID:       strings.TrimSpace(engine.FirstNonEmpty(record, "lot_id", "lot")),
+			Amount:      engine.ParseDecimal(engine.FirstNonEmpty(record, "amount")).Abs(),
+			ReferenceID: strings.TrimSpace(engine.FirstNonEmpty(record, "reference_id", "reference", "refid")),
+			Wallet:      strings.TrimSpace(engine.FirstNonEmpty(record, "wallet")),
+			Line:        line,
+		}
+		d := strings.TrimSpace(engine.FirstNonEmpty(record, "date"))
+		if p.LotID == "" || (p.ReferenceID == "" && d == "") {
+			return nil, fmt.Errorf("%s:%d: lot_id and a reference_id or date are required", path, line)
+		}
+		if d != "" {
+			if p.Date, err = time.ParseInLocation("2006-01-02", d, loc); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid date %q (expected YYYY-MM-DD)", path, line, d)
+			}
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
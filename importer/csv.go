@@ -0,0 +1,267 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+// Package importer reads exchange exports (Kraken, Binance, futures logs, generic CSV) and the
+// auxiliary CSV inputs into engine transactions. Each export format is an Importer registered
+// under a name; Config.ParseFile picks the one whose Detect matches the file's header.
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cryptotax/engine"
+)
+
+// recordReader reads the rows of an input: a csv.Reader, or a sheetReader over an XLSX worksheet.
+type recordReader interface {
+	Read() ([]string, error)
+	FieldPos(field int) (line, column int)
+}
+
+// openRecords opens a CSV export, or the selected sheet of an .xlsx workbook.
+func (c *Config) openRecords(path string) (recordReader, func() error, error) {
+	if isXLSX(path) {
+		sr, err := readXLSX(path, c.sheetOf(path))
+		if err != nil {
+			return nil, nil, err
+		}
+		return sr, func() error { return nil }, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	return r, f.Close, nil
+}
+
+// ParseFile reads a CSV export (or an .xlsx workbook) and parses it with the importer detected
+// from its header (the generic importer when none matches).
+func (c *Config) ParseFile(path string, defaultWallets []string, verbose bool) ([]engine.Tx, []engine.ImportIssue, error) {
+	r, closeFile, err := c.openRecords(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closeFile()
+
+	format, imp, forced := c.formatOf(path)
+	headerIdx, pending, err := readHeader(r, headerAccepter(imp, forced))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !forced {
+		format, imp = Detect(headerIdx)
+	} else if verbose && !imp.Detect(headerIdx) {
+		log.Printf("%s: header not recognized by format %s set with -format, parsing it anyway", path, format)
+	}
+
+	in := &Input{Path: path, Header: headerIdx, DefaultWallets: defaultWallets, Verbose: verbose, Location: c.sourceLocation(path, format),
+		DecimalComma: c.sourceDecimalComma(path, format), Assets: c.Assets, Format: format}
+	sheet, _ := r.(*sheetReader)
+	rowIdx := 0
+	next := func() (Row, bool, error) {
+		var row []string
+		var line int
+		var err error
+		if len(pending) > 0 {
+			row, line, err = pending[0].fields, pending[0].line, pending[0].err
+			pending = pending[1:]
+			if err != nil {
+				return Row{}, false, err
+			}
+		} else {
+			row, err = r.Read()
+			if err == io.EOF {
+				return Row{}, false, nil
+			}
+			if err != nil {
+				return Row{}, false, err
+			}
+			line, _ = r.FieldPos(0)
+		}
+		record := make(map[string]string)
+		for k, i := range headerIdx {
+			if i >= 0 && i < len(row) {
+				record[k] = row[i]
+			} else {
+				record[k] = ""
+			}
+		}
+		if in.DecimalComma {
+			err := toDecimalPoint(record, func(column string) bool {
+				return sheet != nil && sheet.storedNumber(line, headerIdx[column])
+			})
+			if err != nil && c.Strict {
+				return Row{}, false, fmt.Errorf("line %d: %v", line, err)
+			}
+		}
+		rr := Row{Rec: record, Index: rowIdx, Line: line}
+		rowIdx++
+		if c.Strict {
+			if err := checkRow(in, rr); err != nil {
+				return Row{}, false, err
+			}
+		}
+		return rr, true, nil
+	}
+	var txs []engine.Tx
+	if s, ok := imp.(StreamImporter); ok {
+		txs, err = s.ParseStream(in, next)
+	} else {
+		// read all rows into memory first
+		for {
+			rr, ok, err := next()
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s (format=%s): %w", path, format, err)
+			}
+			if !ok {
+				break
+			}
+			in.Rows = append(in.Rows, rr)
+		}
+		txs, err = imp.Parse(in)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s (format=%s): %w", path, format, err)
+	}
+	// one ticker per asset whichever exchange spelling the export uses, wallet names as mapped
+	for i := range txs {
+		txs[i].Commodity = c.Assets.Normalize(txs[i].Commodity)
+		txs[i].Currency = c.Assets.Normalize(txs[i].Currency)
+		txs[i].Location = in.Location
+		if len(c.walletRules) > 0 {
+			txs[i].Wallet = c.mapWallet(txs[i].Wallet, path)
+			if txs[i].PairedComment != "" {
+				txs[i].PairedComment = c.mapWallet(txs[i].PairedComment, path)
+			}
+		}
+	}
+	if verbose {
+		log.Printf("parsed %d tx from %s (format=%s)", len(txs), path, format)
+	}
+	return txs, in.Issues, nil
+}
+
+func lookupWallet(record map[string]string, defaults []string, srcFile string) string {
+	// Prefer explicit wallet column; otherwise use default wallets or filename
+	if w := engine.FirstNonEmpty(record, "wallet", "account"); w != "" {
+		return w
+	}
+	if len(defaults) > 0 && defaults[0] != "" {
+		// pick first if multiple provided; a better implementation could try mapping by currency or formatted name
+		return defaults[0]
+	}
+	return filepath.Base(srcFile)
+}
+
+// Merge and sort transactions by time
+// DetectFile returns the format of a CSV file (or .xlsx workbook) from its header, without reading
+// the rows.
+func (c *Config) DetectFile(path string) (string, error) {
+	r, closeFile, err := c.openRecords(path)
+	if err != nil {
+		return "", err
+	}
+	defer closeFile()
+	format, imp, forced := c.formatOf(path)
+	headerIdx, _, err := readHeader(r, headerAccepter(imp, forced))
+	if err != nil {
+		return "", err
+	}
+	if !forced {
+		format, _ = Detect(headerIdx)
+	}
+	return format, nil
+}
+
+// maxPreamble is how many lines readHeader looks past for the header: some exports (Bitpanda)
+// start with a disclaimer or an account summary.
+const maxPreamble = 10
+
+// csvRow is a row read ahead while looking for the header, or the error reading it.
+type csvRow struct {
+	fields []string
+	line   int
+	err    error
+}
+
+// headerAccepter returns how readHeader recognizes the header row: by the importer of a format
+// forced with -format, else by any registered importer other than the generic one.
+func headerAccepter(imp Importer, forced bool) func(header map[string]int) bool {
+	if forced {
+		return imp.Detect
+	}
+	return func(header map[string]int) bool {
+		format, _ := Detect(header)
+		return format != "generic"
+	}
+}
+
+// readHeader reads the header of a CSV export (lowercased column name -> index): the first of the
+// first maxPreamble+1 rows accept recognizes, else the first row. The rows read after the header
+// are returned to be parsed as data.
+func readHeader(r recordReader, accept func(header map[string]int) bool) (map[string]int, []csvRow, error) {
+	var rows []csvRow
+	for len(rows) <= maxPreamble {
+		fields, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if len(rows) == 0 {
+				return nil, nil, err
+			}
+			// not a header: reported when the row is parsed
+			rows = append(rows, csvRow{err: err})
+			break
+		}
+		line, _ := r.FieldPos(0)
+		rows = append(rows, csvRow{fields: fields, line: line})
+		if accept(headerIndex(fields)) {
+			return headerIndex(fields), nil, nil
+		}
+	}
+	if len(rows) == 0 {
+		return nil, nil, io.EOF
+	}
+	return headerIndex(rows[0].fields), rows[1:], nil
+}
+
+// headerIndex maps the lowercased column names of a header row to their index.
+func headerIndex(fields []string) map[string]int {
+	idx := map[string]int{}
+	for i, h := range fields {
+		idx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	return idx
+}
+
+func MergeAndSort(all [][]engine.Tx) []engine.Tx {
+	var merged []engine.Tx
+	for _, chunk := range all {
+		merged = append(merged, chunk...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].Time.Equal(merged[j].Time) {
+			// tie-breaker by source file, reference id and line
+			if merged[i].SourceFile != merged[j].SourceFile {
+				return merged[i].SourceFile < merged[j].SourceFile
+			}
+			if merged[i].ReferenceID != merged[j].ReferenceID {
+				return merged[i].ReferenceID < merged[j].ReferenceID
+			}
+			return merged[i].SourceLine < merged[j].SourceLine
+		}
+		return merged[i].Time.Before(merged[j].Time)
+	})
+	return merged
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cryptotax/engine"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got with testdata/name, or rewrites the file with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := "testdata/" + name
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs:\n--- got\n%s--- want\n%s", name, got, want)
+	}
+}
+
+// TestParseFileGolden lists the transactions the generic importer reads from the sample.
+func TestParseFileGolden(t *testing.T) {
+	var cfg Config
+	txs, issues, err := cfg.ParseFile("../testdata/sample.csv", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) > 0 {
+		t.Errorf("import issues: %v", issues)
+	}
+	var b strings.Builder
+	for _, tx := range MergeAndSort([][]engine.Tx{txs}) {
+		fmt.Fprintf(&b, "%s %s %s %s %s cost=%s fee=%s %s ref=%s line=%d\n", tx.Time.Format("2006-01-02T15:04:05Z07:00"), tx.Wallet,
+			tx.Type, tx.Amount.String(), tx.Commodity, tx.Cost.String(), tx.Fee.String(), tx.Currency, tx.ReferenceID, tx.SourceLine)
+	}
+	checkGolden(t, "sample.golden", b.String())
+}
+
+func TestMergeAndSortKeepsDustRowsInLineOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "binance.csv")
+	csv := "UTC_Time,Account,Operation,Coin,Change,Remark\n" +
+		"2024-03-01 12:00:00,Spot,Small Assets Exchange BNB,DOT,-0.5,\n" +
+		"2024-03-01 12:00:00,Spot,Small Assets Exchange BNB,BNB,0.001,\n" +
+		"2024-03-01 12:00:00,Spot,Small Assets Exchange BNB,ADA,-2,\n" +
+		"2024-03-01 12:00:00,Spot,Small Assets Exchange BNB,BNB,0.003,\n" +
+		"2024-03-01 12:00:00,Spot,Small Assets Exchange BNB,XRP,-4,\n" +
+		"2024-03-01 12:00:00,Spot,Small Assets Exchange BNB,BNB,0.002,\n"
+	if err := os.WriteFile(path, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var cfg Config
+	txs, _, err := cfg.ParseFile(path, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// reversed, so only the line tie-breaker can restore the order
+	for i, j := 0, len(txs)-1; i < j; i, j = i+1, j-1 {
+		txs[i], txs[j] = txs[j], txs[i]
+	}
+	merged := MergeAndSort([][]engine.Tx{txs})
+	for i, tx := range merged {
+		if tx.SourceLine != i+2 {
+			t.Errorf("position %d: got line %d (%s %s), want line %d", i, tx.SourceLine, tx.Amount.String(), tx.Commodity, i+2)
+		}
+	}
+}
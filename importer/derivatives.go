@@ -0,0 +1,232 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	Register("kraken-futures", derivativesImporter{format: "kraken-futures", detect: func(h map[string]int) bool {
+		return hasColumns(h, "realized pnl", "realized funding")
+	}})
+	Register("bitmex", derivativesImporter{format: "bitmex", detect: func(h map[string]int) bool {
+		return hasColumns(h, "transacttype", "amount", "walletbalance")
+	}})
+	// before bybit: the Deribit transaction log has cash flow and funding columns too
+	Register("deribit", derivativesImporter{format: "deribit", detect: func(h map[string]int) bool {
+		return hasColumns(h, "instrument", "cash flow", "fee charged")
+	}})
+	Register("bybit", derivativesImporter{format: "bybit", detect: func(h map[string]int) bool {
+		return hasColumns(h, "closed p&l") || hasColumns(h, "cash flow", "funding")
+	}})
+	Register("binance-futures", derivativesImporter{format: "binance-futures", detect: func(h map[string]int) bool {
+		_, hasPrice := h["price"]
+		return hasColumns(h, "symbol", "asset", "type", "time(utc)") && !hasPrice
+	}})
+}
+
+// derivativesImporter reads futures exports: settled PnL, funding and fees per closed position,
+// no spot inventory.
+type derivativesImporter struct {
+	format string
+	detect func(header map[string]int) bool
+}
+
+func (d derivativesImporter) Detect(header map[string]int) bool {
+	return d.detect(header)
+}
+
+func (d derivativesImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseDerivativesRows(d.format, in), nil
+}
+
+// parseDerivativesRows maps futures/perpetual exports (Kraken Futures account log, Binance
+// Futures transaction history, Bybit closed P&L and transaction log, Deribit transaction log, BitMEX
+// wallet history) to futures_pnl, funding and futures_fee transactions. Amounts are signed and settled in Commodity; the contract is kept in
+// Raw["contract"].
+func parseDerivativesRows(format string, in *Input) []engine.Tx {
+	rows, path, defaultWallets, loc := in.Rows, in.Path, in.DefaultWallets, in.Location
+	var txs []engine.Tx
+	for _, rr := range rows {
+		rec := rr.Rec
+		timeStr := engine.FirstNonEmpty(rec, "datetime", "time(utc)", "time", "trade time", "date", "transacttime", "timestamp")
+		t, err := engine.ParseTimeIn(timeStr, loc)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		contract := strings.ToUpper(engine.FirstNonEmpty(rec, "contract", "symbol", "contracts", "instrument"))
+		ref := engine.FirstNonEmpty(rec, "uid", "order id", "orderid", "id", "trade id", "transactid")
+		if ref == "" {
+			ref = fmt.Sprintf("%s-%d", filepath.Base(path), rr.Index)
+		}
+		wallet := format
+		if len(defaultWallets) > 0 && defaultWallets[0] != "" {
+			wallet = defaultWallets[0]
+		}
+		emit := func(typ, asset string, amount decimal.Decimal) {
+			if amount.IsZero() {
+				return
+			}
+			txs = append(txs, engine.Tx{
+				Wallet:      wallet,
+				Time:        t,
+				Type:        typ,
+				Commodity:   strings.ToUpper(asset),
+				Currency:    strings.ToUpper(asset),
+				Amount:      amount,
+				Raw:         map[string]string{"contract": contract},
+				SourceFile:  path,
+				SourceLine:  rr.Line,
+				ReferenceID: ref,
+			})
+		}
+		switch format {
+		case "kraken-futures":
+			asset := engine.FirstNonEmpty(rec, "collateral")
+			if asset == "" {
+				asset = "USD"
+			}
+			emit("futures_pnl", asset, engine.ParseDecimal(engine.FirstNonEmpty(rec, "realized pnl")))
+			emit("funding", asset, engine.ParseDecimal(engine.FirstNonEmpty(rec, "realized funding")))
+			// Kraken Futures logs fees as negative changes
+			emit("futures_fee", asset, engine.ParseDecimal(engine.FirstNonEmpty(rec, "fee")).Abs())
+		case "binance-futures":
+			asset := engine.FirstNonEmpty(rec, "asset", "coin")
+			amount := engine.ParseDecimal(engine.FirstNonEmpty(rec, "amount", "change"))
+			switch strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rec, "type", "operation"))) {
+			case "REALIZED_PNL":
+				emit("futures_pnl", asset, amount)
+			case "FUNDING_FEE":
+				emit("funding", asset, amount)
+			case "COMMISSION":
+				emit("futures_fee", asset, amount.Abs())
+			default:
+				// transfers, insurance clearing, etc. move funds without a taxable result
+				if in.Verbose {
+					log.Printf("skipping %s row %d: type %q", format, rr.Line, engine.FirstNonEmpty(rec, "type"))
+				}
+			}
+		case "bybit":
+			asset := engine.FirstNonEmpty(rec, "currency", "coin")
+			if asset == "" {
+				asset = settlementAsset(contract)
+			}
+			if v := engine.FirstNonEmpty(rec, "closed p&l"); v != "" {
+				emit("futures_pnl", asset, engine.ParseDecimal(v))
+				continue
+			}
+			// transaction log: cash flow is the realized PnL, funding and fee paid are positive when paid
+			emit("futures_pnl", asset, engine.ParseDecimal(engine.FirstNonEmpty(rec, "cash flow")))
+			emit("funding", asset, engine.ParseDecimal(engine.FirstNonEmpty(rec, "funding")).Neg())
+			emit("futures_fee", asset, engine.ParseDecimal(engine.FirstNonEmpty(rec, "fee paid")))
+		case "deribit":
+			// trades, settlements and deliveries are settled in the coin of the currency column
+			// (BTC, ETH, USDC for linear contracts); cash flow is the realized PnL including option
+			// premiums, fee charged is positive when paid
+			switch typ := engine.NormalizeType(engine.FirstNonEmpty(rec, "type")); typ {
+			case "trade", "settlement", "delivery", "liquidation":
+				asset := engine.FirstNonEmpty(rec, "currency")
+				if asset == "" {
+					asset = deribitSettlement(contract)
+				}
+				emit("futures_pnl", asset, engine.ParseDecimal(engine.FirstNonEmpty(rec, "cash flow")))
+				emit("funding", asset, engine.ParseDecimal(engine.FirstNonEmpty(rec, "funding")))
+				emit("futures_fee", asset, engine.ParseDecimal(engine.FirstNonEmpty(rec, "fee charged")))
+			default:
+				// deposits, withdrawals and transfers between subaccounts move funds without a taxable result
+				if in.Verbose {
+					log.Printf("skipping %s row %d: type %q", format, rr.Line, engine.FirstNonEmpty(rec, "type"))
+				}
+			}
+		case "bitmex":
+			// amounts are in the smallest unit of the currency column (XBt satoshis, USDt micro
+			// tethers); realized PnL already includes funding and trading fees
+			if s := strings.ToLower(strings.TrimSpace(engine.FirstNonEmpty(rec, "transactstatus"))); s != "" && s != "completed" {
+				continue
+			}
+			asset, amount := bitmexAmount(engine.FirstNonEmpty(rec, "currency"), engine.FirstNonEmpty(rec, "amount"))
+			typ := engine.FirstNonEmpty(rec, "transacttype")
+			switch engine.NormalizeType(typ) {
+			case "realisedpnl", "realizedpnl":
+				if sym := engine.FirstNonEmpty(rec, "address"); sym != "" {
+					contract = strings.ToUpper(sym)
+				}
+				emit("futures_pnl", asset, amount)
+			case "funding":
+				emit("funding", asset, amount)
+			case "affiliatepayout", "referralpayout":
+				// commissions are paid into the wallet: income at their value when received
+				if !amount.IsZero() {
+					txs = append(txs, engine.Tx{
+						Wallet:      wallet,
+						Time:        t,
+						Type:        "income",
+						Commodity:   asset,
+						Amount:      amount.Abs(),
+						Raw:         rec,
+						SourceFile:  path,
+						SourceLine:  rr.Line,
+						ReferenceID: ref,
+					})
+				}
+			default:
+				// deposits, withdrawals and transfers move the margin balance without a taxable result
+				if in.Verbose {
+					log.Printf("skipping %s row %d: type %q", format, rr.Line, typ)
+				}
+			}
+		}
+	}
+	return txs
+}
+
+// bitmexAmount converts a BitMEX wallet history amount from the currency's smallest unit.
+func bitmexAmount(currency, amount string) (string, decimal.Decimal) {
+	v := engine.ParseDecimal(amount)
+	switch strings.TrimSpace(currency) {
+	case "XBt":
+		return "BTC", v.Shift(-8)
+	case "USDt":
+		return "USDT", v.Shift(-6)
+	case "Gwei":
+		return "ETH", v.Shift(-9)
+	}
+	if c := strings.ToUpper(strings.TrimSpace(currency)); c != "XBT" {
+		return c, v
+	}
+	return "BTC", v
+}
+
+// deribitSettlement returns the coin a Deribit instrument settles in: the base coin of inverse
+// contracts (BTC-PERPETUAL, ETH-27DEC24-3000-C), the quote of linear ones (SOL_USDC-PERPETUAL).
+func deribitSettlement(instrument string) string {
+	base, _, _ := strings.Cut(instrument, "-")
+	if _, quote, ok := strings.Cut(base, "_"); ok {
+		return quote
+	}
+	return base
+}
+
+// settlementAsset guesses the asset a perpetual settles in from its symbol: linear contracts
+// settle in the quote stablecoin (BTCUSDT), inverse contracts in the base coin (BTCUSD).
+func settlementAsset(contract string) string {
+	for _, q := range []string{"USDT", "USDC"} {
+		if strings.HasSuffix(contract, q) {
+			return q
+		}
+	}
+	if strings.HasSuffix(contract, "USD") && len(contract) > 3 {
+		return strings.TrimSuffix(contract, "USD")
+	}
+	return "USDT"
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	Register("evm", evmImporter{})
+}
+
+// evmImporter reads the activity of a self-custody EVM address: the transaction and token
+// transfer CSVs of Etherscan-style explorers (Etherscan, BscScan, Polygonscan, Arbiscan, ...),
+// which MetaMask links to, and the activity exports of MetaMask Portfolio and similar trackers.
+type evmImporter struct{}
+
+func (evmImporter) Detect(header map[string]int) bool {
+	if !hasColumns(header, "from", "to") || evmColumn(header, "txhash", "transaction hash", "tx hash", "hash") == "" {
+		return false
+	}
+	return evmNative(header) != "" || hasColumns(header, "tokenvalue") ||
+		(hasColumns(header, "amount") && evmColumn(header, "token", "asset", "symbol") != "")
+}
+
+func (evmImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseEVMRows(in), nil
+}
+
+// evmColumn returns the first of names present in header.
+func evmColumn(header map[string]int, names ...string) string {
+	for _, n := range names {
+		if _, ok := header[n]; ok {
+			return n
+		}
+	}
+	return ""
+}
+
+// evmNative returns the native coin of an explorer transaction export from its Value_IN(ETH)
+// column, or "" for token transfer and tracker exports.
+func evmNative(header map[string]int) string {
+	for h := range header {
+		if strings.HasPrefix(h, "value_in(") && strings.HasSuffix(h, ")") {
+			return strings.ToUpper(strings.TrimSuffix(strings.TrimPrefix(h, "value_in("), ")"))
+		}
+	}
+	return ""
+}
+
+// evmOwner guesses the address whose activity the file holds: the one in the from or to column
+// of the most rows.
+func evmOwner(rows []Row) string {
+	count := map[string]int{}
+	for _, rr := range rows {
+		from := strings.ToLower(strings.TrimSpace(rr.Rec["from"]))
+		to := strings.ToLower(strings.TrimSpace(rr.Rec["to"]))
+		if from != "" {
+			count[from]++
+		}
+		if to != "" && to != from {
+			count[to]++
+		}
+	}
+	owner := ""
+	for a, n := range count {
+		if n > count[owner] || (n == count[owner] && a < owner) {
+			owner = a
+		}
+	}
+	return owner
+}
+
+// parseEVMRows maps the transfers of the file's address (the most frequent from/to address; the
+// wallet is that address unless -wallet is set): coins received are deposits and coins sent
+// withdrawals, for -match-transfers to pair with the exchange side, valued at the USD value of
+// the day when the export has one. Transfers of a transaction whose method or type is a swap,
+// and transactions sending one asset and receiving another within the file, are trades. Gas paid
+// by the address is a fee row in the native coin; failed transactions only pay gas. The token
+// contract (and chain, when there is a column for it) is kept for -asset-ids.
+func parseEVMRows(in *Input) []engine.Tx {
+	owner := evmOwner(in.Rows)
+	wallet := owner
+	if len(in.DefaultWallets) > 0 && in.DefaultWallets[0] != "" {
+		wallet = in.DefaultWallets[0]
+	}
+	native := evmNative(in.Header)
+	hashCol := evmColumn(in.Header, "txhash", "transaction hash", "tx hash", "hash")
+	type leg struct {
+		tx  engine.Tx
+		out bool
+	}
+	var legs []leg
+	byHash := map[string]map[string]bool{} // hash -> "in"/"out" -> seen, to spot swaps
+	var fees []engine.Tx
+	for _, rr := range in.Rows {
+		rec := rr.Rec
+		t, err := evmTime(rec, in.Location)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		hash := strings.ToLower(strings.TrimSpace(rec[hashCol]))
+		from := strings.ToLower(strings.TrimSpace(rec["from"]))
+		to := strings.ToLower(strings.TrimSpace(rec["to"]))
+		failed := strings.Contains(strings.ToLower(engine.FirstNonEmpty(rec, "status", "errcode", "iserror")), "error")
+		raw := map[string]string{}
+		for k, v := range rec {
+			raw[k] = v
+		}
+		if c := engine.FirstNonEmpty(rec, "contractaddress", "contract address", "contract", "token address"); c != "" && native == "" {
+			raw["contract_address"] = c
+		}
+		base := engine.Tx{
+			Wallet:      wallet,
+			Time:        t,
+			Raw:         raw,
+			SourceFile:  filepath.Base(in.Path),
+			SourceLine:  rr.Line,
+			ReferenceID: hash,
+		}
+		if base.ReferenceID == "" {
+			base.ReferenceID = fmt.Sprintf("%s-%d", filepath.Base(in.Path), rr.Index)
+		}
+		kind := engine.NormalizeType(engine.FirstNonEmpty(rec, "method", "type"))
+		swap := strings.Contains(kind, "swap")
+
+		// gas is paid by the sender, also for failed transactions
+		feeAsset := native
+		if feeAsset == "" {
+			feeAsset = strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rec, "fee token", "fee asset", "fee currency")))
+		}
+		fee := engine.ParseDecimal(engine.FirstNonEmpty(rec, "txnfee("+strings.ToLower(native)+")", "fee")).Abs()
+		if feeAsset != "" && fee.IsPositive() && from == owner {
+			f := base
+			f.Type = "network fee"
+			f.Commodity = feeAsset
+			f.Amount = fee.Neg()
+			f.Cost = engine.ParseDecimal(engine.FirstNonEmpty(rec, "txnfee(usd)", "fee (usd)")).Abs()
+			if !f.Cost.IsZero() {
+				f.Currency = "USD"
+			}
+			delete(f.Raw, "contract_address")
+			fees = append(fees, f)
+		}
+		if failed {
+			continue
+		}
+
+		var asset string
+		var amount, value decimal.Decimal
+		out := from == owner
+		if native != "" {
+			asset = native
+			amount = engine.ParseDecimal(rec["value_in("+strings.ToLower(native)+")"])
+			if o := engine.ParseDecimal(rec["value_out("+strings.ToLower(native)+")"]); o.IsPositive() {
+				amount, out = o, true
+			} else {
+				out = false
+			}
+			value = engine.ParseDecimal(rec["historical $price/"+strings.ToLower(native)]).Mul(amount)
+		} else {
+			asset = strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rec, "tokensymbol", "token", "asset", "symbol")))
+			amount = engine.ParseDecimal(engine.FirstNonEmpty(rec, "tokenvalue", "amount")).Abs()
+			value = engine.ParseDecimal(engine.FirstNonEmpty(rec, "usdvaluedayoftx", "value (usd)", "usd value")).Abs()
+			if to != owner && from != owner {
+				continue
+			}
+			if from == owner && to == owner {
+				continue
+			}
+			if d := engine.NormalizeType(engine.FirstNonEmpty(rec, "direction")); d == "in" || d == "out" {
+				out = d == "out"
+			}
+		}
+		if asset == "" || !amount.IsPositive() {
+			continue
+		}
+		tx := base
+		tx.Commodity = asset
+		tx.Amount = amount
+		tx.Cost = value
+		if !value.IsZero() {
+			tx.Currency = "USD"
+			tx.PricePerUnit = value.Div(amount)
+		}
+		if out {
+			tx.Amount = amount.Neg()
+		}
+		dir := "in"
+		if out {
+			dir = "out"
+		}
+		if byHash[hash] == nil {
+			byHash[hash] = map[string]bool{}
+		}
+		byHash[hash][dir] = true
+		if swap {
+			byHash[hash]["swap"] = true
+		}
+		legs = append(legs, leg{tx, out})
+	}
+	var txs []engine.Tx
+	for _, l := range legs {
+		seen := byHash[strings.ToLower(l.tx.ReferenceID)]
+		switch {
+		case seen["swap"] || (seen["in"] && seen["out"]):
+			l.tx.Type = "trade"
+		case l.out:
+			l.tx.Type = "withdrawal"
+		default:
+			l.tx.Type = "deposit"
+		}
+		txs = append(txs, l.tx)
+	}
+	return append(txs, fees...)
+}
+
+// evmTime reads the DateTime (UTC) or UnixTimestamp of an explorer row, or the date of a tracker
+// row.
+func evmTime(rec map[string]string, loc *time.Location) (time.Time, error) {
+	if s := engine.FirstNonEmpty(rec, "datetime (utc)"); s != "" {
+		return engine.ParseTimeIn(s, time.UTC)
+	}
+	if s := engine.FirstNonEmpty(rec, "unixtimestamp"); s != "" {
+		sec, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid UnixTimestamp %q", s)
+		}
+		return time.Unix(sec, 0).UTC(), nil
+	}
+	return engine.ParseTimeIn(engine.FirstNonEmpty(rec, "date", "timestamp", "time"), loc)
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"cryptotax/engine"
+)
+
+func init() {
+	Register("gemini-earn", geminiEarnImporter{})
+}
+
+// geminiEarnImporter reads the Gemini Earn transaction history: interest accrual payouts and the
+// movements between the exchange account and Earn.
+type geminiEarnImporter struct{}
+
+func (geminiEarnImporter) Detect(header map[string]int) bool {
+	_, value := header["value (usd)"]
+	_, usd := header["usd value"]
+	return hasColumns(header, "time (utc)", "type", "symbol", "amount") && (value || usd)
+}
+
+func (geminiEarnImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseGeminiEarnRows(in), nil
+}
+
+// parseGeminiEarnRows maps Gemini Earn rows (Date, Time (UTC), Type, Symbol, Amount, Price (USD),
+// Value (USD)): interest credits are income in the Earn wallet valued at their USD value,
+// deposits into Earn and redemptions move the coins between the exchange wallet (the file name or
+// -wallet) and "<wallet> earn" keeping their basis. Administrative rows are skipped.
+func parseGeminiEarnRows(in *Input) []engine.Tx {
+	exchange := "gemini"
+	if len(in.DefaultWallets) > 0 && in.DefaultWallets[0] != "" {
+		exchange = in.DefaultWallets[0]
+	}
+	earn := exchange + " earn"
+	var txs []engine.Tx
+	for _, rr := range in.Rows {
+		ts := strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "date") + " " + engine.FirstNonEmpty(rr.Rec, "time (utc)"))
+		t, err := engine.ParseTimeIn(ts, in.Location)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		asset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "symbol")))
+		amount := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "amount")).Abs()
+		if asset == "" || amount.IsZero() {
+			continue
+		}
+		value := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "value (usd)", "usd value")).Abs()
+		if value.IsZero() {
+			value = engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "price (usd)", "usd price")).Mul(amount)
+		}
+		tx := engine.Tx{
+			Time:        t,
+			Commodity:   asset,
+			Amount:      amount,
+			Raw:         rr.Rec,
+			SourceFile:  filepath.Base(in.Path),
+			SourceLine:  rr.Line,
+			ReferenceID: fmt.Sprintf("%s-%d", filepath.Base(in.Path), rr.Index),
+		}
+		typ := engine.NormalizeType(engine.FirstNonEmpty(rr.Rec, "type"))
+		switch {
+		case strings.Contains(typ, "interest"):
+			tx.Type = "income"
+			tx.Wallet = earn
+			tx.Cost = value
+			tx.Currency = "USD"
+		case strings.Contains(typ, "redeem") || strings.Contains(typ, "redemption") || strings.Contains(typ, "withdraw"):
+			tx.Type = "transfer"
+			tx.Wallet = exchange
+			tx.PairedComment = earn
+		case strings.Contains(typ, "deposit") || strings.Contains(typ, "transfer"):
+			tx.Type = "transfer"
+			tx.Wallet = earn
+			tx.PairedComment = exchange
+		default:
+			in.Skip(rr.Line, "unsupported Gemini Earn type %q", engine.FirstNonEmpty(rr.Rec, "type"))
+			continue
+		}
+		if !tx.Cost.IsZero() {
+			tx.PricePerUnit = tx.Cost.Div(amount)
+		}
+		txs = append(txs, tx)
+	}
+	return txs
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// genericImporter reads any CSV with common column names (time, type, asset, amount, cost, fee,
+// ...). It is the fallback when no registered importer detects the file.
+type genericImporter struct{}
+
+func (genericImporter) Detect(header map[string]int) bool {
+	return true
+}
+
+func (genericImporter) Parse(in *Input) ([]engine.Tx, error) {
+	path, defaultWallets := in.Path, in.DefaultWallets
+	var txs []engine.Tx
+	// generic: parse each row, but skip fiat-only rows (don't create tx for fiat assets)
+	for _, rr := range in.Rows {
+		asset := engine.FirstNonEmpty(rr.Rec, "asset", "symbol", "commodity", "pair")
+		if in.Assets.IsFiat(asset) && !engine.IsMarginType(engine.FirstNonEmpty(rr.Rec, "type", "tx_type", "category")) {
+			// skip fiat rows
+			continue
+		}
+		if tx, err := parseGenericRecord(rr.Rec, path, defaultWallets, in.Location); err == nil {
+			tx.SourceLine = rr.Line
+			txs = append(txs, tx)
+		} else {
+			in.Skip(rr.Line, "%v", err)
+		}
+	}
+	return txs, nil
+}
+
+func parseGenericRecord(record map[string]string, srcFile string, defaultWallets []string, loc *time.Location) (engine.Tx, error) {
+	// Try common fields
+	timeStr := engine.FirstNonEmpty(record, "time", "date", "datetime")
+	if timeStr == "" {
+		return engine.Tx{}, fmt.Errorf("no time")
+	}
+	t, err := engine.ParseTimeIn(timeStr, loc)
+	if err != nil {
+		return engine.Tx{}, err
+	}
+	typ := strings.ToLower(engine.FirstNonEmpty(record, "type", "tx_type", "category"))
+	asset := engine.FirstNonEmpty(record, "asset", "symbol", "commodity", "pair")
+	amount := engine.ParseDecimal(engine.FirstNonEmpty(record, "amount", "qty", "vol"))
+	fee := engine.ParseDecimal(engine.FirstNonEmpty(record, "fee"))
+	cost := engine.ParseDecimal(engine.FirstNonEmpty(record, "cost", "value", "price", "proceeds"))
+	totalCost := cost
+	pricePer := engine.ParseDecimal(engine.FirstNonEmpty(record, "price"))
+	if totalCost.IsZero() && !pricePer.IsZero() {
+		totalCost = pricePer.Mul(amount.Abs())
+	}
+	if typ == "buy" || strings.Contains(typ, "buy") {
+		totalCost = totalCost.Add(fee)
+	}
+	wallet := lookupWallet(record, defaultWallets, srcFile)
+	tx := engine.Tx{
+		Wallet:       wallet,
+		Time:         t,
+		Type:         typ,
+		Commodity:    asset,
+		Currency:     engine.FirstNonEmpty(record, "currency"),
+		Amount:       amount,
+		Cost:         totalCost,
+		PricePerUnit: decimal.Zero,
+		Fee:          fee,
+		Raw:          record,
+		SourceFile:   filepath.Base(srcFile),
+		ReferenceID:  engine.FirstNonEmpty(record, "id", "txid", "refid"),
+	}
+	if !tx.Amount.IsZero() {
+		tx.PricePerUnit = tx.Cost.Abs().Div(tx.Amount.Abs())
+	}
+	return tx, nil
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+)
+
+// Importer parses one export format. Implementations live in their own file and add themselves
+// with Register from an init function; a separate package can do the same and be compiled in
+// with a blank import.
+type Importer interface {
+	// Detect reports whether a CSV header (lowercased column name -> index) is this format.
+	Detect(header map[string]int) bool
+	// Parse turns the rows of a detected file into transactions.
+	Parse(in *Input) ([]engine.Tx, error)
+}
+
+// StreamImporter is an Importer that can also parse a file row by row. ParseFile then passes the
+// rows as they are read instead of collecting them in Input.Rows first, so a very large export is
+// never held in memory as a whole.
+type StreamImporter interface {
+	Importer
+	// ParseStream reads rows from next until it reports no more rows (false) and returns the
+	// transactions; Input.Rows is empty.
+	ParseStream(in *Input, next func() (Row, bool, error)) ([]engine.Tx, error)
+}
+
+// Config is how ParseFile reads the input files: the importer settings of the command line
+// (-strict, -source-timezone, -number-format, -format, -sheet, -wallet-map, and the asset
+// aliases and stablecoins of Assets). The zero value reads every file in the format detected from
+// its header, with timestamps in UTC, the generic files with decimal points and the first sheet of
+// workbooks.
+type Config struct {
+	Assets *engine.Assets // asset aliases and stablecoins treated as fiat; nil for the built-in aliases
+	Strict bool           // reject rows the importers would skip or read leniently, see checkRow
+
+	defaultLocation     *time.Location            // nil for UTC
+	sourceLocations     map[string]*time.Location // file or format name -> zone
+	defaultDecimalComma bool
+	sourceDecimalCommas map[string]bool // file name or "generic" -> eu
+	forcedFormats       []forcedFormat
+	defaultSheet        string
+	sourceSheets        map[string]string // file name -> sheet
+	walletRules         []walletRule
+}
+
+// Input is a CSV export read into memory.
+type Input struct {
+	Path           string
+	Header         map[string]int
+	Rows           []Row
+	DefaultWallets []string // -wallet names; importers fall back to the file name
+	Verbose        bool
+	Location       *time.Location // zone of timestamps without an offset (see Config.SetSourceTimezones)
+	DecimalComma   bool           // numbers were written 1.234,56 (see Config.SetNumberFormats); Rows have them as 1234.56
+	Assets         *engine.Assets // asset aliases and stablecoins treated as fiat, see Config
+	Format         string
+	Issues         []engine.ImportIssue // rows skipped by Parse, see Skip
+}
+
+// Skip records a row that could not be read; it is reported as an import issue.
+func (in *Input) Skip(line int, format string, args ...interface{}) {
+	reason := fmt.Sprintf(format, args...)
+	in.Issues = append(in.Issues, engine.ImportIssue{File: filepath.Base(in.Path), Line: line, Format: in.Format, Reason: reason})
+	if in.Verbose {
+		log.Printf("skipping %s row %d: %s", in.Format, line, reason)
+	}
+}
+
+// Row is one CSV data row keyed by lowercased header.
+type Row struct {
+	Rec   map[string]string
+	Index int // position among the data rows
+	Line  int // line number in the file (header is line 1)
+}
+
+type registration struct {
+	name string
+	imp  Importer
+}
+
+// registry holds the importers in registration order, which is also the detection order.
+var registry []registration
+
+// Register adds an importer under a format name. It panics if the name is taken.
+func Register(name string, imp Importer) {
+	if _, ok := Lookup(name); ok {
+		panic(fmt.Sprintf("importer: format %q registered twice", name))
+	}
+	registry = append(registry, registration{name: name, imp: imp})
+}
+
+// Lookup returns the importer registered under name ("generic" is always available).
+func Lookup(name string) (Importer, bool) {
+	if name == "generic" {
+		return genericImporter{}, true
+	}
+	for _, r := range registry {
+		if r.name == name {
+			return r.imp, true
+		}
+	}
+	return nil, false
+}
+
+// Formats lists the registered format names, sorted, including "generic".
+func Formats() []string {
+	names := []string{"generic"}
+	for _, r := range registry {
+		names = append(names, r.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Detect returns the first registered importer accepting header, or the generic importer.
+func Detect(header map[string]int) (string, Importer) {
+	for _, r := range registry {
+		if r.imp.Detect(header) {
+			return r.name, r.imp
+		}
+	}
+	return "generic", genericImporter{}
+}
+
+// forcedFormat is a file name (or a pattern of file names) and the format it is parsed with,
+// whatever its header looks like; see Config.SetFormats.
+type forcedFormat struct {
+	pattern, format string
+}
+
+// SetFormats forces the format of input files from a comma-separated list of FILE=FORMAT entries,
+// where FILE is a file name or a pattern of file names (kraken-*.csv) and FORMAT a registered
+// format name (see Formats). The first matching entry wins.
+func (c *Config) SetFormats(spec string) error {
+	c.forcedFormats = nil
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		file, format, ok := strings.Cut(entry, "=")
+		file, format = strings.TrimSpace(file), strings.ToLower(strings.TrimSpace(format))
+		if !ok || file == "" {
+			return fmt.Errorf("%q: expected FILE=FORMAT", entry)
+		}
+		if _, ok := Lookup(format); !ok {
+			return fmt.Errorf("%q: unknown format %q (expected one of %s)", entry, format, strings.Join(Formats(), ", "))
+		}
+		if _, err := filepath.Match(file, ""); err != nil {
+			return fmt.Errorf("%q: %v", entry, err)
+		}
+		c.forcedFormats = append(c.forcedFormats, forcedFormat{pattern: strings.ToLower(file), format: format})
+	}
+	return nil
+}
+
+// formatOf returns the format forced for a file with Config.SetFormats, if any.
+func (c *Config) formatOf(path string) (string, Importer, bool) {
+	name := strings.ToLower(filepath.Base(path))
+	for _, f := range c.forcedFormats {
+		if ok, _ := filepath.Match(f.pattern, name); ok || f.pattern == name {
+			imp, _ := Lookup(f.format)
+			return f.format, imp, true
+		}
+	}
+	return "", nil, false
+}
+
+// hasColumns reports whether header has all the given (lowercase) columns.
+func hasColumns(header map[string]int, cols ...string) bool {
+	for _, c := range cols {
+		if _, ok := header[c]; !ok {
+			return false
+		}
+	}
+	return true
+}
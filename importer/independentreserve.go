@@ -0,0 +1,115 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"cryptotax/engine"
+)
+
+func init() {
+	Register("independent-reserve", independentReserveImporter{})
+}
+
+// independentReserveImporter reads the Independent Reserve order history export.
+type independentReserveImporter struct{}
+
+func (independentReserveImporter) Detect(header map[string]int) bool {
+	return hasColumns(header, "order type", "volume filled", "primary currency", "secondary currency")
+}
+
+func (independentReserveImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseIndependentReserveRows(in), nil
+}
+
+// parseIndependentReserveRows maps Independent Reserve orders (Date Created, Date Completed, Order
+// Guid, Order Type, Status, Volume Ordered, Volume Filled, Price, Avg. Price, Value, Value
+// Currency, Fee, Fee Currency, Primary Currency, Secondary Currency): bids are buys and offers
+// sells of the filled volume of the primary currency for Value in the secondary currency. The fee
+// has its own currency column: a fee in the secondary currency adds to the cost of a buy or is
+// deducted from the proceeds of a sale, a fee in the coin is a fee row. Orders that filled
+// nothing are skipped.
+func parseIndependentReserveRows(in *Input) []engine.Tx {
+	wallet := "independent-reserve"
+	if len(in.DefaultWallets) > 0 && in.DefaultWallets[0] != "" {
+		wallet = in.DefaultWallets[0]
+	}
+	var txs []engine.Tx
+	for _, rr := range in.Rows {
+		amount := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "volume filled")).Abs()
+		if amount.IsZero() {
+			continue
+		}
+		t, err := engine.ParseTimeIn(engine.FirstNonEmpty(rr.Rec, "date completed", "date created", "date"), in.Location)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		asset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "primary currency")))
+		currency := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "value currency", "secondary currency")))
+		value := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "value")).Abs()
+		if value.IsZero() {
+			value = engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "avg. price", "price")).Mul(amount)
+		}
+		fee := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "fee")).Abs()
+		feeAsset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "fee currency")))
+		if feeAsset == "" {
+			feeAsset = currency
+		}
+		tx := engine.Tx{
+			Wallet:      wallet,
+			Time:        t,
+			Commodity:   asset,
+			Currency:    currency,
+			Amount:      amount,
+			Cost:        value,
+			Raw:         rr.Rec,
+			SourceFile:  filepath.Base(in.Path),
+			SourceLine:  rr.Line,
+			ReferenceID: engine.FirstNonEmpty(rr.Rec, "order guid"),
+		}
+		if tx.ReferenceID == "" {
+			tx.ReferenceID = fmt.Sprintf("%s-%d", filepath.Base(in.Path), rr.Index)
+		}
+		typ := engine.NormalizeType(engine.FirstNonEmpty(rr.Rec, "order type"))
+		switch {
+		case strings.Contains(typ, "bid") || strings.Contains(typ, "buy"):
+			tx.Type = "buy"
+			if feeAsset == currency {
+				tx.Cost = tx.Cost.Add(fee)
+			}
+		case strings.Contains(typ, "offer") || strings.Contains(typ, "sell"):
+			tx.Type = "sell"
+			tx.Amount = amount.Neg()
+			if feeAsset == currency {
+				tx.Fee = fee
+			}
+		default:
+			in.Skip(rr.Line, "unsupported Independent Reserve order type %q", engine.FirstNonEmpty(rr.Rec, "order type"))
+			continue
+		}
+		if !tx.Cost.IsZero() {
+			tx.PricePerUnit = tx.Cost.Div(amount)
+		}
+		txs = append(txs, tx)
+		if fee.IsPositive() && feeAsset != currency {
+			txs = append(txs, engine.Tx{
+				Wallet:      wallet,
+				Time:        t,
+				Type:        "fee",
+				Commodity:   feeAsset,
+				Amount:      fee.Neg(),
+				Raw:         rr.Rec,
+				SourceFile:  tx.SourceFile,
+				SourceLine:  rr.Line,
+				ReferenceID: tx.ReferenceID + "-fee",
+			})
+		}
+	}
+	return txs
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"cryptotax/engine"
+)
+
+func init() {
+	Register("wazirx", indianImporter{format: "wazirx", detect: func(h map[string]int) bool {
+		return hasColumns(h, "market", "trade", "volume", "total", "fee currency")
+	}})
+	Register("coindcx", indianImporter{format: "coindcx", detect: func(h map[string]int) bool {
+		return hasColumns(h, "market", "side", "quantity", "fee amount")
+	}})
+}
+
+// indianImporter reads the trade reports of Indian exchanges (WazirX, CoinDCX), which withhold 1%
+// TDS (section 194S) from transfers of virtual digital assets.
+type indianImporter struct {
+	format string
+	detect func(header map[string]int) bool
+}
+
+func (i indianImporter) Detect(header map[string]int) bool {
+	return i.detect(header)
+}
+
+func (i indianImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseIndianRows(i.format, in), nil
+}
+
+// marketQuotes are the quote currencies recognized at the end of a market without separator.
+var marketQuotes = []string{"USDT", "USDC", "INR", "BRL", "MXN", "BTC", "ETH", "WRX", "BNB", "DAI"}
+
+// splitMarket splits a market into coin and quote: "BTCINR", "BTC/INR", "BTC-BRL", and the
+// CoinDCX forms "I-BTC_INR" and "B-ETH_USDT".
+func splitMarket(market string) (string, string) {
+	m := strings.ToUpper(strings.TrimSpace(market))
+	if len(m) > 2 && m[1] == '-' {
+		m = m[2:]
+	}
+	for _, sep := range []string{"/", "_", "-"} {
+		if base, quote, ok := strings.Cut(m, sep); ok {
+			return base, quote
+		}
+	}
+	for _, q := range marketQuotes {
+		if strings.HasSuffix(m, q) && len(m) > len(q) {
+			return strings.TrimSuffix(m, q), q
+		}
+	}
+	return m, ""
+}
+
+// parseIndianRows maps WazirX trades (Date, Market, Price, Volume, Total, Trade, Fee Currency,
+// Fee, TDS, TDS Currency) and CoinDCX trades (Date, Market, Side, Price, Quantity, Total, Fee
+// Amount, Fee Currency, TDS Amount, TDS Currency). Buys cost and sales yield the total in the
+// quote currency, with a fee in the quote currency added to the cost or deducted from the
+// proceeds; trades on USDT or other crypto markets are a pair of trade legs. TDS does not reduce
+// the proceeds: it is recorded as tax withheld (Raw["tax_withheld"]) and listed per year.
+func parseIndianRows(format string, in *Input) []engine.Tx {
+	wallet := format
+	if len(in.DefaultWallets) > 0 && in.DefaultWallets[0] != "" {
+		wallet = in.DefaultWallets[0]
+	}
+	var txs []engine.Tx
+	for _, rr := range in.Rows {
+		rec := rr.Rec
+		t, err := engine.ParseTimeIn(engine.FirstNonEmpty(rec, "date", "time", "created at"), in.Location)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		asset, quote := splitMarket(engine.FirstNonEmpty(rec, "market", "pair"))
+		if quote == "" {
+			in.Skip(rr.Line, "unknown %s market %q", format, engine.FirstNonEmpty(rec, "market", "pair"))
+			continue
+		}
+		amount := engine.ParseDecimal(engine.FirstNonEmpty(rec, "volume", "quantity")).Abs()
+		if amount.IsZero() {
+			continue
+		}
+		total := engine.ParseDecimal(engine.FirstNonEmpty(rec, "total")).Abs()
+		if total.IsZero() {
+			total = engine.ParseDecimal(engine.FirstNonEmpty(rec, "price")).Mul(amount)
+		}
+		fee := engine.ParseDecimal(engine.FirstNonEmpty(rec, "fee", "fee amount")).Abs()
+		feeAsset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rec, "fee currency")))
+		if feeAsset == "" {
+			feeAsset = quote
+		}
+		raw := map[string]string{}
+		for k, v := range rec {
+			raw[k] = v
+		}
+		if tds := engine.FirstNonEmpty(rec, "tds", "tds amount"); tds != "" {
+			raw["tax_withheld"] = tds
+			raw["tax_withheld_currency"] = strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rec, "tds currency")))
+			if raw["tax_withheld_currency"] == "" {
+				raw["tax_withheld_currency"] = quote
+			}
+		}
+		base := engine.Tx{
+			Wallet:      wallet,
+			Time:        t,
+			Raw:         raw,
+			SourceFile:  filepath.Base(in.Path),
+			SourceLine:  rr.Line,
+			ReferenceID: engine.FirstNonEmpty(rec, "trade id", "order id", "id"),
+		}
+		if base.ReferenceID == "" {
+			base.ReferenceID = fmt.Sprintf("%s-%d", filepath.Base(in.Path), rr.Index)
+		}
+		side := engine.NormalizeType(engine.FirstNonEmpty(rec, "trade", "side"))
+		if side != "buy" && side != "sell" {
+			in.Skip(rr.Line, "unsupported %s trade side %q", format, engine.FirstNonEmpty(rec, "trade", "side"))
+			continue
+		}
+		if feeAsset == quote {
+			if side == "buy" {
+				total = total.Add(fee)
+			} else {
+				total = total.Sub(fee)
+			}
+		}
+		if in.Assets.IsFiat(quote) {
+			tx := base
+			tx.Type = side
+			tx.Commodity, tx.Currency = asset, quote
+			tx.Amount, tx.Cost = amount, total
+			if side == "sell" {
+				tx.Amount = amount.Neg()
+			}
+			tx.PricePerUnit = total.Div(amount)
+			txs = append(txs, tx)
+		} else {
+			out, got := base, base
+			out.Type, got.Type = "trade", "trade"
+			if side == "buy" {
+				out.Commodity, out.Amount = quote, total.Neg()
+				got.Commodity, got.Amount = asset, amount
+			} else {
+				out.Commodity, out.Amount = asset, amount.Neg()
+				got.Commodity, got.Amount = quote, total
+			}
+			// the TDS is recorded once, with the leg of the coin given
+			got.Raw = rec
+			txs = append(txs, out, got)
+		}
+		if fee.IsPositive() && feeAsset != quote && !in.Assets.IsFiat(feeAsset) {
+			f := base
+			f.Type = "fee"
+			f.Raw = rec
+			f.Commodity, f.Amount = feeAsset, fee.Neg()
+			f.ReferenceID += "-fee"
+			txs = append(txs, f)
+		}
+	}
+	return txs
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// isInputFile reports whether a file found in a directory is an export to read: CSV or XLSX, not
+// hidden.
+func isInputFile(path string) bool {
+	if strings.HasPrefix(filepath.Base(path), ".") {
+		return false
+	}
+	return strings.EqualFold(filepath.Ext(path), ".csv") || isXLSX(path)
+}
+
+// ExpandInputs expands the input arguments of a command into the files to read: a directory
+// stands for the CSV and XLSX files below it (recursively, hidden files and directories left
+// out), a glob pattern for the files it matches, where ** matches any number of directories
+// (exports/**/*.csv). Other arguments, and files whose name only looks like a pattern, are kept
+// as given. Files are listed once, in the order of
+// the arguments and sorted by path within one argument.
+func ExpandInputs(args []string) ([]string, error) {
+	var files []string
+	seen := map[string]bool{}
+	add := func(f string) {
+		if !seen[f] {
+			seen[f] = true
+			files = append(files, f)
+		}
+	}
+	for _, arg := range args {
+		var found []string
+		var err error
+		info, statErr := os.Stat(arg)
+		if statErr == nil && info.IsDir() {
+			found, err = walkInputs(arg, isInputFile)
+			if err == nil && len(found) == 0 {
+				err = fmt.Errorf("no .csv or .xlsx files in directory %s", arg)
+			}
+		} else if statErr != nil && strings.ContainsAny(arg, "*?[") {
+			found, err = globInputs(arg)
+		} else {
+			add(arg)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range found {
+			add(f)
+		}
+	}
+	return files, nil
+}
+
+// walkInputs lists the files below root that keep returns true for, skipping hidden directories.
+func walkInputs(root string, keep func(path string) bool) ([]string, error) {
+	var found []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if keep(path) {
+			found = append(found, path)
+		}
+		return nil
+	})
+	sort.Strings(found)
+	return found, err
+}
+
+// globInputs lists the files matching a glob pattern in which ** matches across directories.
+func globInputs(pattern string) ([]string, error) {
+	re, err := globRegexp(filepath.ToSlash(pattern))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+	// walk from the directories before the first wildcard
+	root := "."
+	if i := strings.IndexAny(pattern, "*?["); i > 0 {
+		if dir := filepath.Dir(pattern[:i+1]); dir != "" {
+			root = dir
+		}
+	}
+	if _, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("no files match %s", pattern)
+	}
+	found, err := walkInputs(root, func(path string) bool {
+		return re.MatchString(filepath.ToSlash(path))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("no files match %s", pattern)
+	}
+	return found, nil
+}
+
+// globRegexp translates a glob pattern with / separators into an anchored regexp: * and ? do not
+// match /, **/ matches any number of directories (none included) and [...] is a character class.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimPrefix(pattern, "./")
+	var b strings.Builder
+	b.WriteString(`^(\./)?`)
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString(`(.*/)?`)
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(`.*`)
+			i++
+		case c == '*':
+			b.WriteString(`[^/]*`)
+		case c == '?':
+			b.WriteString(`[^/]`)
+		case c == '[':
+			j := strings.IndexByte(pattern[i:], ']')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated [")
+			}
+			class := pattern[i+1 : i+j]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += j
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString(`$`)
+	return regexp.Compile(b.String())
+}
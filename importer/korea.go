@@ -0,0 +1,218 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	Register("upbit", koreanImporter{format: "upbit", columns: koreanColumns{
+		time:       []string{"체결일시", "주문일시", "time", "order time"},
+		asset:      []string{"코인", "coin"},
+		market:     []string{"마켓", "market"},
+		side:       []string{"종류", "type"},
+		amount:     []string{"거래수량", "quantity"},
+		price:      []string{"거래단가", "price"},
+		value:      []string{"거래금액", "amount"},
+		fee:        []string{"수수료", "fee"},
+		settlement: []string{"정산금액", "settlement"},
+	}, detect: func(h map[string]int) bool {
+		return hasColumns(h, "코인", "마켓", "거래수량") || hasColumns(h, "coin", "market", "quantity", "settlement")
+	}})
+	Register("bithumb", koreanImporter{format: "bithumb", columns: koreanColumns{
+		time:       []string{"거래일시", "transaction time"},
+		asset:      []string{"자산", "asset"},
+		side:       []string{"거래구분", "transaction type"},
+		amount:     []string{"거래수량", "quantity"},
+		price:      []string{"체결가격", "거래단가", "price"},
+		value:      []string{"거래금액", "amount"},
+		fee:        []string{"수수료", "fee"},
+		settlement: []string{"정산금액", "settlement amount"},
+	}, detect: func(h map[string]int) bool {
+		return hasColumns(h, "자산", "거래구분", "거래수량") || hasColumns(h, "asset", "transaction type", "quantity", "settlement amount")
+	}})
+}
+
+// koreanColumns are the column names (Korean first, then those of the English export) of the
+// fields of a Korean exchange trade history.
+type koreanColumns struct {
+	time, asset, market, side, amount, price, value, fee, settlement []string
+}
+
+// koreanImporter reads the trade history exports of Korean exchanges (Upbit, Bithumb), priced in
+// KRW.
+type koreanImporter struct {
+	format  string
+	columns koreanColumns
+	detect  func(header map[string]int) bool
+}
+
+func (k koreanImporter) Detect(header map[string]int) bool {
+	return k.detect(header)
+}
+
+func (k koreanImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseKoreanRows(k.format, k.columns, in), nil
+}
+
+// koreanSide maps the trade type of a Korean export, in Korean or English, to buy, sell,
+// deposit or withdrawal.
+func koreanSide(s string) string {
+	s = engine.NormalizeType(s)
+	switch {
+	case strings.Contains(s, "매수") || strings.Contains(s, "buy") || s == "bid":
+		return "buy"
+	case strings.Contains(s, "매도") || strings.Contains(s, "sell") || s == "ask":
+		return "sell"
+	case strings.Contains(s, "입금") || strings.Contains(s, "deposit"):
+		return "deposit"
+	case strings.Contains(s, "출금") || strings.Contains(s, "withdraw"):
+		return "withdrawal"
+	}
+	return ""
+}
+
+// koreanAmount reads an amount the exports write with its unit ("0.01 BTC", "1,250 KRW").
+func koreanAmount(s string) (decimal.Decimal, string) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return decimal.Zero, ""
+	}
+	unit := ""
+	if len(fields) > 1 {
+		unit = strings.ToUpper(fields[len(fields)-1])
+	}
+	return engine.ParseDecimal(fields[0]).Abs(), unit
+}
+
+// koreanAsset reads the ticker of an asset column: "BTC", or "비트코인(BTC)" in Bithumb exports.
+func koreanAsset(s string) string {
+	if i := strings.LastIndex(s, "("); i >= 0 && strings.HasSuffix(s, ")") {
+		s = s[i+1 : len(s)-1]
+	}
+	return strings.ToUpper(strings.TrimSpace(s))
+}
+
+// parseKoreanRows maps Upbit (체결일시, 코인, 마켓, 종류, 거래수량, 거래단가, 거래금액, 수수료,
+// 정산금액) and Bithumb (거래일시, 자산, 거래구분, 거래수량, 체결가격, 거래금액, 수수료, 정산금액)
+// trade histories, or the same columns of their English exports. Buys (매수) cost and sales (매도)
+// yield the settlement amount, the trade amount after the fee, in the market currency (KRW unless
+// the market column says otherwise); trades on a BTC or USDT market are a pair of trade legs.
+// Deposits (입금) and withdrawals (출금) of coins are left to transfer matching. The exports use
+// Korean time: set -source-timezone upbit=Asia/Seoul (or bithumb=).
+func parseKoreanRows(format string, cols koreanColumns, in *Input) []engine.Tx {
+	wallet := format
+	if len(in.DefaultWallets) > 0 && in.DefaultWallets[0] != "" {
+		wallet = in.DefaultWallets[0]
+	}
+	var txs []engine.Tx
+	for _, rr := range in.Rows {
+		rec := rr.Rec
+		t, err := engine.ParseTimeIn(strings.ReplaceAll(engine.FirstNonEmpty(rec, cols.time...), ".", "-"), in.Location)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		asset := koreanAsset(engine.FirstNonEmpty(rec, cols.asset...))
+		amount, _ := koreanAmount(engine.FirstNonEmpty(rec, cols.amount...))
+		if asset == "" || in.Assets.IsFiat(asset) || amount.IsZero() {
+			continue
+		}
+		market := "KRW"
+		if len(cols.market) > 0 {
+			if m := koreanAsset(engine.FirstNonEmpty(rec, cols.market...)); m != "" {
+				market = m
+			}
+		}
+		value, unit := koreanAmount(engine.FirstNonEmpty(rec, cols.value...))
+		if unit != "" && unit != asset {
+			market = unit
+		}
+		if value.IsZero() {
+			price, _ := koreanAmount(engine.FirstNonEmpty(rec, cols.price...))
+			value = price.Mul(amount)
+		}
+		fee, feeAsset := koreanAmount(engine.FirstNonEmpty(rec, cols.fee...))
+		if feeAsset == "" {
+			feeAsset = market
+		}
+		settled, settledUnit := koreanAmount(engine.FirstNonEmpty(rec, cols.settlement...))
+		if settledUnit == asset {
+			// Bithumb takes the fee of a buy in the coin: the settlement is the coins received
+			settled = decimal.Zero
+		}
+		base := engine.Tx{
+			Wallet:      wallet,
+			Time:        t,
+			Raw:         rec,
+			SourceFile:  filepath.Base(in.Path),
+			SourceLine:  rr.Line,
+			ReferenceID: fmt.Sprintf("%s-%d", filepath.Base(in.Path), rr.Index),
+		}
+		side := koreanSide(engine.FirstNonEmpty(rec, cols.side...))
+		switch side {
+		case "buy", "sell":
+			// the settlement amount is what changed hands in the market currency, fee included
+			total := settled
+			if total.IsZero() {
+				total = value
+				if feeAsset == market {
+					if side == "buy" {
+						total = total.Add(fee)
+					} else {
+						total = total.Sub(fee)
+					}
+				}
+			}
+			if !in.Assets.IsFiat(market) {
+				out, got := base, base
+				out.Type, got.Type = "trade", "trade"
+				if side == "buy" {
+					out.Commodity, out.Amount = market, total.Neg()
+					got.Commodity, got.Amount = asset, amount
+				} else {
+					out.Commodity, out.Amount = asset, amount.Neg()
+					got.Commodity, got.Amount = market, total
+				}
+				txs = append(txs, out, got)
+				break
+			}
+			tx := base
+			tx.Type = side
+			tx.Commodity, tx.Currency = asset, market
+			tx.Amount, tx.Cost = amount, total
+			if side == "sell" {
+				tx.Amount = amount.Neg()
+			}
+			tx.PricePerUnit = total.Div(amount)
+			txs = append(txs, tx)
+		case "deposit", "withdrawal":
+			tx := base
+			tx.Type = side
+			tx.Commodity, tx.Amount = asset, amount
+			if side == "withdrawal" {
+				tx.Amount = amount.Neg()
+			}
+			txs = append(txs, tx)
+		default:
+			in.Skip(rr.Line, "unsupported %s type %q", format, engine.FirstNonEmpty(rec, cols.side...))
+			continue
+		}
+		if fee.IsPositive() && feeAsset != market && !in.Assets.IsFiat(feeAsset) {
+			f := base
+			f.Type = "fee"
+			f.Commodity, f.Amount = feeAsset, fee.Neg()
+			f.ReferenceID += "-fee"
+			txs = append(txs, f)
+		}
+	}
+	return txs
+}
@@ -0,0 +1,589 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	Register("kraken", krakenImporter{})
+}
+
+// krakenImporter reads Kraken ledger exports: rows sharing a refid are grouped so fiat legs become
+// the cost of the crypto legs.
+type krakenImporter struct{}
+
+func (krakenImporter) Detect(header map[string]int) bool {
+	// Kraken CSV typically has "txid","time","type","asset","amount","fee","cost","price",...
+	return hasColumns(header, "txid", "time", "type")
+}
+
+func (k krakenImporter) Parse(in *Input) ([]engine.Tx, error) {
+	i := 0
+	return k.ParseStream(in, func() (Row, bool, error) {
+		if i == len(in.Rows) {
+			return Row{}, false, nil
+		}
+		i++
+		return in.Rows[i-1], true, nil
+	})
+}
+
+// krakenGroupWindow bounds the grouping by refid: the legs of a trade are booked within seconds of
+// each other, so a group whose last row is this far from the current row is complete and emitted,
+// which keeps the memory use of large ledgers bounded. krakenMaxOpenGroups caps the open groups
+// of files without usable timestamps.
+var (
+	krakenGroupWindow   = time.Hour
+	krakenMaxOpenGroups = 10000
+)
+
+// krakenGroup is the rows sharing one refid seen so far.
+type krakenGroup struct {
+	key  string
+	rows []Row
+	last time.Time // time of the latest row
+}
+
+// ParseStream groups the rows by refid as they are read and emits each group once no row of it
+// can follow, see krakenGroupWindow.
+func (krakenImporter) ParseStream(in *Input, next func() (Row, bool, error)) ([]engine.Tx, error) {
+	path, defaultWallets := in.Path, in.DefaultWallets
+	var txs []engine.Tx
+	// group by reference id (refid or txid). fallback to index key if none.
+	open := map[string]*krakenGroup{}
+	var queue []*krakenGroup // open groups, oldest first
+	flush := func(now time.Time, all bool) {
+		for len(queue) > 0 {
+			g := queue[0]
+			if !all && len(queue) <= krakenMaxOpenGroups && (now.IsZero() || g.last.IsZero() || now.Sub(g.last).Abs() <= krakenGroupWindow) {
+				return
+			}
+			queue[0] = nil
+			queue = queue[1:]
+			delete(open, g.key)
+			txs = append(txs, krakenGroupTxs(in, g.rows)...)
+		}
+	}
+	for {
+		rr, ok, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		// margin PnL and rollover rows are settled in their own asset (often fiat) and never
+		// touch spot inventory, so they bypass the fiat/crypto grouping below
+		if engine.IsMarginType(engine.FirstNonEmpty(rr.Rec, "type", "tx_type")) {
+			if krakenZeroRow(rr) {
+				continue
+			}
+			tx, err := parseKrakenRecord(rr.Rec, path, defaultWallets, in.Location)
+			if err != nil {
+				in.Skip(rr.Line, "%v", err)
+				continue
+			}
+			tx.SourceLine = rr.Line
+			txs = append(txs, tx)
+			continue
+		}
+		// trades export rows that open or close a margin position (non-zero margin column) are not
+		// spot trades; the ledger export books their PnL and fees
+		if !engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "margin")).IsZero() {
+			in.Skip(rr.Line, "margin position trade (PnL and fees are in the ledger export)")
+			continue
+		}
+		key := engine.FirstNonEmpty(rr.Rec, "refid", "txid")
+		if key == "" {
+			key = fmt.Sprintf("ridx-%d", rr.Index)
+		}
+		t, _ := engine.ParseTimeIn(engine.FirstNonEmpty(rr.Rec, "time", "date", "datetime"), in.Location)
+		g, ok := open[key]
+		if !ok {
+			g = &krakenGroup{key: key}
+			open[key] = g
+			queue = append(queue, g)
+		}
+		g.rows = append(g.rows, rr)
+		if !t.IsZero() {
+			g.last = t
+		}
+		flush(t, false)
+	}
+	flush(time.Time{}, true)
+	return krakenStakingTransfers(in, txs), nil
+}
+
+// krakenStakingSubtypes are the ledger subtypes of moves between the spot and the staking balance.
+var krakenStakingSubtypes = map[string]bool{
+	"spottostaking":   true,
+	"stakingfromspot": true,
+	"stakingtospot":   true,
+	"spotfromstaking": true,
+}
+
+// isKrakenStakingMove reports whether tx moves coins between the spot and the staking balance: a
+// row with a staking subtype, or a deposit, withdrawal or transfer of a staking variant (DOT.S).
+func isKrakenStakingMove(tx engine.Tx) bool {
+	if tx.PairedComment != "" {
+		return false
+	}
+	if krakenStakingSubtypes[strings.ToLower(strings.TrimSpace(tx.Raw["subtype"]))] {
+		return true
+	}
+	switch strings.ToLower(tx.Type) {
+	case "deposit", "withdrawal", "transfer":
+		return engine.IsStakedAsset(tx.Commodity)
+	}
+	return false
+}
+
+// krakenStakingTransfers pairs every staking move with the opposite move of the same asset and
+// amount within krakenGroupWindow; older ledgers book the spot leg as a plain deposit or
+// withdrawal. Staking and unstaking are not disposals: two legs in one wallet cancel out and legs
+// in different wallets become one transfer that keeps basis and acquisition dates. A move without
+// its other leg is skipped.
+func krakenStakingTransfers(in *Input, txs []engine.Tx) []engine.Tx {
+	// candidate legs by asset and amount, so each move only looks at the legs that can match it
+	key := func(tx engine.Tx) string {
+		return in.Assets.Normalize(tx.Commodity) + " " + tx.Amount.Abs().String()
+	}
+	var moves []int
+	candidates := map[string][]int{}
+	for i, tx := range txs {
+		switch {
+		case isKrakenStakingMove(tx):
+			moves = append(moves, i)
+			candidates[key(tx)] = append(candidates[key(tx)], i)
+		case tx.PairedComment == "" && (strings.EqualFold(tx.Type, "deposit") || strings.EqualFold(tx.Type, "withdrawal")):
+			candidates[key(tx)] = append(candidates[key(tx)], i)
+		}
+	}
+	if len(moves) == 0 {
+		return txs
+	}
+	drop := make([]bool, len(txs))
+	for _, i := range moves {
+		if drop[i] {
+			continue
+		}
+		tx := txs[i]
+		drop[i] = true
+		if tx.Amount.IsZero() {
+			continue
+		}
+		j := -1
+		for _, k := range candidates[key(tx)] {
+			o := txs[k]
+			gap := o.Time.Sub(tx.Time).Abs()
+			if drop[k] || o.Amount.Sign() != -tx.Amount.Sign() || gap > krakenGroupWindow {
+				continue
+			}
+			if j < 0 || gap < txs[j].Time.Sub(tx.Time).Abs() {
+				j = k
+			}
+		}
+		if j < 0 {
+			in.Skip(tx.SourceLine, "staking move of %s %s without its other leg", tx.Amount.String(), tx.Commodity)
+			continue
+		}
+		drop[j] = true
+		dst, src := tx, txs[j]
+		if dst.Amount.IsNegative() {
+			dst, src = src, dst
+		}
+		if dst.Wallet == src.Wallet {
+			continue
+		}
+		dst.Type = "transfer"
+		dst.PairedComment = src.Wallet
+		dst.Cost, dst.Fee, dst.PricePerUnit = decimal.Zero, decimal.Zero, decimal.Zero
+		txs[i], drop[i] = dst, false
+	}
+	res := txs[:0]
+	for i, tx := range txs {
+		if !drop[i] {
+			res = append(res, tx)
+		}
+	}
+	return res
+}
+
+// krakenGroupTxs turns the rows of one refid into transactions: fiat legs become the cost of the
+// crypto legs, earn/reward groups are income and allocation groups are transfers.
+func krakenGroupTxs(in *Input, group []Row) []engine.Tx {
+	path, defaultWallets := in.Path, in.DefaultWallets
+	var txs []engine.Tx
+	// detect income-like group (earn/reward/staking) and transfer-like group (autoallocation/allocation)
+	isIncomeGroup := false
+	isTransferGroup := false
+	for _, rr := range group {
+		typ := strings.ToLower(engine.FirstNonEmpty(rr.Rec, "type", "tx_type"))
+		sub := strings.ToLower(engine.FirstNonEmpty(rr.Rec, "subtype"))
+		if strings.Contains(typ, "earn") || strings.Contains(typ, "reward") || strings.Contains(typ, "staking") {
+			isIncomeGroup = true
+		}
+		if strings.Contains(sub, "autoallocation") || strings.Contains(sub, "allocation") {
+			// treat allocation/autoallocation as transfer between wallets (preserve basis)
+			isTransferGroup = true
+		}
+	}
+	// find fiat rows and crypto rows
+	fiatAsset := ""
+	totalFiat := decimal.Zero
+	fiatFee := decimal.Zero
+	cryptoTotalAbs := decimal.Zero
+	// collect parsed crypto rows first (without fiat allocation)
+	var cryptoRows []Row
+	for _, rr := range group {
+		asset := engine.FirstNonEmpty(rr.Rec, "asset", "pair", "symbol")
+		amt := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "vol", "amount", "qty"))
+		if in.Assets.IsFiat(asset) {
+			fiatAsset = asset
+			totalFiat = totalFiat.Add(amt.Abs())
+			fiatFee = fiatFee.Add(engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "fee")))
+		} else {
+			cryptoRows = append(cryptoRows, rr)
+			cryptoTotalAbs = cryptoTotalAbs.Add(amt.Abs())
+		}
+	}
+
+	// instant buys and sales (Buy Crypto, card purchases) are a spend row and a receive row
+	if isKrakenSpendReceive(group) {
+		if fiatAsset != "" && len(cryptoRows) == 1 {
+			return krakenInstantTxs(in, cryptoRows[0], fiatAsset, totalFiat, fiatFee)
+		}
+		if fiatAsset == "" && len(cryptoRows) == 2 {
+			// a coin spent for another coin: trade legs, valued together by the engine
+			return krakenTradeLegs(in, cryptoRows)
+		}
+	}
+
+	if isKrakenSettled(group) {
+		return krakenSettledTxs(in, group)
+	}
+
+	// If this is a transfer group (autoallocation/allocation), synthesize transfer transactions
+	if isTransferGroup && len(cryptoRows) > 0 {
+		// build maps of negative (source) and positive (dest) rows grouped by asset
+		type rowInfo struct {
+			rec  map[string]string
+			amt  decimal.Decimal
+			line int
+		}
+		posMap := map[string][]rowInfo{}
+		negMap := map[string][]rowInfo{}
+		for _, rr := range cryptoRows {
+			asset := engine.FirstNonEmpty(rr.Rec, "asset", "pair", "symbol")
+			amt := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "vol", "amount", "qty"))
+			ri := rowInfo{rec: rr.Rec, amt: amt, line: rr.Line}
+			if amt.Cmp(decimal.Zero) > 0 {
+				posMap[strings.ToLower(asset)] = append(posMap[strings.ToLower(asset)], ri)
+			} else {
+				negMap[strings.ToLower(asset)] = append(negMap[strings.ToLower(asset)], ri)
+			}
+		}
+		// pair positives with negatives and emit transfer txs
+		for asset, posList := range posMap {
+			negList := negMap[asset]
+			for _, p := range posList {
+				// try find a matching negative row with similar absolute amount
+				var matchedNeg *rowInfo
+				for i, n := range negList {
+					if n.amt.Abs().Cmp(p.amt.Abs()) == 0 {
+						matchedNeg = &negList[i]
+						break
+					}
+				}
+				// If not exact match, just pick first negative if exists
+				if matchedNeg == nil && len(negList) > 0 {
+					matchedNeg = &negList[0]
+				}
+				// build transfer tx with dest = pos wallet, source in PairedComment
+				timeStr := engine.FirstNonEmpty(p.rec, "time", "date", "datetime")
+				t, _ := engine.ParseTimeIn(timeStr, in.Location)
+				destWallet := engine.FirstNonEmpty(p.rec, "wallet", "account")
+				if destWallet == "" {
+					destWallet = lookupWallet(p.rec, defaultWallets, path)
+				}
+				ref := engine.FirstNonEmpty(p.rec, "refid", "txid")
+				srcWallet := ""
+				if matchedNeg != nil {
+					srcWallet = engine.FirstNonEmpty(matchedNeg.rec, "wallet", "account")
+					if srcWallet == "" {
+						srcWallet = lookupWallet(matchedNeg.rec, defaultWallets, path)
+					}
+				}
+				amt := p.amt.Abs()
+				tx := engine.Tx{
+					Wallet:        destWallet,
+					Time:          t,
+					Type:          "transfer",
+					Commodity:     p.rec["asset"],
+					Currency:      engine.FirstNonEmpty(p.rec, "currency", "pair"),
+					Amount:        amt,
+					Cost:          decimal.Zero,
+					PricePerUnit:  decimal.Zero,
+					Fee:           decimal.Zero,
+					Raw:           p.rec,
+					SourceFile:    filepath.Base(path),
+					SourceLine:    p.line,
+					ReferenceID:   ref,
+					PairedComment: srcWallet,
+				}
+				txs = append(txs, tx)
+			}
+		}
+		// done with this group
+		return txs
+	}
+
+	// if we have crypto rows, create Tx for each crypto row and allocate fiat amounts/fees proportionally
+	if len(cryptoRows) > 0 {
+		for _, rr := range cryptoRows {
+			rec := rr.Rec
+			// when this is an income group, only keep the receiving (positive) side and treat as income
+			if isIncomeGroup {
+				amt := engine.ParseDecimal(engine.FirstNonEmpty(rec, "vol", "amount", "qty"))
+				if amt.Cmp(decimal.Zero) <= 0 {
+					// skip the negative source line (avoid generating a sell)
+					continue
+				}
+			}
+			tx, err := parseKrakenRecord(rec, path, defaultWallets, in.Location)
+			if err != nil {
+				in.Skip(rr.Line, "%v", err)
+				continue
+			}
+			if fiatAsset != "" && !cryptoTotalAbs.IsZero() {
+				// allocate fiat cost and fee proportionally
+				amtAbs := tx.Amount.Abs()
+				proportion := decimal.Zero
+				if !cryptoTotalAbs.IsZero() {
+					proportion = amtAbs.Div(cryptoTotalAbs)
+				}
+				tx.Cost = totalFiat.Mul(proportion)
+				tx.Currency = fiatAsset
+				tx.Fee = fiatFee.Mul(proportion)
+				if !tx.Amount.IsZero() {
+					tx.PricePerUnit = tx.Cost.Abs().Div(tx.Amount.Abs())
+				}
+			}
+			tx.SourceLine = rr.Line
+			// force income type for earn/reward groups so handler treats as income
+			if isIncomeGroup {
+				tx.Type = "income"
+			}
+			txs = append(txs, tx)
+		}
+	}
+	// a group without crypto (fiat-only) is skipped: we don't treat fiat as commodity
+	return txs
+}
+
+// isKrakenSpendReceive reports whether a refid group has both a spend and a receive row and no
+// other types.
+func isKrakenSpendReceive(group []Row) bool {
+	spend, receive := false, false
+	for _, rr := range group {
+		switch strings.ToLower(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "type", "tx_type"))) {
+		case "spend":
+			spend = true
+		case "receive":
+			receive = true
+		default:
+			return false
+		}
+	}
+	return spend && receive
+}
+
+// isKrakenSettled reports whether all rows of a refid group are margin position settlements.
+func isKrakenSettled(group []Row) bool {
+	for _, rr := range group {
+		if strings.ToLower(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "type", "tx_type"))) != "settled" {
+			return false
+		}
+	}
+	return len(group) > 0
+}
+
+// krakenZeroRow reports whether a row neither moves an asset nor charges a fee, e.g. the
+// bookkeeping entry Kraken writes when a margin position is opened.
+func krakenZeroRow(rr Row) bool {
+	return engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "vol", "amount", "qty")).IsZero() &&
+		engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "fee")).IsZero()
+}
+
+// krakenSettledTxs books the settlement of a margin position. Settling delivers the position in
+// spot funds: coins received for fiat are a buy, coins given for fiat a sale, like an instant
+// trade, and two coins are trade legs. Settlement rows that only charge a fee are margin fees and
+// stay out of spot inventory.
+func krakenSettledTxs(in *Input, group []Row) []engine.Tx {
+	var txs []engine.Tx
+	var moves, cryptoRows []Row
+	fiatAsset := ""
+	totalFiat, fiatFee := decimal.Zero, decimal.Zero
+	for _, rr := range group {
+		amt := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "vol", "amount", "qty"))
+		if amt.IsZero() {
+			if krakenZeroRow(rr) {
+				continue
+			}
+			tx, err := parseKrakenRecord(rr.Rec, in.Path, in.DefaultWallets, in.Location)
+			if err != nil {
+				in.Skip(rr.Line, "%v", err)
+				continue
+			}
+			tx.Type = "margin fee"
+			tx.Cost, tx.PricePerUnit = decimal.Zero, decimal.Zero
+			tx.SourceLine = rr.Line
+			txs = append(txs, tx)
+			continue
+		}
+		moves = append(moves, rr)
+		asset := engine.FirstNonEmpty(rr.Rec, "asset", "pair", "symbol")
+		if in.Assets.IsFiat(asset) {
+			fiatAsset = asset
+			totalFiat = totalFiat.Add(amt.Abs())
+			fiatFee = fiatFee.Add(engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "fee")))
+		} else {
+			cryptoRows = append(cryptoRows, rr)
+		}
+	}
+	switch {
+	case len(moves) == 0:
+	case fiatAsset != "" && len(cryptoRows) == 1:
+		txs = append(txs, krakenInstantTxs(in, cryptoRows[0], fiatAsset, totalFiat, fiatFee)...)
+	case fiatAsset == "" && len(cryptoRows) == 2:
+		txs = append(txs, krakenTradeLegs(in, cryptoRows)...)
+	default:
+		for _, rr := range moves {
+			in.Skip(rr.Line, "settlement of a margin position without a matching counter leg")
+		}
+	}
+	return txs
+}
+
+// krakenTradeLegs turns the two coin rows of a coin-for-coin exchange into trade legs, valued
+// together by the engine. A fee charged in a coin is a fee row.
+func krakenTradeLegs(in *Input, rows []Row) []engine.Tx {
+	var txs []engine.Tx
+	for _, rr := range rows {
+		tx, err := parseKrakenRecord(rr.Rec, in.Path, in.DefaultWallets, in.Location)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		tx.Type = "trade"
+		tx.SourceLine = rr.Line
+		coinFee := tx.Fee.Abs()
+		tx.Cost, tx.Fee, tx.PricePerUnit = decimal.Zero, decimal.Zero, decimal.Zero
+		txs = append(txs, tx)
+		if coinFee.IsPositive() {
+			f := tx
+			f.Type = "fee"
+			f.Amount = coinFee.Neg()
+			f.ReferenceID += "-fee"
+			txs = append(txs, f)
+		}
+	}
+	return txs
+}
+
+// krakenInstantTxs books the coin row of a spend/receive pair against its fiat row: coins received
+// are a buy costing the fiat spent plus the fiat fee, coins spent are a sale yielding the fiat
+// received less the fee. A fee charged in the coin is a fee row.
+func krakenInstantTxs(in *Input, rr Row, fiatAsset string, fiat, fiatFee decimal.Decimal) []engine.Tx {
+	tx, err := parseKrakenRecord(rr.Rec, in.Path, in.DefaultWallets, in.Location)
+	if err != nil {
+		in.Skip(rr.Line, "%v", err)
+		return nil
+	}
+	if tx.Amount.IsZero() {
+		return nil
+	}
+	tx.SourceLine = rr.Line
+	coinFee := tx.Fee.Abs()
+	tx.Currency = fiatAsset
+	if tx.Amount.IsPositive() {
+		tx.Type = "buy"
+		tx.Cost = fiat.Add(fiatFee.Abs())
+		tx.Fee = decimal.Zero
+	} else {
+		tx.Type = "sell"
+		tx.Cost = fiat
+		tx.Fee = fiatFee.Abs()
+	}
+	tx.PricePerUnit = tx.Cost.Div(tx.Amount.Abs())
+	txs := []engine.Tx{tx}
+	if coinFee.IsPositive() {
+		f := tx
+		f.Type = "fee"
+		f.Amount = coinFee.Neg()
+		f.Currency = ""
+		f.Cost, f.Fee, f.PricePerUnit = decimal.Zero, decimal.Zero, decimal.Zero
+		f.ReferenceID += "-fee"
+		txs = append(txs, f)
+	}
+	return txs
+}
+
+// Kraken-specific mapping
+func parseKrakenRecord(record map[string]string, srcFile string, defaultWallets []string, loc *time.Location) (engine.Tx, error) {
+	// required fields: time, type, asset/pair, vol/amount, fee, cost/price
+	timeStr := engine.FirstNonEmpty(record, "time", "date", "datetime")
+	if timeStr == "" {
+		return engine.Tx{}, fmt.Errorf("no time")
+	}
+	t, err := engine.ParseTimeIn(timeStr, loc)
+	if err != nil {
+		return engine.Tx{}, err
+	}
+	typ := strings.ToLower(engine.FirstNonEmpty(record, "type", "tx_type"))
+	asset := engine.FirstNonEmpty(record, "asset", "pair", "symbol")
+	amount := engine.ParseDecimal(engine.FirstNonEmpty(record, "vol", "amount", "qty"))
+	fee := engine.ParseDecimal(engine.FirstNonEmpty(record, "fee"))
+	cost := engine.ParseDecimal(engine.FirstNonEmpty(record, "cost", "value", "price")) // cost may be total or unit price
+	// If cost looks like unit price but we have amount, compute total cost
+	pricePer := engine.ParseDecimal(engine.FirstNonEmpty(record, "price"))
+	totalCost := cost
+	if totalCost.IsZero() && !pricePer.IsZero() {
+		totalCost = pricePer.Mul(amount.Abs())
+	}
+	// add fee to cost for buys; for sells, fee reduces proceeds; general approach include fees into cost for buys, subtract from proceeds for sells
+	if typ == "buy" || typ == "deposit" || typ == "staking" || typ == "reward" || typ == "stakingreward" {
+		totalCost = totalCost.Add(fee)
+	} else if typ == "sell" {
+		// we'll keep fee in Fee field and treat appropriately in processing pass
+	}
+	wallet := lookupWallet(record, defaultWallets, srcFile)
+	tx := engine.Tx{
+		Wallet:       wallet,
+		Time:         t,
+		Type:         typ,
+		Commodity:    asset,
+		Currency:     engine.FirstNonEmpty(record, "currency", "pair"),
+		Amount:       amount,
+		Cost:         totalCost,
+		PricePerUnit: decimal.Zero,
+		Fee:          fee,
+		Raw:          record,
+		SourceFile:   filepath.Base(srcFile),
+		ReferenceID:  engine.FirstNonEmpty(record, "txid", "refid", "orderno"),
+	}
+	if !tx.Amount.IsZero() {
+		tx.PricePerUnit = tx.Cost.Abs().Div(tx.Amount.Abs())
+	}
+	return tx, nil
+}
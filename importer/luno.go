@@ -0,0 +1,112 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	Register("luno", lunoImporter{})
+}
+
+// lunoImporter reads Luno account statements: one file per wallet, one row per balance change.
+type lunoImporter struct{}
+
+func (lunoImporter) Detect(header map[string]int) bool {
+	return hasColumns(header, "description", "currency", "balance delta")
+}
+
+func (lunoImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseLunoRows(in), nil
+}
+
+// lunoCounter matches the other side of a trade in a statement description: "Bought BTC 0.01 for
+// ZAR 5,000.00", "Sold 0.5 ETH for MYR 4,200.00".
+var lunoCounter = regexp.MustCompile(`(?i)\bfor\s+([a-z]{3,5})\s+([0-9][0-9,]*(?:\.[0-9]+)?)`)
+
+// parseLunoRows maps Luno statement rows (Wallet ID, Row, Timestamp (UTC), Description, Currency,
+// Balance delta, Balance, Cryptocurrency transaction ID, Value amount, Value currency, Reference)
+// of crypto wallets; fiat wallet statements only repeat the other side of the trades. The sign of
+// the balance delta and the description tell the row apart: bought and sold coins cost the fiat
+// amount of the description (else the value columns), received and sent coins are deposits and
+// withdrawals, fees are fee rows, and interest and rewards are income at their value.
+func parseLunoRows(in *Input) []engine.Tx {
+	wallet := "luno"
+	if len(in.DefaultWallets) > 0 && in.DefaultWallets[0] != "" {
+		wallet = in.DefaultWallets[0]
+	}
+	var txs []engine.Tx
+	for _, rr := range in.Rows {
+		asset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "currency")))
+		if asset == "" || in.Assets.IsFiat(asset) {
+			continue
+		}
+		delta := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "balance delta"))
+		if delta.IsZero() {
+			// orders placed and cancelled only move the available balance
+			continue
+		}
+		t, err := engine.ParseTimeIn(engine.FirstNonEmpty(rr.Rec, "timestamp (utc)", "timestamp"), in.Location)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		desc := engine.FirstNonEmpty(rr.Rec, "description")
+		amount := delta.Abs()
+		value := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "value amount")).Abs()
+		currency := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "value currency")))
+		tx := engine.Tx{
+			Wallet:      wallet,
+			Time:        t,
+			Commodity:   asset,
+			Amount:      delta,
+			Raw:         rr.Rec,
+			SourceFile:  filepath.Base(in.Path),
+			SourceLine:  rr.Line,
+			ReferenceID: engine.FirstNonEmpty(rr.Rec, "reference", "cryptocurrency transaction id"),
+		}
+		if tx.ReferenceID == "" {
+			tx.ReferenceID = fmt.Sprintf("%s-%d", filepath.Base(in.Path), rr.Index)
+		}
+		d := strings.ToLower(desc)
+		switch {
+		case strings.Contains(d, "fee"):
+			tx.Type = "fee"
+			tx.Amount = amount.Neg()
+			value = decimal.Zero
+		case strings.HasPrefix(d, "bought") || strings.HasPrefix(d, "buy") || strings.HasPrefix(d, "sold") || strings.HasPrefix(d, "sell"):
+			if m := lunoCounter.FindStringSubmatch(desc); m != nil && in.Assets.IsFiat(m[1]) {
+				currency, value = strings.ToUpper(m[1]), engine.ParseDecimal(m[2])
+			}
+			tx.Type = "buy"
+			if delta.IsNegative() {
+				tx.Type = "sell"
+			}
+		case strings.Contains(d, "interest") || strings.Contains(d, "reward") || strings.Contains(d, "staking") || strings.Contains(d, "referral"):
+			tx.Type = "income"
+		case delta.IsPositive():
+			// "Received Bitcoin", deposits from other wallets
+			tx.Type = "deposit"
+		default:
+			// "Sent Bitcoin", withdrawals to other wallets
+			tx.Type = "withdrawal"
+			value = decimal.Zero
+		}
+		if !value.IsZero() && currency != "" {
+			tx.Currency = currency
+			tx.Cost = value
+			tx.PricePerUnit = value.Div(amount)
+		}
+		txs = append(txs, tx)
+	}
+	return txs
+}
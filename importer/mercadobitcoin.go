@@ -0,0 +1,152 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+)
+
+func init() {
+	Register("mercado-bitcoin", mercadoBitcoinImporter{})
+}
+
+// mercadoBitcoinImporter reads the Mercado Bitcoin order history export (Portuguese headers).
+type mercadoBitcoinImporter struct{}
+
+func (mercadoBitcoinImporter) Detect(header map[string]int) bool {
+	_, coin := header["moeda"]
+	_, pair := header["par"]
+	return hasColumns(header, "tipo", "quantidade") && (coin || pair)
+}
+
+func (mercadoBitcoinImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseMercadoBitcoinRows(in), nil
+}
+
+// parseBrazilianTime parses the day-first dates of Brazilian exports ("03/01/2023 10:00:00"),
+// falling back to the common layouts.
+func parseBrazilianTime(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, l := range []string{"02/01/2006 15:04:05", "02/01/2006 15:04", "02/01/2006"} {
+		if t, err := time.ParseInLocation(l, s, loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return engine.ParseTimeIn(s, loc)
+}
+
+// parseMercadoBitcoinRows maps Mercado Bitcoin rows (Data, Tipo, Moeda or Par, Quantidade, Preço
+// unitário, Valor total, Taxa, Moeda da taxa; numbers with a decimal comma): purchases (compra) cost and sales (venda) yield the
+// total in BRL. The fee is charged in the currency received unless a fee currency column says
+// otherwise: the fee of a purchase is a fee row in the coin, that of a sale is deducted from the
+// proceeds. Deposits (depósito) and withdrawals (saque) of coins are left to transfer matching.
+func parseMercadoBitcoinRows(in *Input) []engine.Tx {
+	wallet := "mercado-bitcoin"
+	if len(in.DefaultWallets) > 0 && in.DefaultWallets[0] != "" {
+		wallet = in.DefaultWallets[0]
+	}
+	var txs []engine.Tx
+	for _, rr := range in.Rows {
+		t, err := parseBrazilianTime(engine.FirstNonEmpty(rr.Rec, "data", "data da operação"), in.Location)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		asset, quote := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "moeda"))), "BRL"
+		if p := engine.FirstNonEmpty(rr.Rec, "par"); p != "" {
+			asset, quote = splitMarket(p)
+		}
+		amount := engine.ParseDecimal(commaDecimal(engine.FirstNonEmpty(rr.Rec, "quantidade"))).Abs()
+		if asset == "" || in.Assets.IsFiat(asset) || amount.IsZero() {
+			continue
+		}
+		total := engine.ParseDecimal(commaDecimal(engine.FirstNonEmpty(rr.Rec, "valor total", "total"))).Abs()
+		if total.IsZero() {
+			total = engine.ParseDecimal(commaDecimal(engine.FirstNonEmpty(rr.Rec, "preço unitário", "preco unitario", "preço", "preco"))).Mul(amount)
+		}
+		fee := engine.ParseDecimal(commaDecimal(engine.FirstNonEmpty(rr.Rec, "taxa", "tarifa"))).Abs()
+		feeAsset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "moeda da taxa")))
+		base := engine.Tx{
+			Wallet:      wallet,
+			Time:        t,
+			Raw:         rr.Rec,
+			SourceFile:  filepath.Base(in.Path),
+			SourceLine:  rr.Line,
+			ReferenceID: engine.FirstNonEmpty(rr.Rec, "id", "id da ordem"),
+		}
+		if base.ReferenceID == "" {
+			base.ReferenceID = fmt.Sprintf("%s-%d", filepath.Base(in.Path), rr.Index)
+		}
+		typ := engine.NormalizeType(engine.FirstNonEmpty(rr.Rec, "tipo"))
+		switch {
+		case strings.Contains(typ, "compra") || strings.Contains(typ, "venda"):
+			buying := strings.Contains(typ, "compra")
+			if feeAsset == "" {
+				feeAsset = quote
+				if buying {
+					feeAsset = asset
+				}
+			}
+			if feeAsset == quote {
+				if buying {
+					total = total.Add(fee)
+				} else {
+					total = total.Sub(fee)
+				}
+			}
+			if !in.Assets.IsFiat(quote) {
+				out, got := base, base
+				out.Type, got.Type = "trade", "trade"
+				if buying {
+					out.Commodity, out.Amount = quote, total.Neg()
+					got.Commodity, got.Amount = asset, amount
+				} else {
+					out.Commodity, out.Amount = asset, amount.Neg()
+					got.Commodity, got.Amount = quote, total
+				}
+				txs = append(txs, out, got)
+				break
+			}
+			tx := base
+			tx.Type = "buy"
+			tx.Commodity, tx.Currency = asset, quote
+			tx.Amount, tx.Cost = amount, total
+			if !buying {
+				tx.Type = "sell"
+				tx.Amount = amount.Neg()
+			}
+			tx.PricePerUnit = total.Div(amount)
+			txs = append(txs, tx)
+		case strings.Contains(typ, "depósito") || strings.Contains(typ, "deposito") || strings.Contains(typ, "recebimento"):
+			tx := base
+			tx.Type = "deposit"
+			tx.Commodity, tx.Amount = asset, amount
+			txs = append(txs, tx)
+			feeAsset = asset
+		case strings.Contains(typ, "saque") || strings.Contains(typ, "envio"):
+			tx := base
+			tx.Type = "withdrawal"
+			tx.Commodity, tx.Amount = asset, amount.Neg()
+			txs = append(txs, tx)
+			feeAsset = asset
+		default:
+			in.Skip(rr.Line, "unsupported Mercado Bitcoin type %q", engine.FirstNonEmpty(rr.Rec, "tipo"))
+			continue
+		}
+		if fee.IsPositive() && feeAsset != quote && !in.Assets.IsFiat(feeAsset) {
+			f := base
+			f.Type = "fee"
+			f.Commodity, f.Amount = feeAsset, fee.Neg()
+			f.ReferenceID += "-fee"
+			txs = append(txs, f)
+		}
+	}
+	return txs
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"cryptotax/engine"
+)
+
+func init() {
+	Register("newton", newtonImporter{})
+}
+
+// newtonImporter reads the Newton transaction history export.
+type newtonImporter struct{}
+
+func (newtonImporter) Detect(header map[string]int) bool {
+	return hasColumns(header, "date", "received quantity", "received currency", "sent quantity", "sent currency")
+}
+
+func (newtonImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseNewtonRows(in), nil
+}
+
+// parseNewtonRows maps Newton rows (Date, Type, Received Quantity, Received Currency, Sent
+// Quantity, Sent Currency, Fee Amount, Fee Currency, Tag). Trades against CAD are buys and sells
+// costing the CAD sent or received, with a CAD fee added to the cost of a buy; trades of one coin
+// for another are a pair of trade legs. Crypto deposits tagged as referral or reward are income at
+// the price of a price source, other deposits and withdrawals are left to transfer matching.
+func parseNewtonRows(in *Input) []engine.Tx {
+	wallet := "newton"
+	if len(in.DefaultWallets) > 0 && in.DefaultWallets[0] != "" {
+		wallet = in.DefaultWallets[0]
+	}
+	var txs []engine.Tx
+	for _, rr := range in.Rows {
+		t, err := engine.ParseTimeIn(engine.FirstNonEmpty(rr.Rec, "date"), in.Location)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		received := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "received quantity")).Abs()
+		receivedAsset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "received currency")))
+		sent := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "sent quantity")).Abs()
+		sentAsset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "sent currency")))
+		fee := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "fee amount")).Abs()
+		feeAsset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "fee currency")))
+		base := engine.Tx{
+			Wallet:      wallet,
+			Time:        t,
+			Raw:         rr.Rec,
+			SourceFile:  filepath.Base(in.Path),
+			SourceLine:  rr.Line,
+			ReferenceID: fmt.Sprintf("%s-%d", filepath.Base(in.Path), rr.Index),
+		}
+		cryptoIn := received.IsPositive() && receivedAsset != "" && !in.Assets.IsFiat(receivedAsset)
+		cryptoOut := sent.IsPositive() && sentAsset != "" && !in.Assets.IsFiat(sentAsset)
+		typ := engine.NormalizeType(engine.FirstNonEmpty(rr.Rec, "type"))
+		tag := strings.ToLower(engine.FirstNonEmpty(rr.Rec, "tag"))
+		switch {
+		case typ == "trade" && cryptoIn && cryptoOut:
+			out := base
+			out.Type = "trade"
+			out.Commodity, out.Amount = sentAsset, sent.Neg()
+			buy := base
+			buy.Type = "trade"
+			buy.Commodity, buy.Amount = receivedAsset, received
+			txs = append(txs, out, buy)
+		case typ == "trade" && cryptoIn:
+			tx := base
+			tx.Type = "buy"
+			tx.Commodity, tx.Amount = receivedAsset, received
+			tx.Currency, tx.Cost = sentAsset, sent
+			if in.Assets.IsFiat(feeAsset) {
+				tx.Cost = tx.Cost.Add(fee)
+			}
+			tx.PricePerUnit = tx.Cost.Div(received)
+			txs = append(txs, tx)
+		case typ == "trade" && cryptoOut:
+			tx := base
+			tx.Type = "sell"
+			tx.Commodity, tx.Amount = sentAsset, sent.Neg()
+			tx.Currency, tx.Cost = receivedAsset, received
+			if in.Assets.IsFiat(feeAsset) {
+				tx.Fee = fee
+			}
+			tx.PricePerUnit = tx.Cost.Div(sent)
+			txs = append(txs, tx)
+		case cryptoIn:
+			tx := base
+			tx.Type = "deposit"
+			if strings.Contains(tag, "referral") || strings.Contains(tag, "reward") || strings.Contains(tag, "promotion") {
+				tx.Type = "income"
+			}
+			tx.Commodity, tx.Amount = receivedAsset, received
+			txs = append(txs, tx)
+		case cryptoOut:
+			tx := base
+			tx.Type = "withdrawal"
+			tx.Commodity, tx.Amount = sentAsset, sent.Neg()
+			txs = append(txs, tx)
+		default:
+			// CAD deposits and withdrawals
+			continue
+		}
+		if fee.IsPositive() && feeAsset != "" && !in.Assets.IsFiat(feeAsset) {
+			f := base
+			f.Type = "fee"
+			f.Commodity, f.Amount = feeAsset, fee.Neg()
+			f.ReferenceID += "-fee"
+			txs = append(txs, f)
+		}
+	}
+	return txs
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"cryptotax/engine"
+)
+
+// SetNumberFormats configures how numbers are written in generic CSV files from a comma-separated
+// list of SOURCE=FORMAT entries, where SOURCE is a file name or "generic" and FORMAT is en
+// (1,234.56) or eu (1.234,56). An entry without SOURCE= sets the default for all generic files.
+// The exchange formats have a fixed number format of their own.
+func (c *Config) SetNumberFormats(spec string) error {
+	c.defaultDecimalComma = false
+	c.sourceDecimalCommas = map[string]bool{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		source, format, ok := strings.Cut(entry, "=")
+		if !ok {
+			format = source
+		}
+		var comma bool
+		switch strings.ToLower(strings.TrimSpace(format)) {
+		case "en":
+		case "eu":
+			comma = true
+		default:
+			return fmt.Errorf("%q: unknown number format %q (expected en or eu)", entry, format)
+		}
+		if !ok {
+			c.defaultDecimalComma = comma
+			continue
+		}
+		source = strings.ToLower(strings.TrimSpace(source))
+		if source != "generic" {
+			if _, native := Lookup(source); native {
+				return fmt.Errorf("%q: %s exports have a number format of their own", entry, source)
+			}
+		}
+		c.sourceDecimalCommas[source] = comma
+	}
+	return nil
+}
+
+// sourceDecimalComma reports whether the numbers of a file in format are written with a decimal
+// comma: only files read by the generic importer follow Config.SetNumberFormats.
+func (c *Config) sourceDecimalComma(path, format string) bool {
+	if format != "generic" {
+		return false
+	}
+	if comma, ok := c.sourceDecimalCommas[strings.ToLower(filepath.Base(path))]; ok {
+		return comma
+	}
+	if comma, ok := c.sourceDecimalCommas[format]; ok {
+		return comma
+	}
+	return c.defaultDecimalComma
+}
+
+// commaDecimal reads a number written with a decimal comma (1.234,56), for the exports that
+// always write them that way.
+func commaDecimal(s string) string {
+	if n, err := engine.NormalizeNumber(s, true); err == nil {
+		return n
+	}
+	return s
+}
+
+// toDecimalPoint rewrites the numeric columns of a row written with a decimal comma as the plain
+// numbers ParseDecimal reads. Cells stored as numbers (stored reports true for the columns of
+// workbook cells that are not text) are plain already. Cells that are not a number in the format,
+// such as 1.5, are left as they are and the first of them is returned as an error, which -strict
+// reports instead of reading the cell with a decimal point.
+func toDecimalPoint(rec map[string]string, stored func(column string) bool) error {
+	var bad error
+	for _, c := range numericColumns {
+		v := rec[c]
+		if strings.TrimSpace(v) == "" || stored(c) {
+			continue
+		}
+		n, err := engine.NormalizeNumber(v, true)
+		if err != nil {
+			if bad == nil {
+				bad = fmt.Errorf("column %s: not a number in the eu format: %q (%v)", c, v, err)
+			}
+			continue
+		}
+		rec[c] = n
+	}
+	return bad
+}
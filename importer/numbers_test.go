@@ -0,0 +1,85 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNumberFormatPerSource(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	eu := write("eu.csv", "time,type,asset,amount,cost\n2024-01-01 10:00:00,buy,BTC,\"1,5\",\"20.000,25\"\n")
+	en := write("en.csv", "time,type,asset,amount,cost\n2024-01-01 10:00:00,buy,BTC,\"1,500\",\"20,000.25\"\n")
+	kraken := write("kraken.csv", "txid,refid,time,type,subtype,aclass,asset,wallet,amount,fee,balance\n"+
+		"L1,R1,2024-01-01 10:00:00,deposit,,currency,BTC,spot / main,1.500,0,1.500\n")
+	var cfg Config
+	if err := cfg.SetNumberFormats("eu.csv=eu,kraken.csv=eu"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		path, amount, cost string
+	}{
+		{eu, "1.5", "20000.25"},
+		{en, "1500", "20000.25"},
+		{kraken, "1.5", "0"},
+	} {
+		txs, issues, err := cfg.ParseFile(tt.path, nil, false)
+		if err != nil || len(issues) > 0 || len(txs) != 1 {
+			t.Fatalf("%s: got %d txs, issues %v, error %v", filepath.Base(tt.path), len(txs), issues, err)
+		}
+		if txs[0].Amount.String() != tt.amount || txs[0].Cost.String() != tt.cost {
+			t.Errorf("%s: got amount %s cost %s, want %s and %s", filepath.Base(tt.path), txs[0].Amount.String(), txs[0].Cost.String(), tt.amount, tt.cost)
+		}
+	}
+}
+
+func TestSetNumberFormats(t *testing.T) {
+	var cfg Config
+	for _, spec := range []string{"eu", "en, generic=eu", "a.csv=eu,b.csv=en"} {
+		if err := cfg.SetNumberFormats(spec); err != nil {
+			t.Errorf("SetNumberFormats(%q): %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"us", "kraken=eu", "a.csv=de"} {
+		if err := cfg.SetNumberFormats(spec); err == nil {
+			t.Errorf("SetNumberFormats(%q): no error", spec)
+		}
+	}
+}
+
+func TestStrictNumberFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eu.csv")
+	csv := "time,type,asset,amount,cost\n" +
+		"2024-01-01 10:00:00,buy,BTC,\"1,5\",\"20.000,25\"\n" +
+		"2024-02-01 10:00:00,sell,BTC,-1.5,30000\n"
+	if err := os.WriteFile(path, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Strict: true}
+	if err := cfg.SetNumberFormats("eu"); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := cfg.ParseFile(path, nil, false)
+	if err == nil || !strings.Contains(err.Error(), "eu.csv") || !strings.Contains(err.Error(), "line 3: column amount") {
+		t.Fatalf("got error %v, want one naming eu.csv, line 3 and column amount", err)
+	}
+	// without -strict the cell is read as written
+	cfg.Strict = false
+	txs, _, err := cfg.ParseFile(path, nil, false)
+	if err != nil || len(txs) != 2 || txs[1].Amount.String() != "-1.5" {
+		t.Fatalf("got %v, error %v", txs, err)
+	}
+}
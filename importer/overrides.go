@@ -0,0 +1,120 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cryptotax/engine"
+)
+
+// OverrideKey identifies an input row: the file name without its directory and the line number.
+type OverrideKey struct {
+	File string
+	Line int
+}
+
+// Overrides re-classify single input rows: the row's type is replaced before processing.
+type Overrides map[OverrideKey]string
+
+// KeyOf returns the override key of tx.
+func KeyOf(tx engine.Tx) OverrideKey {
+	return OverrideKey{File: filepath.Base(tx.SourceFile), Line: tx.SourceLine}
+}
+
+// LoadOverrides reads an overrides CSV: file,line,type.
+func LoadOverrides(path string) (Overrides, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	headerRow, err := r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return Overrides{}, nil
+		}
+		return nil, err
+	}
+	headerIdx := map[string]int{}
+	for i, h := range headerRow {
+		headerIdx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	out := Overrides{}
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]string)
+		for k, i := range headerIdx {
+			if i < len(row) {
+				record[k] = row[i]
+			}
+		}
+		line, _ := r.FieldPos(0)
+		file := strings.TrimSpace(engine.FirstNonEmpty(record, "file", "source_file"))
+		typ := strings.TrimSpace(engine.FirstNonEmpty(record, "type"))
+		n, err := strconv.Atoi(strings.TrimSpace(engine.FirstNonEmpty(record, "line", "source_line")))
+		if file == "" || typ == "" || err != nil {
+			return nil, fmt.Errorf("%s:%d: file, line and type are required", path, line)
+		}
+		out[OverrideKey{File: filepath.Base(file), Line: n}] = typ
+	}
+	return out, nil
+}
+
+// Apply sets the type of every overridden row and returns how many rows were changed.
+func (o Overrides) Apply(txs []engine.Tx) int {
+	n := 0
+	for i := range txs {
+		if t, ok := o[KeyOf(txs[i])]; ok {
+			txs[i].Type = t
+			n++
+		}
+	}
+	return n
+}
+
+// Save writes the overrides to path, sorted by file and line.
+func (o Overrides) Save(path string) error {
+	keys := make([]OverrideKey, 0, len(o))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].File != keys[j].File {
+			return keys[i].File < keys[j].File
+		}
+		return keys[i].Line < keys[j].Line
+	})
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	cw.Write([]string{"file", "line", "type"})
+	for _, k := range keys {
+		cw.Write([]string{k.File, strconv.Itoa(k.Line), o[k]})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	Register("shakepay", shakepayImporter{})
+}
+
+// shakepayImporter reads the Shakepay transaction history export.
+type shakepayImporter struct{}
+
+func (shakepayImporter) Detect(header map[string]int) bool {
+	_, debit := header["debit currency"]
+	_, asset := header["asset debited"]
+	return hasColumns(header, "amount debited", "amount credited") && (debit || asset)
+}
+
+func (shakepayImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseShakepayRows(in), nil
+}
+
+// parseShakepayRows maps Shakepay rows: the older export (Transaction Type, Date, Amount Debited,
+// Debit Currency, Amount Credited, Credit Currency, Buy / Sell Rate, Direction, Spot Rate,
+// Source / Destination, Blockchain Transaction ID) and the newer one (Date, Amount Debited, Asset
+// Debited, Amount Credited, Asset Credited, Market Value, Market Value Currency, Type, Spot Rate).
+// Purchases and sales cost the CAD debited or credited; ShakingSats, card cashback, referral and
+// other rewards are income at the spot rate; crypto received and sent are deposits and
+// withdrawals. Fiat funding and cashouts are skipped.
+func parseShakepayRows(in *Input) []engine.Tx {
+	wallet := "shakepay"
+	if len(in.DefaultWallets) > 0 && in.DefaultWallets[0] != "" {
+		wallet = in.DefaultWallets[0]
+	}
+	var txs []engine.Tx
+	for _, rr := range in.Rows {
+		t, err := engine.ParseTimeIn(engine.FirstNonEmpty(rr.Rec, "date"), in.Location)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		debit := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "amount debited")).Abs()
+		debitAsset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "debit currency", "asset debited")))
+		credit := engine.ParseDecimal(engine.FirstNonEmpty(rr.Rec, "amount credited")).Abs()
+		creditAsset := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rr.Rec, "credit currency", "asset credited")))
+		tx := engine.Tx{
+			Wallet:      wallet,
+			Time:        t,
+			Raw:         rr.Rec,
+			SourceFile:  filepath.Base(in.Path),
+			SourceLine:  rr.Line,
+			ReferenceID: engine.FirstNonEmpty(rr.Rec, "blockchain transaction id"),
+		}
+		if tx.ReferenceID == "" {
+			tx.ReferenceID = fmt.Sprintf("%s-%d", filepath.Base(in.Path), rr.Index)
+		}
+		cryptoIn := credit.IsPositive() && creditAsset != "" && !in.Assets.IsFiat(creditAsset)
+		cryptoOut := debit.IsPositive() && debitAsset != "" && !in.Assets.IsFiat(debitAsset)
+		typ := engine.NormalizeType(engine.FirstNonEmpty(rr.Rec, "transaction type", "type"))
+		switch {
+		case cryptoIn && debit.IsPositive() && in.Assets.IsFiat(debitAsset):
+			tx.Type = "buy"
+			tx.Commodity, tx.Amount = creditAsset, credit
+			tx.Currency, tx.Cost = debitAsset, debit
+		case cryptoOut && credit.IsPositive() && in.Assets.IsFiat(creditAsset):
+			tx.Type = "sell"
+			tx.Commodity, tx.Amount = debitAsset, debit.Neg()
+			tx.Currency, tx.Cost = creditAsset, credit
+		case cryptoIn && (strings.Contains(typ, "shakingsats") || strings.Contains(typ, "reward") ||
+			strings.Contains(typ, "cashback") || strings.Contains(typ, "referral") || strings.Contains(typ, "shakesquad")):
+			tx.Type = "income"
+			tx.Commodity, tx.Amount = creditAsset, credit
+			tx.Currency, tx.Cost = shakepayValue(rr.Rec, credit)
+		case cryptoIn:
+			tx.Type = "deposit"
+			tx.Commodity, tx.Amount = creditAsset, credit
+		case cryptoOut:
+			tx.Type = "withdrawal"
+			tx.Commodity, tx.Amount = debitAsset, debit.Neg()
+		default:
+			// fiat funding and cashouts
+			continue
+		}
+		if !tx.Cost.IsZero() {
+			tx.PricePerUnit = tx.Cost.Div(tx.Amount.Abs())
+		}
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+// shakepayValue is the CAD value of a reward: its market value, else amount x spot rate.
+func shakepayValue(rec map[string]string, amount decimal.Decimal) (string, decimal.Decimal) {
+	if v := engine.ParseDecimal(engine.FirstNonEmpty(rec, "market value")).Abs(); !v.IsZero() {
+		currency := strings.ToUpper(strings.TrimSpace(engine.FirstNonEmpty(rec, "market value currency")))
+		if currency == "" {
+			currency = "CAD"
+		}
+		return currency, v
+	}
+	return "CAD", engine.ParseDecimal(engine.FirstNonEmpty(rec, "spot rate")).Mul(amount)
+}
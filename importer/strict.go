@@ -0,0 +1,37 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+
+	"cryptotax/engine"
+)
+
+// timeColumns are the timestamp columns the importers read, in lookup order.
+var timeColumns = []string{"time", "date", "datetime", "utc_time", "time(utc)", "timestamp", "trade time"}
+
+// numericColumns are the amount, price and fee columns the importers read with ParseDecimal.
+var numericColumns = []string{"amount", "vol", "qty", "quantity", "change", "cost", "value", "price", "proceeds", "fee",
+	"balance", "realized pnl", "realized funding", "funding", "cash flow", "fee paid", "contracts", "basis", "to_amount",
+	// read from Tx.Raw by the engine
+	"cost_basis", "to_cost", "new_amount", "tax_withheld", "network_fee", "native amount", "native_amount", "fiat amount", "fiat_amount"}
+
+// checkRow reports a row without a parseable timestamp or with a malformed number.
+func checkRow(in *Input, rr Row) error {
+	ts := engine.FirstNonEmpty(rr.Rec, timeColumns...)
+	if ts == "" {
+		return fmt.Errorf("line %d: missing timestamp", rr.Line)
+	}
+	if _, err := engine.ParseTimeIn(ts, in.Location); err != nil {
+		return fmt.Errorf("line %d: %v", rr.Line, err)
+	}
+	for _, c := range numericColumns {
+		if err := engine.CheckDecimal(rr.Rec[c]); err != nil {
+			return fmt.Errorf("line %d: column %s: %v", rr.Line, c, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SetSourceTimezones configures the zones of timestamps without an offset from a comma-separated
+// list of SOURCE=ZONE entries, where SOURCE is a file name or a format name (kraken, binance,
+// generic, ...) and ZONE an IANA name such as Europe/Berlin. An entry without SOURCE= sets the
+// default for all sources. Timestamps without an offset are wall clock time in the zone of their
+// source: a zone set for the file name, else for the detected format, else the default (UTC
+// unless changed).
+func (c *Config) SetSourceTimezones(spec string) error {
+	c.defaultLocation = nil
+	c.sourceLocations = map[string]*time.Location{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		source, zone, ok := strings.Cut(entry, "=")
+		if !ok {
+			zone = source
+		}
+		loc, err := time.LoadLocation(strings.TrimSpace(zone))
+		if err != nil {
+			return fmt.Errorf("%q: %v", entry, err)
+		}
+		if !ok {
+			c.defaultLocation = loc
+			continue
+		}
+		c.sourceLocations[strings.ToLower(strings.TrimSpace(source))] = loc
+	}
+	return nil
+}
+
+func (c *Config) sourceLocation(path, format string) *time.Location {
+	if loc, ok := c.sourceLocations[strings.ToLower(filepath.Base(path))]; ok {
+		return loc
+	}
+	if loc, ok := c.sourceLocations[format]; ok {
+		return loc
+	}
+	if c.defaultLocation != nil {
+		return c.defaultLocation
+	}
+	return time.UTC
+}
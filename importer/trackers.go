@@ -0,0 +1,263 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"cryptotax/engine"
+)
+
+func init() {
+	Register("accointing", trackerImporter{format: "accointing", columns: trackerColumns{
+		time:     "date",
+		typ:      "transactiontype",
+		class:    "classification (optional)",
+		inAmount: "inbuyamount", inAsset: "inbuyasset",
+		outAmount: "outsellamount", outAsset: "outsellasset",
+		feeAmount: "feeamount (optional)", feeAsset: "feeasset (optional)",
+		id: "operationid (optional)",
+	}})
+	Register("zenledger", trackerImporter{format: "zenledger", columns: trackerColumns{
+		time:     "timestamp",
+		typ:      "type",
+		inAmount: "in amount", inAsset: "in currency",
+		outAmount: "out amount", outAsset: "out currency",
+		feeAmount: "fee amount", feeAsset: "fee currency",
+		inWallet: "exchange(optional)", outWallet: "exchange(optional)",
+		id: "txid",
+	}})
+	Register("taxbit", trackerImporter{format: "taxbit", columns: trackerColumns{
+		time:     "date and time",
+		typ:      "transaction type",
+		inAmount: "received quantity", inAsset: "received currency",
+		outAmount: "sent quantity", outAsset: "sent currency",
+		feeAmount: "fee", feeAsset: "fee currency",
+		inWallet: "receiving destination", outWallet: "sending source",
+		id: "exchange transaction id",
+	}})
+}
+
+// trackerColumns are the column names of a tax tool's transaction export; empty when the tool
+// has no such column.
+type trackerColumns struct {
+	time, typ, class                         string
+	inAmount, inAsset, outAmount, outAsset   string
+	feeAmount, feeAsset, inWallet, outWallet string
+	id                                       string
+}
+
+// trackerImporter reads the transaction exports of other crypto tax tools (Accointing,
+// ZenLedger, TaxBit), keeping the classification of each transaction as edited there.
+type trackerImporter struct {
+	format  string
+	columns trackerColumns
+}
+
+func (t trackerImporter) Detect(header map[string]int) bool {
+	c := t.columns
+	return hasColumns(header, c.time, c.typ, c.inAmount, c.inAsset, c.outAmount, c.outAsset)
+}
+
+func (t trackerImporter) Parse(in *Input) ([]engine.Tx, error) {
+	return parseTrackerRows(t.format, t.columns, in), nil
+}
+
+// trackerIncomeType maps the classification of an incoming transaction in a tax tool to the
+// engine type, "" for a plain deposit.
+func trackerIncomeType(class string) string {
+	switch {
+	case strings.Contains(class, "stak"):
+		return "staking"
+	case strings.Contains(class, "airdrop"):
+		return "airdrop"
+	case strings.Contains(class, "mining") || strings.Contains(class, "mined"):
+		return "mining"
+	case strings.Contains(class, "fork"):
+		return "fork"
+	case strings.Contains(class, "gift"):
+		return "gift_received"
+	case strings.Contains(class, "income") || strings.Contains(class, "interest") || strings.Contains(class, "reward") ||
+		strings.Contains(class, "bounty") || strings.Contains(class, "salary") || strings.Contains(class, "lending"):
+		return "income"
+	}
+	return ""
+}
+
+// trackerRemovalType maps the classification of an outgoing transaction to the engine type, ""
+// for a plain withdrawal.
+func trackerRemovalType(class string) string {
+	switch {
+	case strings.Contains(class, "gift"):
+		return "gift_sent"
+	case strings.Contains(class, "donat") || strings.Contains(class, "charity"):
+		return "donation"
+	case strings.Contains(class, "lost") || strings.Contains(class, "stolen") || strings.Contains(class, "theft") || strings.Contains(class, "hack"):
+		return "lost"
+	case strings.Contains(class, "payment") || strings.Contains(class, "spend") || strings.Contains(class, "expense") || strings.Contains(class, "purchase"):
+		return "spend"
+	case class == "fee" || strings.Contains(class, "fee"):
+		return "fee"
+	}
+	return ""
+}
+
+// parseTrackerRows maps the exports of other tax tools: Accointing (transactionType, date,
+// inBuyAmount, inBuyAsset, outSellAmount, outSellAsset, feeAmount, feeAsset, classification,
+// operationId), ZenLedger (Timestamp, Type, IN Amount, IN Currency, Out Amount, Out
+// Currency, Fee Amount, Fee Currency, Exchange, Txid) and TaxBit (Date and Time, Transaction Type,
+// Sent Quantity, Sent Currency, Sending Source, Received Quantity, Received Currency, Receiving
+// Destination, Fee, Fee Currency, Exchange Transaction ID). A row with both sides is a buy or
+// sale against fiat or a pair of trade legs; a row with one side is classified by its type or
+// classification (staking, airdrop, mining, income, gift, donation, lost, payment, fee) and is
+// otherwise a deposit or withdrawal. A TaxBit transfer from one named wallet to another moves the
+// coins with their basis. Rows classified as ignored are skipped.
+func parseTrackerRows(format string, cols trackerColumns, in *Input) []engine.Tx {
+	defaultWallet := format
+	if len(in.DefaultWallets) > 0 && in.DefaultWallets[0] != "" {
+		defaultWallet = in.DefaultWallets[0]
+	}
+	column := func(rec map[string]string, name string) string {
+		if name == "" {
+			return ""
+		}
+		return strings.TrimSpace(rec[name])
+	}
+	var txs []engine.Tx
+	for _, rr := range in.Rows {
+		rec := rr.Rec
+		t, err := engine.ParseTimeIn(column(rec, cols.time), in.Location)
+		if err != nil {
+			in.Skip(rr.Line, "%v", err)
+			continue
+		}
+		typ := engine.NormalizeType(column(rec, cols.typ))
+		class := engine.NormalizeType(column(rec, cols.class))
+		if class == "" {
+			class = typ
+		}
+		if strings.Contains(class, "ignore") {
+			if in.Verbose {
+				log.Printf("skipping %s row %d: classified %q", format, rr.Line, class)
+			}
+			continue
+		}
+		inAmount := engine.ParseDecimal(column(rec, cols.inAmount)).Abs()
+		inAsset := strings.ToUpper(column(rec, cols.inAsset))
+		outAmount := engine.ParseDecimal(column(rec, cols.outAmount)).Abs()
+		outAsset := strings.ToUpper(column(rec, cols.outAsset))
+		fee := engine.ParseDecimal(column(rec, cols.feeAmount)).Abs()
+		feeAsset := strings.ToUpper(column(rec, cols.feeAsset))
+		hasIn := inAmount.IsPositive() && inAsset != ""
+		hasOut := outAmount.IsPositive() && outAsset != ""
+		inWallet, outWallet := column(rec, cols.inWallet), column(rec, cols.outWallet)
+		wallet := defaultWallet
+		if hasIn && inWallet != "" {
+			wallet = inWallet
+		} else if outWallet != "" {
+			wallet = outWallet
+		}
+		base := engine.Tx{
+			Wallet:      wallet,
+			Time:        t,
+			Raw:         rec,
+			SourceFile:  filepath.Base(in.Path),
+			SourceLine:  rr.Line,
+			ReferenceID: column(rec, cols.id),
+		}
+		if base.ReferenceID == "" {
+			base.ReferenceID = fmt.Sprintf("%s-%d", filepath.Base(in.Path), rr.Index)
+		}
+		feeInCost := false
+		switch {
+		case hasIn && hasOut && inAsset == outAsset && !in.Assets.IsFiat(inAsset):
+			// TaxBit writes transfers with both sides in the same coin
+			tx := base
+			tx.Commodity = inAsset
+			switch {
+			case outWallet != "" && inWallet != "" && outWallet != inWallet:
+				tx.Type, tx.Amount = "transfer", inAmount
+				tx.PairedComment = outWallet
+			case strings.Contains(typ, "out") || strings.Contains(typ, "send") || strings.Contains(typ, "withdraw"):
+				tx.Type, tx.Amount = "withdrawal", outAmount.Neg()
+				if outWallet != "" {
+					tx.Wallet = outWallet
+				}
+			default:
+				tx.Type, tx.Amount = "deposit", inAmount
+			}
+			txs = append(txs, tx)
+		case hasIn && hasOut && in.Assets.IsFiat(outAsset) && !in.Assets.IsFiat(inAsset):
+			tx := base
+			tx.Type = "buy"
+			tx.Commodity, tx.Amount = inAsset, inAmount
+			tx.Currency, tx.Cost = outAsset, outAmount
+			if feeAsset == outAsset {
+				tx.Cost = tx.Cost.Add(fee)
+				feeInCost = true
+			}
+			tx.PricePerUnit = tx.Cost.Div(inAmount)
+			txs = append(txs, tx)
+		case hasIn && hasOut && in.Assets.IsFiat(inAsset) && !in.Assets.IsFiat(outAsset):
+			tx := base
+			tx.Type = "sell"
+			tx.Commodity, tx.Amount = outAsset, outAmount.Neg()
+			tx.Currency, tx.Cost = inAsset, inAmount
+			if feeAsset == inAsset {
+				tx.Fee = fee
+				feeInCost = true
+			}
+			tx.PricePerUnit = inAmount.Div(outAmount)
+			txs = append(txs, tx)
+		case hasIn && hasOut && !in.Assets.IsFiat(inAsset) && !in.Assets.IsFiat(outAsset):
+			out, got := base, base
+			out.Type, got.Type = "trade", "trade"
+			out.Commodity, out.Amount = outAsset, outAmount.Neg()
+			got.Commodity, got.Amount = inAsset, inAmount
+			if outWallet != "" {
+				out.Wallet = outWallet
+			}
+			txs = append(txs, out, got)
+		case hasIn && hasOut:
+			// fiat for fiat
+			continue
+		case hasIn && !in.Assets.IsFiat(inAsset):
+			tx := base
+			tx.Commodity, tx.Amount = inAsset, inAmount
+			tx.Type = trackerIncomeType(class)
+			if tx.Type == "" {
+				tx.Type = "deposit"
+			}
+			txs = append(txs, tx)
+		case hasOut && !in.Assets.IsFiat(outAsset):
+			tx := base
+			tx.Commodity, tx.Amount = outAsset, outAmount.Neg()
+			tx.Type = trackerRemovalType(class)
+			if tx.Type == "" {
+				tx.Type = "withdrawal"
+			}
+			txs = append(txs, tx)
+		default:
+			// fiat deposits and withdrawals, fee-only rows
+			if !(fee.IsPositive() && feeAsset != "" && !in.Assets.IsFiat(feeAsset)) {
+				continue
+			}
+		}
+		if fee.IsPositive() && feeAsset != "" && !feeInCost && !in.Assets.IsFiat(feeAsset) {
+			f := base
+			f.Type = "fee"
+			f.Commodity, f.Amount = feeAsset, fee.Neg()
+			if outWallet != "" {
+				f.Wallet = outWallet
+			}
+			f.ReferenceID += "-fee"
+			txs = append(txs, f)
+		}
+	}
+	return txs
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"cryptotax/engine"
+)
+
+// walletRule renames the wallet of transactions whose file name or account matches a pattern: a
+// glob (kraken-*.csv), or a regular expression between slashes (/^spot.*/).
+type walletRule struct {
+	account bool // match the wallet/account column instead of the file name
+	glob    string
+	re      *regexp.Regexp
+	wallet  string
+}
+
+func (r walletRule) match(s string) bool {
+	if r.re != nil {
+		return r.re.MatchString(s)
+	}
+	ok, _ := filepath.Match(r.glob, s)
+	return ok
+}
+
+// LoadWalletMap reads wallet mapping rules: match,pattern,wallet where match is "file" (the file
+// name, default) or "account" (the wallet or account column). The first matching rule names the
+// wallet; a file rule applies only to rows whose wallet fell back to the file name. An empty path
+// clears the mapping.
+func (c *Config) LoadWalletMap(path string) error {
+	c.walletRules = nil
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	headerRow, err := r.Read()
+	if err != nil {
+		return err
+	}
+	headerIdx := map[string]int{}
+	for i, h := range headerRow {
+		headerIdx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		record := make(map[string]string)
+		for k, i := range headerIdx {
+			if i < len(row) {
+				record[k] = row[i]
+			}
+		}
+		line, _ := r.FieldPos(0)
+		rule := walletRule{wallet: strings.TrimSpace(engine.FirstNonEmpty(record, "wallet", "name"))}
+		switch m := strings.ToLower(strings.TrimSpace(engine.FirstNonEmpty(record, "match", "source"))); m {
+		case "", "file", "filename":
+		case "account", "wallet":
+			rule.account = true
+		default:
+			return fmt.Errorf("%s:%d: unknown match %q (expected file or account)", path, line, m)
+		}
+		pattern := strings.TrimSpace(engine.FirstNonEmpty(record, "pattern"))
+		if pattern == "" || rule.wallet == "" {
+			return fmt.Errorf("%s:%d: pattern and wallet are required", path, line)
+		}
+		if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+			if rule.re, err = regexp.Compile(pattern[1 : len(pattern)-1]); err != nil {
+				return fmt.Errorf("%s:%d: %v", path, line, err)
+			}
+		} else if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%s:%d: pattern %q: %v", path, line, pattern, err)
+		} else {
+			rule.glob = pattern
+		}
+		c.walletRules = append(c.walletRules, rule)
+	}
+	return nil
+}
+
+// mapWallet returns the wallet name for wallet, read from the file at path, see Config.LoadWalletMap.
+func (c *Config) mapWallet(wallet, path string) string {
+	base := filepath.Base(path)
+	for _, r := range c.walletRules {
+		switch {
+		case r.account && r.match(wallet):
+			return r.wallet
+		case !r.account && wallet == base && r.match(base):
+			return r.wallet
+		}
+	}
+	return wallet
+}
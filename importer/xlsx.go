@@ -0,0 +1,351 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package importer
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// SetSheets selects the sheet read from .xlsx inputs from a comma-separated list of FILE=SHEET
+// entries; an entry without FILE= names the sheet of all workbooks. Workbooks without either are
+// read from their first sheet.
+func (c *Config) SetSheets(spec string) {
+	c.defaultSheet = ""
+	c.sourceSheets = map[string]string{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if file, sheet, ok := strings.Cut(entry, "="); ok {
+			c.sourceSheets[strings.ToLower(strings.TrimSpace(file))] = strings.TrimSpace(sheet)
+		} else {
+			c.defaultSheet = entry
+		}
+	}
+}
+
+func (c *Config) sheetOf(p string) string {
+	if s, ok := c.sourceSheets[strings.ToLower(filepath.Base(p))]; ok {
+		return s
+	}
+	return c.defaultSheet
+}
+
+// isXLSX reports whether an input is an Excel workbook rather than CSV.
+func isXLSX(p string) bool {
+	ext := strings.ToLower(filepath.Ext(p))
+	return ext == ".xlsx" || ext == ".xlsm"
+}
+
+// sheetReader returns the rows of a worksheet the way csv.Reader returns records.
+type sheetReader struct {
+	rows    [][]string
+	lines   []int
+	next    int
+	numbers map[int][]bool // row number -> whether each cell was stored as a number
+}
+
+func (s *sheetReader) Read() ([]string, error) {
+	if s.next >= len(s.rows) {
+		return nil, io.EOF
+	}
+	s.next++
+	return s.rows[s.next-1], nil
+}
+
+// FieldPos returns the spreadsheet row number of the record last read.
+func (s *sheetReader) FieldPos(field int) (int, int) {
+	if s.next == 0 {
+		return 0, 0
+	}
+	return s.lines[s.next-1], field + 1
+}
+
+// storedNumber reports whether the cell of column col on spreadsheet row line was stored as a
+// number (read as a plain number whatever the number format of the file).
+func (s *sheetReader) storedNumber(line, col int) bool {
+	n := s.numbers[line]
+	return col >= 0 && col < len(n) && n[col]
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRels struct {
+	Rels []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a shared or inline string: plain text, or runs of formatted text.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxSheetData struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R  string    `xml:"r,attr"`
+			T  string    `xml:"t,attr"`
+			S  int       `xml:"s,attr"`
+			V  string    `xml:"v"`
+			Is *xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX reads a worksheet of an Excel workbook (the one named want, else the first) as rows of
+// cell text. Numbers are written as plain numbers and cells formatted as dates as
+// "2006-01-02 15:04:05", so the rows parse like those of a CSV export.
+func readXLSX(p, want string) (*sheetReader, error) {
+	z, err := zip.OpenReader(p)
+	if err != nil {
+		return nil, fmt.Errorf("%s: not an xlsx workbook: %v", p, err)
+	}
+	defer z.Close()
+	files := map[string]*zip.File{}
+	for _, f := range z.File {
+		files[f.Name] = f
+	}
+	decode := func(name string, v interface{}) error {
+		f, ok := files[name]
+		if !ok {
+			return fmt.Errorf("%s: %s missing from workbook", p, name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		if err := xml.NewDecoder(rc).Decode(v); err != nil {
+			return fmt.Errorf("%s: %s: %v", p, name, err)
+		}
+		return nil
+	}
+
+	var wb xlsxWorkbook
+	if err := decode("xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+	if len(wb.Sheets) == 0 {
+		return nil, fmt.Errorf("%s: workbook has no sheets", p)
+	}
+	sheet := wb.Sheets[0]
+	if want != "" {
+		found := false
+		var names []string
+		for _, s := range wb.Sheets {
+			names = append(names, s.Name)
+			if strings.EqualFold(s.Name, want) {
+				sheet, found = s, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s: no sheet %q (sheets: %s)", p, want, strings.Join(names, ", "))
+		}
+	}
+	var rels xlsxRels
+	if err := decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	target := ""
+	for _, r := range rels.Rels {
+		if r.ID == sheet.RID {
+			target = r.Target
+		}
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+
+	var shared []string
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			SI []xlsxText `xml:"si"`
+		}
+		if err := decode("xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+		for _, si := range sst.SI {
+			shared = append(shared, si.String())
+		}
+	}
+	var dateStyles []bool
+	if _, ok := files["xl/styles.xml"]; ok {
+		var st xlsxStyles
+		if err := decode("xl/styles.xml", &st); err != nil {
+			return nil, err
+		}
+		custom := map[int]string{}
+		for _, f := range st.NumFmts {
+			custom[f.ID] = f.Code
+		}
+		for _, xf := range st.CellXfs {
+			dateStyles = append(dateStyles, isDateFormat(xf.NumFmtID, custom[xf.NumFmtID]))
+		}
+	}
+
+	var data xlsxSheetData
+	if err := decode(target, &data); err != nil {
+		return nil, err
+	}
+	sr := &sheetReader{numbers: map[int][]bool{}}
+	for i, row := range data.Rows {
+		var fields []string
+		var numeric []bool
+		for _, c := range row.Cells {
+			col := len(fields)
+			if c.R != "" {
+				col = xlsxColumnIndex(c.R)
+			}
+			for len(fields) < col {
+				fields = append(fields, "")
+			}
+			var v string
+			switch c.T {
+			case "s":
+				if n, err := strconv.Atoi(c.V); err == nil && n >= 0 && n < len(shared) {
+					v = shared[n]
+				}
+			case "inlineStr":
+				if c.Is != nil {
+					v = c.Is.String()
+				}
+			case "str", "e":
+				v = c.V
+			case "b":
+				v = "FALSE"
+				if c.V == "1" {
+					v = "TRUE"
+				}
+			default:
+				v = xlsxNumber(c.V, c.S < len(dateStyles) && dateStyles[c.S])
+				for len(numeric) < col {
+					numeric = append(numeric, false)
+				}
+				numeric = append(numeric, true)
+			}
+			fields = append(fields, v)
+		}
+		line := row.R
+		if line == 0 {
+			line = i + 1
+		}
+		sr.rows = append(sr.rows, fields)
+		sr.lines = append(sr.lines, line)
+		if len(numeric) > 0 {
+			sr.numbers[line] = numeric
+		}
+	}
+	return sr, nil
+}
+
+// xlsxColumnIndex returns the zero-based column of a cell reference ("A1" -> 0, "AB7" -> 27).
+func xlsxColumnIndex(ref string) int {
+	n := 0
+	for _, r := range strings.ToUpper(ref) {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		n = n*26 + int(r-'A'+1)
+	}
+	return n - 1
+}
+
+// isDateFormat reports whether a number format shows dates: the built-in formats 14-22 and
+// 45-47, or a custom format code with day, month or year fields.
+func isDateFormat(id int, code string) bool {
+	if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) {
+		return true
+	}
+	if code == "" {
+		return false
+	}
+	// drop quoted literals and [colors]/[locales] before looking for date fields
+	var b strings.Builder
+	quoted, bracket := false, false
+	for _, r := range code {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '[':
+			bracket = true
+		case r == ']':
+			bracket = false
+		case !bracket:
+			b.WriteRune(r)
+		}
+	}
+	return strings.ContainsAny(strings.ToLower(b.String()), "dmy")
+}
+
+// xlsxEpoch is day zero of the 1900 date system (Excel's serial 1 is 1900-01-01, counting the
+// non-existent 1900-02-29).
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// xlsxNumber writes the value of a numeric cell as the text a CSV export would hold: a date and
+// time for cells formatted as dates, else the plain number with the 15 significant digits Excel
+// shows (0.30000000000000004 is 0.3).
+func xlsxNumber(v string, date bool) string {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	if date {
+		ms := math.Round(f * 24 * 60 * 60 * 1000)
+		t := xlsxEpoch.Add(time.Duration(ms) * time.Millisecond).Round(time.Second)
+		return t.Format("2006-01-02 15:04:05")
+	}
+	d, err := decimal.NewFromString(strconv.FormatFloat(f, 'g', 15, 64))
+	if err != nil {
+		return v
+	}
+	return d.String()
+}
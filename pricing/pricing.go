@@ -0,0 +1,292 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+// Package pricing provides engine.PriceSource implementations: a local price file, the CoinGecko
+// API (with ECB rates for fiat) and an on-disk cache.
+package pricing
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+func priceKey(asset, currency string, at time.Time) string {
+	return strings.ToLower(strings.TrimSpace(asset)) + "|" + strings.ToLower(strings.TrimSpace(currency)) + "|" + at.UTC().Format("2006-01-02")
+}
+
+// FileSource serves prices from a CSV with columns asset,timestamp,price,currency.
+// The latest price on or before the requested day is used.
+type FileSource struct {
+	points map[string][]pricePoint // asset|currency -> sorted by time
+}
+
+type pricePoint struct {
+	Time  time.Time
+	Price decimal.Decimal
+}
+
+func LoadFile(path string) (*FileSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	headerRow, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	headerIdx := map[string]int{}
+	for i, h := range headerRow {
+		headerIdx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	src := &FileSource{points: map[string][]pricePoint{}}
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]string)
+		for k, i := range headerIdx {
+			if i < len(row) {
+				record[k] = row[i]
+			}
+		}
+		t, err := engine.ParseTimeGuess(engine.FirstNonEmpty(record, "timestamp", "time", "date"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		key := strings.ToLower(strings.TrimSpace(engine.FirstNonEmpty(record, "asset", "symbol"))) + "|" + strings.ToLower(strings.TrimSpace(engine.FirstNonEmpty(record, "currency")))
+		src.points[key] = append(src.points[key], pricePoint{Time: t, Price: engine.ParseDecimal(engine.FirstNonEmpty(record, "price"))})
+	}
+	for _, pts := range src.points {
+		sort.Slice(pts, func(i, j int) bool { return pts[i].Time.Before(pts[j].Time) })
+	}
+	return src, nil
+}
+
+func (p *FileSource) Price(asset, currency string, at time.Time) (decimal.Decimal, error) {
+	pts := p.points[strings.ToLower(strings.TrimSpace(asset))+"|"+strings.ToLower(strings.TrimSpace(currency))]
+	// first point strictly after the end of the requested day; the one before it is the latest usable price
+	dayEnd := at.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	i := sort.Search(len(pts), func(i int) bool { return !pts[i].Time.Before(dayEnd) })
+	if i == 0 {
+		return decimal.Zero, engine.ErrPriceNotFound
+	}
+	return pts[i-1].Price, nil
+}
+
+// CoinGecko looks up daily crypto prices from the public CoinGecko API and
+// fiat exchange rates from the ECB reference rates published by frankfurter.app.
+type CoinGecko struct {
+	Client *http.Client
+}
+
+// coinGeckoIDs maps common ticker symbols to CoinGecko coin ids.
+var coinGeckoIDs = map[string]string{
+	"btc":   "bitcoin",
+	"xbt":   "bitcoin",
+	"eth":   "ethereum",
+	"sol":   "solana",
+	"ada":   "cardano",
+	"dot":   "polkadot",
+	"xrp":   "ripple",
+	"ltc":   "litecoin",
+	"bch":   "bitcoin-cash",
+	"link":  "chainlink",
+	"matic": "matic-network",
+	"pol":   "polygon-ecosystem-token",
+	"atom":  "cosmos",
+	"avax":  "avalanche-2",
+	"doge":  "dogecoin",
+	"xlm":   "stellar",
+	"xmr":   "monero",
+	"usdt":  "tether",
+	"usdc":  "usd-coin",
+	"dai":   "dai",
+	"bnb":   "binancecoin",
+	"trx":   "tron",
+	"algo":  "algorand",
+	"near":  "near",
+}
+
+func (c *CoinGecko) Price(asset, currency string, at time.Time) (decimal.Decimal, error) {
+	a := strings.ToLower(strings.TrimSpace(asset))
+	cur := strings.ToLower(strings.TrimSpace(currency))
+	if engine.IsFiat(a) {
+		return c.fxRate(a, cur, at)
+	}
+	id, ok := coinGeckoIDs[a]
+	if !ok {
+		return decimal.Zero, fmt.Errorf("%w: no coingecko id for %s", engine.ErrPriceNotFound, asset)
+	}
+	u := fmt.Sprintf("https://api.coingecko.com/api/v3/coins/%s/history?date=%s&localization=false", id, at.UTC().Format("02-01-2006"))
+	var body struct {
+		MarketData struct {
+			CurrentPrice map[string]json.Number `json:"current_price"`
+		} `json:"market_data"`
+	}
+	if err := c.getJSON(u, &body); err != nil {
+		return decimal.Zero, err
+	}
+	v, ok := body.MarketData.CurrentPrice[cur]
+	if !ok {
+		return decimal.Zero, fmt.Errorf("%w: %s/%s on %s", engine.ErrPriceNotFound, asset, currency, at.Format("2006-01-02"))
+	}
+	return decimal.NewFromString(v.String())
+}
+
+func (c *CoinGecko) fxRate(from, to string, at time.Time) (decimal.Decimal, error) {
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+	u := fmt.Sprintf("https://api.frankfurter.app/%s?from=%s&to=%s", at.UTC().Format("2006-01-02"), strings.ToUpper(from), strings.ToUpper(to))
+	var body struct {
+		Rates map[string]json.Number `json:"rates"`
+	}
+	if err := c.getJSON(u, &body); err != nil {
+		return decimal.Zero, err
+	}
+	v, ok := body.Rates[strings.ToUpper(to)]
+	if !ok {
+		return decimal.Zero, fmt.Errorf("%w: %s/%s on %s", engine.ErrPriceNotFound, from, to, at.Format("2006-01-02"))
+	}
+	return decimal.NewFromString(v.String())
+}
+
+func (c *CoinGecko) getJSON(url string, v interface{}) error {
+	resp, err := c.Client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// CachedSource persists every lookup of the wrapped source on disk so repeated runs are
+// reproducible and don't hit external APIs again. Entries older than TTL are refreshed when
+// online (TTL <= 0 keeps entries forever). In offline mode only cached entries are used.
+type CachedSource struct {
+	next    engine.PriceSource // nil when there is no external source
+	path    string
+	ttl     time.Duration
+	offline bool
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+type cacheEntry struct {
+	Price   decimal.Decimal `json:"price"`
+	Fetched time.Time       `json:"fetched"`
+}
+
+func OpenCache(path string, next engine.PriceSource, ttl time.Duration, offline bool) (*CachedSource, error) {
+	c := &CachedSource{next: next, path: path, ttl: ttl, offline: offline, entries: map[string]cacheEntry{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, fmt.Errorf("price cache %s: %v", path, err)
+	}
+	return c, nil
+}
+
+func (c *CachedSource) Price(asset, currency string, at time.Time) (decimal.Decimal, error) {
+	key := priceKey(asset, currency, at)
+	e, ok := c.entries[key]
+	if ok && (c.offline || c.ttl <= 0 || time.Since(e.Fetched) < c.ttl) {
+		return e.Price, nil
+	}
+	if c.offline || c.next == nil {
+		if ok {
+			// stale, but the best we have
+			return e.Price, nil
+		}
+		if !c.offline {
+			return decimal.Zero, engine.ErrPriceNotFound
+		}
+		return decimal.Zero, fmt.Errorf("%w: %s/%s on %s", engine.ErrOffline, asset, currency, at.UTC().Format("2006-01-02"))
+	}
+	p, err := c.next.Price(asset, currency, at)
+	if err != nil {
+		if ok {
+			return e.Price, nil
+		}
+		return decimal.Zero, err
+	}
+	c.entries[key] = cacheEntry{Price: p, Fetched: time.Now().UTC()}
+	c.dirty = true
+	return p, nil
+}
+
+// Save writes the cache back to disk if any lookups were added.
+func (c *CachedSource) Save() error {
+	if !c.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// ChainSource asks each source in turn and returns the first price found.
+type ChainSource []engine.PriceSource
+
+func (c ChainSource) Price(asset, currency string, at time.Time) (decimal.Decimal, error) {
+	var lastErr error = engine.ErrPriceNotFound
+	for _, s := range c {
+		p, err := s.Price(asset, currency, at)
+		if err == nil {
+			return p, nil
+		}
+		if errors.Is(err, engine.ErrOffline) {
+			// fail fast: offline misses must not be masked by later sources
+			return decimal.Zero, err
+		}
+		lastErr = err
+	}
+	return decimal.Zero, lastErr
+}
+
+func DefaultCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "cryptotax", "prices.json")
+}
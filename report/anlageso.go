@@ -0,0 +1,73 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// germanFreigrenze returns the §23 EStG exemption limit for private sales in the given year:
+// total gains below it are tax-free, at or above it they are fully taxable.
+func germanFreigrenze(year int) decimal.Decimal {
+	if year >= 2024 {
+		return decimal.NewFromInt(1000)
+	}
+	return decimal.NewFromInt(600)
+}
+
+// writeAnlageSO prints the disposals in the layout of the "private Veräußerungsgeschäfte" section of
+// the German Anlage SO. Lots held for more than one year are exempt (§23 Abs. 1 Nr. 2 EStG).
+func writeAnlageSO(w io.Writer, state *engine.State, yearFilter int) error {
+	byYear := map[int][]engine.Disposal{}
+	for _, d := range state.Disposals {
+		y := d.Disposed.Year()
+		if yearFilter != 0 && y != yearFilter {
+			continue
+		}
+		byYear[y] = append(byYear[y], d)
+	}
+	years := []int{}
+	for y := range byYear {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	for _, y := range years {
+		fmt.Fprintf(w, "Anlage SO %d - Private Veräußerungsgeschäfte (andere Wirtschaftsgüter)\n", y)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "Nr\tWirtschaftsgut\tWallet\tAnschaffung\tVeräußerung\tMenge\tVeräußerungspreis\tAnschaffungskosten\tWerbungskosten\tGewinn/Verlust\tSteuerfrei (>1 Jahr)\t")
+		taxable := decimal.Zero
+		exempt := decimal.Zero
+		for i, d := range byYear[y] {
+			// held more than one year, on calendar dates and following -holding-rule
+			freeStr := "nein"
+			if d.Long {
+				freeStr = "ja"
+				exempt = exempt.Add(d.Gain)
+			} else {
+				taxable = taxable.Add(d.Gain)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+				i+1, d.Commodity, d.Wallet, d.Acquired.Format("02.01.2006"), d.Disposed.Format("02.01.2006"), d.Amount.String(),
+				d.Proceeds.StringFixed(2), d.CostBasis.StringFixed(2), d.Fee.StringFixed(2), d.Gain.StringFixed(2), freeStr)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		limit := germanFreigrenze(y)
+		fmt.Fprintf(w, "  Steuerpflichtiger Gewinn/Verlust: %s\n", taxable.StringFixed(2))
+		fmt.Fprintf(w, "  Steuerfreie Veräußerungen (>1 Jahr): %s\n", exempt.StringFixed(2))
+		if taxable.IsPositive() && taxable.LessThan(limit) {
+			fmt.Fprintf(w, "  Gewinn unter der Freigrenze von %s EUR: steuerfrei\n", limit.StringFixed(0))
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
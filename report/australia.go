@@ -0,0 +1,117 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// australianDiscount is the CGT discount for individuals on assets held for at least 12 months.
+var australianDiscount = decimal.NewFromFloat(0.5)
+
+// australianYear returns the income year t falls in, named by the year it ends: July 1, 2023 to
+// June 30, 2024 is income year 2024 ("2023-24").
+func australianYear(t time.Time) int {
+	if t.Month() >= time.July {
+		return t.Year() + 1
+	}
+	return t.Year()
+}
+
+// personalUse reports whether d disregards a personal use asset: coins spent on goods or services
+// with a basis below State.PersonalUseLimit.
+func personalUse(state *engine.State, d engine.Disposal) bool {
+	return state.PersonalUseLimit.IsPositive() && engine.IsSpendType(d.Type) && d.CostBasis.LessThan(state.PersonalUseLimit)
+}
+
+// writeAustralianCGT prints the CGT events of each income year (July to June, -year names the year
+// it ends) with the figures of item 18 of the tax return: capital losses of the year and net
+// capital losses of earlier years are applied to gains without the discount first, then the 50%
+// discount applies to the remaining gains on assets held for more than 12 months. A net capital
+// loss is carried forward to later years.
+func writeAustralianCGT(w io.Writer, state *engine.State, yearFilter int) error {
+	byYear := map[int][]engine.Disposal{}
+	for _, d := range state.Disposals {
+		y := australianYear(d.Disposed)
+		byYear[y] = append(byYear[y], d)
+	}
+	years := []int{}
+	for y := range byYear {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	carried := decimal.Zero // net capital losses of earlier years; earlier years outside -year still count
+	for _, y := range years {
+		discountable, other, losses, exempt := decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero
+		for _, d := range byYear[y] {
+			switch {
+			case personalUse(state, d):
+				exempt = exempt.Add(d.Gain)
+			case d.Gain.IsNegative():
+				losses = losses.Sub(d.Gain)
+			case d.Long:
+				discountable = discountable.Add(d.Gain)
+			default:
+				other = other.Add(d.Gain)
+			}
+		}
+		gross := discountable.Add(other)
+		priorApplied := decimal.Min(carried, decimal.Max(gross.Sub(losses), decimal.Zero))
+		apply := losses.Add(priorApplied)
+		carried = carried.Sub(priorApplied)
+		fromOther := decimal.Min(apply, other)
+		other = other.Sub(fromOther)
+		fromDiscountable := decimal.Min(apply.Sub(fromOther), discountable)
+		discountable = discountable.Sub(fromDiscountable)
+		if unused := apply.Sub(fromOther).Sub(fromDiscountable); unused.IsPositive() {
+			carried = carried.Add(unused)
+		}
+		discount := discountable.Mul(australianDiscount)
+		net := other.Add(discountable).Sub(discount)
+		if yearFilter != 0 && y != yearFilter {
+			continue
+		}
+		fmt.Fprintf(w, "Capital gains tax %d-%02d income year (1 July %d - 30 June %d), Australia\n", y-1, y%100, y-1, y)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "Nr\tAsset\tWallet\tAcquired\tDisposed\tAmount\tCapital proceeds\tCost base\tGain/loss\tHeld > 12 months\t")
+		for i, d := range byYear[y] {
+			held := "no"
+			switch {
+			case personalUse(state, d):
+				held = "personal use"
+			case d.Long:
+				held = "yes"
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+				i+1, d.Commodity, d.Wallet, d.Acquired.Format("02/01/2006"), d.Disposed.Format("02/01/2006"), d.Amount.String(),
+				d.Proceeds.StringFixed(2), d.CostBasis.Add(d.Fee).StringFixed(2), d.Gain.StringFixed(2), held)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "  Total current year capital gains (18H): %s\n", gross.StringFixed(2))
+		fmt.Fprintf(w, "  Capital losses of the year: %s\n", losses.StringFixed(2))
+		if !priorApplied.IsZero() {
+			fmt.Fprintf(w, "  Net capital losses of earlier years applied: %s\n", priorApplied.StringFixed(2))
+		}
+		fmt.Fprintf(w, "  CGT discount (50%%): %s\n", discount.StringFixed(2))
+		fmt.Fprintf(w, "  Net capital gain (18A): %s\n", net.StringFixed(2))
+		if carried.IsPositive() {
+			fmt.Fprintf(w, "  Net capital losses carried forward to later income years (18V): %s\n", carried.StringFixed(2))
+		}
+		if !exempt.IsZero() {
+			fmt.Fprintf(w, "  Personal use assets (basis below %s), disregarded: %s\n", state.PersonalUseLimit.StringFixed(0), exempt.StringFixed(2))
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
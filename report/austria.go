@@ -0,0 +1,92 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// austrianRate is the special rate (Sondersteuersatz, § 27a EStG) on gains from crypto assets
+// acquired from March 1, 2021 on (Neuvermögen).
+var austrianRate = decimal.NewFromFloat(0.275)
+
+// austrianSpeculationLimit is the Freigrenze of § 31 Abs. 3 EStG: speculative gains of a year
+// below it are tax-free.
+var austrianSpeculationLimit = decimal.NewFromInt(440)
+
+// isAltvermoegen reports whether a lot acquired at t is old holdings (Altvermögen): bought before
+// March 1, 2021, taxed under the old speculation rules instead of the special rate.
+func isAltvermoegen(t time.Time) bool {
+	return t.Before(time.Date(2021, time.March, 1, 0, 0, 0, 0, t.Location()))
+}
+
+// writeE1kv prints the disposals per year split the Austrian way: Neuvermögen gains and losses are
+// netted and taxed at 27.5% (Beilage E 1kv), Altvermögen is tax-free after one year and otherwise
+// a speculative transaction taxed at the progressive rate (E 1, § 31 EStG). Selling fees are not
+// deductible at the special rate and are added back. Crypto-to-crypto trades are not taxable, see
+// -jurisdiction at.
+func writeE1kv(w io.Writer, state *engine.State, yearFilter int) error {
+	byYear := map[int][]engine.Disposal{}
+	for _, d := range state.Disposals {
+		y := d.Disposed.Year()
+		if yearFilter != 0 && y != yearFilter {
+			continue
+		}
+		byYear[y] = append(byYear[y], d)
+	}
+	years := []int{}
+	for y := range byYear {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	for _, y := range years {
+		fmt.Fprintf(w, "Kryptowährungen %d (Österreich)\n", y)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "Nr\tKryptowährung\tWallet\tAnschaffung\tVeräußerung\tMenge\tErlös\tAnschaffungskosten\tSpesen\tGewinn/Verlust\tBestand\t")
+		neu, speculative, exempt := decimal.Zero, decimal.Zero, decimal.Zero
+		for i, d := range byYear[y] {
+			gain := d.Gain
+			var kind string
+			switch {
+			case !isAltvermoegen(d.Acquired):
+				kind = "neu"
+				gain = gain.Add(d.Fee)
+				neu = neu.Add(gain)
+			case state.IsLongTerm(d.Acquired, d.Disposed):
+				kind = "alt, steuerfrei"
+				exempt = exempt.Add(gain)
+			default:
+				kind = "alt, Spekulation"
+				speculative = speculative.Add(gain)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+				i+1, d.Commodity, d.Wallet, d.Acquired.Format("02.01.2006"), d.Disposed.Format("02.01.2006"), d.Amount.String(),
+				d.Proceeds.StringFixed(2), d.CostBasis.StringFixed(2), d.Fee.StringFixed(2), gain.StringFixed(2), kind)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "  Neuvermögen, Überschuss/Verlust (E 1kv): %s\n", neu.StringFixed(2))
+		if neu.IsPositive() {
+			fmt.Fprintf(w, "  Steuer zum Sondersteuersatz 27,5%%: %s\n", neu.Mul(austrianRate).StringFixed(2))
+		} else if neu.IsNegative() {
+			fmt.Fprintln(w, "  Verlust nur mit anderen Kapitaleinkünften des Jahres ausgleichbar, kein Vortrag")
+		}
+		fmt.Fprintf(w, "  Altvermögen, Spekulationsgeschäfte (E 1, § 31 EStG, Tarif): %s\n", speculative.StringFixed(2))
+		if speculative.IsPositive() && speculative.LessThan(austrianSpeculationLimit) {
+			fmt.Fprintf(w, "  Spekulationsgewinn unter der Freigrenze von %s EUR: steuerfrei\n", austrianSpeculationLimit.StringFixed(0))
+		}
+		fmt.Fprintf(w, "  Altvermögen, steuerfrei (> 1 Jahr): %s\n", exempt.StringFixed(2))
+		fmt.Fprintln(w)
+	}
+	return nil
+}
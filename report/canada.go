@@ -0,0 +1,79 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// canadianInclusion is the share of a net capital gain that is taxable.
+var canadianInclusion = decimal.NewFromFloat(0.5)
+
+// writeSchedule3 prints the dispositions of each year in the layout of Schedule 3: proceeds of
+// disposition, adjusted cost base, outlays and expenses and the gain or loss, with superficial
+// losses denied and the taxable capital gain (50%) for line 12700 (-jurisdiction ca). Dispositions
+// of one sale (several lots under FIFO) are shown as one line.
+func writeSchedule3(w io.Writer, state *engine.State, yearFilter int) error {
+	type sale struct {
+		d                                        engine.Disposal
+		amount, proceeds, acb, fee, gain, denied decimal.Decimal
+	}
+	byYear := map[int][]*sale{}
+	var last *sale
+	for _, d := range state.Disposals {
+		y := d.Disposed.Year()
+		if yearFilter != 0 && y != yearFilter {
+			continue
+		}
+		if last == nil || last.d.Disposed != d.Disposed || last.d.ReferenceID != d.ReferenceID || last.d.Wallet != d.Wallet || last.d.Commodity != d.Commodity {
+			last = &sale{d: d}
+			byYear[y] = append(byYear[y], last)
+		}
+		last.amount = last.amount.Add(d.Amount)
+		last.proceeds = last.proceeds.Add(d.Proceeds)
+		last.acb = last.acb.Add(d.CostBasis)
+		last.fee = last.fee.Add(d.Fee)
+		last.gain = last.gain.Add(d.Gain)
+		last.denied = last.denied.Add(d.DeniedLoss)
+	}
+	years := []int{}
+	for y := range byYear {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	for _, y := range years {
+		fmt.Fprintf(w, "Schedule 3 %d - Capital gains (or losses), crypto-assets\n", y)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "Nr\tDescription\tWallet\tDate\tProceeds of disposition\tAdjusted cost base\tOutlays and expenses\tGain (or loss)\tSuperficial loss denied\t")
+		net, denied := decimal.Zero, decimal.Zero
+		for i, s := range byYear[y] {
+			fmt.Fprintf(tw, "%d\t%s %s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+				i+1, s.amount.String(), s.d.Commodity, s.d.Wallet, s.d.Disposed.Format("2006-01-02"),
+				s.proceeds.StringFixed(2), s.acb.StringFixed(2), s.fee.StringFixed(2), s.gain.StringFixed(2), s.denied.StringFixed(2))
+			net = net.Add(s.gain)
+			denied = denied.Add(s.denied)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "  Total gain (or loss): %s\n", net.StringFixed(2))
+		if !denied.IsZero() {
+			fmt.Fprintf(w, "  Superficial losses denied (added to the adjusted cost base): %s\n", denied.StringFixed(2))
+		}
+		if net.IsPositive() {
+			fmt.Fprintf(w, "  Taxable capital gain (line 12700, 50%%): %s\n", net.Mul(canadianInclusion).StringFixed(2))
+		} else if net.IsNegative() {
+			fmt.Fprintf(w, "  Net capital loss (50%%, carried back 3 years or forward indefinitely): %s\n", net.Neg().Mul(canadianInclusion).StringFixed(2))
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
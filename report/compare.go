@@ -0,0 +1,97 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// MethodResult is the state after processing the transactions with one cost basis method.
+type MethodResult struct {
+	Method string
+	State  *engine.State
+}
+
+// PrintMethodComparison prints the short, long and total gains of every year (only yearFilter
+// when not 0) under each method side by side, followed by the totals over all years and the
+// method with the lowest total gain, colored by sign with color.
+func PrintMethodComparison(w io.Writer, results []MethodResult, yearFilter int, color bool) {
+	type sums struct{ short, long decimal.Decimal }
+	perMethod := make([]map[int]*sums, len(results))
+	yearSet := map[int]bool{}
+	for i, r := range results {
+		perMethod[i] = map[int]*sums{}
+		for y, wallets := range r.State.TaxYears {
+			if yearFilter != 0 && y != yearFilter {
+				continue
+			}
+			yearSet[y] = true
+			t := &sums{}
+			for _, commods := range wallets {
+				for _, g := range commods {
+					t.short = t.short.Add(g.Short)
+					t.long = t.long.Add(g.Long)
+				}
+			}
+			perMethod[i][y] = t
+		}
+	}
+	years := []int{}
+	for y := range yearSet {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	fmt.Fprintln(w, "Gains by cost basis method:")
+	if len(years) == 0 {
+		fmt.Fprintln(w, "  No disposals.")
+		return
+	}
+	lines := [][]string{{"Year", "Method", "Short", "Long", "Total"}}
+	signs := [][]int{make([]int, 5)}
+	add := func(label, method string, short, long decimal.Decimal) {
+		total := short.Add(long)
+		lines = append(lines, []string{label, method, formatMoney(short), formatMoney(long), formatMoney(total)})
+		signs = append(signs, []int{0, 0, short.Sign(), long.Sign(), total.Sign()})
+	}
+	for _, y := range years {
+		for i, r := range results {
+			label := ""
+			if i == 0 {
+				label = strconv.Itoa(y)
+			}
+			t := perMethod[i][y]
+			if t == nil {
+				t = &sums{}
+			}
+			add(label, r.Method, t.short, t.long)
+		}
+	}
+	best, bestTotal := -1, decimal.Zero
+	for i, r := range results {
+		short, long := decimal.Zero, decimal.Zero
+		for _, t := range perMethod[i] {
+			short = short.Add(t.short)
+			long = long.Add(t.long)
+		}
+		if len(years) > 1 {
+			label := ""
+			if i == 0 {
+				label = "Total"
+			}
+			add(label, r.Method, short, long)
+		}
+		if total := short.Add(long); best < 0 || total.LessThan(bestTotal) {
+			best, bestTotal = i, total
+		}
+	}
+	writeAligned(w, lines, signs, color)
+	fmt.Fprintf(w, "  Lowest total gain: %s (%s)\n", results[best].Method, formatMoney(bestTotal))
+}
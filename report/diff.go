@@ -0,0 +1,122 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// ReadResult reads a document written by -output json.
+func ReadResult(path string) (Result, error) {
+	var res Result
+	f, err := os.Open(path)
+	if err != nil {
+		return res, err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(&res); err != nil {
+		return res, fmt.Errorf("%s: %w", path, err)
+	}
+	return res, nil
+}
+
+// gainsFigures names the figures of engine.Gains compared by PrintResultDiff, in output order.
+var gainsFigures = []struct {
+	name string
+	get  func(g engine.Gains) decimal.Decimal
+}{
+	{"short", func(g engine.Gains) decimal.Decimal { return g.Short }},
+	{"long", func(g engine.Gains) decimal.Decimal { return g.Long }},
+	{"income", func(g engine.Gains) decimal.Decimal { return g.Income }},
+	{"margin fees", func(g engine.Gains) decimal.Decimal { return g.MarginFees }},
+	{"casualty loss", func(g engine.Gains) decimal.Decimal { return g.Casualty }},
+}
+
+// PrintResultDiff prints the figures of each year, wallet and asset that differ between the
+// results a and b (named nameA and nameB), with the change, followed by the change of each year's
+// totals, colored by sign with color. It returns the number of figures that changed.
+func PrintResultDiff(w io.Writer, a, b Result, nameA, nameB string, color bool) int {
+	if a.BaseCurrency != b.BaseCurrency {
+		fmt.Fprintf(w, "Warning: %s is in %q and %s in %q\n", nameA, a.BaseCurrency, nameB, b.BaseCurrency)
+	}
+	type key struct {
+		year              int
+		wallet, commodity string
+	}
+	gains := func(r Result, k key) engine.Gains {
+		if g := r.Years[k.year][k.wallet][k.commodity]; g != nil {
+			return *g
+		}
+		return engine.Gains{}
+	}
+	seen := map[key]bool{}
+	for _, r := range []Result{a, b} {
+		for y, wallets := range r.Years {
+			for wl, commods := range wallets {
+				for c := range commods {
+					seen[key{y, wl, c}] = true
+				}
+			}
+		}
+	}
+	keys := make([]key, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].year != keys[j].year {
+			return keys[i].year < keys[j].year
+		}
+		if keys[i].wallet != keys[j].wallet {
+			return keys[i].wallet < keys[j].wallet
+		}
+		return keys[i].commodity < keys[j].commodity
+	})
+
+	lines := [][]string{{"Year", "Wallet", "Asset", "Figure", "Before", "After", "Change"}}
+	signs := [][]int{make([]int, 7)}
+	add := func(year, wallet, commodity, figure string, before, after decimal.Decimal) {
+		change := after.Sub(before)
+		lines = append(lines, []string{year, wallet, commodity, figure, formatMoney(before), formatMoney(after), formatMoney(change)})
+		signs = append(signs, []int{0, 0, 0, 0, 0, 0, change.Sign()})
+	}
+	changed := 0
+	for i := 0; i < len(keys); {
+		year := keys[i].year
+		var totalA, totalB engine.Gains
+		for ; i < len(keys) && keys[i].year == year; i++ {
+			k := keys[i]
+			ga, gb := gains(a, k), gains(b, k)
+			addGains(&totalA, ga)
+			addGains(&totalB, gb)
+			for _, f := range gainsFigures {
+				if !f.get(ga).Equal(f.get(gb)) {
+					add(strconv.Itoa(year), k.wallet, k.commodity, f.name, f.get(ga), f.get(gb))
+					changed++
+				}
+			}
+		}
+		for _, f := range gainsFigures {
+			if !f.get(totalA).Equal(f.get(totalB)) {
+				add(strconv.Itoa(year), "Total", "", f.name, f.get(totalA), f.get(totalB))
+			}
+		}
+	}
+	fmt.Fprintf(w, "Changes from %s to %s:\n", nameA, nameB)
+	if changed == 0 {
+		fmt.Fprintln(w, "  No differences.")
+		return 0
+	}
+	writeColumns(w, lines, signs, 4, color)
+	return changed
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// frenchExemption is the yearly total of cessions (art. 150 VH bis CGI) up to which their gains
+// are exempt.
+var frenchExemption = decimal.NewFromInt(305)
+
+// frenchFlatRate is the prélèvement forfaitaire unique: 12.8% income tax and 17.2% social charges.
+var frenchFlatRate = decimal.NewFromFloat(0.30)
+
+// writeForm2086 prints the cessions of each year in the layout of form 2086: the global value of
+// the portfolio at each sale, the price and fees, the net total acquisition price and the gain
+// computed with the global portfolio method (-jurisdiction fr). The net result of the year goes to
+// box 3AN (gain) or 3BN (loss) of form 2042 C.
+func writeForm2086(w io.Writer, state *engine.State, yearFilter int) error {
+	byYear := map[int][]engine.Cession{}
+	for _, c := range state.Cessions {
+		y := c.Time.Year()
+		if yearFilter != 0 && y != yearFilter {
+			continue
+		}
+		byYear[y] = append(byYear[y], c)
+	}
+	years := []int{}
+	for y := range byYear {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	for _, y := range years {
+		fmt.Fprintf(w, "Formulaire 2086 %d - Cessions d'actifs numériques\n", y)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "N°\tDate\tActif\tPortefeuille\tValeur globale du portefeuille\tPrix de cession\tFrais\tPrix de cession net\tPrix total d'acquisition net\tFraction imputée\tPlus ou moins-value\t")
+		total, net := decimal.Zero, decimal.Zero
+		for i, c := range byYear[y] {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+				i+1, c.Time.Format("02/01/2006"), c.Commodity, c.Wallet, c.PortfolioValue.StringFixed(2), c.Proceeds.StringFixed(2), c.Fee.StringFixed(2),
+				c.Proceeds.Sub(c.Fee).StringFixed(2), c.AcquisitionCost.StringFixed(2), c.CostShare.StringFixed(2), c.Gain.StringFixed(2))
+			total = total.Add(c.Proceeds)
+			net = net.Add(c.Gain)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "  Total des cessions: %s\n", total.StringFixed(2))
+		fmt.Fprintf(w, "  Plus ou moins-value nette: %s\n", net.StringFixed(2))
+		switch {
+		case !total.GreaterThan(frenchExemption):
+			fmt.Fprintf(w, "  Cessions de l'année inférieures ou égales à %s EUR: exonérées\n", frenchExemption.StringFixed(0))
+		case net.IsPositive():
+			fmt.Fprintf(w, "  Déclaration 2042 C, case 3AN: %s\n", net.StringFixed(0))
+			fmt.Fprintf(w, "  Prélèvement forfaitaire unique (30%%): %s\n", net.Mul(frenchFlatRate).StringFixed(2))
+		case net.IsNegative():
+			fmt.Fprintf(w, "  Déclaration 2042 C, case 3BN: %s (moins-value non reportable)\n", net.Neg().StringFixed(0))
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
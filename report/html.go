@@ -0,0 +1,169 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"cryptotax/engine"
+)
+
+// htmlReportTemplate renders a self-contained page (inline CSS, no scripts) so it can be mailed as-is.
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Crypto tax report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 0.5em 0 1.5em 0; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.6em; }
+th { background: #f0f0f0; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+tr.total td { font-weight: bold; background: #fafafa; }
+details { margin: 0.3em 0 1em 0; }
+summary { cursor: pointer; }
+</style>
+</head>
+<body>
+<h1>Crypto tax report</h1>
+<p>Generated {{.Generated}}{{if .Currency}}. All amounts in {{.Currency}}{{end}}.</p>
+{{range .Years}}
+<h2>Year {{.Year}}</h2>
+<table>
+<tr><th>Wallet</th><th>Commodity</th><th>Short</th><th>Long</th><th>Income</th></tr>
+{{range .Rows}}<tr><td>{{.Wallet}}</td><td>{{.Commodity}}</td><td class="num">{{.Short}}</td><td class="num">{{.Long}}</td><td class="num">{{.Income}}</td></tr>
+{{end}}<tr class="total"><td colspan="2">Total</td><td class="num">{{.Total.Short}}</td><td class="num">{{.Total.Long}}</td><td class="num">{{.Total.Income}}</td></tr>
+</table>
+<h3>By commodity</h3>
+{{range .Commodities}}
+<details>
+<summary><strong>{{.Commodity}}</strong>: short {{.Short}}, long {{.Long}}, income {{.Income}} ({{len .Disposals}} disposals)</summary>
+{{if .Disposals}}<table>
+<tr><th>Wallet</th><th>Acquired</th><th>Disposed</th><th>Amount</th><th>Unit cost</th><th>Cost basis</th><th>Proceeds</th><th>Fee</th><th>Gain</th><th>Days held</th><th>Term</th><th>Ref</th></tr>
+{{range .Disposals}}<tr><td>{{.Wallet}}</td><td>{{.Acquired}}</td><td>{{.Disposed}}</td><td class="num">{{.Amount}}</td><td class="num">{{.UnitCost}}</td><td class="num">{{.CostBasis}}</td><td class="num">{{.Proceeds}}</td><td class="num">{{.Fee}}</td><td class="num">{{.Gain}}</td><td class="num">{{.Days}}</td><td>{{.Term}}</td><td>{{.Ref}}</td></tr>
+{{end}}</table>{{end}}
+</details>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+type htmlGainsRow struct {
+	Wallet, Commodity   string
+	Short, Long, Income string
+}
+
+type htmlDisposalRow struct {
+	Wallet, Acquired, Disposed, Amount, UnitCost, CostBasis, Proceeds, Fee, Gain, Days, Term, Ref string
+}
+
+type htmlCommodity struct {
+	Commodity           string
+	Short, Long, Income string
+	Disposals           []htmlDisposalRow
+}
+
+type htmlYear struct {
+	Year        int
+	Rows        []htmlGainsRow
+	Total       htmlGainsRow
+	Commodities []htmlCommodity
+}
+
+func writeHTML(w io.Writer, state *engine.State, yearFilter int) error {
+	data := struct {
+		Generated string
+		Currency  string
+		Years     []htmlYear
+	}{Generated: time.Now().Format("2006-01-02 15:04"), Currency: state.BaseCurrency}
+
+	years := []int{}
+	for y := range state.TaxYears {
+		if yearFilter == 0 || y == yearFilter {
+			years = append(years, y)
+		}
+	}
+	sort.Ints(years)
+	for _, y := range years {
+		hy := htmlYear{Year: y}
+		total := engine.Gains{}
+		perCommodity := map[string]*engine.Gains{}
+		wallets := []string{}
+		for w := range state.TaxYears[y] {
+			wallets = append(wallets, w)
+		}
+		sort.Strings(wallets)
+		for _, w := range wallets {
+			commods := []string{}
+			for c := range state.TaxYears[y][w] {
+				commods = append(commods, c)
+			}
+			sort.Strings(commods)
+			for _, c := range commods {
+				g := state.TaxYears[y][w][c]
+				hy.Rows = append(hy.Rows, htmlGainsRow{Wallet: w, Commodity: c, Short: g.Short.StringFixed(2), Long: g.Long.StringFixed(2), Income: g.Income.StringFixed(2)})
+				total.Short = total.Short.Add(g.Short)
+				total.Long = total.Long.Add(g.Long)
+				total.Income = total.Income.Add(g.Income)
+				pc, ok := perCommodity[c]
+				if !ok {
+					pc = &engine.Gains{}
+					perCommodity[c] = pc
+				}
+				pc.Short = pc.Short.Add(g.Short)
+				pc.Long = pc.Long.Add(g.Long)
+				pc.Income = pc.Income.Add(g.Income)
+			}
+		}
+		hy.Total = htmlGainsRow{Short: total.Short.StringFixed(2), Long: total.Long.StringFixed(2), Income: total.Income.StringFixed(2)}
+		disposals := map[string][]htmlDisposalRow{}
+		for _, d := range state.Disposals {
+			if d.Disposed.Year() != y {
+				continue
+			}
+			term := "short"
+			if d.Long {
+				term = "long"
+			}
+			disposals[d.Commodity] = append(disposals[d.Commodity], htmlDisposalRow{
+				Wallet:    d.Wallet,
+				Acquired:  d.Acquired.Format("2006-01-02"),
+				Disposed:  d.Disposed.Format("2006-01-02"),
+				Amount:    d.Amount.String(),
+				UnitCost:  d.UnitCost.StringFixed(2),
+				CostBasis: d.CostBasis.StringFixed(2),
+				Proceeds:  d.Proceeds.StringFixed(2),
+				Fee:       d.Fee.StringFixed(2),
+				Gain:      d.Gain.StringFixed(2),
+				Days:      strconv.FormatFloat(d.HoldingDays, 'f', 0, 64),
+				Term:      term,
+				Ref:       d.ReferenceID,
+			})
+		}
+		commods := []string{}
+		for c := range perCommodity {
+			commods = append(commods, c)
+		}
+		sort.Strings(commods)
+		for _, c := range commods {
+			g := perCommodity[c]
+			hy.Commodities = append(hy.Commodities, htmlCommodity{
+				Commodity: c,
+				Short:     g.Short.StringFixed(2),
+				Long:      g.Long.StringFixed(2),
+				Income:    g.Income.StringFixed(2),
+				Disposals: disposals[c],
+			})
+		}
+		data.Years = append(data.Years, hy)
+	}
+	return htmlReportTemplate.Execute(w, data)
+}
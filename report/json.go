@@ -0,0 +1,101 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"encoding/json"
+	"io"
+
+	"cryptotax/engine"
+)
+
+// Result is the document written by -output json.
+type Result struct {
+	BaseCurrency   string                                                  `json:"base_currency,omitempty"`
+	Years          map[int]map[string]map[string]*engine.Gains             `json:"years"`                    // year -> wallet -> commodity
+	Disposals      []engine.Disposal                                       `json:"disposals"`                // in processing order
+	Inventory      map[string]map[string][]engine.InventoryEntry           `json:"inventory"`                // remaining lots: wallet -> commodity
+	Derivatives    map[int]map[string]map[string]*engine.DerivativesResult `json:"derivatives,omitempty"`    // year -> wallet -> contract
+	Removals       []engine.Removal                                        `json:"removals,omitempty"`       // gifts sent, donations
+	Cessions       []engine.Cession                                        `json:"cessions,omitempty"`       // sales under the global portfolio method
+	ImportIssues   []engine.ImportIssue                                    `json:"import_issues,omitempty"`  // rows the importers skipped
+	Reconciliation []engine.BalanceResult                                  `json:"reconciliation,omitempty"` // -balances compared with the inventory
+	Withheld       []engine.Withholding                                    `json:"withheld,omitempty"`       // tax withheld at source (TDS)
+}
+
+// NewResult collects the results of state for yearFilter (0 = all years), as written by WriteJSON.
+func NewResult(state *engine.State, yearFilter int) Result {
+	res := Result{
+		BaseCurrency: state.BaseCurrency,
+		Years:        map[int]map[string]map[string]*engine.Gains{},
+		Disposals:    []engine.Disposal{},
+		Inventory:    map[string]map[string][]engine.InventoryEntry{},
+	}
+	for y, wallets := range state.TaxYears {
+		if yearFilter != 0 && y != yearFilter {
+			continue
+		}
+		res.Years[y] = wallets
+	}
+	for y, wallets := range state.Derivatives {
+		if yearFilter != 0 && y != yearFilter {
+			continue
+		}
+		if res.Derivatives == nil {
+			res.Derivatives = map[int]map[string]map[string]*engine.DerivativesResult{}
+		}
+		res.Derivatives[y] = wallets
+	}
+	for _, d := range state.Disposals {
+		if yearFilter != 0 && d.Disposed.Year() != yearFilter {
+			continue
+		}
+		res.Disposals = append(res.Disposals, d)
+	}
+	for _, r := range state.Removals {
+		if yearFilter != 0 && r.Time.Year() != yearFilter {
+			continue
+		}
+		res.Removals = append(res.Removals, r)
+	}
+	for _, wh := range state.Withheld {
+		if yearFilter != 0 && wh.Time.Year() != yearFilter {
+			continue
+		}
+		res.Withheld = append(res.Withheld, wh)
+	}
+	for _, c := range state.Cessions {
+		if yearFilter != 0 && c.Time.Year() != yearFilter {
+			continue
+		}
+		res.Cessions = append(res.Cessions, c)
+	}
+	res.ImportIssues = state.ImportIssues
+	for _, r := range state.Reconciliation {
+		if yearFilter != 0 && r.At.Year() != yearFilter {
+			continue
+		}
+		res.Reconciliation = append(res.Reconciliation, r)
+	}
+	for w, commods := range state.Inventories {
+		for c, lots := range commods {
+			if len(lots) == 0 {
+				continue
+			}
+			if _, ok := res.Inventory[w]; !ok {
+				res.Inventory[w] = map[string][]engine.InventoryEntry{}
+			}
+			res.Inventory[w][c] = lots
+		}
+	}
+	return res
+}
+
+func WriteJSON(w io.Writer, state *engine.State, yearFilter int) error {
+	res := NewResult(state, yearFilter)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// writeBeancount and writeLedger emit the processed journal as plain-text-accounting entries. Every
+// lot carries its cost and acquisition date, so sells reduce exactly the lots FIFO consumed and the
+// realized gain is booked to Income:Crypto:Gains:{Short,Long}. Entries after yearFilter are omitted.
+func writeBeancount(w io.Writer, state *engine.State, yearFilter int) error {
+	return writePlainTextAccounting(w, state, yearFilter, true)
+}
+
+func writeLedger(w io.Writer, state *engine.State, yearFilter int) error {
+	return writePlainTextAccounting(w, state, yearFilter, false)
+}
+
+func writePlainTextAccounting(w io.Writer, state *engine.State, yearFilter int, beancount bool) error {
+	dateFmt := "2006/01/02"
+	if beancount {
+		dateFmt = "2006-01-02"
+	}
+	account := func(parts ...string) string {
+		out := []string{}
+		for _, p := range parts {
+			out = append(out, ledgerAccountComponent(p))
+		}
+		return strings.Join(out, ":")
+	}
+	commodity := func(c string) string {
+		c = strings.ToUpper(strings.TrimSpace(c))
+		if beancount {
+			return beancountCommodity(c)
+		}
+		if strings.Trim(c, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return strconv.Quote(c)
+		}
+		return c
+	}
+	lotPosting := func(acct string, amount decimal.Decimal, c string, lot engine.InventoryEntry, cur string) string {
+		if beancount {
+			return fmt.Sprintf("  %s  %s %s {%s %s, %s}", acct, amount.String(), commodity(c), lot.UnitCost.String(), cur, lot.Time.Format(dateFmt))
+		}
+		return fmt.Sprintf("    %s  %s %s {%s %s} [%s]", acct, amount.String(), commodity(c), lot.UnitCost.String(), cur, lot.Time.Format(dateFmt))
+	}
+	posting := func(acct string, amount decimal.Decimal, cur string) string {
+		indent := "    "
+		if beancount {
+			indent = "  "
+		}
+		return fmt.Sprintf("%s%s  %s %s", indent, acct, amount.String(), cur)
+	}
+
+	if beancount && state.BaseCurrency != "" {
+		fmt.Fprintf(w, "option \"operating_currency\" \"%s\"\n\n", state.BaseCurrency)
+	}
+	opened := map[string]bool{}
+	for _, je := range state.Journal {
+		tx := je.Tx
+		if yearFilter != 0 && tx.Time.Year() > yearFilter {
+			break
+		}
+		cur := strings.ToUpper(strings.TrimSpace(tx.Currency))
+		if !state.Assets.IsFiat(cur) {
+			cur = engine.DefaultCurrency
+			if state.BaseCurrency != "" {
+				cur = state.BaseCurrency
+			}
+		}
+		lines := []string{}
+		used := []string{}
+		switch {
+		case len(je.Disposals) > 0:
+			proceeds := decimal.Zero
+			for i, lot := range je.Consumed {
+				d := je.Disposals[i]
+				acct := account("Assets", "Crypto", lot.Wallet, lot.Commodity)
+				lines = append(lines, lotPosting(acct, lot.Lot.Amount.Neg(), lot.Commodity, lot.Lot, cur))
+				used = append(used, acct)
+				term := "Short"
+				if d.Long {
+					term = "Long"
+				}
+				gainAcct := account("Income", "Crypto", "Gains", term)
+				lines = append(lines, posting(gainAcct, d.Gain.Neg(), cur))
+				used = append(used, gainAcct)
+				proceeds = proceeds.Add(d.Proceeds.Sub(d.Fee))
+			}
+			fiat := account("Assets", "Fiat", tx.Wallet)
+			lines = append(lines, posting(fiat, proceeds, cur))
+			used = append(used, fiat)
+		case len(je.Added) > 0:
+			total := decimal.Zero
+			for _, lot := range je.Consumed {
+				acct := account("Assets", "Crypto", lot.Wallet, lot.Commodity)
+				lines = append(lines, lotPosting(acct, lot.Lot.Amount.Neg(), lot.Commodity, lot.Lot, cur))
+				used = append(used, acct)
+			}
+			for _, lot := range je.Added {
+				acct := account("Assets", "Crypto", lot.Wallet, lot.Commodity)
+				lines = append(lines, lotPosting(acct, lot.Lot.Amount, lot.Commodity, lot.Lot, cur))
+				used = append(used, acct)
+				total = total.Add(lot.Lot.TotalCost)
+			}
+			if len(je.Consumed) == 0 {
+				// acquisitions are paid from fiat, income is booked against an income account
+				counter := account("Assets", "Fiat", tx.Wallet)
+				switch je.Handler {
+				case "income":
+					counter = account("Income", "Crypto", "Rewards")
+				case "reward", "staking", "deposit":
+					counter = account("Income", "Crypto", je.Handler)
+				}
+				lines = append(lines, posting(counter, total.Neg(), cur))
+				used = append(used, counter)
+			}
+		default:
+			continue
+		}
+		date := tx.Time.Format(dateFmt)
+		for _, acct := range used {
+			if opened[acct] {
+				continue
+			}
+			opened[acct] = true
+			if beancount {
+				fmt.Fprintf(w, "%s open %s\n", date, acct)
+			} else {
+				fmt.Fprintf(w, "account %s\n", acct)
+			}
+		}
+		if beancount {
+			fmt.Fprintf(w, "%s * %s %s\n", date, strconv.Quote(tx.Type), strconv.Quote(tx.Amount.String()+" "+tx.Commodity))
+			fmt.Fprintf(w, "  source: %s\n", strconv.Quote(tx.SourceFile))
+			if tx.ReferenceID != "" {
+				fmt.Fprintf(w, "  ref: %s\n", strconv.Quote(tx.ReferenceID))
+			}
+			if !tx.Fee.IsZero() {
+				fmt.Fprintf(w, "  fee: %s\n", strconv.Quote(tx.Fee.String()+" "+cur))
+			}
+		} else {
+			fmt.Fprintf(w, "%s * %s %s %s\n", date, tx.Type, tx.Amount.String(), tx.Commodity)
+			fmt.Fprintf(w, "    ; source: %s ref: %s fee: %s %s\n", tx.SourceFile, tx.ReferenceID, tx.Fee.String(), cur)
+		}
+		for _, l := range lines {
+			fmt.Fprintln(w, l)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// ledgerAccountComponent turns a wallet or commodity name into a valid account component:
+// letters, digits and dashes, starting with an upper-case letter.
+func ledgerAccountComponent(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range strings.TrimSpace(s) {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9':
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			b.WriteRune(r)
+		default:
+			if b.Len() > 0 && !upper {
+				b.WriteByte('-')
+			}
+			upper = true
+		}
+	}
+	out := strings.TrimRight(b.String(), "-")
+	if out == "" || out[0] < 'A' || out[0] > 'Z' {
+		out = "X" + out
+	}
+	return out
+}
+
+// beancountCommodity maps a symbol to beancount's commodity syntax (upper-case, starting with a letter).
+func beancountCommodity(c string) string {
+	var b strings.Builder
+	for _, r := range c {
+		if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' || r == '\'' {
+			b.WriteRune(r)
+		}
+	}
+	out := b.String()
+	if out == "" || out[0] < 'A' || out[0] > 'Z' {
+		out = "X" + out
+	}
+	if len(out) == 1 {
+		out += "X"
+	}
+	return out
+}
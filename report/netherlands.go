@@ -0,0 +1,115 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// box3Year holds the Box 3 parameters of a year: the deemed return on other assets (overige
+// bezittingen, where crypto belongs), the tax rate on that return and the tax-free allowance per
+// person (heffingsvrij vermogen), which applies to the total Box 3 wealth, not to crypto alone.
+type box3Year struct {
+	deemedReturn decimal.Decimal
+	rate         decimal.Decimal
+	allowance    decimal.Decimal
+}
+
+var box3Years = map[int]box3Year{
+	2022: {decimal.RequireFromString("0.0553"), decimal.RequireFromString("0.31"), decimal.NewFromInt(50650)},
+	2023: {decimal.RequireFromString("0.0617"), decimal.RequireFromString("0.32"), decimal.NewFromInt(57000)},
+	2024: {decimal.RequireFromString("0.0604"), decimal.RequireFromString("0.36"), decimal.NewFromInt(57000)},
+	2025: {decimal.RequireFromString("0.0588"), decimal.RequireFromString("0.36"), decimal.NewFromInt(57684)},
+}
+
+// writeBox3 values the holdings of every asset on the Box 3 reference date (peildatum), January 1
+// of each year, or on the -valuation-date dates when set. Realized gains are not taxed in the
+// Netherlands; the deemed return on the value is shown for the years with known parameters.
+func writeBox3(w io.Writer, state *engine.State, yearFilter int) error {
+	if state.Prices == nil {
+		return fmt.Errorf("the box3 report needs a price source (-pricefile or -priceapi)")
+	}
+	valuations := state.Valuations
+	if len(state.ValuationDates) == 0 {
+		// the holdings on January 1 are the holdings at the end of the previous year
+		years := []int{}
+		for y := range state.YearEnd {
+			years = append(years, y+1)
+		}
+		if yearFilter != 0 {
+			years = []int{yearFilter}
+		}
+		sort.Ints(years)
+		for _, y := range years {
+			at := time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+			if at.After(time.Now()) {
+				continue
+			}
+			valuations = append(valuations, engine.Valuation{At: at, Holdings: holdingsAsOf(state, y-1)})
+		}
+	}
+	for _, v := range valuations {
+		if yearFilter != 0 && v.At.Year() != yearFilter {
+			continue
+		}
+		amounts := map[string]decimal.Decimal{}
+		for _, commods := range v.Holdings {
+			for c, lots := range commods {
+				for _, lot := range lots {
+					amounts[c] = amounts[c].Add(lot.Amount)
+				}
+			}
+		}
+		assets := []string{}
+		for c, a := range amounts {
+			if !a.IsZero() {
+				assets = append(assets, c)
+			}
+		}
+		sort.Strings(assets)
+		fmt.Fprintf(w, "Box 3 %d - crypto-activa op %s (%s)\n", v.At.Year(), v.At.Format("02-01-2006"), state.PriceCurrency)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "Activum\tAantal\tKoers\tWaarde\t")
+		total := decimal.Zero
+		for _, c := range assets {
+			price, err := state.Prices.Price(c, state.PriceCurrency, v.At)
+			if err != nil {
+				if errors.Is(err, engine.ErrOffline) {
+					return err
+				}
+				state.Warnf("BOX 3: no price for %s/%s on %s: %v", c, state.PriceCurrency, v.At.Format("2006-01-02"), err)
+				fmt.Fprintf(tw, "%s\t%s\tn/a\tn/a\t\n", c, amounts[c].String())
+				continue
+			}
+			value := price.Mul(amounts[c])
+			total = total.Add(value)
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", c, amounts[c].String(), price.StringFixed(2), value.StringFixed(2))
+		}
+		fmt.Fprintf(tw, "Totaal\t\t\t%s\t\n", total.StringFixed(2))
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if p, ok := box3Years[v.At.Year()]; ok {
+			hundred := decimal.NewFromInt(100)
+			fmt.Fprintf(w, "  Forfaitair rendement overige bezittingen (%s%%): %s\n",
+				p.deemedReturn.Mul(hundred).StringFixed(2), total.Mul(p.deemedReturn).StringFixed(2))
+			fmt.Fprintf(w, "  Tarief box 3: %s%%; heffingsvrij vermogen %s per persoon over het totale box 3-vermogen\n",
+				p.rate.Mul(hundred).StringFixed(0), p.allowance.StringFixed(0))
+		}
+		if state.PriceCurrency != "EUR" {
+			fmt.Fprintf(w, "  Bedragen in %s; de aangifte wordt in euro gedaan (-base EUR)\n", state.PriceCurrency)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// writePDF renders the annual summary, income, disposal schedule and year-end holdings as a
+// printable PDF. Only a built-in monospace font is used, so no font files need to be embedded.
+func writePDF(w io.Writer, state *engine.State, yearFilter int) error {
+	const pageBreak = "\f"
+	lines := []string{}
+	add := func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	currency := ""
+	if state.BaseCurrency != "" {
+		currency = " (amounts in " + state.BaseCurrency + ")"
+	}
+	years := []int{}
+	for y := range state.TaxYears {
+		if yearFilter == 0 || y == yearFilter {
+			years = append(years, y)
+		}
+	}
+	sort.Ints(years)
+	for i, y := range years {
+		if i > 0 {
+			lines = append(lines, pageBreak)
+		}
+		add("Crypto tax report %d%s", y, currency)
+		add("Generated %s", time.Now().Format("2006-01-02"))
+		add("")
+		add("1. Summary")
+		add("  %-24s %-10s %16s %16s %16s", "Wallet", "Commodity", "Short", "Long", "Income")
+		wallets := []string{}
+		for wl := range state.TaxYears[y] {
+			wallets = append(wallets, wl)
+		}
+		sort.Strings(wallets)
+		total := engine.Gains{}
+		type incomeLine struct {
+			wallet, commodity string
+			amount            decimal.Decimal
+		}
+		income := []incomeLine{}
+		for _, wl := range wallets {
+			commods := []string{}
+			for c := range state.TaxYears[y][wl] {
+				commods = append(commods, c)
+			}
+			sort.Strings(commods)
+			for _, c := range commods {
+				g := state.TaxYears[y][wl][c]
+				add("  %-24s %-10s %16s %16s %16s", wl, c, g.Short.StringFixed(2), g.Long.StringFixed(2), g.Income.StringFixed(2))
+				total.Short = total.Short.Add(g.Short)
+				total.Long = total.Long.Add(g.Long)
+				total.Income = total.Income.Add(g.Income)
+				if !g.Income.IsZero() {
+					income = append(income, incomeLine{wl, c, g.Income})
+				}
+			}
+		}
+		add("  %-35s %16s %16s %16s", "Total", total.Short.StringFixed(2), total.Long.StringFixed(2), total.Income.StringFixed(2))
+		add("")
+		add("2. Income")
+		if len(income) == 0 {
+			add("  none")
+		}
+		for _, in := range income {
+			add("  %-24s %-10s %16s", in.wallet, in.commodity, in.amount.StringFixed(2))
+		}
+		add("")
+		add("3. Disposal schedule")
+		add("  %-20s %-8s %-10s %-10s %16s %14s %14s %10s %14s %5s %-5s", "Wallet", "Asset", "Acquired", "Disposed", "Amount", "Proceeds", "Cost", "Fee", "Gain", "Days", "Term")
+		n := 0
+		for _, d := range state.Disposals {
+			if d.Disposed.Year() != y {
+				continue
+			}
+			n++
+			term := "short"
+			if d.Long {
+				term = "long"
+			}
+			add("  %-20.20s %-8.8s %-10s %-10s %16s %14s %14s %10s %14s %5.0f %-5s", d.Wallet, d.Commodity, d.Acquired.Format("2006-01-02"), d.Disposed.Format("2006-01-02"),
+				d.Amount.String(), d.Proceeds.StringFixed(2), d.CostBasis.StringFixed(2), d.Fee.StringFixed(2), d.Gain.StringFixed(2), d.HoldingDays, term)
+		}
+		if n == 0 {
+			add("  none")
+		}
+		add("")
+		add("4. Holdings on %d-12-31", y)
+		add("  %-24s %-10s %20s %16s %12s", "Wallet", "Commodity", "Amount", "Cost basis", "Oldest lot")
+		held := state.YearEnd[y]
+		hw := []string{}
+		for wl := range held {
+			hw = append(hw, wl)
+		}
+		sort.Strings(hw)
+		for _, wl := range hw {
+			commods := []string{}
+			for c := range held[wl] {
+				commods = append(commods, c)
+			}
+			sort.Strings(commods)
+			for _, c := range commods {
+				amt, basis := decimal.Zero, decimal.Zero
+				lots := held[wl][c]
+				for _, lot := range lots {
+					amt = amt.Add(lot.Amount)
+					basis = basis.Add(lot.TotalCost)
+				}
+				add("  %-24s %-10s %20s %16s %12s", wl, c, amt.String(), basis.StringFixed(2), lots[0].Time.Format("2006-01-02"))
+			}
+		}
+		if len(hw) == 0 {
+			add("  none")
+		}
+	}
+
+	// paginate: landscape A4, Courier 8pt
+	const (
+		pageW, pageH = 842, 595
+		margin       = 36
+		fontSize     = 8
+		leading      = 10
+		perPage      = (pageH - 2*margin) / leading
+	)
+	pages := [][]string{{}}
+	for _, l := range lines {
+		cur := len(pages) - 1
+		if l == pageBreak || len(pages[cur]) >= perPage {
+			pages = append(pages, []string{})
+			cur++
+			if l == pageBreak {
+				continue
+			}
+		}
+		pages[cur] = append(pages[cur], l)
+	}
+
+	var buf strings.Builder
+	offsets := []int{}
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	buf.WriteString("%PDF-1.4\n")
+	// objects: 1 catalog, 2 pages, 3 font, then content+page per page
+	kids := []string{}
+	for i := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+2*i))
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	for i, page := range pages {
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL %d %d Td\n", fontSize, leading, margin, pageH-margin)
+		for _, l := range page {
+			fmt.Fprintf(&content, "(%s) '\n", pdfEscape(l))
+		}
+		fmt.Fprintf(&content, "ET\nBT /F1 %d Tf %d %d Td (Page %d of %d) Tj ET", fontSize, pageW-margin-80, margin/2, i+1, len(pages))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pageW, pageH, 4+2*i))
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// pdfEscape converts s to a WinAnsi (Latin-1 subset) PDF string literal body.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20:
+			b.WriteByte(' ')
+		case r < 0x80:
+			b.WriteRune(r)
+		case r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,301 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+// Package report renders a processed engine.State: text summaries, JSON and the file reports
+// selected with -report.
+package report
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// Spec is one -report FORMAT[=PATH] request; an empty Path writes to stdout.
+type Spec struct {
+	Format string
+	Path   string
+}
+
+type WriterFunc func(w io.Writer, state *engine.State, yearFilter int) error
+
+var Writers = map[string]WriterFunc{
+	"anlage-so":      writeAnlageSO,
+	"disposals":      writeDisposalsCSV,
+	"e1kv":           writeE1kv,
+	"2086":           writeForm2086,
+	"box3":           writeBox3,
+	"wertschriften":  writeWertschriften,
+	"modelo-721":     writeModelo721,
+	"cgt-schedule":   writeAustralianCGT,
+	"schedule-3":     writeSchedule3,
+	"audit":          writeAuditTrail,
+	"html":           writeHTML,
+	"pdf":            writePDF,
+	"xlsx":           writeXLSX,
+	"beancount":      writeBeancount,
+	"ledger":         writeLedger,
+	"harvest":        writeHarvest,
+	"holdings":       writeHoldings,
+	"lots":           writeLotsCSV,
+	"import-issues":  writeImportIssuesCSV,
+	"mining":         writeMining,
+	"ppdg-3r":        writePPDG3R,
+	"unrealized":     writeUnrealized,
+	"derivatives":    WriteDerivatives,
+	"donations":      WriteDonations,
+	"network-fees":   WriteNetworkFees,
+	"fees":           WriteFees,
+	"tds":            WriteWithholding,
+	"reconciliation": WriteReconciliation,
+	"transactions":   writeTransactionsCSV,
+	"portfolio":      writePortfolioCSV,
+}
+
+func WriteAll(specs []Spec, state *engine.State, yearFilter int) error {
+	for _, spec := range specs {
+		write := Writers[spec.Format]
+		if spec.Path == "" {
+			if err := write(os.Stdout, state, yearFilter); err != nil {
+				return err
+			}
+			continue
+		}
+		f, err := os.Create(spec.Path)
+		if err != nil {
+			return err
+		}
+		if err := write(f, state, yearFilter); err != nil {
+			f.Close()
+			return fmt.Errorf("%s report: %v", spec.Format, err)
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeImportIssuesCSV writes one row per skipped input row. Issues have no year, so yearFilter
+// is ignored.
+func writeImportIssuesCSV(w io.Writer, state *engine.State, yearFilter int) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"file", "line", "format", "reason"})
+	for _, is := range state.ImportIssues {
+		cw.Write([]string{is.File, strconv.Itoa(is.Line), is.Format, is.Reason})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeDisposalsCSV writes one row per consumed FIFO lot so the gain math can be checked by hand.
+func writeDisposalsCSV(w io.Writer, state *engine.State, yearFilter int) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"wallet", "commodity", "acquired", "disposed", "amount", "unit_cost", "cost_basis", "proceeds", "fee", "gain", "holding_days", "term", "source_files", "reference_id", "lot_id"})
+	for _, d := range state.Disposals {
+		if yearFilter != 0 && d.Disposed.Year() != yearFilter {
+			continue
+		}
+		term := "short"
+		if d.Long {
+			term = "long"
+		}
+		cw.Write([]string{
+			d.Wallet,
+			d.Commodity,
+			d.Acquired.Format(time.RFC3339),
+			d.Disposed.Format(time.RFC3339),
+			d.Amount.String(),
+			d.UnitCost.String(),
+			d.CostBasis.String(),
+			d.Proceeds.String(),
+			d.Fee.String(),
+			d.Gain.String(),
+			strconv.FormatFloat(d.HoldingDays, 'f', 1, 64),
+			term,
+			strings.Join(d.SourceFiles, ";"),
+			d.ReferenceID,
+			d.LotID,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeTransactionsCSV writes the normalized ledger as processed, one row per transaction in
+// processing order: the basis of the lots it acquired and, for disposals, the basis of the lots it
+// consumed, the proceeds, fees and gain, split into short and long term. These are the working
+// papers behind the summary; the per-lot detail is in the disposals and audit reports.
+func writeTransactionsCSV(w io.Writer, state *engine.State, yearFilter int) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "wallet", "type", "handler", "commodity", "amount", "currency", "cost", "fee",
+		"reference_id", "source_file", "source_line", "acquired_basis", "lots", "cost_basis", "proceeds",
+		"disposal_fee", "gain", "short_gain", "long_gain", "exempt_gain", "term"})
+	for _, je := range state.Journal {
+		tx := je.Tx
+		if yearFilter != 0 && tx.Time.Year() != yearFilter {
+			continue
+		}
+		acquired := decimal.Zero
+		for _, l := range je.Added {
+			acquired = acquired.Add(l.Lot.TotalCost)
+		}
+		var basis, proceeds, fee, short, long, exempt decimal.Decimal
+		terms := map[bool]bool{}
+		for _, d := range je.Disposals {
+			basis = basis.Add(d.CostBasis)
+			proceeds = proceeds.Add(d.Proceeds)
+			fee = fee.Add(d.Fee)
+			terms[d.Long] = true
+			if d.Long {
+				long = long.Add(d.Gain)
+			} else {
+				short = short.Add(d.Gain)
+			}
+			if d.Exempt {
+				exempt = exempt.Add(d.Gain)
+			}
+		}
+		row := []string{tx.Time.Format(time.RFC3339), tx.Wallet, tx.Type, je.Handler, tx.Commodity, tx.Amount.String(),
+			tx.Currency, tx.Cost.String(), tx.Fee.String(), tx.ReferenceID, tx.SourceFile, "", "", "", "", "", "", "", "", "", "", ""}
+		if tx.SourceLine > 0 {
+			row[11] = strconv.Itoa(tx.SourceLine)
+		}
+		if len(je.Added) > 0 {
+			row[12] = acquired.String()
+		}
+		if n := len(je.Disposals); n > 0 {
+			term := "short"
+			if len(terms) > 1 {
+				term = "mixed"
+			} else if terms[true] {
+				term = "long"
+			}
+			copy(row[13:], []string{strconv.Itoa(n), basis.String(), proceeds.String(), fee.String(),
+				short.Add(long).String(), short.String(), long.String(), exempt.String(), term})
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writePortfolioCSV writes the holdings over time, one CSV row per asset held at the end of each
+// day or week (State.SeriesInterval) with the amount and basis over all wallets and, with a price
+// source, the price and value on that day. Days without transactions repeat the day before.
+func writePortfolioCSV(w io.Writer, state *engine.State, yearFilter int) error {
+	if state.SeriesInterval == "" {
+		return fmt.Errorf("the portfolio report needs the holdings series (State.SeriesInterval)")
+	}
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"date", "commodity", "amount", "basis", "price", "value"})
+	unpriced := map[string]bool{}
+	for _, p := range state.Series {
+		if yearFilter != 0 && p.Date.Year() != yearFilter {
+			continue
+		}
+		commods := make([]string, 0, len(p.Holdings))
+		for c := range p.Holdings {
+			commods = append(commods, c)
+		}
+		sort.Strings(commods)
+		for _, c := range commods {
+			h := p.Holdings[c]
+			row := []string{p.Date.Format("2006-01-02"), c, h.Amount.String(), h.Basis.StringFixed(2), "", ""}
+			if state.Prices != nil {
+				price, err := state.Prices.Price(c, state.PriceCurrency, p.Date)
+				if err != nil {
+					if errors.Is(err, engine.ErrOffline) {
+						return err
+					}
+					if !unpriced[c] {
+						state.Warnf("PORTFOLIO: no price for %s/%s on %s: %v (value left empty)", c, state.PriceCurrency, p.Date.Format("2006-01-02"), err)
+					}
+					unpriced[c] = true
+				} else {
+					row[4] = price.String()
+					row[5] = price.Mul(h.Amount).StringFixed(2)
+				}
+			}
+			cw.Write(row)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeLotsCSV writes the open lots with their IDs as of Dec 31 of yearFilter (the end of the data
+// when 0), oldest first per wallet and commodity: the IDs to name in a -lots instruction file.
+func writeLotsCSV(w io.Writer, state *engine.State, yearFilter int) error {
+	held := holdingsAsOf(state, yearFilter)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"wallet", "commodity", "lot_id", "acquired", "amount", "unit_cost", "total_cost", "source_file", "source_line", "reference_id"})
+	wallets := make([]string, 0, len(held))
+	for wl := range held {
+		wallets = append(wallets, wl)
+	}
+	sort.Strings(wallets)
+	for _, wl := range wallets {
+		commods := make([]string, 0, len(held[wl]))
+		for c := range held[wl] {
+			commods = append(commods, c)
+		}
+		sort.Strings(commods)
+		for _, c := range commods {
+			for _, lot := range held[wl][c] {
+				if !lot.Amount.IsPositive() {
+					continue
+				}
+				file, line := "", ""
+				if len(lot.SourceFiles) > 0 {
+					file = lot.SourceFiles[0]
+				}
+				if lot.SourceLine > 0 {
+					line = strconv.Itoa(lot.SourceLine)
+				}
+				cw.Write([]string{wl, c, lot.ID, lot.Time.Format(time.RFC3339), lot.Amount.String(), lot.UnitCost.String(), lot.TotalCost.String(), file, line, lot.ReferenceID})
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeAuditTrail writes one CSV row per consumed lot linking the gain to the exact source rows
+// (file, line and reference id) of both the acquisition and the disposal.
+func writeAuditTrail(w io.Writer, state *engine.State, yearFilter int) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"wallet", "commodity", "amount", "cost_basis", "proceeds", "fee", "gain", "term",
+		"acquired", "acquired_file", "acquired_line", "acquired_ref",
+		"disposed", "disposed_file", "disposed_line", "disposed_ref"})
+	line := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.Itoa(n)
+	}
+	for _, d := range state.Disposals {
+		if yearFilter != 0 && d.Disposed.Year() != yearFilter {
+			continue
+		}
+		term := "short"
+		if d.Long {
+			term = "long"
+		}
+		cw.Write([]string{d.Wallet, d.Commodity, d.Amount.String(), d.CostBasis.String(), d.Proceeds.String(), d.Fee.String(), d.Gain.String(), term,
+			d.Acquired.Format(time.RFC3339), d.AcquiredFile, line(d.AcquiredLine), d.AcquiredRef,
+			d.Disposed.Format(time.RFC3339), d.DisposedFile, line(d.DisposedLine), d.ReferenceID})
+	}
+	cw.Flush()
+	return cw.Error()
+}
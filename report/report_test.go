@@ -0,0 +1,95 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cryptotax/engine"
+	"cryptotax/importer"
+	"cryptotax/report"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got with testdata/name, or rewrites the file with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := "testdata/" + name
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs:\n--- got\n%s--- want\n%s", name, got, want)
+	}
+}
+
+// TestWritersGolden writes reports of the sample. The holdings, audit and beancount goldens are
+// the output of the command line before the packages were split; disposals adds the lot_id
+// column added since.
+func TestWritersGolden(t *testing.T) {
+	var cfg importer.Config
+	txs, _, err := cfg.ParseFile("../testdata/sample.csv", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := engine.NewState(false, nil, nil)
+	if err := engine.ProcessTransactions(state, importer.MergeAndSort([][]engine.Tx{txs})); err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range []string{"disposals", "holdings", "audit", "beancount"} {
+		var b strings.Builder
+		if err := report.Writers[format](&b, state, 0); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		checkGolden(t, format+".golden", b.String())
+	}
+}
+
+// TestAnlageSOAnniversary sells on the anniversary of the purchase, which is short term under the
+// default holding rule and long term with -holding-rule at-least; the Anlage SO table must agree.
+func TestAnlageSOAnniversary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anniversary.csv")
+	csv := "time,type,asset,amount,cost,currency,wallet\n" +
+		"2023-03-01 10:00:00,buy,BTC,1,10000,EUR,w\n" +
+		"2024-03-01 12:00:00,sell,BTC,-1,20000,EUR,w\n"
+	if err := os.WriteFile(path, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var cfg importer.Config
+	txs, _, err := cfg.ParseFile(path, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ rule, taxable, free string }{
+		{"more-than", "10000.00", "nein"},
+		{"at-least", "0.00", "ja"},
+	} {
+		state := engine.NewState(false, nil, nil)
+		state.HoldingRule = tt.rule
+		if err := engine.ProcessTransactions(state, importer.MergeAndSort([][]engine.Tx{txs})); err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		if err := report.Writers["anlage-so"](&b, state, 2024); err != nil {
+			t.Fatal(err)
+		}
+		out := b.String()
+		if !strings.Contains(out, "Steuerpflichtiger Gewinn/Verlust: "+tt.taxable+"\n") || !strings.Contains(out, " "+tt.free+"\n") {
+			t.Errorf("%s: got\n%s", tt.rule, out)
+		}
+	}
+}
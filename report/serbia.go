@@ -0,0 +1,115 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// serbianRate is the tax on capital gains from digital assets (čl. 72a Zakona o porezu na dohodak
+// građana). The acquisition price of digital assets is not indexed and there is no exemption after
+// a holding period.
+var serbianRate = decimal.NewFromFloat(0.15)
+
+// serbianLossYears is how many years after the year it arose a capital loss can still be offset
+// against capital gains (čl. 79).
+const serbianLossYears = 5
+
+// writePPDG3R prints the disposals grouped by the two half-year periods of the PPDG-3R return
+// (January-June, due July 30; July-December, due January 30), with the tax at 15% of the gains
+// left after offsetting the losses of the same and earlier periods. Crypto-to-crypto trades are not
+// transfers of digital assets in Serbia, see -jurisdiction rs.
+func writePPDG3R(w io.Writer, state *engine.State, yearFilter int) error {
+	type period struct{ year, half int }
+	byPeriod := map[period][]engine.Disposal{}
+	for _, d := range state.Disposals {
+		p := period{d.Disposed.Year(), 1}
+		if d.Disposed.Month() > time.June {
+			p.half = 2
+		}
+		byPeriod[p] = append(byPeriod[p], d)
+	}
+	periods := []period{}
+	for p := range byPeriod {
+		periods = append(periods, p)
+	}
+	sort.Slice(periods, func(i, j int) bool {
+		if periods[i].year != periods[j].year {
+			return periods[i].year < periods[j].year
+		}
+		return periods[i].half < periods[j].half
+	})
+	// losses not yet offset, oldest first; periods outside -year still carry their losses forward
+	type carry struct {
+		year   int
+		amount decimal.Decimal
+	}
+	var carries []carry
+	for _, p := range periods {
+		gains, losses := decimal.Zero, decimal.Zero
+		for _, d := range byPeriod[p] {
+			if d.Gain.IsNegative() {
+				losses = losses.Sub(d.Gain)
+			} else {
+				gains = gains.Add(d.Gain)
+			}
+		}
+		net := gains.Sub(losses)
+		carried := decimal.Zero
+		for i := range carries {
+			if !net.IsPositive() {
+				break
+			}
+			if carries[i].year < p.year-serbianLossYears || carries[i].amount.IsZero() {
+				continue
+			}
+			use := decimal.Min(carries[i].amount, net)
+			carries[i].amount = carries[i].amount.Sub(use)
+			carried = carried.Add(use)
+			net = net.Sub(use)
+		}
+		if net.IsNegative() {
+			carries = append(carries, carry{p.year, net.Neg()})
+			net = decimal.Zero
+		}
+		if yearFilter != 0 && p.year != yearFilter {
+			continue
+		}
+		start, end, due := fmt.Sprintf("01.01.%d", p.year), fmt.Sprintf("30.06.%d", p.year), fmt.Sprintf("30.07.%d", p.year)
+		if p.half == 2 {
+			start, end, due = fmt.Sprintf("01.07.%d", p.year), fmt.Sprintf("31.12.%d", p.year), fmt.Sprintf("30.01.%d", p.year+1)
+		}
+		fmt.Fprintf(w, "PPDG-3R %d, %d. polugodište (%s-%s, rok za podnošenje %s) - prenos digitalne imovine\n", p.year, p.half, start, end, due)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "Rb\tDigitalna imovina\tNovčanik\tDatum sticanja\tDatum prenosa\tKoličina\tProdajna cena\tNabavna cena\tTroškovi\tDobitak/gubitak\t")
+		for i, d := range byPeriod[p] {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+				i+1, d.Commodity, d.Wallet, d.Acquired.Format("02.01.2006"), d.Disposed.Format("02.01.2006"), d.Amount.String(),
+				d.Proceeds.StringFixed(2), d.CostBasis.StringFixed(2), d.Fee.StringFixed(2), d.Gain.StringFixed(2))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "  Kapitalni dobitak: %s\n", gains.StringFixed(2))
+		fmt.Fprintf(w, "  Kapitalni gubitak: %s\n", losses.StringFixed(2))
+		if !carried.IsZero() {
+			fmt.Fprintf(w, "  Prebijeni gubitak iz ranijih perioda: %s\n", carried.StringFixed(2))
+		}
+		fmt.Fprintf(w, "  Poreska osnovica: %s\n", net.StringFixed(2))
+		fmt.Fprintf(w, "  Porez (%s%%): %s\n", serbianRate.Mul(decimal.NewFromInt(100)).StringFixed(0), net.Mul(serbianRate).StringFixed(2))
+		if state.BaseCurrency != "RSD" {
+			fmt.Fprintf(w, "  Iznosi su u %s; prijava se podnosi u dinarima (-base RSD)\n", state.PriceCurrency)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
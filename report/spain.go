@@ -0,0 +1,111 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// modelo721Threshold is the value of virtual currencies held abroad on December 31 above which
+// Modelo 721 is due; a later return is only due when the value grew by modelo721Increase since the
+// last one.
+var (
+	modelo721Threshold = decimal.NewFromInt(50000)
+	modelo721Increase  = decimal.NewFromInt(20000)
+)
+
+// writeModelo721 prints the holdings of each wallet (custodian) and asset on December 31 of every
+// year at that day's price, as declared in Modelo 721 (virtual currencies held abroad). Only
+// custodians outside Spain are declared, not self-custody wallets: select them with -wallet.
+func writeModelo721(w io.Writer, state *engine.State, yearFilter int) error {
+	if state.Prices == nil {
+		return fmt.Errorf("the modelo-721 report needs a price source (-pricefile or -priceapi)")
+	}
+	years := []int{}
+	for y := range state.YearEnd {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	var last decimal.Decimal
+	declared := false
+	for _, y := range years {
+		at := time.Date(y, 12, 31, 23, 59, 59, 0, time.UTC)
+		held := state.YearEnd[y]
+		wallets := []string{}
+		for wl := range held {
+			wallets = append(wallets, wl)
+		}
+		sort.Strings(wallets)
+		var out io.Writer = io.Discard
+		if yearFilter == 0 || y == yearFilter {
+			out = w
+		}
+		fmt.Fprintf(out, "Modelo 721 %d - monedas virtuales situadas en el extranjero a 31/12/%d (%s)\n", y, y, state.PriceCurrency)
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "Custodio\tMoneda virtual\tUnidades\tCotización\tValoración\t")
+		total := decimal.Zero
+		for _, wl := range wallets {
+			commods := []string{}
+			for c := range held[wl] {
+				commods = append(commods, c)
+			}
+			sort.Strings(commods)
+			for _, c := range commods {
+				amount := decimal.Zero
+				for _, lot := range held[wl][c] {
+					amount = amount.Add(lot.Amount)
+				}
+				if amount.IsZero() {
+					continue
+				}
+				price, err := state.Prices.Price(c, state.PriceCurrency, at)
+				if err != nil {
+					if errors.Is(err, engine.ErrOffline) {
+						return err
+					}
+					if out == w {
+						state.Warnf("MODELO 721: no price for %s/%s on %s: %v", c, state.PriceCurrency, at.Format("2006-01-02"), err)
+					}
+					fmt.Fprintf(tw, "%s\t%s\t%s\tn/a\tn/a\t\n", wl, c, amount.String())
+					continue
+				}
+				value := price.Mul(amount)
+				total = total.Add(value)
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n", wl, c, amount.String(), price.StringFixed(2), value.StringFixed(2))
+			}
+		}
+		fmt.Fprintf(tw, "Total\t\t\t\t%s\t\n", total.StringFixed(2))
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		due := total.GreaterThan(modelo721Threshold)
+		if declared {
+			due = total.Sub(last).GreaterThan(modelo721Increase)
+		}
+		if due {
+			declared = true
+			last = total
+			fmt.Fprintf(out, "  Obligación de declarar: sí (plazo 1 de enero - 31 de marzo de %d)\n", y+1)
+		} else if declared {
+			fmt.Fprintf(out, "  Obligación de declarar: no (incremento de %s sobre la última declaración, límite %s)\n",
+				total.Sub(last).StringFixed(2), modelo721Increase.StringFixed(0))
+		} else {
+			fmt.Fprintf(out, "  Obligación de declarar: no (valor total hasta %s)\n", modelo721Threshold.StringFixed(0))
+		}
+		if state.PriceCurrency != "EUR" {
+			fmt.Fprintf(out, "  Importes en %s; el modelo se presenta en euros (-base EUR)\n", state.PriceCurrency)
+		}
+		fmt.Fprintln(out)
+	}
+	return nil
+}
@@ -0,0 +1,718 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// Output helpers
+
+// PrintSummary prints the gains and income of each year per wallet and asset; color colors gains
+// green and losses red (for a terminal).
+func PrintSummary(state *engine.State, yearFilter int, walletFilter []string, commodityFilter []string, color bool) {
+	// Build set for wallet filter
+	wset := map[string]bool{}
+	for _, w := range walletFilter {
+		wset[w] = true
+	}
+	// Build set for commodity filter (case-insensitive)
+	cset := map[string]bool{}
+	for _, c := range commodityFilter {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c != "" {
+			cset[c] = true
+		}
+	}
+
+	var netting map[int]*engine.Netting
+	if (state.Rules != nil || state.LossNetting != "") && len(wset) == 0 && len(cset) == 0 {
+		netting = engine.NetGains(state)
+	}
+	rules := "Netting"
+	if state.Rules != nil {
+		rules = state.Rules.Name() + " rules"
+	}
+
+	years := []int{}
+	for y := range state.TaxYears {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	printed := 0
+	overall := map[string]*engine.Gains{} // wallet -> all years
+	for _, y := range years {
+		if yearFilter != 0 && y != yearFilter {
+			continue
+		}
+		printed++
+		fmt.Printf("Year %d:\n", y)
+		wallets := []string{}
+		for w := range state.TaxYears[y] {
+			if len(wset) > 0 {
+				if !wset[w] {
+					continue
+				}
+			}
+			wallets = append(wallets, w)
+		}
+		sort.Strings(wallets)
+		rows := []gainsRow{}
+		for _, w := range wallets {
+			commods := []string{}
+			for c := range state.TaxYears[y][w] {
+				// apply commodity filter if provided
+				if len(cset) > 0 {
+					if !cset[strings.ToLower(c)] {
+						continue
+					}
+				}
+				commods = append(commods, c)
+			}
+			sort.Strings(commods)
+			for _, c := range commods {
+				rows = append(rows, gainsRow{wallet: w, commodity: c, gains: *state.TaxYears[y][w][c]})
+				if overall[w] == nil {
+					overall[w] = &engine.Gains{}
+				}
+				addGains(overall[w], *state.TaxYears[y][w][c])
+			}
+		}
+		writeGainsTable(os.Stdout, rows, color)
+		if n := netting[y]; n != nil && state.LossNetting == "us" {
+			fmt.Printf("  %s: net short-term=%s net long-term=%s carried losses used=%s net capital gain=%s loss deducted from ordinary income=%s loss carried forward=%s (short-term %s, long-term %s)\n",
+				rules, n.Short.StringFixed(2), n.Long.StringFixed(2), n.Carried.StringFixed(2), n.Taxable.StringFixed(2), n.Ordinary.StringFixed(2),
+				n.CarryOut.StringFixed(2), n.CarryOutShort.StringFixed(2), n.CarryOutLong.StringFixed(2))
+		} else if n != nil {
+			fmt.Printf("  %s: taxable gains=%s losses=%s carried losses used=%s net=%s loss carried forward=%s\n",
+				rules, n.Gains.StringFixed(2), n.Losses.StringFixed(2), n.Carried.StringFixed(2), n.Taxable.StringFixed(2), n.CarryOut.StringFixed(2))
+		}
+	}
+	if printed > 1 && len(overall) > 0 {
+		// grand total over the years, per wallet
+		fmt.Println("All years:")
+		wallets := []string{}
+		for w := range overall {
+			wallets = append(wallets, w)
+		}
+		sort.Strings(wallets)
+		rows := []gainsRow{}
+		for _, w := range wallets {
+			rows = append(rows, gainsRow{wallet: w, gains: *overall[w]})
+		}
+		writeGainsTable(os.Stdout, rows, color)
+	}
+}
+
+// periodLabel names the reporting period t falls in: "2024-Q1", "2024-H2" or "2024-03".
+func periodLabel(t time.Time, period string) string {
+	switch period {
+	case "monthly":
+		return t.Format("2006-01")
+	case "quarterly":
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+	case "semiannual":
+		return fmt.Sprintf("%d-H%d", t.Year(), (int(t.Month())-1)/6+1)
+	}
+	return strconv.Itoa(t.Year())
+}
+
+// PrintPeriodSummary prints realized gains and income per period instead of per calendar year,
+// built from the individual disposals and income receipts. color is as in PrintSummary.
+func PrintPeriodSummary(state *engine.State, yearFilter int, period string, color bool) {
+	printGainsBy(state, yearFilter, func(t time.Time) string { return periodLabel(t, period) }, color)
+}
+
+// PrintRangeSummary prints realized gains and income of the transactions between from and to
+// (-from/-to, dates in the tax time zone) as one period. color is as in PrintSummary.
+func PrintRangeSummary(state *engine.State, from, to string, color bool) {
+	if from == "" {
+		from = "start"
+	}
+	if to == "" {
+		to = "end of data"
+	}
+	label := from + " to " + to
+	printGainsBy(state, 0, func(time.Time) string { return label }, color)
+}
+
+// printGainsBy prints the disposals and income receipts summed per period label.
+func printGainsBy(state *engine.State, yearFilter int, label func(time.Time) string, color bool) {
+	periods := map[string]map[string]map[string]*engine.Gains{} // period -> wallet -> commodity
+	slot := func(t time.Time, wallet, commodity string) *engine.Gains {
+		p := label(t)
+		if _, ok := periods[p]; !ok {
+			periods[p] = map[string]map[string]*engine.Gains{}
+		}
+		if _, ok := periods[p][wallet]; !ok {
+			periods[p][wallet] = map[string]*engine.Gains{}
+		}
+		if _, ok := periods[p][wallet][commodity]; !ok {
+			periods[p][wallet][commodity] = &engine.Gains{}
+		}
+		return periods[p][wallet][commodity]
+	}
+	for _, d := range state.Disposals {
+		if yearFilter != 0 && d.Disposed.Year() != yearFilter {
+			continue
+		}
+		g := slot(d.Disposed, d.Wallet, d.Commodity)
+		if d.Long {
+			g.Long = g.Long.Add(d.Gain)
+		} else {
+			g.Short = g.Short.Add(d.Gain)
+		}
+	}
+	for _, in := range state.Incomes {
+		if yearFilter != 0 && in.Time.Year() != yearFilter {
+			continue
+		}
+		g := slot(in.Time, in.Wallet, in.Commodity)
+		g.Income = g.Income.Add(in.Value)
+	}
+	labels := []string{}
+	for p := range periods {
+		labels = append(labels, p)
+	}
+	sort.Strings(labels)
+	for _, p := range labels {
+		fmt.Printf("Period %s:\n", p)
+		wallets := []string{}
+		for w := range periods[p] {
+			wallets = append(wallets, w)
+		}
+		sort.Strings(wallets)
+		rows := []gainsRow{}
+		for _, w := range wallets {
+			commods := []string{}
+			for c := range periods[p][w] {
+				commods = append(commods, c)
+			}
+			sort.Strings(commods)
+			for _, c := range commods {
+				rows = append(rows, gainsRow{wallet: w, commodity: c, gains: *periods[p][w][c]})
+			}
+		}
+		writeGainsTable(os.Stdout, rows, color)
+	}
+}
+
+// writeMining reports mining income (income rows of type mining) per year with the expenses
+// netted against it, for users who report mining as self-employment.
+func writeMining(w io.Writer, state *engine.State, yearFilter int) error {
+	type yearTotals struct {
+		income   decimal.Decimal
+		expenses map[string]decimal.Decimal
+	}
+	years := map[int]*yearTotals{}
+	get := func(y int) *yearTotals {
+		if _, ok := years[y]; !ok {
+			years[y] = &yearTotals{expenses: map[string]decimal.Decimal{}}
+		}
+		return years[y]
+	}
+	for _, in := range state.Incomes {
+		if engine.NormalizeType(in.Type) != "mining" || (yearFilter != 0 && in.Time.Year() != yearFilter) {
+			continue
+		}
+		yt := get(in.Time.Year())
+		yt.income = yt.income.Add(in.Value)
+	}
+	for _, ex := range state.MiningExpenses {
+		if yearFilter != 0 && ex.Time.Year() != yearFilter {
+			continue
+		}
+		yt := get(ex.Time.Year())
+		yt.expenses[ex.Category] = yt.expenses[ex.Category].Add(ex.Amount)
+	}
+	ys := []int{}
+	for y := range years {
+		ys = append(ys, y)
+	}
+	sort.Ints(ys)
+	for _, y := range ys {
+		yt := years[y]
+		fmt.Fprintf(w, "Mining %d:\n", y)
+		fmt.Fprintf(w, "  income=%s\n", yt.income.StringFixed(2))
+		cats := []string{}
+		for c := range yt.expenses {
+			cats = append(cats, c)
+		}
+		sort.Strings(cats)
+		total := decimal.Zero
+		for _, c := range cats {
+			fmt.Fprintf(w, "  expense %s=%s\n", c, yt.expenses[c].StringFixed(2))
+			total = total.Add(yt.expenses[c])
+		}
+		fmt.Fprintf(w, "  expenses=%s net=%s\n", total.StringFixed(2), yt.income.Sub(total).StringFixed(2))
+	}
+	return nil
+}
+
+// WriteDerivatives prints the futures/perpetuals section: PnL, funding and fees per contract
+// with a yearly net total.
+func WriteDerivatives(w io.Writer, state *engine.State, yearFilter int) error {
+	years := []int{}
+	for y := range state.Derivatives {
+		if yearFilter == 0 || y == yearFilter {
+			years = append(years, y)
+		}
+	}
+	sort.Ints(years)
+	for _, y := range years {
+		fmt.Fprintf(w, "Derivatives %d:\n", y)
+		total := decimal.Zero
+		wallets := []string{}
+		for wl := range state.Derivatives[y] {
+			wallets = append(wallets, wl)
+		}
+		sort.Strings(wallets)
+		for _, wl := range wallets {
+			fmt.Fprintf(w, "  Wallet: %s\n", wl)
+			contracts := []string{}
+			for c := range state.Derivatives[y][wl] {
+				contracts = append(contracts, c)
+			}
+			sort.Strings(contracts)
+			for _, c := range contracts {
+				d := state.Derivatives[y][wl][c]
+				fmt.Fprintf(w, "    %s (%s): pnl=%s funding=%s fees=%s net=%s\n",
+					c, d.Asset, d.PnL.StringFixed(2), d.Funding.StringFixed(2), d.Fees.StringFixed(2), d.Net().StringFixed(2))
+				total = total.Add(d.Net())
+			}
+		}
+		fmt.Fprintf(w, "  net=%s\n", total.StringFixed(2))
+	}
+	return nil
+}
+
+// WriteDonations lists donations per year with the fair market value donated (the deductible
+// amount in most regimes) and the basis of the donated lots.
+func WriteDonations(w io.Writer, state *engine.State, yearFilter int) error {
+	byYear := map[int][]engine.Removal{}
+	for _, r := range state.Removals {
+		if r.Type != "donation" || (yearFilter != 0 && r.Time.Year() != yearFilter) {
+			continue
+		}
+		byYear[r.Time.Year()] = append(byYear[r.Time.Year()], r)
+	}
+	years := []int{}
+	for y := range byYear {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	for _, y := range years {
+		fmt.Fprintf(w, "Donations %d:\n", y)
+		value, basis := decimal.Zero, decimal.Zero
+		for _, r := range byYear[y] {
+			fmt.Fprintf(w, "  %s %s %s %s: value=%s basis=%s\n", r.Time.Format("2006-01-02"), r.Wallet, r.Amount.String(), r.Commodity, r.Value.StringFixed(2), r.CostBasis.StringFixed(2))
+			value = value.Add(r.Value)
+			basis = basis.Add(r.CostBasis)
+		}
+		fmt.Fprintf(w, "  total value=%s basis=%s\n", value.StringFixed(2), basis.StringFixed(2))
+	}
+	return nil
+}
+
+// WriteNetworkFees lists per year the network fees of transfers between own wallets that left at
+// basis (-transfer-fee deductible); the basis is the deductible cost.
+func WriteNetworkFees(w io.Writer, state *engine.State, yearFilter int) error {
+	byYear := map[int][]engine.Removal{}
+	for _, r := range state.Removals {
+		if r.Type != "network fee" || (yearFilter != 0 && r.Time.Year() != yearFilter) {
+			continue
+		}
+		byYear[r.Time.Year()] = append(byYear[r.Time.Year()], r)
+	}
+	years := []int{}
+	for y := range byYear {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	for _, y := range years {
+		fmt.Fprintf(w, "Network fees %d:\n", y)
+		basis := decimal.Zero
+		for _, r := range byYear[y] {
+			fmt.Fprintf(w, "  %s %s %s %s: basis=%s value=%s\n", r.Time.Format("2006-01-02"), r.Wallet, r.Amount.String(), r.Commodity, r.CostBasis.StringFixed(2), r.Value.StringFixed(2))
+			basis = basis.Add(r.CostBasis)
+		}
+		fmt.Fprintf(w, "  total deductible=%s\n", basis.StringFixed(2))
+	}
+	return nil
+}
+
+// WriteFees prints per year the fees paid per wallet (exchange) by kind: trading fees, network
+// fees and margin fees, valued when charged. Several jurisdictions let each kind be deducted
+// separately; the fees are already in the gains, this only adds them up.
+func WriteFees(w io.Writer, state *engine.State, yearFilter int) error {
+	kinds := []string{engine.FeeTrading, engine.FeeNetwork, engine.FeeMargin}
+	byYear := map[int]map[string]map[string]decimal.Decimal{}
+	unvalued := map[int]int{}
+	for _, f := range state.Fees {
+		y := f.Time.Year()
+		if yearFilter != 0 && y != yearFilter {
+			continue
+		}
+		if _, ok := byYear[y]; !ok {
+			byYear[y] = map[string]map[string]decimal.Decimal{}
+		}
+		if _, ok := byYear[y][f.Wallet]; !ok {
+			byYear[y][f.Wallet] = map[string]decimal.Decimal{}
+		}
+		byYear[y][f.Wallet][f.Kind] = byYear[y][f.Wallet][f.Kind].Add(f.Value)
+		if f.Value.IsZero() {
+			unvalued[y]++
+		}
+	}
+	years := []int{}
+	for y := range byYear {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	for _, y := range years {
+		fmt.Fprintf(w, "Fees %d:\n", y)
+		wallets := []string{}
+		for wl := range byYear[y] {
+			wallets = append(wallets, wl)
+		}
+		sort.Strings(wallets)
+		lines := [][]string{{"Wallet", "Trading", "Network", "Margin", "Total"}}
+		totals := map[string]decimal.Decimal{}
+		line := func(name string, fees map[string]decimal.Decimal) []string {
+			cells := []string{name}
+			sum := decimal.Zero
+			for _, k := range kinds {
+				cells = append(cells, formatMoney(fees[k]))
+				sum = sum.Add(fees[k])
+			}
+			return append(cells, formatMoney(sum))
+		}
+		for _, wl := range wallets {
+			lines = append(lines, line(wl, byYear[y][wl]))
+			for _, k := range kinds {
+				totals[k] = totals[k].Add(byYear[y][wl][k])
+			}
+		}
+		lines = append(lines, line("Total", totals))
+		writeColumns(w, lines, make([][]int, len(lines)), 1, false)
+		if n := unvalued[y]; n > 0 {
+			fmt.Fprintf(w, "  %d fees in crypto could not be valued and count as zero (-pricefile or -priceapi)\n", n)
+		}
+	}
+	return nil
+}
+
+// WriteWithholding lists per year the tax exchanges withheld at source (India's TDS on transfers
+// of virtual digital assets), to reconcile with the tax credit statement (Form 26AS).
+func WriteWithholding(w io.Writer, state *engine.State, yearFilter int) error {
+	byYear := map[int][]engine.Withholding{}
+	for _, wh := range state.Withheld {
+		if yearFilter != 0 && wh.Time.Year() != yearFilter {
+			continue
+		}
+		byYear[wh.Time.Year()] = append(byYear[wh.Time.Year()], wh)
+	}
+	years := []int{}
+	for y := range byYear {
+		years = append(years, y)
+	}
+	sort.Ints(years)
+	for _, y := range years {
+		fmt.Fprintf(w, "Tax withheld at source (TDS) %d:\n", y)
+		total := decimal.Zero
+		for _, wh := range byYear[y] {
+			fmt.Fprintf(w, "  %s %s %s: %s %s value=%s ref=%s\n", wh.Time.Format("2006-01-02"), wh.Wallet, wh.Commodity, wh.Amount.String(), wh.Currency, wh.Value.StringFixed(2), wh.ReferenceID)
+			total = total.Add(wh.Value)
+		}
+		fmt.Fprintf(w, "  total withheld=%s\n", total.StringFixed(2))
+	}
+	return nil
+}
+
+// WriteImportIssues lists the input rows the importers skipped, with the reason.
+func WriteImportIssues(w io.Writer, state *engine.State, yearFilter int) error {
+	if len(state.ImportIssues) == 0 {
+		return nil
+	}
+	fmt.Fprintf(w, "Import issues (%d rows skipped):\n", len(state.ImportIssues))
+	for _, is := range state.ImportIssues {
+		fmt.Fprintf(w, "  %s:%d (%s): %s\n", is.File, is.Line, is.Format, is.Reason)
+	}
+	return nil
+}
+
+// WriteReconciliation compares the declared balances (-balances) with the computed inventory and
+// lists the mismatches with their difference.
+func WriteReconciliation(w io.Writer, state *engine.State, yearFilter int) error {
+	checked, mismatched := 0, 0
+	for _, r := range state.Reconciliation {
+		if yearFilter != 0 && r.At.Year() != yearFilter {
+			continue
+		}
+		if checked == 0 {
+			fmt.Fprintln(w, "Balance reconciliation:")
+		}
+		checked++
+		if r.OK {
+			continue
+		}
+		mismatched++
+		wallet := r.Wallet
+		if wallet == "" {
+			wallet = "all wallets"
+		}
+		fmt.Fprintf(w, "  MISMATCH %s %s %s: declared=%s computed=%s difference=%s\n", r.At.Format("2006-01-02"), wallet, r.Commodity, r.Balance.String(), r.Computed.String(), r.Difference.String())
+	}
+	if checked > 0 {
+		fmt.Fprintf(w, "  %d of %d balances match\n", checked-mismatched, checked)
+	}
+	return nil
+}
+
+// holdingsAsOf returns the lots held at the end of year, or the current inventory when year is 0.
+// Years after the last processed tx use the final inventory; years before the first are empty.
+// valuationTime is the time the holdings of holdingsAsOf are valued at: the end of year, else
+// State.AsOf, else now.
+func valuationTime(state *engine.State, year int) time.Time {
+	switch {
+	case year != 0:
+		return time.Date(year, 12, 31, 23, 59, 59, 0, time.UTC)
+	case !state.AsOf.IsZero():
+		return state.AsOf
+	}
+	return time.Now().UTC()
+}
+
+func holdingsAsOf(state *engine.State, year int) map[string]map[string][]engine.InventoryEntry {
+	if year == 0 {
+		return state.Inventories
+	}
+	if snap, ok := state.YearEnd[year]; ok {
+		return snap
+	}
+	best := 0
+	for y := range state.YearEnd {
+		if y < year && y > best {
+			best = y
+		}
+	}
+	if best == 0 {
+		return nil
+	}
+	return state.YearEnd[best]
+}
+
+// writeHoldings prints the remaining lots per wallet and commodity as of Dec 31 of yearFilter
+// (or the end of the processed data when yearFilter is 0).
+func writeHoldings(w io.Writer, state *engine.State, yearFilter int) error {
+	held := holdingsAsOf(state, yearFilter)
+	switch {
+	case yearFilter != 0:
+		fmt.Fprintf(w, "Holdings on %d-12-31:\n", yearFilter)
+	case !state.AsOf.IsZero():
+		fmt.Fprintf(w, "Holdings on %s:\n", state.AsOf.Format("2006-01-02"))
+	default:
+		fmt.Fprintln(w, "Holdings at end of data:")
+	}
+	wallets := []string{}
+	for wl := range held {
+		wallets = append(wallets, wl)
+	}
+	sort.Strings(wallets)
+	for _, wl := range wallets {
+		commods := []string{}
+		for c, lots := range held[wl] {
+			if len(lots) > 0 {
+				commods = append(commods, c)
+			}
+		}
+		if len(commods) == 0 {
+			continue
+		}
+		sort.Strings(commods)
+		fmt.Fprintf(w, "  Wallet: %s\n", wl)
+		for _, c := range commods {
+			lots := held[wl][c]
+			amount := decimal.Zero
+			basis := decimal.Zero
+			oldest := lots[0].Time
+			for _, lot := range lots {
+				amount = amount.Add(lot.Amount)
+				basis = basis.Add(lot.TotalCost)
+				if lot.Time.Before(oldest) {
+					oldest = lot.Time
+				}
+			}
+			avg := decimal.Zero
+			if !amount.IsZero() {
+				avg = basis.Div(amount)
+			}
+			fmt.Fprintf(w, "    %s: amount=%s avg_cost=%s basis=%s oldest=%s lots=%d\n",
+				c, amount.String(), avg.StringFixed(2), basis.StringFixed(2), oldest.Format("2006-01-02"), len(lots))
+		}
+	}
+	return nil
+}
+
+// writeUnrealized values the holdings of writeHoldings at the year-end price of yearFilter (today's
+// price when yearFilter is 0) and reports unrealized gain/loss split by holding period.
+func writeUnrealized(w io.Writer, state *engine.State, yearFilter int) error {
+	if state.Prices == nil {
+		return fmt.Errorf("unrealized gains need a price source (-pricefile or -priceapi)")
+	}
+	at := valuationTime(state, yearFilter)
+	held := holdingsAsOf(state, yearFilter)
+	fmt.Fprintf(w, "Unrealized gains at %s prices (%s):\n", at.Format("2006-01-02"), state.PriceCurrency)
+	wallets := []string{}
+	for wl := range held {
+		wallets = append(wallets, wl)
+	}
+	sort.Strings(wallets)
+	totalValue, totalBasis := decimal.Zero, decimal.Zero
+	for _, wl := range wallets {
+		commods := []string{}
+		for c, lots := range held[wl] {
+			if len(lots) > 0 {
+				commods = append(commods, c)
+			}
+		}
+		if len(commods) == 0 {
+			continue
+		}
+		sort.Strings(commods)
+		fmt.Fprintf(w, "  Wallet: %s\n", wl)
+		for _, c := range commods {
+			lots := held[wl][c]
+			amount, basis := decimal.Zero, decimal.Zero
+			for _, lot := range lots {
+				amount = amount.Add(lot.Amount)
+				basis = basis.Add(lot.TotalCost)
+			}
+			price, err := state.Prices.Price(c, state.PriceCurrency, at)
+			if err != nil {
+				if errors.Is(err, engine.ErrOffline) {
+					return err
+				}
+				state.Warnf("UNREALIZED: no price for %s/%s on %s: %v", c, state.PriceCurrency, at.Format("2006-01-02"), err)
+				fmt.Fprintf(w, "    %s: amount=%s basis=%s price=n/a\n", c, amount.String(), basis.StringFixed(2))
+				continue
+			}
+			short, long := decimal.Zero, decimal.Zero
+			for _, lot := range lots {
+				g := price.Mul(lot.Amount).Sub(lot.TotalCost)
+				if state.IsLongTerm(lot.Time, at) {
+					long = long.Add(g)
+				} else {
+					short = short.Add(g)
+				}
+			}
+			value := price.Mul(amount)
+			totalValue = totalValue.Add(value)
+			totalBasis = totalBasis.Add(basis)
+			fmt.Fprintf(w, "    %s: amount=%s basis=%s price=%s value=%s unrealized=%s (short=%s long=%s)\n",
+				c, amount.String(), basis.StringFixed(2), price.StringFixed(2), value.StringFixed(2), value.Sub(basis).StringFixed(2), short.StringFixed(2), long.StringFixed(2))
+		}
+	}
+	fmt.Fprintf(w, "  Total: basis=%s value=%s unrealized=%s\n", totalBasis.StringFixed(2), totalValue.StringFixed(2), totalValue.Sub(totalBasis).StringFixed(2))
+	return nil
+}
+
+// harvestLot is an open lot worth less than its basis, see writeHarvest.
+type harvestLot struct {
+	wallet    string
+	commodity string
+	lot       engine.InventoryEntry
+	value     decimal.Decimal
+	loss      decimal.Decimal
+	long      bool
+}
+
+// writeHarvest lists the lots held at the year-end price of yearFilter (today's price when
+// yearFilter is 0) that are worth less than their basis: the loss a sale would realize, largest
+// first, with the days held and the holding period the loss would fall in.
+func writeHarvest(w io.Writer, state *engine.State, yearFilter int) error {
+	if state.Prices == nil {
+		return fmt.Errorf("tax-loss harvesting needs a price source (-pricefile or -priceapi)")
+	}
+	at := valuationTime(state, yearFilter)
+	held := holdingsAsOf(state, yearFilter)
+	prices := map[string]decimal.Decimal{}
+	missing := map[string]bool{}
+	var rows []harvestLot
+	for wl, commods := range held {
+		for c, lots := range commods {
+			if len(lots) == 0 || missing[c] {
+				continue
+			}
+			price, ok := prices[c]
+			if !ok {
+				p, err := state.Prices.Price(c, state.PriceCurrency, at)
+				if err != nil {
+					if errors.Is(err, engine.ErrOffline) {
+						return err
+					}
+					state.Warnf("HARVEST: no price for %s/%s on %s: %v", c, state.PriceCurrency, at.Format("2006-01-02"), err)
+					missing[c] = true
+					continue
+				}
+				price, prices[c] = p, p
+			}
+			for _, lot := range lots {
+				value := price.Mul(lot.Amount)
+				if !value.LessThan(lot.TotalCost) {
+					continue
+				}
+				rows = append(rows, harvestLot{
+					wallet: wl, commodity: c, lot: lot, value: value,
+					loss: value.Sub(lot.TotalCost), long: state.IsLongTerm(lot.Time, at),
+				})
+			}
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if c := a.loss.Cmp(b.loss); c != 0 {
+			return c < 0
+		}
+		if a.wallet != b.wallet {
+			return a.wallet < b.wallet
+		}
+		if a.commodity != b.commodity {
+			return a.commodity < b.commodity
+		}
+		return a.lot.Time.Before(b.lot.Time)
+	})
+	fmt.Fprintf(w, "Tax-loss harvesting at %s prices (%s):\n", at.Format("2006-01-02"), state.PriceCurrency)
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "  No lots held at a loss.")
+		return nil
+	}
+	short, long := decimal.Zero, decimal.Zero
+	for _, r := range rows {
+		term := "short"
+		if r.long {
+			term = "long"
+			long = long.Add(r.loss)
+		} else {
+			short = short.Add(r.loss)
+		}
+		days := int(at.Sub(r.lot.Time).Hours() / 24)
+		fmt.Fprintf(w, "  %s %s: lot=%s acquired=%s days=%d term=%s amount=%s basis=%s value=%s loss=%s\n",
+			r.wallet, r.commodity, r.lot.ID, r.lot.Time.Format("2006-01-02"), days, term,
+			r.lot.Amount.String(), r.lot.TotalCost.StringFixed(2), r.value.StringFixed(2), r.loss.StringFixed(2))
+	}
+	fmt.Fprintf(w, "  Total: short=%s long=%s harvestable=%s\n", short.StringFixed(2), long.StringFixed(2), short.Add(long).StringFixed(2))
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// writeWertschriften prints per year what a private investor declares in Switzerland: the
+// holdings at their value on December 31 for the Wertschriftenverzeichnis (wealth tax), and staking,
+// mining and other income by type. Capital gains on private wealth are tax-free (Art. 16 Abs. 3
+// DBG); their total is shown for information only.
+func writeWertschriften(w io.Writer, state *engine.State, yearFilter int) error {
+	if state.Prices == nil {
+		return fmt.Errorf("the wertschriften report needs a price source (-pricefile or -priceapi)")
+	}
+	byYear := map[int][]engine.IncomeEvent{}
+	for _, in := range state.Incomes {
+		byYear[in.Time.Year()] = append(byYear[in.Time.Year()], in)
+	}
+	gains := map[int]decimal.Decimal{}
+	for _, d := range state.Disposals {
+		gains[d.Disposed.Year()] = gains[d.Disposed.Year()].Add(d.Gain)
+	}
+	seen := map[int]bool{}
+	for y := range state.YearEnd {
+		seen[y] = true
+	}
+	for y := range byYear {
+		seen[y] = true
+	}
+	years := []int{}
+	for y := range seen {
+		if yearFilter == 0 || y == yearFilter {
+			years = append(years, y)
+		}
+	}
+	if yearFilter != 0 && len(years) == 0 {
+		years = append(years, yearFilter)
+	}
+	sort.Ints(years)
+	for _, y := range years {
+		at := time.Date(y, 12, 31, 23, 59, 59, 0, time.UTC)
+		fmt.Fprintf(w, "Wertschriftenverzeichnis %d - Kryptowährungen (Steuerwert per 31.12.%d, %s)\n", y, y, state.PriceCurrency)
+		amounts := map[string]decimal.Decimal{}
+		for _, commods := range holdingsAsOf(state, y) {
+			for c, lots := range commods {
+				for _, lot := range lots {
+					amounts[c] = amounts[c].Add(lot.Amount)
+				}
+			}
+		}
+		assets := []string{}
+		for c, a := range amounts {
+			if !a.IsZero() {
+				assets = append(assets, c)
+			}
+		}
+		sort.Strings(assets)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "Bezeichnung\tMenge\tKurs 31.12.\tSteuerwert\t")
+		wealth := decimal.Zero
+		for _, c := range assets {
+			price, err := state.Prices.Price(c, state.PriceCurrency, at)
+			if err != nil {
+				if errors.Is(err, engine.ErrOffline) {
+					return err
+				}
+				state.Warnf("WERTSCHRIFTEN: no price for %s/%s on %s: %v", c, state.PriceCurrency, at.Format("2006-01-02"), err)
+				fmt.Fprintf(tw, "%s\t%s\tn/a\tn/a\t\n", c, amounts[c].String())
+				continue
+			}
+			value := price.Mul(amounts[c])
+			wealth = wealth.Add(value)
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", c, amounts[c].String(), price.StringFixed(2), value.StringFixed(2))
+		}
+		fmt.Fprintf(tw, "Total Steuerwert\t\t\t%s\t\n", wealth.StringFixed(2))
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		// income per type and asset, in the order of first receipt
+		type key struct{ typ, commodity string }
+		amount, value := map[key]decimal.Decimal{}, map[key]decimal.Decimal{}
+		keys := []key{}
+		for _, in := range byYear[y] {
+			k := key{engine.NormalizeType(in.Type), in.Commodity}
+			if _, ok := value[k]; !ok {
+				keys = append(keys, k)
+			}
+			amount[k] = amount[k].Add(in.Amount)
+			value[k] = value[k].Add(in.Value)
+		}
+		sort.SliceStable(keys, func(i, j int) bool { return keys[i].typ < keys[j].typ })
+		if len(keys) > 0 {
+			fmt.Fprintf(w, "  Erträge (steuerbares Einkommen, Wert bei Zufluss):\n")
+			tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+			fmt.Fprintln(tw, "Art\tBezeichnung\tMenge\tErtrag\t")
+			income := decimal.Zero
+			for _, k := range keys {
+				income = income.Add(value[k])
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", k.typ, k.commodity, amount[k].String(), value[k].StringFixed(2))
+			}
+			fmt.Fprintf(tw, "Total Erträge\t\t\t%s\t\n", income.StringFixed(2))
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+		}
+		if g, ok := gains[y]; ok {
+			fmt.Fprintf(w, "  Kapitalgewinne/-verluste aus Privatvermögen (steuerfrei, nicht zu deklarieren): %s\n", g.StringFixed(2))
+		}
+		if state.PriceCurrency != "CHF" {
+			fmt.Fprintf(w, "  Beträge in %s; die Steuererklärung wird in Franken eingereicht (-base CHF)\n", state.PriceCurrency)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// ANSI colors of gains (green) and losses (red) in a terminal.
+const (
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiReset = "\x1b[0m"
+)
+
+// formatMoney formats d with two decimals and comma thousands separators: -1,234.56.
+func formatMoney(d decimal.Decimal) string {
+	s := d.StringFixed(2)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, frac = s[:i], s[i:]
+	}
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	return sign + b.String() + frac
+}
+
+// gainsRow is one wallet/asset line of a summary section.
+type gainsRow struct {
+	wallet    string
+	commodity string
+	gains     engine.Gains
+}
+
+// addGains adds the amounts of b to a.
+func addGains(a *engine.Gains, b engine.Gains) {
+	a.Short = a.Short.Add(b.Short)
+	a.Long = a.Long.Add(b.Long)
+	a.Income = a.Income.Add(b.Income)
+	a.MarginFees = a.MarginFees.Add(b.MarginFees)
+	a.Casualty = a.Casualty.Add(b.Casualty)
+}
+
+// writeGainsTable prints rows (sorted by wallet) as aligned columns (wallet, asset, short, long,
+// income, net = short + long + income, and margin fees and casualty losses when any row has them),
+// a total per wallet with several assets when there are several wallets, and the section total.
+// color colors the amounts by sign.
+func writeGainsTable(w io.Writer, rows []gainsRow, color bool) {
+	if len(rows) == 0 {
+		return
+	}
+	var total engine.Gains
+	margin, casualty := false, false
+	perWallet := map[string]int{}
+	for _, r := range rows {
+		addGains(&total, r.gains)
+		margin = margin || !r.gains.MarginFees.IsZero()
+		casualty = casualty || !r.gains.Casualty.IsZero()
+		perWallet[r.wallet]++
+	}
+	header := []string{"Wallet", "Asset", "Short", "Long", "Income", "Net"}
+	if margin {
+		header = append(header, "Margin fees")
+	}
+	if casualty {
+		header = append(header, "Casualty loss")
+	}
+	// cells holds the plain text, signs the values colored by sign (short, long, income)
+	line := func(wallet, commodity string, g engine.Gains) ([]string, []int) {
+		net := g.Short.Add(g.Long).Add(g.Income)
+		cells := []string{wallet, commodity, formatMoney(g.Short), formatMoney(g.Long), formatMoney(g.Income), formatMoney(net)}
+		signs := []int{0, 0, g.Short.Sign(), g.Long.Sign(), g.Income.Sign(), net.Sign()}
+		if margin {
+			cells = append(cells, formatMoney(g.MarginFees))
+			signs = append(signs, 0)
+		}
+		if casualty {
+			cells = append(cells, formatMoney(g.Casualty))
+			signs = append(signs, 0)
+		}
+		return cells, signs
+	}
+	lines := [][]string{header}
+	signs := [][]int{make([]int, len(header))}
+	var sub engine.Gains
+	for i, r := range rows {
+		c, s := line(r.wallet, r.commodity, r.gains)
+		lines, signs = append(lines, c), append(signs, s)
+		addGains(&sub, r.gains)
+		if i == len(rows)-1 || rows[i+1].wallet != r.wallet {
+			if len(perWallet) > 1 && perWallet[r.wallet] > 1 {
+				c, s := line(r.wallet, "Total", sub)
+				lines, signs = append(lines, c), append(signs, s)
+			}
+			sub = engine.Gains{}
+		}
+	}
+	c, s := line("Total", "", total)
+	lines, signs = append(lines, c), append(signs, s)
+	writeAligned(w, lines, signs, color)
+}
+
+// writeAligned prints lines as columns padded to the widest cell: the first two left aligned as
+// text, the others right aligned and, with color, colored by the sign given in signs.
+func writeAligned(w io.Writer, lines [][]string, signs [][]int, color bool) {
+	writeColumns(w, lines, signs, 2, color)
+}
+
+// writeColumns is writeAligned with the first text columns left aligned.
+func writeColumns(w io.Writer, lines [][]string, signs [][]int, text int, color bool) {
+	widths := make([]int, len(lines[0]))
+	for _, l := range lines {
+		for i, cell := range l {
+			if n := len([]rune(cell)); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	for li, l := range lines {
+		var b strings.Builder
+		b.WriteString("  ")
+		for i, cell := range l {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			pad := strings.Repeat(" ", widths[i]-len([]rune(cell)))
+			if i < text {
+				// text columns are left aligned; no trailing spaces after the last one
+				b.WriteString(cell)
+				if i < len(l)-1 {
+					b.WriteString(pad)
+				}
+				continue
+			}
+			b.WriteString(pad)
+			switch {
+			case color && signs[li][i] < 0:
+				b.WriteString(ansiRed + cell + ansiReset)
+			case color && signs[li][i] > 0:
+				b.WriteString(ansiGreen + cell + ansiReset)
+			default:
+				b.WriteString(cell)
+			}
+		}
+		fmt.Fprintln(w, b.String())
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package report
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+	"github.com/shopspring/decimal"
+)
+
+// xlsxSheet is one worksheet of a workbook written by writeXLSXWorkbook. Cells may be
+// strings, decimal.Decimal, ints or float64; the first row is treated as a header.
+type xlsxSheet struct {
+	Name string
+	Rows [][]interface{}
+}
+
+// writeXLSX exports the results as a multi-sheet Excel workbook.
+func writeXLSX(w io.Writer, state *engine.State, yearFilter int) error {
+	inYear := func(t time.Time) bool { return yearFilter == 0 || t.Year() == yearFilter }
+
+	summary := xlsxSheet{Name: "Summary", Rows: [][]interface{}{{"Year", "Wallet", "Commodity", "Short", "Long", "Income"}}}
+	years := []int{}
+	for y := range state.TaxYears {
+		if yearFilter == 0 || y == yearFilter {
+			years = append(years, y)
+		}
+	}
+	sort.Ints(years)
+	for _, y := range years {
+		wallets := []string{}
+		for wl := range state.TaxYears[y] {
+			wallets = append(wallets, wl)
+		}
+		sort.Strings(wallets)
+		for _, wl := range wallets {
+			commods := []string{}
+			for c := range state.TaxYears[y][wl] {
+				commods = append(commods, c)
+			}
+			sort.Strings(commods)
+			for _, c := range commods {
+				g := state.TaxYears[y][wl][c]
+				summary.Rows = append(summary.Rows, []interface{}{y, wl, c, g.Short, g.Long, g.Income})
+			}
+		}
+	}
+
+	disposals := xlsxSheet{Name: "Disposals", Rows: [][]interface{}{{"Wallet", "Commodity", "Acquired", "Disposed", "Amount", "Unit cost", "Cost basis", "Proceeds", "Fee", "Gain", "Holding days", "Term", "Source files", "Reference"}}}
+	for _, d := range state.Disposals {
+		if !inYear(d.Disposed) {
+			continue
+		}
+		term := "short"
+		if d.Long {
+			term = "long"
+		}
+		disposals.Rows = append(disposals.Rows, []interface{}{d.Wallet, d.Commodity, d.Acquired.Format("2006-01-02 15:04:05"), d.Disposed.Format("2006-01-02 15:04:05"),
+			d.Amount, d.UnitCost, d.CostBasis, d.Proceeds, d.Fee, d.Gain, d.HoldingDays, term, strings.Join(d.SourceFiles, ";"), d.ReferenceID})
+	}
+
+	income := xlsxSheet{Name: "Income", Rows: [][]interface{}{{"Time", "Wallet", "Commodity", "Type", "Amount", "Value", "Source file", "Reference"}}}
+	for _, in := range state.Incomes {
+		if !inYear(in.Time) {
+			continue
+		}
+		income.Rows = append(income.Rows, []interface{}{in.Time.Format("2006-01-02 15:04:05"), in.Wallet, in.Commodity, in.Type, in.Amount, in.Value, in.SourceFile, in.ReferenceID})
+	}
+
+	holdings := xlsxSheet{Name: "Holdings", Rows: [][]interface{}{{"Year end", "Wallet", "Commodity", "Acquired", "Amount", "Unit cost", "Cost basis", "Source files"}}}
+	holdingYears := []int{}
+	for y := range state.YearEnd {
+		if yearFilter == 0 || y == yearFilter {
+			holdingYears = append(holdingYears, y)
+		}
+	}
+	sort.Ints(holdingYears)
+	for _, y := range holdingYears {
+		wallets := []string{}
+		for wl := range state.YearEnd[y] {
+			wallets = append(wallets, wl)
+		}
+		sort.Strings(wallets)
+		for _, wl := range wallets {
+			commods := []string{}
+			for c := range state.YearEnd[y][wl] {
+				commods = append(commods, c)
+			}
+			sort.Strings(commods)
+			for _, c := range commods {
+				for _, lot := range state.YearEnd[y][wl][c] {
+					holdings.Rows = append(holdings.Rows, []interface{}{fmt.Sprintf("%d-12-31", y), wl, c, lot.Time.Format("2006-01-02"), lot.Amount, lot.UnitCost, lot.TotalCost, strings.Join(lot.SourceFiles, ";")})
+				}
+			}
+		}
+	}
+
+	warnings := xlsxSheet{Name: "Warnings", Rows: [][]interface{}{{"Warning"}}}
+	for _, msg := range state.Warnings {
+		warnings.Rows = append(warnings.Rows, []interface{}{msg})
+	}
+
+	return writeXLSXWorkbook(w, []xlsxSheet{summary, disposals, income, holdings, warnings})
+}
+
+// writeXLSXWorkbook writes a minimal Office Open XML workbook using inline strings only.
+func writeXLSXWorkbook(w io.Writer, sheets []xlsxSheet) error {
+	zw := zip.NewWriter(w)
+	put := func(name, body string) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, xml.Header+body)
+		return err
+	}
+	var ct, wb, rels strings.Builder
+	ct.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	wb.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	rels.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i, sh := range sheets {
+		n := i + 1
+		fmt.Fprintf(&ct, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		fmt.Fprintf(&wb, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sh.Name), n, n)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+	}
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(sheets)+1)
+	ct.WriteString(`</Types>`)
+	wb.WriteString(`</sheets></workbook>`)
+	rels.WriteString(`</Relationships>`)
+
+	if err := put("[Content_Types].xml", ct.String()); err != nil {
+		return err
+	}
+	if err := put("_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`); err != nil {
+		return err
+	}
+	if err := put("xl/workbook.xml", wb.String()); err != nil {
+		return err
+	}
+	if err := put("xl/_rels/workbook.xml.rels", rels.String()); err != nil {
+		return err
+	}
+	// style 1 = bold header row
+	if err := put("xl/styles.xml", `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>`+
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>`+
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>`+
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>`+
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`); err != nil {
+		return err
+	}
+	for i, sh := range sheets {
+		var b strings.Builder
+		b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+		for r, row := range sh.Rows {
+			fmt.Fprintf(&b, `<row r="%d">`, r+1)
+			style := ""
+			if r == 0 {
+				style = ` s="1"`
+			}
+			for c, cell := range row {
+				ref := xlsxColumn(c) + strconv.Itoa(r+1)
+				switch v := cell.(type) {
+				case decimal.Decimal:
+					fmt.Fprintf(&b, `<c r="%s"%s><v>%s</v></c>`, ref, style, v.String())
+				case int:
+					fmt.Fprintf(&b, `<c r="%s"%s><v>%d</v></c>`, ref, style, v)
+				case float64:
+					fmt.Fprintf(&b, `<c r="%s"%s><v>%s</v></c>`, ref, style, strconv.FormatFloat(v, 'f', -1, 64))
+				default:
+					fmt.Fprintf(&b, `<c r="%s"%s t="inlineStr"><is><t>%s</t></is></c>`, ref, style, xmlEscape(fmt.Sprint(v)))
+				}
+			}
+			b.WriteString(`</row>`)
+		}
+		b.WriteString(`</sheetData></worksheet>`)
+		if err := put(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), b.String()); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// xlsxColumn converts a zero-based column index to its spreadsheet letter (0 -> A, 26 -> AA).
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,365 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+// Package server exposes the calculator over HTTP: upload transaction files, run the
+// calculation and fetch the results as JSON or in any report format.
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cryptotax/engine"
+	"cryptotax/report"
+)
+
+// maxUpload limits the size of one uploaded file.
+const maxUpload = 64 << 20
+
+// CalculateFunc parses and processes the given files.
+type CalculateFunc func(files []string) ([]engine.Tx, *engine.State, error)
+
+// Server keeps the uploaded files in Dir and the result of the last calculation in memory.
+// Calculations run one at a time.
+type Server struct {
+	Dir       string
+	Calculate CalculateFunc
+	UI        bool // serve the web dashboard at /
+
+	mu           sync.Mutex
+	state        *engine.State
+	transactions int
+	calculated   time.Time
+}
+
+// New returns a server storing uploads in dir, which is created if needed.
+func New(dir string, calculate CalculateFunc) (*Server, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Server{Dir: dir, Calculate: calculate}, nil
+}
+
+// Handler returns the API routes:
+//
+//	GET    /api/files              list uploaded files
+//	POST   /api/files              upload files (multipart form, field "file", repeatable)
+//	PUT    /api/files/{name}       upload one file from the request body
+//	DELETE /api/files/{name}       remove an uploaded file
+//	POST   /api/calculate          process all uploaded files
+//	GET    /api/result?year=Y      full result of the last calculation as JSON
+//	GET    /api/reports/{format}   any -report format of the last calculation (?year=Y)
+//	GET    /api/transactions       processed transactions with the handler of each (?year=Y)
+//
+// With UI set, GET / serves the dashboard.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/files", s.listFiles)
+	mux.HandleFunc("POST /api/files", s.uploadForm)
+	mux.HandleFunc("PUT /api/files/{name}", s.uploadBody)
+	mux.HandleFunc("DELETE /api/files/{name}", s.deleteFile)
+	mux.HandleFunc("POST /api/calculate", s.calculate)
+	mux.HandleFunc("GET /api/result", s.result)
+	mux.HandleFunc("GET /api/reports/{format}", s.report)
+	mux.HandleFunc("GET /api/transactions", s.listTransactions)
+	if s.UI {
+		mux.HandleFunc("GET /{$}", serveUI)
+	}
+	return mux
+}
+
+type fileInfo struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+func (s *Server) files() ([]fileInfo, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	out := []fileInfo{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, fileInfo{Name: e.Name(), Size: info.Size(), Modified: info.ModTime().UTC()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (s *Server) listFiles(w http.ResponseWriter, r *http.Request) {
+	files, err := s.files()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, files)
+}
+
+// filePath maps an uploaded file name to its path in Dir. The name becomes the default wallet,
+// so it is kept as given but must not leave the directory.
+func (s *Server) filePath(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || name != filepath.Base(name) || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid file name %q", name)
+	}
+	return filepath.Join(s.Dir, name), nil
+}
+
+func (s *Server) save(name string, body io.Reader) (fileInfo, error) {
+	path, err := s.filePath(name)
+	if err != nil {
+		return fileInfo{}, err
+	}
+	data, err := io.ReadAll(io.LimitReader(body, maxUpload+1))
+	if err != nil {
+		return fileInfo{}, err
+	}
+	if len(data) > maxUpload {
+		return fileInfo{}, fmt.Errorf("%s: file larger than %d bytes", name, maxUpload)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fileInfo{}, err
+	}
+	return fileInfo{Name: name, Size: int64(len(data)), Modified: time.Now().UTC()}, nil
+}
+
+func (s *Server) uploadForm(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(maxUpload); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	headers := r.MultipartForm.File["file"]
+	if len(headers) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New(`no "file" field in the form`))
+		return
+	}
+	saved := []fileInfo{}
+	for _, h := range headers {
+		f, err := h.Open()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		info, err := s.save(h.Filename, f)
+		f.Close()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		saved = append(saved, info)
+	}
+	writeJSON(w, http.StatusCreated, saved)
+}
+
+func (s *Server) uploadBody(w http.ResponseWriter, r *http.Request) {
+	info, err := s.save(r.PathValue("name"), r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, info)
+}
+
+func (s *Server) deleteFile(w http.ResponseWriter, r *http.Request) {
+	path, err := s.filePath(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			writeError(w, http.StatusNotFound, err)
+		} else {
+			writeError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// calculation is the response of POST /api/calculate.
+type calculation struct {
+	Files        []string             `json:"files"`
+	Transactions int                  `json:"transactions"`
+	Warnings     []string             `json:"warnings"`
+	ImportIssues []engine.ImportIssue `json:"import_issues"`
+	Calculated   time.Time            `json:"calculated"`
+}
+
+func (s *Server) calculate(w http.ResponseWriter, r *http.Request) {
+	files, err := s.files()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if len(files) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("no files uploaded"))
+		return
+	}
+	names, paths := []string{}, []string{}
+	for _, f := range files {
+		names = append(names, f.Name)
+		paths = append(paths, filepath.Join(s.Dir, f.Name))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	txs, state, err := s.Calculate(paths)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	s.state, s.transactions, s.calculated = state, len(txs), time.Now().UTC()
+	warnings := state.Warnings
+	if warnings == nil {
+		warnings = []string{}
+	}
+	issues := state.ImportIssues
+	if issues == nil {
+		issues = []engine.ImportIssue{}
+	}
+	writeJSON(w, http.StatusOK, calculation{Files: names, Transactions: s.transactions, Warnings: warnings, ImportIssues: issues, Calculated: s.calculated})
+}
+
+// lastState returns the state of the last calculation and the ?year= filter of the request.
+func (s *Server) lastState(w http.ResponseWriter, r *http.Request) (*engine.State, int, bool) {
+	year := 0
+	if v := r.URL.Query().Get("year"); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid year %q", v))
+			return nil, 0, false
+		}
+		year = y
+	}
+	s.mu.Lock()
+	state := s.state
+	s.mu.Unlock()
+	if state == nil {
+		writeError(w, http.StatusConflict, errors.New("nothing calculated yet: POST /api/calculate first"))
+		return nil, 0, false
+	}
+	return state, year, true
+}
+
+func (s *Server) result(w http.ResponseWriter, r *http.Request) {
+	state, year, ok := s.lastState(w, r)
+	if !ok {
+		return
+	}
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf, state, year); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
+
+// transaction is one processed row in GET /api/transactions.
+type transaction struct {
+	Time        time.Time `json:"time"`
+	Wallet      string    `json:"wallet"`
+	Type        string    `json:"type"`
+	Handler     string    `json:"handler"` // how the engine treated the row (buy, sell, income, transfer, ...)
+	Commodity   string    `json:"commodity"`
+	Amount      string    `json:"amount"`
+	Cost        string    `json:"cost"`
+	Fee         string    `json:"fee"`
+	Currency    string    `json:"currency"`
+	SourceFile  string    `json:"source_file"`
+	SourceLine  int       `json:"source_line"`
+	ReferenceID string    `json:"reference_id"`
+}
+
+func (s *Server) listTransactions(w http.ResponseWriter, r *http.Request) {
+	state, year, ok := s.lastState(w, r)
+	if !ok {
+		return
+	}
+	out := []transaction{}
+	for _, je := range state.Journal {
+		tx := je.Tx
+		if year != 0 && tx.Time.Year() != year {
+			continue
+		}
+		out = append(out, transaction{
+			Time:        tx.Time,
+			Wallet:      tx.Wallet,
+			Type:        tx.Type,
+			Handler:     je.Handler,
+			Commodity:   tx.Commodity,
+			Amount:      tx.Amount.String(),
+			Cost:        tx.Cost.String(),
+			Fee:         tx.Fee.String(),
+			Currency:    tx.Currency,
+			SourceFile:  filepath.Base(tx.SourceFile),
+			SourceLine:  tx.SourceLine,
+			ReferenceID: tx.ReferenceID,
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// reportTypes are the content types of the binary and markup report formats; the rest are text.
+var reportTypes = map[string]string{
+	"html": "text/html; charset=utf-8",
+	"pdf":  "application/pdf",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+func (s *Server) report(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(r.PathValue("format"))
+	write, ok := report.Writers[format]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown report format %q", format))
+		return
+	}
+	state, year, ok := s.lastState(w, r)
+	if !ok {
+		return
+	}
+	// render first so an error can still be reported with a proper status
+	var buf bytes.Buffer
+	if err := write(&buf, state, year); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	contentType := reportTypes[format]
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(buf.Bytes())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
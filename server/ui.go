@@ -0,0 +1,21 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package server
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// indexHTML is the dashboard: upload and calculate, yearly summaries, holdings, income and a
+// searchable transaction table, all built from the API in the browser.
+//
+//go:embed ui/index.html
+var indexHTML []byte
+
+func serveUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexHTML)
+}
@@ -0,0 +1,353 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+// Package taxcalc embeds the calculator in other Go programs (bots, dashboards): a Calculator is
+// configured with options, fed transactions or export files, and processed into typed results
+// or any of the report formats of the command line.
+//
+//	calc, err := taxcalc.New(taxcalc.WithMethod("hifo"), taxcalc.WithJurisdiction("us"))
+//	if err != nil { ... }
+//	if err := calc.AddFile("kraken.csv"); err != nil { ... }
+//	res, err := calc.Process()
+//	gains := res.Years[2024]
+package taxcalc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"cryptotax/engine"
+	"cryptotax/importer"
+	"cryptotax/report"
+)
+
+// Result is the outcome of Calculator.Process: gains and income per year, wallet and asset, the
+// disposals, the remaining inventory and the other sections of -output json.
+type Result = report.Result
+
+// Option configures a Calculator, see New.
+type Option func(*Calculator) error
+
+// WithMethod sets the cost basis method: fifo (the default), lifo, hifo or acb.
+func WithMethod(method string) Option {
+	return func(c *Calculator) error {
+		method = strings.ToLower(strings.TrimSpace(method))
+		if !engine.IsMethod(method) {
+			return fmt.Errorf("unknown method %q (expected %s)", method, strings.Join(engine.Methods, ", "))
+		}
+		c.method = method
+		return nil
+	}
+}
+
+// WithJurisdiction applies a country's rules (engine.Jurisdictions), like -jurisdiction.
+func WithJurisdiction(code string) Option {
+	return func(c *Calculator) error {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if _, ok := engine.Jurisdictions[code]; !ok {
+			return fmt.Errorf("unknown jurisdiction %q (expected %s)", code, engine.JurisdictionCodes())
+		}
+		c.jurisdiction = code
+		return nil
+	}
+}
+
+// WithBaseCurrency reports in cur: fiat costs and fees in other currencies are converted with the
+// price source, like -base.
+func WithBaseCurrency(cur string) Option {
+	return func(c *Calculator) error {
+		c.base = strings.ToUpper(strings.TrimSpace(cur))
+		return nil
+	}
+}
+
+// WithPrices sets the price source used to value income, crypto fees and holdings, e.g. a
+// pricing.FileSource or pricing.CoinGecko.
+func WithPrices(prices engine.PriceSource) Option {
+	return func(c *Calculator) error {
+		c.prices = prices
+		return nil
+	}
+}
+
+// WithTimezone takes years and dates in loc instead of UTC, like -tax-timezone.
+func WithTimezone(loc *time.Location) Option {
+	return func(c *Calculator) error {
+		if loc == nil {
+			return errors.New("nil time zone")
+		}
+		c.loc = loc
+		return nil
+	}
+}
+
+// WithWallets limits the calculation to the transactions of the given wallets.
+func WithWallets(wallets ...string) Option {
+	return func(c *Calculator) error {
+		c.wallets = append(c.wallets, wallets...)
+		return nil
+	}
+}
+
+// WithCommodities limits the calculation to the transactions of the given assets.
+func WithCommodities(commodities ...string) Option {
+	return func(c *Calculator) error {
+		c.commodities = append(c.commodities, commodities...)
+		return nil
+	}
+}
+
+// WithStrict fails on unreadable rows, unknown transaction types and coins removed that were never
+// acquired instead of warning, like -strict.
+func WithStrict() Option {
+	return func(c *Calculator) error {
+		c.strict = true
+		c.input.Strict = true
+		return nil
+	}
+}
+
+// WithAssetAliases adds "ALIAS=ASSET[,...]" aliases naming the same asset, like -asset-aliases.
+func WithAssetAliases(spec string) Option {
+	return func(c *Calculator) error {
+		return c.assets.AddAliases(spec)
+	}
+}
+
+// WithStablecoinsAsFiat treats stablecoins ("USDT,USDC", "XUSD=USD" or "all") as the fiat
+// currency they track, like -stablecoins-as-fiat.
+func WithStablecoinsAsFiat(spec string) Option {
+	return func(c *Calculator) error {
+		return c.assets.SetFiatEquivalents(spec)
+	}
+}
+
+// WithSourceTimezones sets the zones of timestamps without an offset in the files of AddFile
+// (ZONE, or SOURCE=ZONE entries for a file or format name), like -source-timezone.
+func WithSourceTimezones(spec string) Option {
+	return func(c *Calculator) error {
+		return c.input.SetSourceTimezones(spec)
+	}
+}
+
+// WithNumberFormats sets how numbers are written in generic CSV files of AddFile (en or eu, or
+// SOURCE=FORMAT entries for a file name or generic), like -number-format.
+func WithNumberFormats(spec string) Option {
+	return func(c *Calculator) error {
+		return c.input.SetNumberFormats(spec)
+	}
+}
+
+// WithFormats forces the format of the files of AddFile (FILE=FORMAT entries), like -format.
+func WithFormats(spec string) Option {
+	return func(c *Calculator) error {
+		return c.input.SetFormats(spec)
+	}
+}
+
+// WithSheets selects the sheet read from .xlsx files (SHEET, or FILE=SHEET entries), like -sheet.
+func WithSheets(spec string) Option {
+	return func(c *Calculator) error {
+		c.input.SetSheets(spec)
+		return nil
+	}
+}
+
+// WithWalletMap names the wallets of the files of AddFile by the rules of the CSV file at path,
+// like -wallet-map.
+func WithWalletMap(path string) Option {
+	return func(c *Calculator) error {
+		return c.input.LoadWalletMap(path)
+	}
+}
+
+// WithClassRules classifies the transactions by rules (first match wins) before processing, like
+// -classify; see importer.LoadClassRules to read them from a file.
+func WithClassRules(rules ...engine.ClassRule) Option {
+	return func(c *Calculator) error {
+		c.classRules = append(c.classRules, rules...)
+		return nil
+	}
+}
+
+// WithState calls configure on every new engine.State before processing, for the treatments
+// without an option of their own (State.AirdropTreatment, State.TransferWindow, ...). It runs
+// after the jurisdiction rules are applied, so it can override them.
+func WithState(configure func(*engine.State)) Option {
+	return func(c *Calculator) error {
+		c.configure = append(c.configure, configure)
+		return nil
+	}
+}
+
+// Calculator computes gains, income and holdings from the transactions added to it. It is not
+// safe for concurrent use.
+type Calculator struct {
+	method       string
+	jurisdiction string
+	base         string
+	prices       engine.PriceSource
+	loc          *time.Location
+	wallets      []string
+	commodities  []string
+	strict       bool
+	classRules   []engine.ClassRule
+	configure    []func(*engine.State)
+	assets       *engine.Assets
+	input        importer.Config
+
+	batches [][]engine.Tx
+	issues  []engine.ImportIssue
+	state   *engine.State // of the last Process; nil when transactions were added since
+}
+
+// New returns a Calculator configured with opts.
+func New(opts ...Option) (*Calculator, error) {
+	c := &Calculator{loc: time.UTC, assets: &engine.Assets{}}
+	c.input.Assets = c.assets
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// AddTransactions adds txs to the calculation. They can be in any order.
+func (c *Calculator) AddTransactions(txs ...engine.Tx) {
+	c.batches = append(c.batches, append([]engine.Tx{}, txs...))
+	c.state = nil
+}
+
+// AddFile reads an export file (CSV or .xlsx) with the importer detected from its header and adds
+// its transactions. Rows that cannot be read are skipped and listed in Result.ImportIssues, or
+// fail with WithStrict.
+func (c *Calculator) AddFile(path string) error {
+	txs, issues, err := c.input.ParseFile(path, c.wallets, false)
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	if c.strict && len(issues) > 0 {
+		return fmt.Errorf("error parsing %s: line %d: %s", path, issues[0].Line, issues[0].Reason)
+	}
+	c.AddTransactions(txs...)
+	c.issues = append(c.issues, issues...)
+	return nil
+}
+
+// Process runs the transactions added so far through the engine and returns the results of all
+// years. Each call starts from an empty inventory, so transactions can be added and Process
+// called again.
+func (c *Calculator) Process() (*Result, error) {
+	state, err := c.process()
+	if err != nil {
+		return nil, err
+	}
+	res := report.NewResult(state, 0)
+	return &res, nil
+}
+
+// State returns the engine state of the last Process (processing first when needed), for the
+// details Result does not carry such as the journal of every transaction.
+func (c *Calculator) State() (*engine.State, error) {
+	return c.process()
+}
+
+// Report writes the report format (a report.Writers key such as "disposals", "holdings" or
+// "xlsx", or "json" for the Result) for year (0 = all years) to w, processing first when needed.
+func (c *Calculator) Report(w io.Writer, format string, year int) error {
+	state, err := c.process()
+	if err != nil {
+		return err
+	}
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "json" {
+		return report.WriteJSON(w, state, year)
+	}
+	write, ok := report.Writers[format]
+	if !ok {
+		return fmt.Errorf("unknown report format %q (expected %s)", format, strings.Join(Formats(), ", "))
+	}
+	return write(w, state, year)
+}
+
+// Formats lists the report formats of Report.
+func Formats() []string {
+	formats := []string{"json"}
+	for f := range report.Writers {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// process builds the state from the options and the transactions, unless already done.
+func (c *Calculator) process() (*engine.State, error) {
+	if c.state != nil {
+		return c.state, nil
+	}
+	all := importer.MergeAndSort(c.batches)
+	all = c.filter(all)
+	for i := range all {
+		all[i].Time = all[i].Time.In(c.loc)
+	}
+	if c.base != "" {
+		if err := engine.ConvertToBase(all, c.base, c.prices, c.assets); err != nil {
+			return nil, fmt.Errorf("currency conversion error: %w", err)
+		}
+	}
+	state := engine.NewState(false, c.wallets, c.commodities)
+	state.Prices = c.prices
+	state.PriceCurrency = engine.DefaultCurrency
+	if c.base != "" {
+		state.PriceCurrency = c.base
+	}
+	state.BaseCurrency = c.base
+	state.Assets = c.assets
+	state.Strict = c.strict
+	state.ImportIssues = c.issues
+	state.ClassRules = c.classRules
+	if c.method != "" {
+		state.Method = c.method
+	}
+	if r, ok := engine.Jurisdictions[c.jurisdiction]; ok {
+		state.Rules = r
+		r.Configure(state)
+	}
+	for _, configure := range c.configure {
+		configure(state)
+	}
+	if err := engine.ProcessTransactions(state, all); err != nil {
+		return nil, fmt.Errorf("processing error: %w", err)
+	}
+	c.state = state
+	return state, nil
+}
+
+// filter keeps the transactions of the WithWallets wallets and WithCommodities assets.
+func (c *Calculator) filter(txs []engine.Tx) []engine.Tx {
+	wallets := map[string]bool{}
+	for _, w := range c.wallets {
+		wallets[w] = true
+	}
+	commodities := map[string]bool{}
+	for _, cm := range c.commodities {
+		commodities[strings.ToLower(cm)] = true
+	}
+	var kept []engine.Tx
+	for _, tx := range txs {
+		if len(wallets) > 0 && !wallets[tx.Wallet] {
+			continue
+		}
+		if len(commodities) > 0 && !commodities[strings.ToLower(tx.Commodity)] {
+			continue
+		}
+		kept = append(kept, tx)
+	}
+	return kept
+}
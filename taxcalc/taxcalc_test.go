@@ -0,0 +1,136 @@
+// Copyright (c) 2025-present Marko Kocić <marko@euptera.com>
+// SPDX-License-Identifier: EPL-2.0
+// See LICENSE for full license text.
+
+package taxcalc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cryptotax/engine"
+)
+
+func writeCSV(t *testing.T, name string, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func processFile(t *testing.T, c *Calculator, path string) *engine.State {
+	t.Helper()
+	if err := c.AddFile(path); err != nil {
+		t.Fatal(err)
+	}
+	s, err := c.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// TestCalculatorsAreIndependent reads one file with two calculators: the settings of one must not
+// leak into the other, whichever was created or used first.
+func TestCalculatorsAreIndependent(t *testing.T) {
+	path := writeCSV(t, "generic.csv",
+		"time,type,asset,amount,cost,currency,refid,wallet",
+		`2024-01-01 10:00:00,buy,WXT,"1,5","100,25",EUR,r1,w`,
+		"2024-01-02 10:00:00,buy,USDT,100,90,EUR,r2,w",
+	)
+	a, err := New(WithNumberFormats("eu"), WithAssetAliases("WXT=WIRTUAL"), WithSourceTimezones("Asia/Tokyo"),
+		WithStablecoinsAsFiat("USDT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb := processFile(t, b, path)
+	sa := processFile(t, a, path)
+
+	for _, tt := range []struct {
+		name      string
+		s         *engine.State
+		commodity string
+		amount    string
+		cost      string
+		time      time.Time
+		usdt      bool
+	}{
+		{"a", sa, "WIRTUAL", "1.5", "100.25", time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC), false},
+		{"b", sb, "WXT", "15", "10025", time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), true},
+	} {
+		lots := tt.s.Inventories["w"][tt.commodity]
+		if len(lots) != 1 {
+			t.Fatalf("%s: %d lots of %s, want 1 (inventory %v)", tt.name, len(lots), tt.commodity, tt.s.Inventories["w"])
+		}
+		lot := lots[0]
+		if lot.Amount.String() != tt.amount || lot.TotalCost.Round(2).String() != tt.cost || !lot.Time.Equal(tt.time) {
+			t.Errorf("%s: got %s %s costing %s at %s, want %s costing %s at %s", tt.name, lot.Amount.String(), tt.commodity,
+				lot.TotalCost.Round(2).String(), lot.Time, tt.amount, tt.cost, tt.time)
+		}
+		if _, held := tt.s.Inventories["w"]["USDT"]; held != tt.usdt {
+			t.Errorf("%s: USDT held %v, want %v", tt.name, held, tt.usdt)
+		}
+	}
+}
+
+func TestStrictIsPerCalculator(t *testing.T) {
+	path := writeCSV(t, "generic.csv",
+		"time,type,asset,amount,cost,currency,refid,wallet",
+		"2024-01-01 10:00:00,buy,BTC,1,$100,EUR,r1,w",
+	)
+	strict, err := New(WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lenient, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := strict.AddFile(path); err == nil {
+		t.Error("strict: malformed cost accepted")
+	}
+	if err := lenient.AddFile(path); err != nil {
+		t.Errorf("lenient: %v", err)
+	}
+}
+
+func TestWalletMapPerCalculator(t *testing.T) {
+	path := writeCSV(t, "exchange.csv",
+		"time,type,asset,amount,cost,currency,refid",
+		"2024-01-01 10:00:00,buy,BTC,1,100,EUR,r1",
+	)
+	rules := writeCSV(t, "wallets.csv",
+		"match,pattern,wallet",
+		"file,exchange*.csv,Exchange",
+	)
+	mapped, err := New(WithWalletMap(rules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unmapped, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name   string
+		c      *Calculator
+		wallet string
+	}{
+		{"mapped", mapped, "Exchange"},
+		{"unmapped", unmapped, "exchange.csv"},
+	} {
+		s := processFile(t, tt.c, path)
+		if _, ok := s.Inventories[tt.wallet]; !ok {
+			t.Errorf("%s: no wallet %q in %v", tt.name, tt.wallet, s.Inventories)
+		}
+	}
+}